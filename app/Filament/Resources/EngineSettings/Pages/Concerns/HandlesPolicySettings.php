@@ -57,6 +57,7 @@ private function policyFormData(string $mode, array $data): array
             'policy_'.$mode.'_smtp_read_timeout_ms' => $data['smtp_read_timeout_ms'] ?? $defaults['smtp_read_timeout_ms'] ?? null,
             'policy_'.$mode.'_max_mx_attempts' => $data['max_mx_attempts'] ?? $defaults['max_mx_attempts'] ?? null,
             'policy_'.$mode.'_max_concurrency_default' => $data['max_concurrency_default'] ?? $defaults['max_concurrency_default'] ?? null,
+            'policy_'.$mode.'_chunk_parallelism' => $data['chunk_parallelism'] ?? $defaults['chunk_parallelism'] ?? null,
             'policy_'.$mode.'_per_domain_concurrency' => $data['per_domain_concurrency'] ?? $defaults['per_domain_concurrency'] ?? null,
             'policy_'.$mode.'_catch_all_detection_enabled' => (bool) ($data['catch_all_detection_enabled'] ?? $defaults['catch_all_detection_enabled'] ?? false),
             'policy_'.$mode.'_global_connects_per_minute' => $data['global_connects_per_minute'] ?? $defaults['global_connects_per_minute'] ?? null,
@@ -91,6 +92,7 @@ private function splitPolicyData(array $data): array
                 'smtp_read_timeout_ms' => $this->toInt($entries[$prefix.'smtp_read_timeout_ms'] ?? null),
                 'max_mx_attempts' => $this->toInt($entries[$prefix.'max_mx_attempts'] ?? null),
                 'max_concurrency_default' => $this->toInt($entries[$prefix.'max_concurrency_default'] ?? null),
+                'chunk_parallelism' => max(1, $this->toInt($entries[$prefix.'chunk_parallelism'] ?? 1)),
                 'per_domain_concurrency' => $this->toInt($entries[$prefix.'per_domain_concurrency'] ?? null),
                 'catch_all_detection_enabled' => (bool) ($entries[$prefix.'catch_all_detection_enabled']
                     ?? data_get(config('engine.policy_defaults'), $mode.'.catch_all_detection_enabled', false)),
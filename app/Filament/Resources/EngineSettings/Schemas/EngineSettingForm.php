@@ -579,6 +579,12 @@ private static function policyFields(string $mode): array
                 ->numeric()
                 ->minValue(1)
                 ->required(),
+            TextInput::make($prefix.'chunk_parallelism')
+                ->label('Chunk parallelism')
+                ->numeric()
+                ->minValue(1)
+                ->maxValue(256)
+                ->helperText('Emails verified concurrently inside one chunk.'),
             TextInput::make($prefix.'per_domain_concurrency')
                 ->label('Per-domain concurrency')
                 ->numeric()
@@ -54,6 +54,12 @@ public static function configure(Schema $schema): Schema
                             ->numeric()
                             ->minValue(1)
                             ->required(),
+                        TextInput::make('chunk_parallelism')
+                            ->label('Chunk parallelism')
+                            ->numeric()
+                            ->minValue(1)
+                            ->maxValue(256)
+                            ->helperText('Emails verified concurrently inside one chunk.'),
                         TextInput::make('per_domain_concurrency')
                             ->label('Per-domain concurrency')
                             ->numeric()
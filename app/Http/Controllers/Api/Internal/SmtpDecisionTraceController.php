@@ -66,6 +66,7 @@ public function index(SmtpDecisionTraceIndexRequest $request): JsonResponse
                     'session_strategy_id' => $trace->session_strategy_id,
                     'attempt_route' => $trace->attempt_route,
                     'attempt_chain' => Arr::get($tracePayload, 'attempt_chain', []),
+                    'transcript' => Arr::get($tracePayload, 'transcript', []),
                     'observed_at' => $trace->observed_at?->toISOString(),
                     'created_at' => $trace->created_at?->toISOString(),
                 ];
@@ -116,6 +116,7 @@ public function __invoke(
             'valid_key' => $payload['valid_key'],
             'invalid_key' => $payload['invalid_key'],
             'risky_key' => $payload['risky_key'],
+            'transcript_key' => $payload['transcript_key'] ?? $chunk->transcript_key,
             'email_count' => $payload['email_count'] ?? $chunk->email_count,
             'valid_count' => $payload['valid_count'] ?? $chunk->valid_count,
             'invalid_count' => $payload['invalid_count'] ?? $chunk->invalid_count,
@@ -225,6 +226,7 @@ private function payloadMatches(VerificationJobChunk $chunk, array $payload, ?st
             'valid_key' => $payload['valid_key'] ?? null,
             'invalid_key' => $payload['invalid_key'] ?? null,
             'risky_key' => $payload['risky_key'] ?? null,
+            'transcript_key' => $payload['transcript_key'] ?? null,
             'email_count' => $payload['email_count'] ?? null,
             'valid_count' => $payload['valid_count'] ?? null,
             'invalid_count' => $payload['invalid_count'] ?? null,
@@ -0,0 +1,42 @@
+<?php
+
+namespace App\Http\Controllers\Api\Verifier;
+
+use App\Http\Requests\Verifier\ChunkLeaseRequest;
+use App\Models\VerificationJobChunk;
+use Illuminate\Http\JsonResponse;
+
+class VerifierChunkLeaseController
+{
+    public function __invoke(ChunkLeaseRequest $request, VerificationJobChunk $chunk): JsonResponse
+    {
+        $payload = $request->validated();
+
+        if ($chunk->status !== 'processing') {
+            return response()->json([
+                'message' => 'Chunk is not being processed.',
+            ], 409);
+        }
+
+        if ((string) $chunk->assigned_worker_id !== $payload['worker_id']) {
+            return response()->json([
+                'message' => 'Chunk is claimed by another worker.',
+            ], 409);
+        }
+
+        $leaseSeconds = (int) ($payload['lease_seconds'] ?? config('engine.lease_seconds', 600));
+        $leaseSeconds = max(1, $leaseSeconds);
+
+        $chunk->update([
+            'claim_expires_at' => now()->addSeconds($leaseSeconds),
+        ]);
+
+        return response()->json([
+            'data' => [
+                'chunk_id' => (string) $chunk->id,
+                'status' => $chunk->status,
+                'lease_expires_at' => $chunk->claim_expires_at?->toIso8601String(),
+            ],
+        ]);
+    }
+}
@@ -23,6 +23,7 @@ public function __invoke(VerificationJobChunk $chunk, EngineStorageUrlSigner $si
         $validKey = $storage->chunkOutputKey($chunk->job, $chunk->chunk_no, 'valid');
         $invalidKey = $storage->chunkOutputKey($chunk->job, $chunk->chunk_no, 'invalid');
         $riskyKey = $storage->chunkOutputKey($chunk->job, $chunk->chunk_no, 'risky');
+        $transcriptKey = $storage->chunkOutputKey($chunk->job, $chunk->chunk_no, 'transcript', 'jsonl');
 
         return response()->json([
             'data' => [
@@ -41,6 +42,10 @@ public function __invoke(VerificationJobChunk $chunk, EngineStorageUrlSigner $si
                         'key' => $riskyKey,
                         'url' => $signer->temporaryUploadUrl($disk, $riskyKey, $expiry, 'text/csv'),
                     ],
+                    'transcript' => [
+                        'key' => $transcriptKey,
+                        'url' => $signer->temporaryUploadUrl($disk, $transcriptKey, $expiry, 'application/x-ndjson'),
+                    ],
                 ],
             ],
         ]);
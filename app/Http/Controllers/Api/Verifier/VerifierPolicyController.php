@@ -19,6 +19,7 @@ public function __invoke(): JsonResponse
                 'enhanced_mode_enabled' => EngineSettings::enhancedModeEnabled(),
                 'role_accounts_behavior' => EngineSettings::roleAccountsBehavior(),
                 'role_accounts_list' => EngineSettings::roleAccountsList(),
+                'typo_domains' => EngineSettings::typoDomains(),
                 'provider_policies' => EngineSettings::providerPolicies(),
                 'policies' => [
                     'standard' => $policies['standard'] ?? [],
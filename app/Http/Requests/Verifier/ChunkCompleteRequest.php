@@ -18,6 +18,7 @@ public function rules(): array
             'valid_key' => ['required', 'string', 'max:1024'],
             'invalid_key' => ['required', 'string', 'max:1024'],
             'risky_key' => ['required', 'string', 'max:1024'],
+            'transcript_key' => ['nullable', 'string', 'max:1024'],
             'email_count' => ['nullable', 'integer', 'min:0'],
             'valid_count' => ['nullable', 'integer', 'min:0'],
             'invalid_count' => ['nullable', 'integer', 'min:0'],
@@ -0,0 +1,21 @@
+<?php
+
+namespace App\Http\Requests\Verifier;
+
+use Illuminate\Foundation\Http\FormRequest;
+
+class ChunkLeaseRequest extends FormRequest
+{
+    public function authorize(): bool
+    {
+        return true;
+    }
+
+    public function rules(): array
+    {
+        return [
+            'worker_id' => ['required', 'string', 'max:255'],
+            'lease_seconds' => ['nullable', 'integer', 'min:1', 'max:86400'],
+        ];
+    }
+}
@@ -14,6 +14,7 @@ class EngineVerificationPolicy extends Model
         'smtp_read_timeout_ms',
         'max_mx_attempts',
         'max_concurrency_default',
+        'chunk_parallelism',
         'per_domain_concurrency',
         'catch_all_detection_enabled',
         'global_connects_per_minute',
@@ -28,6 +29,7 @@ class EngineVerificationPolicy extends Model
         'smtp_read_timeout_ms' => 'integer',
         'max_mx_attempts' => 'integer',
         'max_concurrency_default' => 'integer',
+        'chunk_parallelism' => 'integer',
         'per_domain_concurrency' => 'integer',
         'catch_all_detection_enabled' => 'boolean',
         'global_connects_per_minute' => 'integer',
@@ -34,6 +34,7 @@ class VerificationJobChunk extends Model
         'valid_key',
         'invalid_key',
         'risky_key',
+        'transcript_key',
         'email_count',
         'valid_count',
         'invalid_count',
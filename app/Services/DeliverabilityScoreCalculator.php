@@ -5,9 +5,12 @@
 class DeliverabilityScoreCalculator
 {
     /**
+     * The worker's own score, when given and enabled, replaces the status and
+     * reason baseline; cache adjustments and sub-status caps still apply.
+     *
      * @param  array<string, mixed>|null  $cacheOutcome
      */
-    public function calculate(string $status, string $subStatus, string $reason, ?array $cacheOutcome = null): int
+    public function calculate(string $status, string $subStatus, string $reason, ?array $cacheOutcome = null, ?int $engineScore = null): int
     {
         $config = (array) config('engine.deliverability_score', []);
         $baseScores = (array) ($config['base'] ?? []);
@@ -22,6 +25,10 @@ public function calculate(string $status, string $subStatus, string $reason, ?ar
             $base = (int) $reasonOverrides[$reasonKey];
         }
 
+        if ($engineScore !== null && (bool) ($config['use_engine_score'] ?? false)) {
+            $base = $engineScore;
+        }
+
         if ($cacheOutcome) {
             $cacheStatus = strtolower((string) ($cacheOutcome['outcome'] ?? $cacheOutcome['status'] ?? ''));
             if ($cacheStatus !== '' && array_key_exists($cacheStatus, $cacheAdjustments)) {
@@ -42,7 +42,7 @@ public function plan(VerificationJob $job, VerificationJobChunk $chunk, string $
                 $reason = strtolower(trim((string) ($row['reason'] ?? '')));
                 $reasonBase = explode(':', $reason, 2)[0];
 
-                if ($status === 'invalid' && in_array($reasonBase, $hardInvalidReasons, true)) {
+                if ($status === 'invalid' && $this->isHardInvalidReason($reasonBase, $hardInvalidReasons)) {
                     $hardInvalidRows[] = [
                         'email' => $email,
                         'reason' => $reasonBase,
@@ -512,9 +512,9 @@ private function writeStream(string $disk, string $key, $stream): void
      */
     private function hardInvalidReasons(): array
     {
-        $reasons = config('engine.screening_hard_invalid_reasons', ['syntax', 'mx_missing']);
+        $reasons = config('engine.screening_hard_invalid_reasons', ['syntax', 'mx_missing', 'null_mx']);
         if (! is_array($reasons)) {
-            return ['syntax', 'mx_missing'];
+            return ['syntax', 'mx_missing', 'null_mx'];
         }
 
         $normalized = [];
@@ -525,7 +525,21 @@ private function hardInvalidReasons(): array
             }
         }
 
-        return $normalized === [] ? ['syntax', 'mx_missing'] : array_values(array_unique($normalized));
+        return $normalized === [] ? ['syntax', 'mx_missing', 'null_mx'] : array_values(array_unique($normalized));
+    }
+
+    /**
+     * Granular syntax codes such as syntax_local_too_long count as "syntax".
+     *
+     * @param  array<int, string>  $hardInvalidReasons
+     */
+    private function isHardInvalidReason(string $reasonBase, array $hardInvalidReasons): bool
+    {
+        if (in_array($reasonBase, $hardInvalidReasons, true)) {
+            return true;
+        }
+
+        return str_starts_with($reasonBase, 'syntax_') && in_array('syntax', $hardInvalidReasons, true);
     }
 
     private function normalizeEmail(string $email): ?string
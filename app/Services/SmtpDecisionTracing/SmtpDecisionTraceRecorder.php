@@ -27,9 +27,11 @@ public function recordFromChunk(VerificationJobChunk $chunk): int
             return 0;
         }
 
+        $transcripts = $this->transcriptsFromOutput($outputDisk, (string) $chunk->transcript_key);
+
         $rows = [];
-        $rows = array_merge($rows, $this->rowsFromOutput($chunk, $outputDisk, (string) $chunk->invalid_key, 'invalid'));
-        $rows = array_merge($rows, $this->rowsFromOutput($chunk, $outputDisk, (string) $chunk->risky_key, 'risky'));
+        $rows = array_merge($rows, $this->rowsFromOutput($chunk, $outputDisk, (string) $chunk->invalid_key, 'invalid', $transcripts));
+        $rows = array_merge($rows, $this->rowsFromOutput($chunk, $outputDisk, (string) $chunk->risky_key, 'risky', $transcripts));
 
         if ($rows === []) {
             return 0;
@@ -63,9 +65,10 @@ public function recordFromChunk(VerificationJobChunk $chunk): int
     }
 
     /**
+     * @param  array<string, array<int, array<string, mixed>>>  $transcripts
      * @return array<int, array<string, mixed>>
      */
-    private function rowsFromOutput(VerificationJobChunk $chunk, string $disk, string $key, string $bucket): array
+    private function rowsFromOutput(VerificationJobChunk $chunk, string $disk, string $key, string $bucket, array $transcripts): array
     {
         $key = trim($key);
         if ($key === '') {
@@ -157,6 +160,7 @@ private function rowsFromOutput(VerificationJobChunk $chunk, string $disk, strin
                     'reason_raw' => $reason,
                     'base_reason' => $parsed['base_reason'] ?? null,
                     'attempt_chain' => $attemptChain,
+                    'transcript' => $transcripts[$emailHash] ?? [],
                     'metadata' => $parsed,
                 ], JSON_UNESCAPED_SLASHES),
                 'observed_at' => $now,
@@ -170,6 +174,45 @@ private function rowsFromOutput(VerificationJobChunk $chunk, string $disk, strin
         return $rows;
     }
 
+    /**
+     * Reads the worker's transcript sidecar: one JSON object per line with
+     * the email hash and the redacted SMTP transcript of that address.
+     *
+     * @return array<string, array<int, array<string, mixed>>>
+     */
+    private function transcriptsFromOutput(string $disk, string $key): array
+    {
+        $key = trim($key);
+        if ($key === '') {
+            return [];
+        }
+
+        $stream = Storage::disk($disk)->readStream($key);
+        if (! is_resource($stream)) {
+            return [];
+        }
+
+        $transcripts = [];
+        while (($line = fgets($stream)) !== false) {
+            $record = json_decode(trim($line), true);
+            if (! is_array($record)) {
+                continue;
+            }
+
+            $emailHash = strtolower(trim((string) ($record['email_hash'] ?? '')));
+            $transcript = $record['transcript'] ?? null;
+            if ($emailHash === '' || ! is_array($transcript)) {
+                continue;
+            }
+
+            $transcripts[$emailHash] = array_values(array_filter($transcript, static fn (mixed $entry): bool => is_array($entry)));
+        }
+
+        fclose($stream);
+
+        return $transcripts;
+    }
+
     private function isHeaderRow(string $email, string $reason): bool
     {
         return strtolower(trim($email)) === 'email' && strtolower(trim($reason)) === 'reason';
@@ -17,7 +17,7 @@ public function map(string $email, string $sourceStatus, string $reason, ?array
         $normalizedReason = $this->normalizeReason($reason);
         $status = $this->normalizeStatus($sourceStatus);
         $subStatus = $this->mapSubStatus($normalizedReason);
-        $score = $this->calculator->calculate($status, $subStatus, $normalizedReason, $cacheOutcome);
+        $score = $this->calculator->calculate($status, $subStatus, $normalizedReason, $cacheOutcome, $this->engineScore($reason));
 
         if (str_starts_with($normalizedReason, 'catch_all')) {
             $status = $this->applyCatchAllPolicy($score);
@@ -41,6 +41,26 @@ private function normalizeStatus(string $status): string
         return in_array($normalized, ['valid', 'invalid', 'risky'], true) ? $normalized : 'risky';
     }
 
+    /**
+     * Reads the worker's score=<0-100> reason metadata, if any.
+     */
+    private function engineScore(string $reason): ?int
+    {
+        $parts = explode(':', $reason, 2);
+        if (count($parts) < 2) {
+            return null;
+        }
+
+        foreach (explode(';', $parts[1]) as $segment) {
+            [$key, $value] = array_pad(explode('=', trim($segment), 2), 2, '');
+            if ($key === 'score' && ctype_digit($value)) {
+                return min(100, (int) $value);
+            }
+        }
+
+        return null;
+    }
+
     private function normalizeReason(string $reason): string
     {
         $reason = strtolower(trim($reason));
@@ -57,17 +77,19 @@ private function normalizeReason(string $reason): string
     private function mapSubStatus(string $reason): string
     {
         return match ($reason) {
-            'catch_all', 'catch_all_high_confidence', 'catch_all_medium_confidence', 'catch_all_low_confidence' => 'catch_all',
+            'catch_all', 'catch_all_high_confidence', 'catch_all_medium_confidence', 'catch_all_low_confidence', 'catch_all_inconsistent' => 'catch_all',
             'smtp_connect_ok', 'rcpt_ok' => 'smtp_connect_ok',
-            'mx_missing' => 'mx_missing',
+            'mx_missing', 'null_mx' => 'mx_missing',
             'syntax' => 'syntax',
-            'disposable_domain' => 'disposable_domain',
+            'disposable_domain', 'disposable_mx' => 'disposable_domain',
+            'parked_domain' => 'parked_domain',
             'role_account' => 'role_account',
             'domain_typo_suspected' => 'domain_typo_suspected',
             'smtp_timeout', 'smtp_connect_timeout', 'dns_timeout' => 'timeout',
             'smtp_tempfail', 'dns_servfail' => 'tempfail',
+            'smtp_proxy_connect_failed', 'smtp_proxy_auth_failed', 'smtp_proxy_refused' => 'tempfail',
             'rcpt_rejected', 'smtp_unavailable' => 'mailbox_not_found',
-            default => 'unknown',
+            default => str_starts_with($reason, 'syntax_') ? 'syntax' : 'unknown',
         };
     }
 
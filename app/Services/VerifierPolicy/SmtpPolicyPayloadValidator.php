@@ -2,17 +2,21 @@
 
 namespace App\Services\VerifierPolicy;
 
+use Illuminate\Support\Facades\Log;
+
+/**
+ * Field lists, enums and bounds come from the policy schema shared with the
+ * Go worker and control plane (engine-worker-go/policylint/schema.json),
+ * published to resources/verifier/policy-schema.json. Without a readable
+ * schema only the structural checks run; the control plane still lints the
+ * full payload.
+ */
 class SmtpPolicyPayloadValidator
 {
-    private const ALLOWED_RULE_TAGS = [
-        'greylist',
-        'rate_limit',
-        'mailbox_full',
-        'policy_blocked',
-        'mailbox_not_found',
-        'auth_required',
-        'unknown_transient',
-    ];
+    /**
+     * @var array<string, mixed>|false|null
+     */
+    private array|false|null $schema = null;
 
     /**
      * @return array<int, string>
@@ -20,9 +24,10 @@ class SmtpPolicyPayloadValidator
     public function validate(array $payload, string $expectedVersion): array
     {
         $errors = [];
-        $schemaVersion = strtolower(trim((string) ($payload['schema_version'] ?? 'v2')));
-        if (! in_array($schemaVersion, ['v2', 'v3', 'v4'], true)) {
-            $errors[] = 'Payload field "schema_version" must be either v2, v3, or v4.';
+        $schema = $this->schema();
+        $schemaVersion = strtolower(trim((string) ($payload['schema_version'] ?? $schema['default_schema_version'] ?? '')));
+        if ($schema !== null && ! in_array($schemaVersion, $schema['schema_versions'], true)) {
+            $errors[] = sprintf('Payload field "schema_version" must be one of %s.', implode(', ', $schema['schema_versions']));
         }
 
         if (! array_key_exists('enabled', $payload) || ! is_bool($payload['enabled'])) {
@@ -57,22 +62,67 @@ public function validate(array $payload, string $expectedVersion): array
             return $errors;
         }
 
-        foreach ([
-            'default_seconds',
-            'tempfail_seconds',
-            'greylist_seconds',
-            'policy_blocked_seconds',
-            'unknown_seconds',
-        ] as $retryField) {
+        if ($schema === null) {
+            return $errors;
+        }
+
+        foreach ($schema['required_retry_fields'] as $retryField) {
             if (! array_key_exists($retryField, $retry) || ! is_numeric($retry[$retryField])) {
                 $errors[] = sprintf('Payload retry field "%s" must be present and numeric.', $retryField);
             }
         }
 
-        if (in_array($schemaVersion, ['v3', 'v4'], true)) {
+        if (in_array($schemaVersion, $schema['structured_schema_versions'], true)) {
             $errors = array_merge($errors, $this->validateV3Rules($profiles));
             $errors = array_merge($errors, $this->validateV3Modes($payload));
         }
+        $errors = array_merge($errors, $this->validateRuleValues($profiles));
+        $errors = array_merge($errors, $this->validateCanonicalization($profiles));
+
+        if (array_key_exists('scoring', $payload)) {
+            $errors = array_merge($errors, $this->validateScoring($payload['scoring']));
+        }
+
+        return $errors;
+    }
+
+    /**
+     * The optional scoring block carries the worker's deliverability score
+     * weights. Weights left out fall back to the worker defaults; factors
+     * the worker does not know are rejected.
+     *
+     * @return array<int, string>
+     */
+    private function validateScoring(mixed $scoring): array
+    {
+        if (! is_array($scoring)) {
+            return ['Payload field "scoring" must be an object.'];
+        }
+
+        $errors = [];
+        if (! is_string($scoring['version'] ?? null) || trim($scoring['version']) === '') {
+            $errors[] = 'Payload field "scoring.version" is required.';
+        }
+
+        $weights = $scoring['weights'] ?? [];
+        if (! is_array($weights)) {
+            $errors[] = 'Payload field "scoring.weights" must be an object.';
+
+            return $errors;
+        }
+
+        $bounds = $this->schema()['scoring_weight'];
+        $factors = $this->schema()['scoring_factors'];
+        foreach ($weights as $factor => $weight) {
+            if (! in_array(strtolower(trim((string) $factor)), $factors, true)) {
+                $errors[] = sprintf('Payload field "scoring.weights.%s" is not a known score factor.', $factor);
+
+                continue;
+            }
+            if (! is_int($weight) || $weight < $bounds['min'] || $weight > $bounds['max']) {
+                $errors[] = sprintf('Payload field "scoring.weights.%s" must be an integer between %d and %d.', $factor, $bounds['min'], $bounds['max']);
+            }
+        }
 
         return $errors;
     }
@@ -84,6 +134,7 @@ public function validate(array $payload, string $expectedVersion): array
     private function validateV3Rules(array $profiles): array
     {
         $errors = [];
+        $schema = $this->schema();
 
         foreach ($profiles as $profileName => $profile) {
             if (! is_array($profile)) {
@@ -94,20 +145,23 @@ private function validateV3Rules(array $profiles): array
             if (! is_array($session)) {
                 $errors[] = sprintf('Payload field "profiles.%s.session" is required for schema v3.', $profileName);
             } else {
-                foreach ([
-                    'max_concurrency',
-                    'connects_per_minute',
-                    'reuse_connection_for_retries',
-                    'retry_jitter_percent',
-                    'ehlo_profile',
-                ] as $field) {
+                foreach ($schema['required_session_fields'] as $field) {
                     if (! array_key_exists($field, $session)) {
                         $errors[] = sprintf('Payload field "profiles.%s.session.%s" is required for schema v3.', $profileName, $field);
                     }
                 }
+
+                if (array_key_exists('tls_mode', $session)
+                    && ! in_array($session['tls_mode'], $schema['tls_modes'], true)) {
+                    $errors[] = sprintf('Payload field "profiles.%s.session.tls_mode" must be one of %s.', $profileName, implode(', ', $schema['tls_modes']));
+                }
+
+                if (array_key_exists('proxy', $session) && ! is_string($session['proxy'])) {
+                    $errors[] = sprintf('Payload field "profiles.%s.session.proxy" must be a string.', $profileName);
+                }
             }
 
-            foreach (['enhanced_rules', 'smtp_code_rules', 'message_rules'] as $ruleCollection) {
+            foreach ($schema['rule_collections'] as $ruleCollection) {
                 $rules = $profile[$ruleCollection] ?? null;
                 if (! is_array($rules)) {
                     continue;
@@ -118,7 +172,7 @@ private function validateV3Rules(array $profiles): array
                         continue;
                     }
 
-                    foreach (['rule_tag', 'confidence_hint', 'provider_scope'] as $field) {
+                    foreach ($schema['required_rule_fields'] as $field) {
                         if (! array_key_exists($field, $rule) || trim((string) $rule[$field]) === '') {
                             $errors[] = sprintf(
                                 'Payload rule field "profiles.%s.%s.%d.%s" is required for schema v3.',
@@ -131,7 +185,7 @@ private function validateV3Rules(array $profiles): array
                     }
 
                     $ruleTag = strtolower(trim((string) ($rule['rule_tag'] ?? '')));
-                    if ($ruleTag !== '' && ! in_array($ruleTag, self::ALLOWED_RULE_TAGS, true)) {
+                    if ($ruleTag !== '' && ! in_array($ruleTag, $schema['rule_tags'], true)) {
                         $errors[] = sprintf(
                             'Payload rule field "profiles.%s.%s.%d.rule_tag" has unsupported value "%s".',
                             $profileName,
@@ -154,12 +208,13 @@ private function validateV3Rules(array $profiles): array
     private function validateV3Modes(array $payload): array
     {
         $errors = [];
+        $schema = $this->schema();
         $modes = $payload['modes'] ?? null;
         if (! is_array($modes)) {
             return ['Payload field "modes" is required and must be an object for schema v3.'];
         }
 
-        foreach (['normal', 'cautious', 'drain', 'quarantine', 'degraded_probe'] as $mode) {
+        foreach ($schema['required_modes'] as $mode) {
             $definition = $modes[$mode] ?? null;
             if (! is_array($definition)) {
                 $errors[] = sprintf('Payload field "modes.%s" is required for schema v3.', $mode);
@@ -167,7 +222,7 @@ private function validateV3Modes(array $payload): array
                 continue;
             }
 
-            foreach (['probe_enabled', 'max_concurrency_multiplier', 'connects_per_minute_multiplier'] as $field) {
+            foreach ($schema['required_mode_fields'] as $field) {
                 if (! array_key_exists($field, $definition)) {
                     $errors[] = sprintf('Payload field "modes.%s.%s" is required for schema v3.', $mode, $field);
                 }
@@ -176,4 +231,142 @@ private function validateV3Modes(array $payload): array
 
         return $errors;
     }
+
+    /**
+     * Checks rule enums, stages and priority for every schema version.
+     * Message patterns use Go's RE2 syntax and are compiled by the control
+     * plane when the version is validated there.
+     *
+     * @param  array<string, mixed>  $profiles
+     * @return array<int, string>
+     */
+    private function validateRuleValues(array $profiles): array
+    {
+        $errors = [];
+        $schema = $this->schema();
+        $enums = [
+            'decision_class' => $schema['decision_classes'],
+            'category' => $schema['categories'],
+            'confidence_hint' => $schema['confidence_hints'],
+        ];
+
+        foreach ($profiles as $profileName => $profile) {
+            if (! is_array($profile)) {
+                continue;
+            }
+
+            foreach ($schema['rule_collections'] as $ruleCollection) {
+                $rules = $profile[$ruleCollection] ?? null;
+                if (! is_array($rules)) {
+                    continue;
+                }
+
+                foreach ($rules as $ruleIndex => $rule) {
+                    if (! is_array($rule)) {
+                        continue;
+                    }
+
+                    $path = sprintf('profiles.%s.%s.%d', $profileName, $ruleCollection, $ruleIndex);
+                    foreach ($enums as $field => $allowed) {
+                        $value = strtolower(trim((string) ($rule[$field] ?? '')));
+                        if ($value !== '' && ! in_array($value, $allowed, true)) {
+                            $errors[] = sprintf('Payload rule field "%s.%s" has unsupported value "%s".', $path, $field, $value);
+                        }
+                    }
+
+                    foreach ((array) ($rule['stages'] ?? []) as $stage) {
+                        if (! in_array(strtolower(trim((string) $stage)), $schema['rule_stages'], true)) {
+                            $errors[] = sprintf('Payload rule field "%s.stages" has unsupported value "%s".', $path, $stage);
+                        }
+                    }
+
+                    if (array_key_exists('priority', $rule) && ! is_int($rule['priority'])) {
+                        $errors[] = sprintf('Payload rule field "%s.priority" must be an integer.', $path);
+                    }
+                }
+            }
+        }
+
+        return $errors;
+    }
+
+    /**
+     * The optional per-profile canonicalization block tells the worker which
+     * local-part variants of the listed domains share a mailbox.
+     *
+     * @param  array<string, mixed>  $profiles
+     * @return array<int, string>
+     */
+    private function validateCanonicalization(array $profiles): array
+    {
+        $errors = [];
+        $schema = $this->schema();
+        $owners = [];
+
+        foreach ($profiles as $profileName => $profile) {
+            if (! is_array($profile) || ! array_key_exists('canonicalization', $profile)) {
+                continue;
+            }
+
+            $path = sprintf('profiles.%s.canonicalization', $profileName);
+            $canonicalization = $profile['canonicalization'];
+            if (! is_array($canonicalization)) {
+                $errors[] = sprintf('Payload field "%s" must be an object.', $path);
+
+                continue;
+            }
+
+            foreach ($schema['canonicalization_flags'] as $flag) {
+                if (array_key_exists($flag, $canonicalization) && ! is_bool($canonicalization[$flag])) {
+                    $errors[] = sprintf('Payload field "%s.%s" must be a boolean.', $path, $flag);
+                }
+            }
+
+            $domains = $canonicalization['domains'] ?? [];
+            if (! is_array($domains)) {
+                $errors[] = sprintf('Payload field "%s.domains" must be an array of strings.', $path);
+
+                continue;
+            }
+
+            foreach ($domains as $domain) {
+                $domain = is_string($domain) ? rtrim(strtolower(trim($domain)), '.') : '';
+                if ($domain === '') {
+                    $errors[] = sprintf('Payload field "%s.domains" must only contain domain names.', $path);
+
+                    continue;
+                }
+                if (isset($owners[$domain]) && $owners[$domain] !== $profileName) {
+                    $errors[] = sprintf('Payload field "%s.domains" lists "%s", which profile "%s" already lists.', $path, $domain, $owners[$domain]);
+                }
+                $owners[$domain] = $profileName;
+            }
+        }
+
+        return $errors;
+    }
+
+    /**
+     * Returns null, after logging once, when the schema file is missing or
+     * invalid.
+     *
+     * @return array<string, mixed>|null
+     */
+    private function schema(): ?array
+    {
+        if ($this->schema !== null) {
+            return $this->schema ?: null;
+        }
+
+        $path = (string) config('verifier.policy_schema_path');
+        $decoded = is_file($path) ? json_decode((string) file_get_contents($path), true) : null;
+        if (! is_array($decoded)) {
+            Log::warning('SMTP policy schema is missing or invalid; skipping schema checks', ['path' => $path]);
+            $this->schema = false;
+
+            return null;
+        }
+
+        return $this->schema = $decoded;
+    }
 }
@@ -63,6 +63,7 @@ private static function policyToArray(string $mode, ?EngineVerificationPolicy $p
             'smtp_read_timeout_ms' => $policy?->smtp_read_timeout_ms ?? (int) ($fallback['smtp_read_timeout_ms'] ?? 0),
             'max_mx_attempts' => $policy?->max_mx_attempts ?? (int) ($fallback['max_mx_attempts'] ?? 0),
             'max_concurrency_default' => $policy?->max_concurrency_default ?? (int) ($fallback['max_concurrency_default'] ?? 0),
+            'chunk_parallelism' => $policy?->chunk_parallelism ?? (int) ($fallback['chunk_parallelism'] ?? 1),
             'per_domain_concurrency' => $policy?->per_domain_concurrency ?? (int) ($fallback['per_domain_concurrency'] ?? 0),
             'catch_all_detection_enabled' => $policy?->catch_all_detection_enabled
                 ?? (bool) ($fallback['catch_all_detection_enabled'] ?? false),
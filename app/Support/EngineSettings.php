@@ -42,6 +42,23 @@ public static function roleAccountsList(): array
         return array_values(array_unique(array_filter(array_map('strtolower', $entries))));
     }
 
+    /**
+     * Ranked popular domains and dot-prefixed TLDs for the worker typo engine.
+     *
+     * @return array<int, string>
+     */
+    public static function typoDomains(): array
+    {
+        $list = self::stringValue('typo_domains', (string) config('engine.typo_domains', ''));
+        if ($list === '') {
+            return [];
+        }
+
+        $entries = array_map('trim', explode(',', $list));
+
+        return array_values(array_unique(array_filter(array_map('strtolower', $entries))));
+    }
+
     public static function catchAllPolicy(): string
     {
         $policy = self::stringValue('catch_all_policy', (string) config('engine.catch_all_policy', 'risky_only'));
@@ -6,6 +6,7 @@
     'smtp_read_timeout_ms' => (int) env('ENGINE_POLICY_SMTP_READ_TIMEOUT_MS', 2000),
     'max_mx_attempts' => (int) env('ENGINE_POLICY_MAX_MX_ATTEMPTS', 2),
     'max_concurrency_default' => (int) env('ENGINE_POLICY_MAX_CONCURRENCY_DEFAULT', 1),
+    'chunk_parallelism' => (int) env('ENGINE_POLICY_CHUNK_PARALLELISM', 1),
     'per_domain_concurrency' => (int) env('ENGINE_POLICY_PER_DOMAIN_CONCURRENCY', 2),
     'global_connects_per_minute' => env('ENGINE_POLICY_GLOBAL_CONNECTS_PER_MINUTE') !== null
         ? (int) env('ENGINE_POLICY_GLOBAL_CONNECTS_PER_MINUTE')
@@ -88,6 +89,7 @@
     'enhanced_requires_entitlement' => (bool) env('ENGINE_ENHANCED_REQUIRES_ENTITLEMENT', false),
     'role_accounts_behavior' => env('ENGINE_ROLE_ACCOUNTS_BEHAVIOR', 'risky'),
     'role_accounts_list' => env('ENGINE_ROLE_ACCOUNTS_LIST', 'info,admin,support,sales,contact,hello,hr'),
+    'typo_domains' => env('ENGINE_TYPO_DOMAINS', ''),
     'catch_all_policy' => env('ENGINE_CATCH_ALL_POLICY', 'risky_only'),
     'catch_all_promote_threshold' => env('ENGINE_CATCH_ALL_PROMOTE_THRESHOLD') !== null
         ? (int) env('ENGINE_CATCH_ALL_PROMOTE_THRESHOLD')
@@ -97,11 +99,11 @@
     'tempfail_retry_backoff_minutes' => env('ENGINE_TEMPFAIL_RETRY_BACKOFF_MINUTES', '10,30,60'),
     'tempfail_retry_reasons' => env(
         'ENGINE_TEMPFAIL_RETRY_REASONS',
-        'smtp_tempfail,smtp_timeout,smtp_connect_timeout,dns_timeout,dns_servfail'
+        'smtp_tempfail,smtp_timeout,smtp_connect_timeout,dns_timeout,dns_servfail,smtp_proxy_connect_failed,smtp_proxy_refused'
     ),
     'screening_hard_invalid_reasons' => array_values(array_filter(array_map(
         'trim',
-        explode(',', (string) env('ENGINE_SCREENING_HARD_INVALID_REASONS', 'syntax,mx_missing'))
+        explode(',', (string) env('ENGINE_SCREENING_HARD_INVALID_REASONS', 'syntax,mx_missing,null_mx'))
     ))),
     'advanced_smtp_probing_enabled' => (bool) env('ADVANCED_SMTP_PROBING_ENABLED', false),
     'probe_sharding_enabled' => (bool) env('PROBE_SHARDING_ENABLED', true),
@@ -199,6 +201,7 @@
     'worker_runtime_cpu_limit' => env('ENGINE_WORKER_RUNTIME_CPU_LIMIT'),
     'worker_agent_port' => (int) env('ENGINE_WORKER_AGENT_PORT', 9713),
     'deliverability_score' => [
+        'use_engine_score' => (bool) env('ENGINE_SCORE_USE_ENGINE_SCORE', false),
         'base' => [
             'valid' => (int) env('ENGINE_SCORE_BASE_VALID', 90),
             'invalid' => (int) env('ENGINE_SCORE_BASE_INVALID', 10),
@@ -208,15 +211,23 @@
             'smtp_connect_ok' => (int) env('ENGINE_SCORE_REASON_SMTP_CONNECT_OK', 95),
             'rcpt_ok' => (int) env('ENGINE_SCORE_REASON_RCPT_OK', 95),
             'syntax' => (int) env('ENGINE_SCORE_REASON_SYNTAX', 5),
+            'syntax_ip_literal' => (int) env('ENGINE_SCORE_REASON_SYNTAX_IP_LITERAL', 20),
             'mx_missing' => (int) env('ENGINE_SCORE_REASON_MX_MISSING', 10),
+            'null_mx' => (int) env('ENGINE_SCORE_REASON_NULL_MX', 5),
             'rcpt_rejected' => (int) env('ENGINE_SCORE_REASON_RCPT_REJECTED', 10),
             'smtp_unavailable' => (int) env('ENGINE_SCORE_REASON_SMTP_UNAVAILABLE', 10),
             'catch_all' => (int) env('ENGINE_SCORE_REASON_CATCH_ALL', 55),
             'disposable_domain' => (int) env('ENGINE_SCORE_REASON_DISPOSABLE', 40),
+            'disposable_mx' => (int) env('ENGINE_SCORE_REASON_DISPOSABLE_MX', 40),
+            'parked_domain' => (int) env('ENGINE_SCORE_REASON_PARKED_DOMAIN', 20),
+            'mail_auth_missing' => (int) env('ENGINE_SCORE_REASON_MAIL_AUTH_MISSING', 50),
             'role_account' => (int) env('ENGINE_SCORE_REASON_ROLE_ACCOUNT', 65),
             'domain_typo_suspected' => (int) env('ENGINE_SCORE_REASON_DOMAIN_TYPO', 50),
             'smtp_timeout' => (int) env('ENGINE_SCORE_REASON_SMTP_TIMEOUT', 45),
             'smtp_connect_timeout' => (int) env('ENGINE_SCORE_REASON_SMTP_CONNECT_TIMEOUT', 45),
+            'smtp_proxy_connect_failed' => (int) env('ENGINE_SCORE_REASON_SMTP_PROXY_CONNECT_FAILED', 45),
+            'smtp_proxy_auth_failed' => (int) env('ENGINE_SCORE_REASON_SMTP_PROXY_AUTH_FAILED', 45),
+            'smtp_proxy_refused' => (int) env('ENGINE_SCORE_REASON_SMTP_PROXY_REFUSED', 45),
             'smtp_tempfail' => (int) env('ENGINE_SCORE_REASON_SMTP_TEMPFAIL', 40),
             'dns_timeout' => (int) env('ENGINE_SCORE_REASON_DNS_TIMEOUT', 45),
             'dns_servfail' => (int) env('ENGINE_SCORE_REASON_DNS_SERVFAIL', 40),
@@ -22,4 +22,5 @@
     'dedupe_in_memory_limit' => (int) env('VERIFIER_DEDUPE_IN_MEMORY_LIMIT', 100000),
     'cache_store' => env('VERIFIER_CACHE_STORE', \App\Services\EmailVerificationCache\NullCacheStore::class),
     'xlsx_row_batch_size' => (int) env('VERIFIER_XLSX_ROW_BATCH_SIZE', 1000),
+    'policy_schema_path' => env('VERIFIER_POLICY_SCHEMA_PATH', resource_path('verifier/policy-schema.json')),
 ];
@@ -0,0 +1,36 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\DB;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::table('engine_verification_policies', function (Blueprint $table) {
+            $table->unsignedInteger('chunk_parallelism')->default(1)->after('max_concurrency_default');
+        });
+
+        $defaults = config('engine.policy_defaults', []);
+
+        foreach (['standard', 'enhanced'] as $mode) {
+            $value = max(1, (int) data_get($defaults, $mode.'.chunk_parallelism', 1));
+
+            DB::table('engine_verification_policies')
+                ->where('mode', $mode)
+                ->update([
+                    'chunk_parallelism' => $value,
+                    'updated_at' => now(),
+                ]);
+        }
+    }
+
+    public function down(): void
+    {
+        Schema::table('engine_verification_policies', function (Blueprint $table) {
+            $table->dropColumn('chunk_parallelism');
+        });
+    }
+};
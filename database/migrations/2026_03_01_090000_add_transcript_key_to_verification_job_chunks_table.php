@@ -0,0 +1,28 @@
+<?php
+
+use Illuminate\Database\Migrations\Migration;
+use Illuminate\Database\Schema\Blueprint;
+use Illuminate\Support\Facades\Schema;
+
+return new class extends Migration
+{
+    public function up(): void
+    {
+        Schema::table('verification_job_chunks', function (Blueprint $table): void {
+            if (! Schema::hasColumn('verification_job_chunks', 'transcript_key')) {
+                $table->string('transcript_key', 1024)
+                    ->nullable()
+                    ->after('risky_key');
+            }
+        });
+    }
+
+    public function down(): void
+    {
+        Schema::table('verification_job_chunks', function (Blueprint $table): void {
+            if (Schema::hasColumn('verification_job_chunks', 'transcript_key')) {
+                $table->dropColumn('transcript_key');
+            }
+        });
+    }
+};
@@ -0,0 +1,73 @@
+// Command policy-lint checks provider reply policy payloads against the
+// shared schema and reports rules that cannot apply as written.
+//
+//	policy-lint [-json] policy.json...
+//	policy-lint -schema
+//
+// It exits 1 when any payload has an error finding.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"engine-worker-go/policylint"
+)
+
+type fileReport struct {
+	File string `json:"file"`
+	policylint.Report
+}
+
+func main() {
+	jsonOutput := flag.Bool("json", false, "print the findings as JSON")
+	printSchema := flag.Bool("schema", false, "print the shared policy schema and exit")
+	flag.Parse()
+
+	if *printSchema {
+		_, _ = os.Stdout.Write(policylint.SchemaJSON())
+		return
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	reports := make([]fileReport, 0, flag.NArg())
+	failed := false
+	for _, path := range flag.Args() {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read policy: %v\n", err)
+			os.Exit(1)
+		}
+
+		report := policylint.Lint(raw)
+		reports = append(reports, fileReport{File: path, Report: report})
+		if !report.Valid {
+			failed = true
+		}
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write findings: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		for _, report := range reports {
+			for _, finding := range report.Findings {
+				fmt.Printf("%s: %s [%s] %s\n", report.File, finding.Severity, finding.Code, finding)
+			}
+			fmt.Printf("%s: %d errors, %d warnings\n", report.File, report.Errors, report.Warnings)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
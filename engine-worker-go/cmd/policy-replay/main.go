@@ -0,0 +1,117 @@
+// Command policy-replay runs a recorded reply corpus through two provider
+// reply policy versions and prints how the candidate reclassifies each
+// provider's replies.
+//
+//	policy-replay -corpus replies.jsonl -baseline current.json -candidate next.json
+//
+// Either policy may be "default" for the worker's built-in policy.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"engine-worker-go/internal/verifier"
+)
+
+func main() {
+	corpusPath := flag.String("corpus", "", "reply corpus recorded with REPLY_CORPUS_PATH")
+	baselinePath := flag.String("baseline", "default", "current policy JSON file, or \"default\"")
+	candidatePath := flag.String("candidate", "", "candidate policy JSON file, or \"default\"")
+	jsonOutput := flag.Bool("json", false, "print the diff as JSON")
+	flag.Parse()
+
+	if *corpusPath == "" || *candidatePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	samples, err := readCorpus(*corpusPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read corpus: %v\n", err)
+		os.Exit(1)
+	}
+	baseline, err := loadPolicy(*baselinePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid baseline policy: %v\n", err)
+		os.Exit(1)
+	}
+	candidate, err := loadPolicy(*candidatePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid candidate policy: %v\n", err)
+		os.Exit(1)
+	}
+
+	diffs := verifier.ReplayReplyCorpus(samples, baseline, candidate)
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diffs); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write diff: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	printDiffs(os.Stdout, baseline, candidate, len(samples), diffs)
+}
+
+func readCorpus(path string) ([]verifier.ReplySample, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return verifier.ReadReplyCorpus(file)
+}
+
+func loadPolicy(path string) (*verifier.ProviderReplyPolicyEngine, error) {
+	if path == "default" {
+		return verifier.DefaultProviderReplyPolicyEngine(), nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return verifier.ParseProviderReplyPolicyEngineJSON(string(raw))
+}
+
+func printDiffs(out io.Writer, baseline, candidate *verifier.ProviderReplyPolicyEngine, total int, diffs []verifier.ProviderReplayDiff) {
+	fmt.Fprintf(out, "replayed %d replies: %s -> %s\n\n", total, baseline.Version, candidate.Version)
+
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "PROVIDER\tSAMPLES\tCHANGED")
+	for _, diff := range diffs {
+		fmt.Fprintf(writer, "%s\t%d\t%d\n", diff.Provider, diff.Samples, diff.Changed)
+	}
+	_ = writer.Flush()
+
+	for _, diff := range diffs {
+		if len(diff.Changes) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(out, "\n%s\n", diff.Provider)
+		writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		for _, change := range diff.Changes {
+			fmt.Fprintf(writer, "  %s\t%s -> %s\t%d\n", change.Field, valueOrDash(change.From), valueOrDash(change.To), change.Count)
+		}
+		_ = writer.Flush()
+	}
+}
+
+// valueOrDash shows a session reply that let the session continue.
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -0,0 +1,6 @@
+package data
+
+import _ "embed"
+
+//go:embed free_mail_domains.txt
+var FreeMailDomains string
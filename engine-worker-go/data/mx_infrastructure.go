@@ -0,0 +1,6 @@
+package data
+
+import _ "embed"
+
+//go:embed mx_infrastructure.txt
+var MXInfrastructure string
@@ -0,0 +1,6 @@
+package data
+
+import _ "embed"
+
+//go:embed typo_domains.txt
+var TypoDomains string
@@ -95,10 +95,27 @@ type OutputURLsResponse struct {
 				Key string `json:"key"`
 				URL string `json:"url"`
 			} `json:"risky"`
+			Transcript struct {
+				Key string `json:"key"`
+				URL string `json:"url"`
+			} `json:"transcript"`
 		} `json:"targets"`
 	} `json:"data"`
 }
 
+type ChunkLeaseRequest struct {
+	WorkerID     string `json:"worker_id"`
+	LeaseSeconds *int   `json:"lease_seconds,omitempty"`
+}
+
+type ChunkLeaseResponse struct {
+	Data struct {
+		ChunkID        string `json:"chunk_id"`
+		Status         string `json:"status"`
+		LeaseExpiresAt string `json:"lease_expires_at"`
+	} `json:"data"`
+}
+
 type HeartbeatResponse struct {
 	Data struct {
 		ServerID                 int    `json:"server_id"`
@@ -243,6 +260,23 @@ func (c *Client) FailChunk(ctx context.Context, chunkID string, payload map[stri
 	return nil
 }
 
+// ExtendChunkLease renews the claim on a chunk this worker is processing.
+func (c *Client) ExtendChunkLease(ctx context.Context, chunkID string, req ChunkLeaseRequest) (*ChunkLeaseResponse, error) {
+	status, body, err := c.do(ctx, http.MethodPost, "/api/verifier/chunks/"+chunkID+"/lease", req)
+	if err != nil {
+		return nil, err
+	}
+	if status < 200 || status >= 300 {
+		return nil, APIError{Status: status, Body: string(body)}
+	}
+	var resp ChunkLeaseResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
 func (c *Client) LogChunk(ctx context.Context, chunkID string, payload map[string]interface{}) error {
 	status, body, err := c.do(ctx, http.MethodPost, "/api/verifier/chunks/"+chunkID+"/log", payload)
 	if err != nil {
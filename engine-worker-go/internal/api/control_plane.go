@@ -50,6 +50,14 @@ type ControlPlaneProviderMetric struct {
 	PolicyBlockRate float64 `json:"policy_block_rate,omitempty"`
 }
 
+type ControlPlaneEHLOProfileMetric struct {
+	Profile         string  `json:"profile"`
+	Processed       int64   `json:"processed"`
+	TempfailRate    float64 `json:"tempfail_rate,omitempty"`
+	RejectRate      float64 `json:"reject_rate,omitempty"`
+	PolicyBlockRate float64 `json:"policy_block_rate,omitempty"`
+}
+
 type ControlPlaneRoutingMetrics struct {
 	RetryClaimsTotal              int64 `json:"retry_claims_total,omitempty"`
 	RetryAntiAffinitySuccessTotal int64 `json:"retry_anti_affinity_success_total,omitempty"`
@@ -64,37 +72,57 @@ type ControlPlaneSessionMetrics struct {
 	SessionRetrySameConnTotal int64   `json:"session_retry_same_conn_total,omitempty"`
 	SessionRetryNewConnTotal  int64   `json:"session_retry_new_conn_total,omitempty"`
 	ThrottleAppliedTotal      int64   `json:"throttle_applied_total,omitempty"`
+	RateLimitWaitsTotal       int64   `json:"rate_limit_waits_total,omitempty"`
+	RateLimitThrottledTotal   int64   `json:"rate_limit_throttled_total,omitempty"`
+	RateLimitWaitAvgMS        float64 `json:"rate_limit_wait_avg_ms,omitempty"`
+	RateLimitWaitMaxMS        float64 `json:"rate_limit_wait_max_ms,omitempty"`
 }
 
 type ControlPlaneAttemptRouteMetrics struct {
 	AttemptsTotal           int64 `json:"attempts_total,omitempty"`
 	RetryAttemptsTotal      int64 `json:"retry_attempts_total,omitempty"`
 	MXFallbackAttemptsTotal int64 `json:"mx_fallback_attempts_total,omitempty"`
+	GreylistParkedTotal     int64 `json:"greylist_parked_total,omitempty"`
+	GreylistRecoveredTotal  int64 `json:"greylist_recovered_total,omitempty"`
+	GreylistDeferredTotal   int64 `json:"greylist_deferred_total,omitempty"`
+}
+
+type ControlPlaneCircuitBreakerEvent struct {
+	Mode         string  `json:"mode,omitempty"`
+	Key          string  `json:"key"`
+	Scope        string  `json:"scope"`
+	From         string  `json:"from"`
+	To           string  `json:"to"`
+	TempfailRate float64 `json:"tempfail_rate"`
+	Samples      int     `json:"samples,omitempty"`
+	At           string  `json:"at"`
 }
 
 type ControlPlaneHeartbeatRequest struct {
-	WorkerID              string                           `json:"worker_id"`
-	Host                  string                           `json:"host,omitempty"`
-	IPAddress             string                           `json:"ip_address,omitempty"`
-	Version               string                           `json:"version,omitempty"`
-	Pool                  string                           `json:"pool,omitempty"`
-	Tags                  []string                         `json:"tags,omitempty"`
-	Status                string                           `json:"status"`
-	CurrentJobID          string                           `json:"current_job_id,omitempty"`
-	CurrentChunkID        string                           `json:"current_chunk_id,omitempty"`
-	CorrelationID         string                           `json:"correlation_id,omitempty"`
-	Metrics               *ControlPlaneWorkerMetrics       `json:"metrics,omitempty"`
-	StageMetrics          *ControlPlaneStageMetrics        `json:"stage_metrics,omitempty"`
-	SMTPMetrics           *ControlPlaneSMTPMetrics         `json:"smtp_metrics,omitempty"`
-	ProviderMetrics       []ControlPlaneProviderMetric     `json:"provider_metrics,omitempty"`
-	RoutingMetrics        *ControlPlaneRoutingMetrics      `json:"routing_metrics,omitempty"`
-	SessionMetrics        *ControlPlaneSessionMetrics      `json:"session_metrics,omitempty"`
-	AttemptRouteMetrics   *ControlPlaneAttemptRouteMetrics `json:"attempt_route_metrics,omitempty"`
-	RetryAntiAffinityHits int64                            `json:"retry_anti_affinity_hits,omitempty"`
-	UnknownReasonTags     map[string]int64                 `json:"unknown_reason_tags,omitempty"`
-	SessionStrategyID     string                           `json:"session_strategy_id,omitempty"`
-	ReasonTagCounts       map[string]int64                 `json:"reason_tag_counters,omitempty"`
-	PoolHealthHint        *float64                         `json:"pool_health_hint,omitempty"`
+	WorkerID              string                            `json:"worker_id"`
+	Host                  string                            `json:"host,omitempty"`
+	IPAddress             string                            `json:"ip_address,omitempty"`
+	Version               string                            `json:"version,omitempty"`
+	Pool                  string                            `json:"pool,omitempty"`
+	Tags                  []string                          `json:"tags,omitempty"`
+	Status                string                            `json:"status"`
+	CurrentJobID          string                            `json:"current_job_id,omitempty"`
+	CurrentChunkID        string                            `json:"current_chunk_id,omitempty"`
+	CorrelationID         string                            `json:"correlation_id,omitempty"`
+	Metrics               *ControlPlaneWorkerMetrics        `json:"metrics,omitempty"`
+	StageMetrics          *ControlPlaneStageMetrics         `json:"stage_metrics,omitempty"`
+	SMTPMetrics           *ControlPlaneSMTPMetrics          `json:"smtp_metrics,omitempty"`
+	ProviderMetrics       []ControlPlaneProviderMetric      `json:"provider_metrics,omitempty"`
+	EHLOProfileMetrics    []ControlPlaneEHLOProfileMetric   `json:"ehlo_profile_metrics,omitempty"`
+	RoutingMetrics        *ControlPlaneRoutingMetrics       `json:"routing_metrics,omitempty"`
+	SessionMetrics        *ControlPlaneSessionMetrics       `json:"session_metrics,omitempty"`
+	AttemptRouteMetrics   *ControlPlaneAttemptRouteMetrics  `json:"attempt_route_metrics,omitempty"`
+	RetryAntiAffinityHits int64                             `json:"retry_anti_affinity_hits,omitempty"`
+	UnknownReasonTags     map[string]int64                  `json:"unknown_reason_tags,omitempty"`
+	SessionStrategyID     string                            `json:"session_strategy_id,omitempty"`
+	ReasonTagCounts       map[string]int64                  `json:"reason_tag_counters,omitempty"`
+	PoolHealthHint        *float64                          `json:"pool_health_hint,omitempty"`
+	CircuitBreakerEvents  []ControlPlaneCircuitBreakerEvent `json:"circuit_breaker_events,omitempty"`
 }
 
 type ControlPlaneHeartbeatResponse struct {
@@ -118,6 +146,30 @@ type ControlPlaneProviderModeState struct {
 	Mode     string `json:"mode"`
 }
 
+type ControlPlaneDomainListManifest struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Checksum string `json:"checksum"`
+	Count    int    `json:"count"`
+}
+
+type ControlPlaneDomainListsResponse struct {
+	Data struct {
+		Lists []ControlPlaneDomainListManifest `json:"lists"`
+	} `json:"data"`
+}
+
+type ControlPlaneDomainListResponse struct {
+	Data struct {
+		ControlPlaneDomainListManifest
+		Mode        string   `json:"mode"`
+		BaseVersion string   `json:"base_version,omitempty"`
+		Entries     []string `json:"entries,omitempty"`
+		Added       []string `json:"added,omitempty"`
+		Removed     []string `json:"removed,omitempty"`
+	} `json:"data"`
+}
+
 type ControlPlaneModeSemantics struct {
 	ProbeEnabled                bool    `json:"probe_enabled"`
 	MaxConcurrencyMultiplier    float64 `json:"max_concurrency_multiplier,omitempty"`
@@ -179,3 +231,46 @@ func (c *ControlPlaneClient) ProviderPoliciesForPool(ctx context.Context, worker
 
 	return &resp, nil
 }
+
+func (c *ControlPlaneClient) DomainLists(ctx context.Context) (*ControlPlaneDomainListsResponse, error) {
+	status, body, err := doJSON(ctx, c.httpClient, c.baseURL, c.token, http.MethodGet, "/api/domain-lists", nil)
+	if err != nil {
+		return nil, err
+	}
+	if status < 200 || status >= 300 {
+		return nil, APIError{Status: status, Body: string(body)}
+	}
+
+	var resp ControlPlaneDomainListsResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// DomainList fetches one list. With since set the control plane answers
+// with a delta from that version when it still has it.
+func (c *ControlPlaneClient) DomainList(ctx context.Context, name string, since string) (*ControlPlaneDomainListResponse, error) {
+	path := "/api/domain-lists/" + url.PathEscape(strings.TrimSpace(name))
+	if normalizedSince := strings.TrimSpace(since); normalizedSince != "" {
+		query := url.Values{}
+		query.Set("since", normalizedSince)
+		path += "?" + query.Encode()
+	}
+
+	status, body, err := doJSON(ctx, c.httpClient, c.baseURL, c.token, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status < 200 || status >= 300 {
+		return nil, APIError{Status: status, Body: string(body)}
+	}
+
+	var resp ControlPlaneDomainListResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
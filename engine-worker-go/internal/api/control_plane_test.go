@@ -125,3 +125,40 @@ func TestControlPlaneProviderPoliciesForPool(t *testing.T) {
 		t.Fatalf("unexpected active version: %s", resp.Data.ActiveVersion)
 	}
 }
+
+func TestControlPlaneDomainListDelta(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/domain-lists/disposable_domains" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("since"); got != "abc123" {
+			t.Fatalf("expected since query abc123, got %q", got)
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{
+				"name":         "disposable_domains",
+				"version":      "def456",
+				"checksum":     "def456ffff",
+				"count":        2,
+				"mode":         "delta",
+				"base_version": "abc123",
+				"added":        []string{"new.test"},
+				"removed":      []string{"old.test"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := NewControlPlaneClient(server.URL, "token")
+	resp, err := client.DomainList(context.Background(), "disposable_domains", "abc123")
+	if err != nil {
+		t.Fatalf("domain list returned error: %v", err)
+	}
+
+	if resp.Data.Version != "def456" || resp.Data.Mode != "delta" || len(resp.Data.Added) != 1 || len(resp.Data.Removed) != 1 {
+		t.Fatalf("unexpected domain list response: %+v", resp.Data)
+	}
+}
@@ -15,6 +15,7 @@ type Policy struct {
 	SMTPReadTimeoutMs          int      `json:"smtp_read_timeout_ms"`
 	MaxMXAttempts              int      `json:"max_mx_attempts"`
 	MaxConcurrencyDefault      int      `json:"max_concurrency_default"`
+	ChunkParallelism           int      `json:"chunk_parallelism"`
 	PerDomainConcurrency       int      `json:"per_domain_concurrency"`
 	CatchAllDetectionEnabled   bool     `json:"catch_all_detection_enabled"`
 	GlobalConnectsPerMinute    *int     `json:"global_connects_per_minute"`
@@ -39,6 +40,7 @@ type PolicyResponse struct {
 		EnhancedModeEnabled  bool              `json:"enhanced_mode_enabled"`
 		RoleAccountsBehavior string            `json:"role_accounts_behavior"`
 		RoleAccountsList     []string          `json:"role_accounts_list"`
+		TypoDomains          []string          `json:"typo_domains"`
 		ProviderPolicies     []ProviderPolicy  `json:"provider_policies"`
 		Policies             map[string]Policy `json:"policies"`
 	} `json:"data"`
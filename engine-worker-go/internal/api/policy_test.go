@@ -31,6 +31,7 @@ func TestPolicyResponseParsing(t *testing.T) {
         "smtp_read_timeout_ms": 2100,
         "max_mx_attempts": 2,
         "max_concurrency_default": 4,
+        "chunk_parallelism": 8,
         "per_domain_concurrency": 2,
         "catch_all_detection_enabled": true,
         "global_connects_per_minute": 120,
@@ -71,6 +72,9 @@ func TestPolicyResponseParsing(t *testing.T) {
 	if standard.DNSTimeoutMs != 2500 {
 		t.Fatalf("expected dns timeout 2500, got %d", standard.DNSTimeoutMs)
 	}
+	if standard.ChunkParallelism != 8 {
+		t.Fatalf("expected chunk parallelism 8, got %d", standard.ChunkParallelism)
+	}
 	if standard.GlobalConnectsPerMinute == nil || *standard.GlobalConnectsPerMinute != 120 {
 		t.Fatalf("expected global connects per minute 120")
 	}
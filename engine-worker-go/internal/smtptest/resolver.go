@@ -0,0 +1,52 @@
+package smtptest
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// Resolver points arbitrary domains at the test server: every domain gets a
+// single MX host named mx.<domain>, and every host resolves to 127.0.0.1.
+// Domains listed in NXDomains do not exist.
+type Resolver struct {
+	NXDomains []string
+}
+
+func (r Resolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	if r.missing(domain) {
+		return nil, notFound(domain)
+	}
+
+	return []*net.MX{{Host: "mx." + domain + ".", Pref: 10}}, nil
+}
+
+func (r Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+	if r.missing(strings.TrimPrefix(host, "mx.")) {
+		return nil, notFound(host)
+	}
+
+	return []string{"127.0.0.1"}, nil
+}
+
+// LookupTXT reports that no TXT records exist, so domain authentication
+// lookups see a domain without SPF, DMARC, MTA-STS or BIMI.
+func (r Resolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return nil, notFound(name)
+}
+
+func (r Resolver) missing(domain string) bool {
+	for _, nx := range r.NXDomains {
+		if strings.EqualFold(strings.TrimSpace(nx), domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
@@ -0,0 +1,330 @@
+// Package smtptest runs a scripted SMTP server on the loopback interface so
+// probers and the worker loop can be exercised against realistic MX
+// behavior without network access.
+package smtptest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const hostname = "smtptest.local"
+
+// Reply is one scripted server reply. Several lines make a multi-line reply.
+// Delay tarpits the reply; Drop closes the connection instead of replying.
+type Reply struct {
+	Code  int
+	Lines []string
+	Delay time.Duration
+	Drop  bool
+}
+
+func (r Reply) isZero() bool {
+	return r.Code == 0 && !r.Drop
+}
+
+func (r Reply) or(fallback Reply) Reply {
+	if r.isZero() {
+		return fallback
+	}
+
+	return r
+}
+
+func (r Reply) wire() string {
+	lines := r.Lines
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+
+	var b strings.Builder
+	for i, line := range lines {
+		separator := "-"
+		if i == len(lines)-1 {
+			separator = " "
+		}
+		b.WriteString(fmt.Sprintf("%d%s%s\r\n", r.Code, separator, line))
+	}
+
+	return b.String()
+}
+
+// Script describes how the server answers each stage of a session. Zero
+// replies fall back to a well-behaved server that accepts every command.
+//
+// RCPT TO is answered by Rcpt when it is set. Otherwise recipients are
+// greylisted for Greylist on their first attempt, then accepted when they
+// are listed in Mailboxes or CatchAll is set, and rejected with Unknown.
+type Script struct {
+	Banner    Reply
+	EHLO      Reply
+	HELO      Reply
+	Mail      Reply
+	Rcpt      func(recipient string) Reply
+	Mailboxes []string
+	CatchAll  bool
+	Greylist  time.Duration
+	Unknown   Reply
+	// Now is the clock for greylist windows; nil uses time.Now.
+	Now func() time.Time
+}
+
+// Server is a scripted SMTP listener on 127.0.0.1.
+type Server struct {
+	script    Script
+	listener  net.Listener
+	mailboxes map[string]struct{}
+	closed    chan struct{}
+	wg        sync.WaitGroup
+
+	mu         sync.Mutex
+	conns      map[net.Conn]struct{}
+	commands   []string
+	sessions   int
+	greylisted map[string]time.Time
+}
+
+// NewServer starts a server running script on a free loopback port.
+func NewServer(script Script) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	if script.Now == nil {
+		script.Now = time.Now
+	}
+
+	s := &Server{
+		script:     script,
+		listener:   listener,
+		mailboxes:  map[string]struct{}{},
+		closed:     make(chan struct{}),
+		conns:      map[net.Conn]struct{}{},
+		greylisted: map[string]time.Time{},
+	}
+	for _, mailbox := range script.Mailboxes {
+		s.mailboxes[strings.ToLower(strings.TrimSpace(mailbox))] = struct{}{}
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// Addr returns the host:port the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the listener, drops open sessions and waits for them to end.
+func (s *Server) Close() error {
+	select {
+	case <-s.closed:
+		return nil
+	default:
+	}
+	close(s.closed)
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+
+	return err
+}
+
+// Commands returns every command received so far, across sessions.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.commands...)
+}
+
+// CommandCount returns how many received commands start with prefix.
+func (s *Server) CommandCount(prefix string) int {
+	count := 0
+	for _, command := range s.Commands() {
+		if strings.HasPrefix(strings.ToUpper(command), strings.ToUpper(prefix)) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Sessions returns how many connections the server has accepted.
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sessions
+}
+
+// Dialer returns a dialer that connects every address to the server.
+func (s *Server) Dialer() Dialer {
+	return Dialer{Addr: s.Addr()}
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.sessions++
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.forget(conn)
+
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) forget(conn net.Conn) {
+	_ = conn.Close()
+
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+}
+
+func (s *Server) handle(conn net.Conn) {
+	writer := bufio.NewWriter(conn)
+	if !s.reply(writer, s.script.Banner.or(Reply{Code: 220, Lines: []string{hostname + " ESMTP smtptest"}})) {
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+
+		verb, arg, _ := strings.Cut(line, " ")
+		var response Reply
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			response = s.script.EHLO.or(Reply{Code: 250, Lines: []string{hostname, "PIPELINING", "8BITMIME", "ENHANCEDSTATUSCODES"}})
+		case "HELO":
+			response = s.script.HELO.or(Reply{Code: 250, Lines: []string{hostname}})
+		case "MAIL":
+			response = s.script.Mail.or(Reply{Code: 250, Lines: []string{"2.1.0 Sender OK"}})
+		case "RCPT":
+			response = s.rcpt(recipientOf(arg))
+		case "RSET":
+			response = Reply{Code: 250, Lines: []string{"2.0.0 Reset OK"}}
+		case "NOOP":
+			response = Reply{Code: 250, Lines: []string{"2.0.0 OK"}}
+		case "QUIT":
+			s.reply(writer, Reply{Code: 221, Lines: []string{"2.0.0 Bye"}})
+			return
+		case "STARTTLS":
+			response = Reply{Code: 454, Lines: []string{"4.7.0 TLS not available"}}
+		default:
+			response = Reply{Code: 502, Lines: []string{"5.5.2 Command not recognized"}}
+		}
+
+		if !s.reply(writer, response) {
+			return
+		}
+	}
+}
+
+// reply writes response after its delay and reports whether the session
+// should go on.
+func (s *Server) reply(writer *bufio.Writer, response Reply) bool {
+	if response.Delay > 0 {
+		timer := time.NewTimer(response.Delay)
+		select {
+		case <-timer.C:
+		case <-s.closed:
+			timer.Stop()
+			return false
+		}
+	}
+	if response.Drop {
+		return false
+	}
+
+	if _, err := writer.WriteString(response.wire()); err != nil {
+		return false
+	}
+
+	return writer.Flush() == nil
+}
+
+func (s *Server) rcpt(recipient string) Reply {
+	if s.script.Rcpt != nil {
+		return s.script.Rcpt(recipient)
+	}
+
+	key := strings.ToLower(recipient)
+	if s.script.Greylist > 0 {
+		now := s.script.Now()
+
+		s.mu.Lock()
+		until, seen := s.greylisted[key]
+		if !seen {
+			until = now.Add(s.script.Greylist)
+			s.greylisted[key] = until
+		}
+		s.mu.Unlock()
+
+		if now.Before(until) {
+			return Reply{Code: 451, Lines: []string{fmt.Sprintf("4.7.1 Greylisted, try again in %d seconds", int(s.script.Greylist.Seconds()))}}
+		}
+	}
+
+	if _, ok := s.mailboxes[key]; ok || s.script.CatchAll {
+		return Reply{Code: 250, Lines: []string{"2.1.5 Recipient OK"}}
+	}
+
+	return s.script.Unknown.or(Reply{Code: 550, Lines: []string{"5.1.1 Mailbox does not exist"}})
+}
+
+func recipientOf(arg string) string {
+	start := strings.Index(arg, "<")
+	end := strings.LastIndex(arg, ">")
+	if start < 0 || end <= start {
+		return strings.TrimSpace(arg)
+	}
+
+	return arg[start+1 : end]
+}
+
+// Dialer connects every address to one server, so any MX host the resolver
+// hands out reaches it.
+type Dialer struct {
+	Addr string
+}
+
+func (d Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var dialer net.Dialer
+
+	return dialer.DialContext(ctx, "tcp", d.Addr)
+}
@@ -0,0 +1,158 @@
+package smtptest
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSession struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialSession(t *testing.T, server *Server) *testSession {
+	t.Helper()
+
+	conn, err := server.Dialer().DialContext(context.Background(), "tcp", "mx.example.com:25")
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	return &testSession{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// read returns the lines of one reply, or nil when the connection closed.
+func (s *testSession) read() []string {
+	s.t.Helper()
+
+	lines := []string{}
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if len(lines) == 0 {
+				return nil
+			}
+			s.t.Fatalf("reply cut short after %q: %v", lines, err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		lines = append(lines, line)
+		if len(line) < 4 || line[3] != '-' {
+			return lines
+		}
+	}
+}
+
+func (s *testSession) send(command string) []string {
+	s.t.Helper()
+
+	if _, err := s.conn.Write([]byte(command + "\r\n")); err != nil {
+		s.t.Fatalf("write %q failed: %v", command, err)
+	}
+
+	return s.read()
+}
+
+func newTestServer(t *testing.T, script Script) *Server {
+	t.Helper()
+
+	server, err := NewServer(script)
+	if err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	return server
+}
+
+func TestServerAnswersMailboxesAndRecordsCommands(t *testing.T) {
+	server := newTestServer(t, Script{Mailboxes: []string{"User@Example.com"}})
+	session := dialSession(t, server)
+
+	if banner := session.read(); len(banner) != 1 || !strings.HasPrefix(banner[0], "220 ") {
+		t.Fatalf("expected a 220 banner, got %q", banner)
+	}
+	if ehlo := session.send("EHLO probe.test"); len(ehlo) < 2 || !strings.HasPrefix(ehlo[0], "250-") {
+		t.Fatalf("expected a multi-line EHLO reply, got %q", ehlo)
+	}
+	if reply := session.send("MAIL FROM:<probe@probe.test>"); !strings.HasPrefix(reply[0], "250 ") {
+		t.Fatalf("expected MAIL FROM accepted, got %q", reply)
+	}
+	if reply := session.send("RCPT TO:<user@example.com>"); !strings.HasPrefix(reply[0], "250 ") {
+		t.Fatalf("expected listed mailbox accepted, got %q", reply)
+	}
+	if reply := session.send("RCPT TO:<other@example.com>"); !strings.HasPrefix(reply[0], "550 5.1.1") {
+		t.Fatalf("expected unknown mailbox rejected, got %q", reply)
+	}
+	session.send("QUIT")
+
+	if server.Sessions() != 1 || server.CommandCount("RCPT TO") != 2 {
+		t.Fatalf("expected one session with two RCPTs, got %d sessions and %q", server.Sessions(), server.Commands())
+	}
+}
+
+func TestServerGreylistsUntilWindowPasses(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := newTestServer(t, Script{
+		CatchAll: true,
+		Greylist: 5 * time.Minute,
+		Now:      func() time.Time { return clock },
+	})
+	session := dialSession(t, server)
+	session.read()
+	session.send("EHLO probe.test")
+	session.send("MAIL FROM:<probe@probe.test>")
+
+	if reply := session.send("RCPT TO:<user@example.com>"); !strings.HasPrefix(reply[0], "451 4.7.1 Greylisted") {
+		t.Fatalf("expected first attempt greylisted, got %q", reply)
+	}
+	clock = clock.Add(time.Minute)
+	if reply := session.send("RCPT TO:<user@example.com>"); !strings.HasPrefix(reply[0], "451 ") {
+		t.Fatalf("expected retry inside the window greylisted, got %q", reply)
+	}
+	clock = clock.Add(5 * time.Minute)
+	if reply := session.send("RCPT TO:<user@example.com>"); !strings.HasPrefix(reply[0], "250 ") {
+		t.Fatalf("expected retry after the window accepted, got %q", reply)
+	}
+}
+
+func TestServerTarpitsAndDropsConnections(t *testing.T) {
+	server := newTestServer(t, Script{
+		Banner: Reply{Code: 220, Lines: []string{"slow.test ESMTP"}, Delay: 50 * time.Millisecond},
+		Mail:   Reply{Drop: true},
+	})
+	session := dialSession(t, server)
+
+	start := time.Now()
+	session.read()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected the banner tarpitted, got it after %s", elapsed)
+	}
+	session.send("EHLO probe.test")
+	if reply := session.send("MAIL FROM:<probe@probe.test>"); reply != nil {
+		t.Fatalf("expected the connection dropped, got %q", reply)
+	}
+}
+
+func TestResolverPointsDomainsAtServer(t *testing.T) {
+	resolver := Resolver{NXDomains: []string{"missing.test"}}
+
+	records, err := resolver.LookupMX(context.Background(), "Example.COM")
+	if err != nil || len(records) != 1 || records[0].Host != "mx.example.com." {
+		t.Fatalf("expected mx.example.com, got %v %v", records, err)
+	}
+	if addresses, err := resolver.LookupHost(context.Background(), "mx.example.com"); err != nil || addresses[0] != "127.0.0.1" {
+		t.Fatalf("expected loopback address, got %v %v", addresses, err)
+	}
+
+	_, err = resolver.LookupMX(context.Background(), "missing.test")
+	if dnsErr, ok := err.(*net.DNSError); !ok || !dnsErr.IsNotFound {
+		t.Fatalf("expected NXDOMAIN, got %v", err)
+	}
+}
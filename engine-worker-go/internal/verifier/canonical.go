@@ -0,0 +1,64 @@
+package verifier
+
+import (
+	"sort"
+	"strings"
+
+	"engine-worker-go/replypolicy"
+)
+
+// ProviderCanonicalization lists the address domains a provider profile
+// owns and the local-part variants that provider treats as one mailbox.
+type ProviderCanonicalization = replypolicy.Canonicalization
+
+// CanonicalEmail returns the address email is delivered to under the
+// canonicalization of the profile owning its domain, and that profile's
+// name. The whole address is lowercased first, as parseEmail does before
+// probing. Addresses that fail ValidateAddressSyntax, IP literals and quoted
+// local parts are returned trimmed and lowercased, with an empty profile
+// name. A nil engine uses the default profiles.
+func CanonicalEmail(engine *ProviderReplyPolicyEngine, email string) (string, string) {
+	email = strings.TrimSpace(strings.ToLower(email))
+	syntax, reason := ValidateAddressSyntax(email)
+	if reason != "" || strings.HasPrefix(syntax.Local, `"`) {
+		return email, ""
+	}
+
+	profiles := replypolicy.DefaultProfiles()
+	if engine != nil && len(engine.Profiles) > 0 {
+		profiles = engine.Profiles
+	}
+
+	domain := syntax.Domain
+	provider, rules := canonicalizationFor(profiles, domain)
+
+	local := syntax.Local
+	if rules.PlusAddressing {
+		if tag := strings.Index(local, "+"); tag > 0 {
+			local = local[:tag]
+		}
+	}
+	if rules.IgnoreDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+
+	return local + "@" + domain, provider
+}
+
+func canonicalizationFor(profiles map[string]ProviderReplyProfile, domain string) (string, ProviderCanonicalization) {
+	providers := make([]string, 0, len(profiles))
+	for provider := range profiles {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	for _, provider := range providers {
+		for _, candidate := range profiles[provider].Canonicalization.Domains {
+			if replypolicy.NormalizeCanonicalDomain(candidate) == domain {
+				return provider, profiles[provider].Canonicalization
+			}
+		}
+	}
+
+	return "generic", profiles["generic"].Canonicalization
+}
@@ -0,0 +1,65 @@
+package verifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalEmailAppliesProviderRules(t *testing.T) {
+	cases := []struct {
+		email     string
+		canonical string
+		provider  string
+	}{
+		{"John.Doe+promo@Gmail.com", "johndoe@gmail.com", "gmail"},
+		{"johndoe@googlemail.com", "johndoe@googlemail.com", "gmail"},
+		{"J.Doe+news@outlook.com", "j.doe@outlook.com", "microsoft"},
+		{"J.Doe+news@Yahoo.com", "j.doe+news@yahoo.com", "yahoo"},
+		{"J.Doe+news@Example.com", "j.doe+news@example.com", "generic"},
+		{"+only@gmail.com", "+only@gmail.com", "gmail"},
+		{" first..last@gmail.com ", "first..last@gmail.com", ""},
+		{`"J.Doe"@gmail.com`, `"j.doe"@gmail.com`, ""},
+		{"user@[192.0.2.1]", "user@[192.0.2.1]", ""},
+	}
+
+	for _, tc := range cases {
+		canonical, provider := CanonicalEmail(nil, tc.email)
+		if canonical != tc.canonical || provider != tc.provider {
+			t.Fatalf("expected %q (%s) for %q, got %q (%s)", tc.canonical, tc.provider, tc.email, canonical, provider)
+		}
+	}
+}
+
+func TestCanonicalizationFromPolicyPayload(t *testing.T) {
+	engine, err := ParseProviderReplyPolicyEngineJSON(`{
+		"profiles": {
+			"generic": {"canonicalization": {"ignore_dots": true}},
+			"fastmail": {"canonicalization": {"domains": ["FastMail.com"], "plus_addressing": true}}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("expected payload to parse, got %v", err)
+	}
+
+	cases := map[string]string{
+		"Jane+tag@fastmail.com":  "jane@fastmail.com",
+		"Jane.Doe@Example.com":   "janedoe@example.com",
+		"Jane.Doe+x@gmail.com":   "janedoe@gmail.com",
+		"Jane.Doe+x@hotmail.com": "jane.doe@hotmail.com",
+	}
+	for email, expected := range cases {
+		if canonical, _ := CanonicalEmail(engine, email); canonical != expected {
+			t.Fatalf("expected %q for %q, got %q", expected, email, canonical)
+		}
+	}
+
+	_, err = ParseProviderReplyPolicyEngineJSON(`{
+		"profiles": {
+			"gmail": {"canonicalization": {"domains": ["gmail.com"], "ignore_dots": true}},
+			"workspace": {"canonicalization": {"domains": ["Gmail.com"]}}
+		}
+	}`)
+	if err == nil || !strings.Contains(err.Error(), `profiles.workspace.canonicalization.domains[0]: domain "gmail.com" is already listed by profile gmail`) {
+		t.Fatalf("expected a duplicate canonicalization domain error, got %v", err)
+	}
+}
@@ -0,0 +1,377 @@
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCatchAllCacheTTL        = 6 * time.Hour
+	defaultCatchAllUndeterminedTTL = 5 * time.Minute
+	defaultCatchAllCacheMaxEntries = 50000
+	defaultCatchAllSamples         = 3
+	maxCatchAllSamples             = 5
+)
+
+// Catch-all states of a domain.
+const (
+	CatchAllAcceptsAll     = "accepts_all"
+	CatchAllRejectsUnknown = "rejects_unknown"
+	CatchAllInconsistent   = "inconsistent"
+	CatchAllUndetermined   = "undetermined"
+)
+
+// reasonCatchAllInconsistent is reported when random recipients at one
+// domain are both accepted and rejected.
+const reasonCatchAllInconsistent = "catch_all_inconsistent"
+
+// CatchAllVerdict summarises the random-recipient samples taken at a domain.
+type CatchAllVerdict struct {
+	// Requested is how many samples were asked for; Samples is how many
+	// were taken before the session broke or was discarded.
+	Requested int
+	Samples   int
+	Accepted  int
+	Rejected  int
+	// Deferred is set when an undecided sample was a tempfail, policy
+	// block or unknown reply rather than a connection failure.
+	Deferred bool
+
+	// last is the final undecided sample, whose retry details a medium
+	// confidence result carries.
+	last Result
+}
+
+// State classifies the samples. Accepted and rejected samples together mean
+// the server answers inconsistently, e.g. behind a load balancer.
+func (v CatchAllVerdict) State() string {
+	switch {
+	case v.Accepted > 0 && v.Rejected > 0:
+		return CatchAllInconsistent
+	case v.Accepted > 0:
+		return CatchAllAcceptsAll
+	case v.Rejected > 0:
+		return CatchAllRejectsUnknown
+	default:
+		return CatchAllUndetermined
+	}
+}
+
+// Confidence is high when every requested sample was taken and agreed, and
+// medium when some were undecided or never taken. Undetermined verdicts are
+// medium when a sample was deferred.
+func (v CatchAllVerdict) Confidence() string {
+	switch v.State() {
+	case CatchAllInconsistent:
+		return "low"
+	case CatchAllUndetermined:
+		if v.Deferred {
+			return "medium"
+		}
+		return "low"
+	}
+	if v.complete() && (v.Accepted == v.Samples || v.Rejected == v.Samples) {
+		return "high"
+	}
+
+	return "medium"
+}
+
+// decisive reports whether the verdict may be cached for the full TTL: a
+// sample was accepted or rejected and every requested sample was taken.
+func (v CatchAllVerdict) decisive() bool {
+	return v.State() != CatchAllUndetermined && v.complete()
+}
+
+func (v CatchAllVerdict) complete() bool {
+	return v.Samples >= v.Requested
+}
+
+func (v *CatchAllVerdict) record(sample Result) {
+	v.Samples++
+	switch {
+	case sample.Category == CategoryValid:
+		v.Accepted++
+	case sample.Category == CategoryInvalid:
+		v.Rejected++
+	default:
+		if sample.DecisionClass == DecisionRetryable || sample.DecisionClass == DecisionPolicyBlocked || sample.DecisionClass == DecisionUnknown {
+			v.Deferred = true
+		}
+		v.last = sample
+	}
+}
+
+type CatchAllCacheConfig struct {
+	TTL time.Duration
+	// UndeterminedTTL is how long a verdict without an accepted or rejected
+	// sample is kept, so a tempfailing domain is not sampled for every
+	// address. It never exceeds TTL.
+	UndeterminedTTL time.Duration
+	MaxEntries      int
+}
+
+// CatchAllCache remembers each domain's catch-all verdict so the random
+// recipients are sampled once per domain per TTL. Undetermined verdicts and
+// verdicts missing some of their samples are kept for the shorter
+// UndeterminedTTL, and concurrent probes of one domain
+// share the sampling.
+type CatchAllCache struct {
+	config   CatchAllCacheConfig
+	mu       sync.Mutex
+	entries  map[string]catchAllEntry
+	inflight map[string]*catchAllCall
+	now      func() time.Time
+}
+
+type catchAllEntry struct {
+	verdict CatchAllVerdict
+	expires time.Time
+}
+
+type catchAllCall struct {
+	done    chan struct{}
+	verdict CatchAllVerdict
+}
+
+func NewCatchAllCache(config CatchAllCacheConfig) *CatchAllCache {
+	if config.TTL <= 0 {
+		config.TTL = defaultCatchAllCacheTTL
+	}
+	if config.UndeterminedTTL <= 0 {
+		config.UndeterminedTTL = defaultCatchAllUndeterminedTTL
+	}
+	if config.UndeterminedTTL > config.TTL {
+		config.UndeterminedTTL = config.TTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultCatchAllCacheMaxEntries
+	}
+
+	return &CatchAllCache{
+		config:   config,
+		entries:  map[string]catchAllEntry{},
+		inflight: map[string]*catchAllCall{},
+		now:      time.Now,
+	}
+}
+
+// Determine returns the verdict for domain, calling sample on a cache miss.
+// cached reports whether the verdict came from the cache or from another
+// probe's sampling. A caller whose wait is cancelled samples itself.
+func (c *CatchAllCache) Determine(ctx context.Context, domain string, sample func() CatchAllVerdict) (CatchAllVerdict, bool) {
+	key := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		if c.now().Before(entry.expires) {
+			c.mu.Unlock()
+			return entry.verdict, true
+		}
+		delete(c.entries, key)
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.verdict, true
+		case <-ctx.Done():
+		}
+
+		return sample(), false
+	}
+
+	call := &catchAllCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.verdict = sample()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if len(c.entries) >= c.config.MaxEntries {
+		c.evictExpiredLocked()
+	}
+	if len(c.entries) >= c.config.MaxEntries {
+		for existing := range c.entries {
+			delete(c.entries, existing)
+			break
+		}
+	}
+	ttl := c.config.TTL
+	if !call.verdict.decisive() {
+		ttl = c.config.UndeterminedTTL
+	}
+	c.entries[key] = catchAllEntry{verdict: call.verdict, expires: c.now().Add(ttl)}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.verdict, false
+}
+
+// EvictExpired drops expired verdicts and returns how many were removed.
+func (c *CatchAllCache) EvictExpired() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.evictExpiredLocked()
+}
+
+func (c *CatchAllCache) evictExpiredLocked() int {
+	now := c.now()
+	evicted := 0
+	for key, entry := range c.entries {
+		if now.Before(entry.expires) {
+			continue
+		}
+		delete(c.entries, key)
+		evicted++
+	}
+
+	return evicted
+}
+
+// checkCatchAll decides whether the domain of an accepted email accepts any
+// recipient. With a cache the verdict is sampled once per domain and reused
+// without further RCPTs; without one every accepted address costs a single
+// random RCPT.
+func (p NetSMTPProber) checkCatchAll(ctx context.Context, session *smtpSession, host, email string, accepted Result) Result {
+	domain := ""
+	if at := strings.LastIndex(email, "@"); at != -1 && at+1 < len(email) {
+		domain = email[at+1:]
+	}
+	if domain == "" {
+		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_tempfail"})
+	}
+
+	if p.CatchAllCache == nil {
+		return p.catchAllResult(p.sampleCatchAll(ctx, session, host, domain, 1), false, accepted)
+	}
+
+	sample := func() CatchAllVerdict {
+		return p.sampleCatchAll(ctx, session, host, domain, p.catchAllSamples())
+	}
+	verdict, cached := p.CatchAllCache.Determine(ctx, domain, sample)
+	return p.catchAllResult(verdict, cached, accepted)
+}
+
+func (p NetSMTPProber) catchAllSamples() int {
+	samples := p.CatchAllSamples
+	if samples <= 0 {
+		samples = defaultCatchAllSamples
+	}
+	if samples > maxCatchAllSamples {
+		samples = maxCatchAllSamples
+	}
+
+	return samples
+}
+
+// sampleCatchAll sends RCPTs for up to samples random local parts at domain,
+// stopping early once the session can no longer be used.
+func (p NetSMTPProber) sampleCatchAll(ctx context.Context, session *smtpSession, host, domain string, samples int) CatchAllVerdict {
+	verdict := CatchAllVerdict{Requested: samples}
+	for i := 0; i < samples; i++ {
+		randomEmail := fmt.Sprintf("%s@%s", p.randomLocalPart(), domain)
+		verdict.record(p.checkRcpt(ctx, session, host, randomEmail, true))
+		if session.broken || session.discard {
+			break
+		}
+	}
+
+	return verdict
+}
+
+func (p NetSMTPProber) catchAllResult(verdict CatchAllVerdict, cached bool, accepted Result) Result {
+	confidence := verdict.Confidence()
+	var result Result
+	switch verdict.State() {
+	case CatchAllAcceptsAll:
+		reason := "catch_all_high_confidence"
+		if confidence != "high" {
+			reason = "catch_all_medium_confidence"
+		}
+		result = Result{
+			Category:           CategoryRisky,
+			Reason:             reason,
+			ReasonCode:         reason,
+			DecisionClass:      DecisionUnknown,
+			DecisionConfidence: confidence,
+			RetryStrategy:      "none",
+			ProviderProfile:    accepted.ProviderProfile,
+			SMTPCode:           accepted.SMTPCode,
+			EnhancedCode:       accepted.EnhancedCode,
+		}
+	case CatchAllRejectsUnknown:
+		result = Result{
+			Category:           CategoryValid,
+			Reason:             "rcpt_ok",
+			ReasonCode:         "rcpt_ok",
+			DecisionClass:      DecisionDeliverable,
+			DecisionConfidence: confidence,
+			RetryStrategy:      "none",
+			ProviderProfile:    accepted.ProviderProfile,
+		}
+	case CatchAllInconsistent:
+		result = Result{
+			Category:           CategoryRisky,
+			Reason:             reasonCatchAllInconsistent,
+			ReasonCode:         reasonCatchAllInconsistent,
+			DecisionClass:      DecisionUnknown,
+			DecisionConfidence: confidence,
+			RetryStrategy:      "none",
+			ProviderProfile:    accepted.ProviderProfile,
+			SMTPCode:           accepted.SMTPCode,
+			EnhancedCode:       accepted.EnhancedCode,
+		}
+	default:
+		last := verdict.last
+		if verdict.Deferred {
+			result = Result{
+				Category:           CategoryRisky,
+				Reason:             "catch_all_medium_confidence",
+				ReasonCode:         "catch_all_medium_confidence",
+				DecisionClass:      DecisionUnknown,
+				DecisionConfidence: confidence,
+				RetryStrategy:      last.RetryStrategy,
+				ProviderProfile:    last.ProviderProfile,
+				SMTPCode:           last.SMTPCode,
+				EnhancedCode:       last.EnhancedCode,
+				RetryAfterSecond:   last.RetryAfterSecond,
+				Evidence:           last.Evidence,
+			}
+		} else {
+			result = Result{
+				Category:           CategoryRisky,
+				Reason:             "catch_all_low_confidence",
+				ReasonCode:         "catch_all_low_confidence",
+				DecisionClass:      DecisionUnknown,
+				DecisionConfidence: confidence,
+				RetryStrategy:      "none",
+				ProviderProfile:    last.ProviderProfile,
+				SMTPCode:           last.SMTPCode,
+				EnhancedCode:       last.EnhancedCode,
+				Evidence:           last.Evidence,
+			}
+		}
+	}
+
+	if result.Evidence == nil {
+		result.Evidence = &ReplyEvidence{}
+	} else {
+		evidence := *result.Evidence
+		result.Evidence = &evidence
+	}
+	result.Evidence.CatchAll = verdict.State()
+	result.Evidence.CatchAllSamples = fmt.Sprintf("%d/%d", verdict.Accepted, verdict.Samples)
+	result.Evidence.CatchAllCached = cached
+
+	return p.applySessionContext(result)
+}
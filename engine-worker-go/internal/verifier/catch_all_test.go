@@ -0,0 +1,179 @@
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// catchAllServer accepts the RCPTs for which accept returns true and counts
+// every RCPT it sees.
+func catchAllServer(t *testing.T, accept func(rcpt string) bool) (net.Conn, *int) {
+	client, server := net.Pipe()
+	rcpts := 0
+	go runSMTPServer(t, server, func(line string) string {
+		switch {
+		case strings.HasPrefix(line, "RCPT TO:<"):
+			rcpts++
+			if accept(strings.TrimSuffix(strings.TrimPrefix(line, "RCPT TO:<"), ">")) {
+				return "250 OK"
+			}
+			return "550 No such user"
+		case strings.HasPrefix(line, "QUIT"):
+			return "221 Bye"
+		default:
+			return "250 OK"
+		}
+	})
+
+	return client, &rcpts
+}
+
+func sequenceLocalParts() func() string {
+	var mu sync.Mutex
+	next := 0
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		next++
+		return fmt.Sprintf("random%d", next)
+	}
+}
+
+func TestCatchAllVerdictStates(t *testing.T) {
+	cases := []struct {
+		verdict    CatchAllVerdict
+		state      string
+		confidence string
+	}{
+		{CatchAllVerdict{Samples: 3, Accepted: 3}, CatchAllAcceptsAll, "high"},
+		{CatchAllVerdict{Samples: 3, Accepted: 2, Deferred: true}, CatchAllAcceptsAll, "medium"},
+		{CatchAllVerdict{Samples: 3, Rejected: 3}, CatchAllRejectsUnknown, "high"},
+		{CatchAllVerdict{Samples: 3, Accepted: 1, Rejected: 2}, CatchAllInconsistent, "low"},
+		{CatchAllVerdict{Samples: 3, Deferred: true}, CatchAllUndetermined, "medium"},
+		{CatchAllVerdict{Samples: 1}, CatchAllUndetermined, "low"},
+		{CatchAllVerdict{Requested: 3, Samples: 1, Accepted: 1}, CatchAllAcceptsAll, "medium"},
+		{CatchAllVerdict{Requested: 3, Samples: 1, Rejected: 1}, CatchAllRejectsUnknown, "medium"},
+	}
+	for _, tc := range cases {
+		if state := tc.verdict.State(); state != tc.state {
+			t.Fatalf("expected state %s for %+v, got %s", tc.state, tc.verdict, state)
+		}
+		if confidence := tc.verdict.Confidence(); confidence != tc.confidence {
+			t.Fatalf("expected confidence %s for %+v, got %s", tc.confidence, tc.verdict, confidence)
+		}
+	}
+}
+
+func TestSMTPProberReusesCachedCatchAllVerdict(t *testing.T) {
+	cache := NewCatchAllCache(CatchAllCacheConfig{})
+
+	conn, rcpts := catchAllServer(t, func(string) bool { return true })
+	prober := newProber(t, conn)
+	prober.CatchAllDetectionEnabled = true
+	prober.CatchAllCache = cache
+	prober.RandomLocalPart = sequenceLocalParts()
+
+	first := prober.Check(context.Background(), "mx.test", "first@test.com")
+	if first.Reason != "catch_all_high_confidence" || first.Evidence == nil || first.Evidence.CatchAllSamples != "3/3" {
+		t.Fatalf("expected sampled high-confidence catch-all, got %s %+v", first.Reason, first.Evidence)
+	}
+	if *rcpts != 4 {
+		t.Fatalf("expected the address and three samples, got %d RCPTs", *rcpts)
+	}
+
+	conn, rcpts = catchAllServer(t, func(string) bool { return true })
+	prober.Dialer = pipeDialer{conn: conn}
+	second := prober.Check(context.Background(), "mx.test", "second@TEST.com")
+	if second.Reason != "catch_all_high_confidence" || !second.Evidence.CatchAllCached {
+		t.Fatalf("expected cached catch-all verdict, got %s %+v", second.Reason, second.Evidence)
+	}
+	if *rcpts != 1 {
+		t.Fatalf("expected only the address RCPT on a cache hit, got %d", *rcpts)
+	}
+}
+
+func TestSMTPProberFlagsInconsistentCatchAll(t *testing.T) {
+	conn, _ := catchAllServer(t, func(rcpt string) bool {
+		return rcpt == "user@test.com" || rcpt == "random2@test.com"
+	})
+	prober := newProber(t, conn)
+	prober.CatchAllDetectionEnabled = true
+	prober.CatchAllCache = NewCatchAllCache(CatchAllCacheConfig{})
+	prober.RandomLocalPart = sequenceLocalParts()
+
+	res := prober.Check(context.Background(), "mx.test", "user@test.com")
+	if res.Category != CategoryRisky || res.Reason != reasonCatchAllInconsistent {
+		t.Fatalf("expected risky catch_all_inconsistent, got %s/%s", res.Category, res.Reason)
+	}
+	if res.Evidence.CatchAll != CatchAllInconsistent || res.Evidence.CatchAllSamples != "1/3" {
+		t.Fatalf("expected inconsistent evidence with 1/3 accepted, got %+v", res.Evidence)
+	}
+}
+
+func TestSMTPProberKeepsMailboxWhenSamplesRejected(t *testing.T) {
+	conn, _ := catchAllServer(t, func(rcpt string) bool { return rcpt == "user@test.com" })
+	prober := newProber(t, conn)
+	prober.CatchAllDetectionEnabled = true
+	prober.RandomLocalPart = sequenceLocalParts()
+
+	res := prober.Check(context.Background(), "mx.test", "user@test.com")
+	if res.Category != CategoryValid || res.Reason != "rcpt_ok" || res.DecisionConfidence != "high" {
+		t.Fatalf("expected high-confidence rcpt_ok, got %s/%s %s", res.Category, res.Reason, res.DecisionConfidence)
+	}
+}
+
+func TestCatchAllCacheKeepsUndeterminedVerdictsBriefly(t *testing.T) {
+	cache := NewCatchAllCache(CatchAllCacheConfig{UndeterminedTTL: time.Minute})
+	now := time.Unix(1_700_000_000, 0)
+	cache.now = func() time.Time { return now }
+	calls := 0
+	sample := func() CatchAllVerdict {
+		calls++
+		return CatchAllVerdict{Samples: 3, Deferred: true}
+	}
+
+	cache.Determine(context.Background(), "example.com", sample)
+	if _, cached := cache.Determine(context.Background(), "example.com", sample); !cached || calls != 1 {
+		t.Fatalf("expected undetermined verdict to be reused within its TTL, cached=%t calls=%d", cached, calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, cached := cache.Determine(context.Background(), "example.com", sample); cached || calls != 2 {
+		t.Fatalf("expected undetermined verdict to be sampled again after its TTL, cached=%t calls=%d", cached, calls)
+	}
+}
+
+func TestCatchAllCacheKeepsIncompleteVerdictsBriefly(t *testing.T) {
+	cache := NewCatchAllCache(CatchAllCacheConfig{UndeterminedTTL: time.Minute})
+	now := time.Unix(1_700_000_000, 0)
+	cache.now = func() time.Time { return now }
+	calls := 0
+	sample := func() CatchAllVerdict {
+		calls++
+		return CatchAllVerdict{Requested: 3, Samples: 1, Accepted: 1}
+	}
+
+	cache.Determine(context.Background(), "example.com", sample)
+	now = now.Add(2 * time.Minute)
+	if _, cached := cache.Determine(context.Background(), "example.com", sample); cached || calls != 2 {
+		t.Fatalf("expected a verdict missing samples to expire with the undetermined TTL, cached=%t calls=%d", cached, calls)
+	}
+}
+
+func TestSMTPProberTakesOneCatchAllSampleWithoutCache(t *testing.T) {
+	conn, rcpts := catchAllServer(t, func(string) bool { return true })
+	prober := newProber(t, conn)
+	prober.CatchAllDetectionEnabled = true
+	prober.CatchAllSamples = 3
+	prober.RandomLocalPart = sequenceLocalParts()
+
+	res := prober.Check(context.Background(), "mx.test", "user@test.com")
+	if res.Evidence == nil || res.Evidence.CatchAllSamples != "1/1" || *rcpts != 2 {
+		t.Fatalf("expected the address and one sample without a cache, got %+v after %d RCPTs", res.Evidence, *rcpts)
+	}
+}
@@ -0,0 +1,381 @@
+package verifier
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+
+	defaultCircuitWindowSize      = 50
+	defaultCircuitMinSamples      = 10
+	defaultCircuitCooldown        = 60 * time.Second
+	defaultCircuitIdleTimeout     = 10 * time.Minute
+	maxPendingCircuitTransitions  = 200
+	circuitBreakerProviderKeyBase = "provider:"
+	circuitBreakerMXKeyBase       = "mx:"
+)
+
+type CircuitTransition struct {
+	Key          string    `json:"key"`
+	Scope        string    `json:"scope"`
+	From         string    `json:"from"`
+	To           string    `json:"to"`
+	TempfailRate float64   `json:"tempfail_rate"`
+	Samples      int       `json:"samples"`
+	At           time.Time `json:"at"`
+}
+
+type CircuitBreakerConfig struct {
+	TempfailRate float64
+	WindowSize   int
+	MinSamples   int
+	Cooldown     time.Duration
+	// IdleTimeout is how long a closed circuit may go without outcomes
+	// before EvictIdle drops it.
+	IdleTimeout time.Duration
+}
+
+// CircuitBreaker tracks a rolling tempfail ratio per provider and per MX
+// host. Once a key crosses the configured ratio it opens and rejects probes
+// until the cooldown elapses, then lets a single trial probe through.
+type CircuitBreaker struct {
+	mu          sync.Mutex
+	config      CircuitBreakerConfig
+	circuits    map[string]*circuitState
+	transitions []CircuitTransition
+	now         func() time.Time
+}
+
+type circuitState struct {
+	state         string
+	outcomes      []bool
+	next          int
+	filled        int
+	tempfails     int
+	openedAt      time.Time
+	lastUsed      time.Time
+	trialInFlight bool
+}
+
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		config:   normalizeCircuitBreakerConfig(config),
+		circuits: map[string]*circuitState{},
+		now:      time.Now,
+	}
+}
+
+func normalizeCircuitBreakerConfig(config CircuitBreakerConfig) CircuitBreakerConfig {
+	if config.WindowSize <= 0 {
+		config.WindowSize = defaultCircuitWindowSize
+	}
+	if config.MinSamples <= 0 {
+		config.MinSamples = defaultCircuitMinSamples
+	}
+	if config.MinSamples > config.WindowSize {
+		config.MinSamples = config.WindowSize
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = defaultCircuitCooldown
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaultCircuitIdleTimeout
+	}
+	if config.TempfailRate < 0 {
+		config.TempfailRate = 0
+	}
+
+	return config
+}
+
+// SetTempfailRate updates the trip threshold. A threshold of zero or above
+// one disables the breaker and closes every open circuit.
+func (b *CircuitBreaker) SetTempfailRate(rate float64) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.config.TempfailRate = rate
+	if b.enabledLocked() {
+		return
+	}
+
+	for key, circuit := range b.circuits {
+		if circuit.state != CircuitClosed {
+			b.transitionLocked(key, circuit, CircuitClosed)
+		}
+		circuit.reset()
+	}
+}
+
+func (b *CircuitBreaker) Enabled() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.enabledLocked()
+}
+
+func (b *CircuitBreaker) enabledLocked() bool {
+	return b.config.TempfailRate > 0 && b.config.TempfailRate <= 1
+}
+
+// Allow reports whether a probe may run for every given key. When any key
+// is open the first open key is returned so callers can report it.
+func (b *CircuitBreaker) Allow(keys ...string) (bool, string) {
+	if b == nil {
+		return true, ""
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.enabledLocked() {
+		return true, ""
+	}
+
+	now := b.now()
+	for _, key := range keys {
+		circuit, ok := b.circuits[key]
+		if !ok {
+			continue
+		}
+
+		switch circuit.state {
+		case CircuitOpen:
+			if now.Sub(circuit.openedAt) < b.config.Cooldown {
+				return false, key
+			}
+		case CircuitHalfOpen:
+			if circuit.trialInFlight {
+				return false, key
+			}
+		}
+	}
+
+	for _, key := range keys {
+		circuit, ok := b.circuits[key]
+		if !ok {
+			continue
+		}
+
+		switch circuit.state {
+		case CircuitOpen:
+			b.transitionLocked(key, circuit, CircuitHalfOpen)
+			circuit.trialInFlight = true
+		case CircuitHalfOpen:
+			circuit.trialInFlight = true
+		}
+	}
+
+	return true, ""
+}
+
+// Record adds a probe outcome to every given key.
+func (b *CircuitBreaker) Record(tempfail bool, keys ...string) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.enabledLocked() {
+		return
+	}
+
+	for _, key := range keys {
+		if strings.TrimSpace(key) == "" {
+			continue
+		}
+
+		circuit, ok := b.circuits[key]
+		if !ok {
+			circuit = &circuitState{state: CircuitClosed, outcomes: make([]bool, b.config.WindowSize)}
+			b.circuits[key] = circuit
+		}
+		circuit.lastUsed = b.now()
+
+		switch circuit.state {
+		case CircuitHalfOpen:
+			circuit.trialInFlight = false
+			if tempfail {
+				circuit.openedAt = b.now()
+				b.transitionLocked(key, circuit, CircuitOpen)
+				continue
+			}
+			circuit.reset()
+			b.transitionLocked(key, circuit, CircuitClosed)
+			continue
+		case CircuitOpen:
+			continue
+		}
+
+		circuit.add(tempfail)
+		if circuit.filled >= b.config.MinSamples && circuit.rate() >= b.config.TempfailRate {
+			circuit.openedAt = b.now()
+			b.transitionLocked(key, circuit, CircuitOpen)
+		}
+	}
+}
+
+// State returns the current state of a key, defaulting to closed.
+func (b *CircuitBreaker) State(key string) string {
+	if b == nil {
+		return CircuitClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if circuit, ok := b.circuits[key]; ok {
+		return circuit.state
+	}
+
+	return CircuitClosed
+}
+
+// EvictIdle drops closed circuits that have recorded no outcome within the
+// idle timeout. Open and half-open circuits are kept until they close.
+func (b *CircuitBreaker) EvictIdle() int {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	evicted := 0
+	for key, circuit := range b.circuits {
+		if circuit.state != CircuitClosed || now.Sub(circuit.lastUsed) < b.config.IdleTimeout {
+			continue
+		}
+		delete(b.circuits, key)
+		evicted++
+	}
+
+	return evicted
+}
+
+// DrainTransitions returns and clears the transitions recorded since the
+// previous call.
+func (b *CircuitBreaker) DrainTransitions() []CircuitTransition {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.transitions) == 0 {
+		return nil
+	}
+
+	drained := b.transitions
+	b.transitions = nil
+
+	return drained
+}
+
+func (b *CircuitBreaker) transitionLocked(key string, circuit *circuitState, to string) {
+	from := circuit.state
+	circuit.state = to
+
+	scope := "provider"
+	if strings.HasPrefix(key, circuitBreakerMXKeyBase) {
+		scope = "mx"
+	}
+
+	b.transitions = append(b.transitions, CircuitTransition{
+		Key:          key,
+		Scope:        scope,
+		From:         from,
+		To:           to,
+		TempfailRate: circuit.rate(),
+		Samples:      circuit.filled,
+		At:           b.now(),
+	})
+	if overflow := len(b.transitions) - maxPendingCircuitTransitions; overflow > 0 {
+		b.transitions = append([]CircuitTransition(nil), b.transitions[overflow:]...)
+	}
+}
+
+func (c *circuitState) add(tempfail bool) {
+	if c.filled == len(c.outcomes) {
+		if c.outcomes[c.next] {
+			c.tempfails--
+		}
+	} else {
+		c.filled++
+	}
+
+	c.outcomes[c.next] = tempfail
+	if tempfail {
+		c.tempfails++
+	}
+	c.next = (c.next + 1) % len(c.outcomes)
+}
+
+func (c *circuitState) rate() float64 {
+	if c.filled == 0 {
+		return 0
+	}
+
+	return float64(c.tempfails) / float64(c.filled)
+}
+
+func (c *circuitState) reset() {
+	for i := range c.outcomes {
+		c.outcomes[i] = false
+	}
+	c.next = 0
+	c.filled = 0
+	c.tempfails = 0
+	c.trialInFlight = false
+}
+
+func providerCircuitKey(provider string) string {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if provider == "" {
+		provider = "generic"
+	}
+
+	return circuitBreakerProviderKeyBase + provider
+}
+
+func mxCircuitKey(host string) string {
+	return circuitBreakerMXKeyBase + strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+}
+
+func isCircuitTempfailResult(result Result) bool {
+	if result.DecisionClass == DecisionRetryable {
+		return true
+	}
+
+	return strings.TrimSpace(result.Reason) == "smtp_tempfail" && result.DecisionClass != DecisionPolicyBlocked
+}
+
+func circuitOpenResult(key string) Result {
+	return Result{
+		Category:           CategoryRisky,
+		Reason:             "circuit_open",
+		ReasonCode:         "circuit_open",
+		ReasonTag:          "circuit_open",
+		DecisionClass:      DecisionRetryable,
+		DecisionConfidence: "low",
+		RetryStrategy:      "circuit_open",
+		AttemptRoute:       "circuit:" + key,
+	}
+}
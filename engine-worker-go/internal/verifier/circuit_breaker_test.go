@@ -0,0 +1,223 @@
+package verifier
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestCircuitBreaker(rate float64, clock *time.Time) *CircuitBreaker {
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{
+		TempfailRate: rate,
+		WindowSize:   4,
+		MinSamples:   4,
+		Cooldown:     time.Minute,
+	})
+	breaker.now = func() time.Time { return *clock }
+
+	return breaker
+}
+
+func TestCircuitBreakerOpensAtTempfailRate(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	breaker := newTestCircuitBreaker(0.5, &clock)
+	key := providerCircuitKey("gmail")
+
+	breaker.Record(true, key)
+	breaker.Record(false, key)
+	breaker.Record(false, key)
+	if state := breaker.State(key); state != CircuitClosed {
+		t.Fatalf("expected closed circuit below min samples, got %q", state)
+	}
+
+	breaker.Record(true, key)
+	if state := breaker.State(key); state != CircuitOpen {
+		t.Fatalf("expected open circuit at 50%% tempfail rate, got %q", state)
+	}
+
+	allowed, openKey := breaker.Allow(key, mxCircuitKey("mx.gmail.test"))
+	if allowed || openKey != key {
+		t.Fatalf("expected open provider key to block probes, got allowed=%v key=%q", allowed, openKey)
+	}
+}
+
+func TestCircuitBreakerHalfOpensAfterCooldownAndCloses(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	breaker := newTestCircuitBreaker(0.5, &clock)
+	key := mxCircuitKey("mx.greylist.test")
+
+	for i := 0; i < 4; i++ {
+		breaker.Record(true, key)
+	}
+	if state := breaker.State(key); state != CircuitOpen {
+		t.Fatalf("expected open circuit, got %q", state)
+	}
+
+	clock = clock.Add(2 * time.Minute)
+	if allowed, _ := breaker.Allow(key); !allowed {
+		t.Fatal("expected a trial probe after cooldown")
+	}
+	if state := breaker.State(key); state != CircuitHalfOpen {
+		t.Fatalf("expected half-open circuit, got %q", state)
+	}
+	if allowed, _ := breaker.Allow(key); allowed {
+		t.Fatal("expected only one trial probe while half-open")
+	}
+
+	breaker.Record(false, key)
+	if state := breaker.State(key); state != CircuitClosed {
+		t.Fatalf("expected closed circuit after successful trial, got %q", state)
+	}
+
+	transitions := breaker.DrainTransitions()
+	expected := []string{CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(transitions) != len(expected) {
+		t.Fatalf("expected %d transitions, got %+v", len(expected), transitions)
+	}
+	for i, to := range expected {
+		if transitions[i].To != to || transitions[i].Scope != "mx" {
+			t.Fatalf("unexpected transition %d: %+v", i, transitions[i])
+		}
+	}
+	if len(breaker.DrainTransitions()) != 0 {
+		t.Fatal("expected transitions to be drained")
+	}
+}
+
+func TestCircuitBreakerReopensWhenTrialTempfails(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	breaker := newTestCircuitBreaker(0.5, &clock)
+	key := providerCircuitKey("yahoo")
+
+	for i := 0; i < 4; i++ {
+		breaker.Record(true, key)
+	}
+
+	clock = clock.Add(2 * time.Minute)
+	breaker.Allow(key)
+	breaker.Record(true, key)
+
+	if state := breaker.State(key); state != CircuitOpen {
+		t.Fatalf("expected circuit to reopen after failed trial, got %q", state)
+	}
+	if allowed, _ := breaker.Allow(key); allowed {
+		t.Fatal("expected reopened circuit to block probes during the new cooldown")
+	}
+}
+
+func TestCircuitBreakerDisabledWithoutThreshold(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	breaker := newTestCircuitBreaker(0, &clock)
+	key := providerCircuitKey("generic")
+
+	for i := 0; i < 10; i++ {
+		breaker.Record(true, key)
+	}
+
+	if allowed, _ := breaker.Allow(key); !allowed {
+		t.Fatal("expected disabled breaker to allow probes")
+	}
+}
+
+func TestPipelineShortCircuitsWhenCircuitOpen(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	breaker := newTestCircuitBreaker(0.5, &clock)
+	for i := 0; i < 4; i++ {
+		breaker.Record(true, mxCircuitKey("mx.open.local"))
+	}
+
+	config := baseConfig(1)
+	config.CircuitBreaker = breaker
+	resolver := &fakeResolver{records: map[string][]*net.MX{
+		"open.local": {{Host: "mx.open.local", Pref: 10}},
+	}}
+	smtp := &fakeSMTP{results: map[string]Result{
+		"mx.open.local": {Category: CategoryValid, Reason: "rcpt_ok"},
+	}}
+
+	v := NewPipelineVerifier(config, resolver, smtp)
+	res := v.Verify(context.Background(), "user@open.local")
+
+	if res.Category != CategoryRisky || res.Reason != "circuit_open" {
+		t.Fatalf("expected circuit_open risky, got %s/%s", res.Category, res.Reason)
+	}
+	if smtp.calls["mx.open.local"] != 0 {
+		t.Fatalf("expected no SMTP probe while circuit is open, got %d", smtp.calls["mx.open.local"])
+	}
+	if len(res.AttemptChain) != 1 || res.AttemptChain[0].ReasonCode != "circuit_open" {
+		t.Fatalf("expected circuit_open attempt evidence, got %+v", res.AttemptChain)
+	}
+}
+
+func TestPipelineRecordsTempfailsIntoCircuitBreaker(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	breaker := newTestCircuitBreaker(0.5, &clock)
+
+	config := baseConfig(1)
+	config.CircuitBreaker = breaker
+	resolver := &fakeResolver{records: map[string][]*net.MX{
+		"grey.local": {{Host: "mx.grey.local", Pref: 10}},
+	}}
+	smtp := &fakeSMTP{results: map[string]Result{
+		"mx.grey.local": {Category: CategoryRisky, Reason: "smtp_tempfail", DecisionClass: DecisionRetryable},
+	}}
+
+	v := NewPipelineVerifier(config, resolver, smtp)
+	for i := 0; i < 4; i++ {
+		v.Verify(context.Background(), "user@grey.local")
+	}
+
+	if state := breaker.State(mxCircuitKey("mx.grey.local")); state != CircuitOpen {
+		t.Fatalf("expected mx circuit to open after repeated tempfails, got %q", state)
+	}
+	if state := breaker.State(providerCircuitKey("generic")); state != CircuitClosed {
+		t.Fatalf("expected no shared generic provider circuit, got %q", state)
+	}
+}
+
+func TestPipelineKeepsGenericDomainsOnSeparateCircuits(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	breaker := newTestCircuitBreaker(0.5, &clock)
+
+	config := baseConfig(1)
+	config.CircuitBreaker = breaker
+	resolver := &fakeResolver{records: map[string][]*net.MX{
+		"grey.local":    {{Host: "mx.grey.local", Pref: 10}},
+		"healthy.local": {{Host: "mx.healthy.local", Pref: 10}},
+	}}
+	smtp := &fakeSMTP{results: map[string]Result{
+		"mx.grey.local":    {Category: CategoryRisky, Reason: "smtp_tempfail", DecisionClass: DecisionRetryable},
+		"mx.healthy.local": {Category: CategoryValid, Reason: "rcpt_ok"},
+	}}
+
+	v := NewPipelineVerifier(config, resolver, smtp)
+	for i := 0; i < 4; i++ {
+		v.Verify(context.Background(), "user@grey.local")
+	}
+
+	res := v.Verify(context.Background(), "user@healthy.local")
+	if res.Category != CategoryValid || smtp.calls["mx.healthy.local"] != 1 {
+		t.Fatalf("expected the healthy generic domain to keep probing, got %s/%s after %d calls", res.Category, res.Reason, smtp.calls["mx.healthy.local"])
+	}
+}
+
+func TestCircuitBreakerEvictsIdleClosedCircuits(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	breaker := newTestCircuitBreaker(0.5, &clock)
+	closed := mxCircuitKey("mx.closed.test")
+	open := mxCircuitKey("mx.open.test")
+
+	breaker.Record(false, closed)
+	for i := 0; i < 4; i++ {
+		breaker.Record(true, open)
+	}
+
+	clock = clock.Add(11 * time.Minute)
+	if evicted := breaker.EvictIdle(); evicted != 1 {
+		t.Fatalf("expected only the idle closed circuit to be evicted, got %d", evicted)
+	}
+	if state := breaker.State(open); state != CircuitOpen {
+		t.Fatalf("expected the open circuit to be kept, got %q", state)
+	}
+}
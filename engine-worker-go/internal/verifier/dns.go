@@ -5,12 +5,22 @@ import (
 	"errors"
 	"net"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
 )
 
 type MXResolver interface {
 	LookupMX(ctx context.Context, domain string) ([]*net.MX, error)
 }
 
+// HostResolver looks up the addresses of a host. Resolvers that implement
+// it let the pipeline fall back to implicit MX delivery.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
 type NetMXResolver struct {
 	Resolver *net.Resolver
 }
@@ -24,6 +34,198 @@ func (r NetMXResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX,
 	return resolver.LookupMX(ctx, domain)
 }
 
+func (r NetMXResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return resolver.LookupHost(ctx, host)
+}
+
+func (r NetMXResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return resolver.LookupTXT(ctx, name)
+}
+
+// MXTTLResolver is implemented by resolvers that can report how long an MX
+// answer may be cached. A zero TTL means the answer carried none.
+type MXTTLResolver interface {
+	LookupMXTTL(ctx context.Context, domain string) ([]*net.MX, time.Duration, error)
+}
+
+// LookupMXTTL resolves MX records with the pure Go resolver and reads the
+// TTL from the raw DNS answers. For NXDOMAIN and empty answers the TTL is
+// the SOA negative-caching TTL when the server sent one. A caller-supplied
+// Resolver is used as is and reports no TTL.
+func (r NetMXResolver) LookupMXTTL(ctx context.Context, domain string) ([]*net.MX, time.Duration, error) {
+	if r.Resolver != nil {
+		records, err := r.Resolver.LookupMX(ctx, domain)
+		return records, 0, err
+	}
+
+	recorder := &dnsAnswerRecorder{}
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+
+			return recorder.wrap(conn), nil
+		},
+	}
+
+	records, err := resolver.LookupMX(ctx, domain)
+	return records, recorder.ttl(), err
+}
+
+// dnsAnswerRecorder keeps the raw DNS responses read by the Go resolver so
+// their TTLs can be inspected after the lookup.
+type dnsAnswerRecorder struct {
+	mu       sync.Mutex
+	messages [][]byte
+}
+
+func (d *dnsAnswerRecorder) wrap(conn net.Conn) net.Conn {
+	if packetConn, ok := conn.(net.PacketConn); ok {
+		return &recordingPacketConn{recordingConn: recordingConn{Conn: conn, recorder: d}, packetConn: packetConn}
+	}
+
+	return &recordingConn{Conn: conn, recorder: d, stream: true}
+}
+
+func (d *dnsAnswerRecorder) record(data []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.messages = append(d.messages, append([]byte(nil), data...))
+}
+
+// ttl returns the smallest MX answer TTL of the last parsable response, or
+// the SOA minimum from its authority section when it had no MX answers.
+func (d *dnsAnswerRecorder) ttl() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for index := len(d.messages) - 1; index >= 0; index-- {
+		if ttl, ok := dnsMessageTTL(d.messages[index]); ok {
+			return ttl
+		}
+	}
+
+	return 0
+}
+
+func dnsMessageTTL(message []byte) (time.Duration, bool) {
+	var parser dnsmessage.Parser
+	if _, err := parser.Start(message); err != nil {
+		return 0, false
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return 0, false
+	}
+
+	answers, err := parser.AllAnswers()
+	if err != nil {
+		return 0, false
+	}
+
+	var minTTL uint32
+	found := false
+	for _, answer := range answers {
+		if answer.Header.Type != dnsmessage.TypeMX {
+			continue
+		}
+		if !found || answer.Header.TTL < minTTL {
+			minTTL = answer.Header.TTL
+			found = true
+		}
+	}
+	if found {
+		return time.Duration(minTTL) * time.Second, true
+	}
+
+	authorities, err := parser.AllAuthorities()
+	if err != nil {
+		return 0, false
+	}
+	for _, authority := range authorities {
+		soa, ok := authority.Body.(*dnsmessage.SOAResource)
+		if !ok {
+			continue
+		}
+
+		// RFC 2308: the negative TTL is the lower of the SOA TTL and MINIMUM.
+		ttl := authority.Header.TTL
+		if soa.MinTTL < ttl {
+			ttl = soa.MinTTL
+		}
+		return time.Duration(ttl) * time.Second, true
+	}
+
+	return 0, false
+}
+
+type recordingConn struct {
+	net.Conn
+	recorder *dnsAnswerRecorder
+	stream   bool
+	buffer   []byte
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		if c.stream {
+			c.readStream(b[:n])
+		} else {
+			c.recorder.record(b[:n])
+		}
+	}
+
+	return n, err
+}
+
+// readStream splits TCP DNS responses on their two-byte length prefix.
+func (c *recordingConn) readStream(data []byte) {
+	c.buffer = append(c.buffer, data...)
+	for len(c.buffer) >= 2 {
+		length := int(c.buffer[0])<<8 | int(c.buffer[1])
+		if len(c.buffer) < length+2 {
+			return
+		}
+		c.recorder.record(c.buffer[2 : length+2])
+		c.buffer = c.buffer[length+2:]
+	}
+}
+
+// recordingPacketConn keeps the net.PacketConn methods visible so the Go
+// resolver still treats the connection as UDP.
+type recordingPacketConn struct {
+	recordingConn
+	packetConn net.PacketConn
+}
+
+func (c *recordingPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.packetConn.ReadFrom(b)
+	if n > 0 {
+		c.recorder.record(b[:n])
+	}
+
+	return n, addr, err
+}
+
+func (c *recordingPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.packetConn.WriteTo(b, addr)
+}
+
 func classifyDNSError(err error) Result {
 	if err == nil {
 		return Result{}
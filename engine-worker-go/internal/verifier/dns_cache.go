@@ -0,0 +1,367 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultMXCacheTTL         = 5 * time.Minute
+	defaultMXCacheMinTTL      = 30 * time.Second
+	defaultMXCacheMaxTTL      = time.Hour
+	defaultMXCacheNegativeTTL = 5 * time.Minute
+	defaultMXCacheMaxEntries  = 100000
+	// defaultMXCacheLookupTimeout matches the pipeline's default DNS timeout.
+	defaultMXCacheLookupTimeout = 2 * time.Second
+)
+
+type MXCacheConfig struct {
+	// DefaultTTL applies to answers whose TTL the resolver cannot report.
+	DefaultTTL time.Duration
+	// MinTTL and MaxTTL clamp record TTLs.
+	MinTTL      time.Duration
+	MaxTTL      time.Duration
+	NegativeTTL time.Duration
+	MaxEntries  int
+	// LookupTimeout bounds a shared query, which runs apart from the
+	// context of the caller that started it.
+	LookupTimeout time.Duration
+}
+
+// CachingMXResolver caches MX answers per domain. Positive answers live for
+// their record TTL, NXDOMAIN and empty answers for the negative TTL, and
+// transient failures are never cached. Concurrent lookups for one domain
+// share a single DNS query. Address lookups of MX hosts are cached too, so
+// hosts shared by many domains are resolved once.
+type CachingMXResolver struct {
+	resolver MXResolver
+	config   MXCacheConfig
+	mu       sync.Mutex
+	entries  map[string]*mxCacheEntry
+	inflight map[string]*mxLookupCall
+	hosts    map[string]*hostCacheEntry
+	hits     atomic.Int64
+	misses   atomic.Int64
+	now      func() time.Time
+}
+
+type mxCacheEntry struct {
+	records []*net.MX
+	err     error
+	expires time.Time
+}
+
+type hostCacheEntry struct {
+	addresses []string
+	err       error
+	expires   time.Time
+}
+
+type mxLookupCall struct {
+	done    chan struct{}
+	records []*net.MX
+	err     error
+}
+
+func NewCachingMXResolver(resolver MXResolver, config MXCacheConfig) *CachingMXResolver {
+	if resolver == nil {
+		resolver = NetMXResolver{}
+	}
+	if config.DefaultTTL <= 0 {
+		config.DefaultTTL = defaultMXCacheTTL
+	}
+	if config.MinTTL <= 0 {
+		config.MinTTL = defaultMXCacheMinTTL
+	}
+	if config.MaxTTL <= 0 {
+		config.MaxTTL = defaultMXCacheMaxTTL
+	}
+	if config.MaxTTL < config.MinTTL {
+		config.MaxTTL = config.MinTTL
+	}
+	if config.NegativeTTL <= 0 {
+		config.NegativeTTL = defaultMXCacheNegativeTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultMXCacheMaxEntries
+	}
+	if config.LookupTimeout <= 0 {
+		config.LookupTimeout = defaultMXCacheLookupTimeout
+	}
+
+	return &CachingMXResolver{
+		resolver: resolver,
+		config:   config,
+		entries:  map[string]*mxCacheEntry{},
+		inflight: map[string]*mxLookupCall{},
+		hosts:    map[string]*hostCacheEntry{},
+		now:      time.Now,
+	}
+}
+
+func (c *CachingMXResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	key := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		if c.now().Before(entry.expires) {
+			c.mu.Unlock()
+			c.hits.Add(1)
+			return cloneMXRecords(entry.records), entry.err
+		}
+		delete(c.entries, key)
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return c.wait(ctx, call)
+	}
+
+	call := &mxLookupCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	// Every caller joining the query waits on it, so it must not be cut
+	// short when the caller that started it gives up.
+	go c.resolve(context.WithoutCancel(ctx), key, domain, call)
+
+	return c.wait(ctx, call)
+}
+
+// resolve runs the shared query for domain under the lookup timeout, caches
+// the answer and releases every caller waiting on call.
+func (c *CachingMXResolver) resolve(ctx context.Context, key, domain string, call *mxLookupCall) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.LookupTimeout)
+	defer cancel()
+
+	records, ttl, err := c.lookup(ctx, domain)
+	call.records, call.err = records, err
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if expiry, cacheable := c.expiry(records, ttl, err); cacheable {
+		c.store(key, &mxCacheEntry{records: records, err: err, expires: expiry})
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// LookupHost caches address lookups per host. Answers live for the default
+// TTL, missing hosts for the negative TTL, and transient failures are never
+// cached. A resolver without address lookups reports every host as missing.
+func (c *CachingMXResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	resolver, ok := c.resolver.(HostResolver)
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	key := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+	c.mu.Lock()
+	if entry, ok := c.hosts[key]; ok {
+		if c.now().Before(entry.expires) {
+			c.mu.Unlock()
+			return append([]string(nil), entry.addresses...), entry.err
+		}
+		delete(c.hosts, key)
+	}
+	c.mu.Unlock()
+
+	addresses, err := resolver.LookupHost(ctx, host)
+	ttl := c.config.DefaultTTL
+	if err != nil || len(addresses) == 0 {
+		if err != nil && !isNegativeDNSAnswer(err) {
+			return addresses, err
+		}
+		ttl = c.config.NegativeTTL
+	}
+
+	c.mu.Lock()
+	c.storeHost(key, &hostCacheEntry{addresses: append([]string(nil), addresses...), err: err, expires: c.now().Add(ttl)})
+	c.mu.Unlock()
+
+	return addresses, err
+}
+
+// LookupTXT passes TXT lookups through to the wrapped resolver; domain
+// authentication answers are cached by DomainAuthCache.
+func (c *CachingMXResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	resolver, ok := c.resolver.(TXTResolver)
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	return resolver.LookupTXT(ctx, name)
+}
+
+func (c *CachingMXResolver) lookup(ctx context.Context, domain string) ([]*net.MX, time.Duration, error) {
+	if resolver, ok := c.resolver.(MXTTLResolver); ok {
+		return resolver.LookupMXTTL(ctx, domain)
+	}
+
+	records, err := c.resolver.LookupMX(ctx, domain)
+	return records, 0, err
+}
+
+func (c *CachingMXResolver) wait(ctx context.Context, call *mxLookupCall) ([]*net.MX, error) {
+	select {
+	case <-call.done:
+		return cloneMXRecords(call.records), call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// expiry decides whether an answer may be cached and until when.
+func (c *CachingMXResolver) expiry(records []*net.MX, ttl time.Duration, err error) (time.Time, bool) {
+	now := c.now()
+
+	if err != nil || len(records) == 0 {
+		if err != nil && !isNegativeDNSAnswer(err) {
+			return time.Time{}, false
+		}
+
+		negativeTTL := c.config.NegativeTTL
+		if ttl > 0 && ttl < negativeTTL {
+			negativeTTL = ttl
+		}
+		return now.Add(negativeTTL), true
+	}
+
+	if ttl <= 0 {
+		ttl = c.config.DefaultTTL
+	}
+	if ttl < c.config.MinTTL {
+		ttl = c.config.MinTTL
+	}
+	if ttl > c.config.MaxTTL {
+		ttl = c.config.MaxTTL
+	}
+
+	return now.Add(ttl), true
+}
+
+// store adds entry, making room by dropping expired entries first and an
+// arbitrary entry when the cache is still full.
+func (c *CachingMXResolver) store(key string, entry *mxCacheEntry) {
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.config.MaxEntries {
+		c.evictExpiredLocked()
+		for existing := range c.entries {
+			if len(c.entries) < c.config.MaxEntries {
+				break
+			}
+			delete(c.entries, existing)
+		}
+	}
+
+	c.entries[key] = entry
+}
+
+// storeHost adds entry under the same bound as the MX entries.
+func (c *CachingMXResolver) storeHost(key string, entry *hostCacheEntry) {
+	if _, ok := c.hosts[key]; !ok && len(c.hosts) >= c.config.MaxEntries {
+		c.evictExpiredLocked()
+		for existing := range c.hosts {
+			if len(c.hosts) < c.config.MaxEntries {
+				break
+			}
+			delete(c.hosts, existing)
+		}
+	}
+
+	c.hosts[key] = entry
+}
+
+// EvictExpired drops expired entries and returns how many were removed.
+func (c *CachingMXResolver) EvictExpired() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.evictExpiredLocked()
+}
+
+func (c *CachingMXResolver) evictExpiredLocked() int {
+	now := c.now()
+	evicted := 0
+	for key, entry := range c.entries {
+		if now.Before(entry.expires) {
+			continue
+		}
+		delete(c.entries, key)
+		evicted++
+	}
+	for key, entry := range c.hosts {
+		if now.Before(entry.expires) {
+			continue
+		}
+		delete(c.hosts, key)
+		evicted++
+	}
+
+	return evicted
+}
+
+// HitRate returns the share of lookups answered from the cache or by joining
+// an in-flight query.
+func (c *CachingMXResolver) HitRate() float64 {
+	if c == nil {
+		return 0
+	}
+
+	hits := c.hits.Load()
+	total := hits + c.misses.Load()
+	if total == 0 {
+		return 0
+	}
+
+	return float64(hits) / float64(total)
+}
+
+// Len returns the number of cached domains.
+func (c *CachingMXResolver) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// isNegativeDNSAnswer reports whether err is an authoritative "no such
+// domain" or "no records" answer rather than a transient failure.
+func isNegativeDNSAnswer(err error) bool {
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) {
+		return false
+	}
+
+	return dnsErr.IsNotFound && !dnsErr.IsTimeout && !dnsErr.IsTemporary
+}
+
+func cloneMXRecords(records []*net.MX) []*net.MX {
+	if records == nil {
+		return nil
+	}
+
+	cloned := make([]*net.MX, len(records))
+	for index, record := range records {
+		if record == nil {
+			continue
+		}
+		copied := *record
+		cloned[index] = &copied
+	}
+
+	return cloned
+}
@@ -0,0 +1,297 @@
+package verifier
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type countingTTLResolver struct {
+	calls   atomic.Int32
+	records []*net.MX
+	ttl     time.Duration
+	err     error
+	release chan struct{}
+
+	hostCalls atomic.Int32
+	addresses []string
+	hostErr   error
+}
+
+func (r *countingTTLResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	records, _, err := r.LookupMXTTL(ctx, domain)
+	return records, err
+}
+
+func (r *countingTTLResolver) LookupMXTTL(ctx context.Context, domain string) ([]*net.MX, time.Duration, error) {
+	r.calls.Add(1)
+	if r.release != nil {
+		select {
+		case <-r.release:
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+	}
+
+	return cloneMXRecords(r.records), r.ttl, r.err
+}
+
+func (r *countingTTLResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.hostCalls.Add(1)
+	return append([]string(nil), r.addresses...), r.hostErr
+}
+
+func newTestMXCache(resolver MXResolver, clock *time.Time) *CachingMXResolver {
+	cache := NewCachingMXResolver(resolver, MXCacheConfig{NegativeTTL: time.Minute})
+	cache.now = func() time.Time { return *clock }
+
+	return cache
+}
+
+func TestCachingMXResolverHonorsRecordTTL(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	resolver := &countingTTLResolver{records: []*net.MX{{Host: "mx.test.", Pref: 10}}, ttl: 2 * time.Minute}
+	cache := newTestMXCache(resolver, &clock)
+
+	for i := 0; i < 3; i++ {
+		records, err := cache.LookupMX(context.Background(), "Test.com")
+		if err != nil || len(records) != 1 {
+			t.Fatalf("expected cached MX answer, got %v/%v", records, err)
+		}
+	}
+	if calls := resolver.calls.Load(); calls != 1 {
+		t.Fatalf("expected one DNS query, got %d", calls)
+	}
+
+	clock = clock.Add(3 * time.Minute)
+	_, _ = cache.LookupMX(context.Background(), "test.com")
+	if calls := resolver.calls.Load(); calls != 2 {
+		t.Fatalf("expected a fresh query after the record TTL, got %d", calls)
+	}
+	if rate := cache.HitRate(); rate != 0.5 {
+		t.Fatalf("expected hit rate 0.5, got %f", rate)
+	}
+}
+
+func TestCachingMXResolverClampsShortTTL(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	resolver := &countingTTLResolver{records: []*net.MX{{Host: "mx.test.", Pref: 10}}, ttl: time.Second}
+	cache := newTestMXCache(resolver, &clock)
+
+	_, _ = cache.LookupMX(context.Background(), "test.com")
+	clock = clock.Add(10 * time.Second)
+	_, _ = cache.LookupMX(context.Background(), "test.com")
+
+	if calls := resolver.calls.Load(); calls != 1 {
+		t.Fatalf("expected TTL to be raised to the minimum, got %d queries", calls)
+	}
+}
+
+func TestCachingMXResolverCachesNegativeAnswers(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	resolver := &countingTTLResolver{err: &net.DNSError{Err: "no such host", Name: "missing.test", IsNotFound: true}}
+	cache := newTestMXCache(resolver, &clock)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.LookupMX(context.Background(), "missing.test"); err == nil {
+			t.Fatal("expected cached NXDOMAIN error")
+		}
+	}
+	if calls := resolver.calls.Load(); calls != 1 {
+		t.Fatalf("expected NXDOMAIN to be cached, got %d queries", calls)
+	}
+
+	clock = clock.Add(2 * time.Minute)
+	_, _ = cache.LookupMX(context.Background(), "missing.test")
+	if calls := resolver.calls.Load(); calls != 2 {
+		t.Fatalf("expected negative entry to expire, got %d queries", calls)
+	}
+}
+
+func TestCachingMXResolverSkipsTransientFailures(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	resolver := &countingTTLResolver{err: &net.DNSError{Err: "i/o timeout", Name: "slow.test", IsTimeout: true}}
+	cache := newTestMXCache(resolver, &clock)
+
+	_, _ = cache.LookupMX(context.Background(), "slow.test")
+	_, _ = cache.LookupMX(context.Background(), "slow.test")
+
+	if calls := resolver.calls.Load(); calls != 2 {
+		t.Fatalf("expected timeouts not to be cached, got %d queries", calls)
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("expected empty cache, got %d entries", cache.Len())
+	}
+}
+
+func TestCachingMXResolverCoalescesConcurrentLookups(t *testing.T) {
+	resolver := &countingTTLResolver{records: []*net.MX{{Host: "mx.test.", Pref: 10}}, release: make(chan struct{})}
+	cache := NewCachingMXResolver(resolver, MXCacheConfig{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if records, err := cache.LookupMX(context.Background(), "test.com"); err != nil || len(records) != 1 {
+				t.Errorf("expected shared MX answer, got %v/%v", records, err)
+			}
+		}()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for resolver.calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(resolver.release)
+	wg.Wait()
+
+	if calls := resolver.calls.Load(); calls != 1 {
+		t.Fatalf("expected a single DNS query, got %d", calls)
+	}
+}
+
+func TestCachingMXResolverSharedLookupOutlivesFirstCaller(t *testing.T) {
+	resolver := &countingTTLResolver{records: []*net.MX{{Host: "mx.test.", Pref: 10}}, release: make(chan struct{})}
+	cache := NewCachingMXResolver(resolver, MXCacheConfig{})
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := cache.LookupMX(leaderCtx, "test.com")
+		leaderErr <- err
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for resolver.calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	followerRecords := make(chan []*net.MX, 1)
+	go func() {
+		records, _ := cache.LookupMX(context.Background(), "test.com")
+		followerRecords <- records
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancelLeader()
+	if err := <-leaderErr; err != context.Canceled {
+		t.Fatalf("expected the first caller to give up with its context, got %v", err)
+	}
+	close(resolver.release)
+
+	if records := <-followerRecords; len(records) != 1 || records[0].Host != "mx.test." {
+		t.Fatalf("expected the waiting caller to get the shared answer, got %v", records)
+	}
+	if calls := resolver.calls.Load(); calls != 1 {
+		t.Fatalf("expected a single DNS query, got %d", calls)
+	}
+}
+
+func TestCachingMXResolverReturnsIndependentCopies(t *testing.T) {
+	resolver := &countingTTLResolver{records: []*net.MX{{Host: "mx.test.", Pref: 10}}}
+	cache := NewCachingMXResolver(resolver, MXCacheConfig{})
+
+	first, _ := cache.LookupMX(context.Background(), "test.com")
+	first[0].Host = "changed."
+
+	second, _ := cache.LookupMX(context.Background(), "test.com")
+	if second[0].Host != "mx.test." {
+		t.Fatalf("expected cached records to be unaffected by callers, got %q", second[0].Host)
+	}
+}
+
+func TestDNSMessageTTLReadsMXAndSOA(t *testing.T) {
+	name := dnsmessage.MustNewName("test.com.")
+
+	positive := buildDNSMessage(t, func(builder *dnsmessage.Builder) {
+		_ = builder.StartAnswers()
+		_ = builder.MXResource(dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: 600}, dnsmessage.MXResource{Pref: 10, MX: name})
+		_ = builder.MXResource(dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: 120}, dnsmessage.MXResource{Pref: 20, MX: name})
+	})
+	if ttl, ok := dnsMessageTTL(positive); !ok || ttl != 120*time.Second {
+		t.Fatalf("expected lowest MX TTL of 120s, got %s/%v", ttl, ok)
+	}
+
+	negative := buildDNSMessage(t, func(builder *dnsmessage.Builder) {
+		_ = builder.StartAuthorities()
+		_ = builder.SOAResource(dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: 3600}, dnsmessage.SOAResource{NS: name, MBox: name, MinTTL: 90})
+	})
+	if ttl, ok := dnsMessageTTL(negative); !ok || ttl != 90*time.Second {
+		t.Fatalf("expected SOA minimum of 90s, got %s/%v", ttl, ok)
+	}
+}
+
+func TestRecordingConnSplitsStreamResponses(t *testing.T) {
+	recorder := &dnsAnswerRecorder{}
+	conn := &recordingConn{recorder: recorder, stream: true}
+
+	conn.readStream([]byte{0, 2, 'a'})
+	conn.readStream([]byte{'b', 0, 1, 'c'})
+
+	if len(recorder.messages) != 2 || string(recorder.messages[0]) != "ab" || string(recorder.messages[1]) != "c" {
+		t.Fatalf("expected two length-prefixed messages, got %q", recorder.messages)
+	}
+}
+
+func buildDNSMessage(t *testing.T, build func(builder *dnsmessage.Builder)) []byte {
+	t.Helper()
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true})
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		t.Fatalf("start questions: %v", err)
+	}
+	if err := builder.Question(dnsmessage.Question{Name: dnsmessage.MustNewName("test.com."), Type: dnsmessage.TypeMX, Class: dnsmessage.ClassINET}); err != nil {
+		t.Fatalf("add question: %v", err)
+	}
+	build(&builder)
+
+	message, err := builder.Finish()
+	if err != nil {
+		t.Fatalf("finish message: %v", err)
+	}
+
+	return message
+}
+
+func TestCachingMXResolverCachesHostAddresses(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	resolver := &countingTTLResolver{addresses: []string{"192.0.2.1"}}
+	cache := newTestMXCache(resolver, &clock)
+
+	for i := 0; i < 3; i++ {
+		addresses, err := cache.LookupHost(context.Background(), "MX.Example.test.")
+		if err != nil || len(addresses) != 1 || addresses[0] != "192.0.2.1" {
+			t.Fatalf("unexpected host answer: %v %v", addresses, err)
+		}
+	}
+	if calls := resolver.hostCalls.Load(); calls != 1 {
+		t.Fatalf("expected one address query per host, got %d", calls)
+	}
+
+	clock = clock.Add(defaultMXCacheTTL + time.Second)
+	_, _ = cache.LookupHost(context.Background(), "mx.example.test")
+	if calls := resolver.hostCalls.Load(); calls != 2 {
+		t.Fatalf("expected the host entry to expire, got %d queries", calls)
+	}
+}
+
+func TestCachingMXResolverSkipsTransientHostFailures(t *testing.T) {
+	clock := time.Unix(1700000000, 0)
+	resolver := &countingTTLResolver{hostErr: &net.DNSError{Err: "i/o timeout", Name: "mx.slow.test", IsTimeout: true}}
+	cache := newTestMXCache(resolver, &clock)
+
+	_, _ = cache.LookupHost(context.Background(), "mx.slow.test")
+	_, _ = cache.LookupHost(context.Background(), "mx.slow.test")
+
+	if calls := resolver.hostCalls.Load(); calls != 2 {
+		t.Fatalf("expected host timeouts not to be cached, got %d queries", calls)
+	}
+}
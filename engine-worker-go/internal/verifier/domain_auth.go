@@ -0,0 +1,297 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDomainAuthCacheTTL        = time.Hour
+	defaultDomainAuthCacheMaxEntries = 50000
+)
+
+// Domain authentication states. An empty state means the lookup failed and
+// nothing is known about the record.
+const (
+	AuthMissing     = "missing"
+	AuthPresent     = "present"
+	AuthInvalid     = "invalid"
+	SPFPass         = "pass"
+	SPFNeutral      = "neutral"
+	SPFSoftfail     = "softfail"
+	SPFHardfail     = "hardfail"
+	DMARCNone       = "none"
+	DMARCQuarantine = "quarantine"
+	DMARCReject     = "reject"
+)
+
+// TXTResolver looks up TXT records. Resolvers that implement it let the
+// pipeline enrich results with domain authentication records.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DomainAuth summarises the mail authentication records a domain publishes.
+type DomainAuth struct {
+	// SPF is the qualifier of the record's "all" mechanism (pass, neutral,
+	// softfail, hardfail), missing, or invalid when several records exist.
+	SPF string
+	// DMARC is the p= policy (none, quarantine, reject), missing or invalid.
+	DMARC string
+	// MTASTS and BIMI are present or missing.
+	MTASTS string
+	BIMI   string
+}
+
+// Missing reports whether the domain publishes none of the records.
+func (a DomainAuth) Missing() bool {
+	return a.SPF == AuthMissing && a.DMARC == AuthMissing && a.MTASTS == AuthMissing && a.BIMI == AuthMissing
+}
+
+func (a DomainAuth) complete() bool {
+	return a.SPF != "" && a.DMARC != "" && a.MTASTS != "" && a.BIMI != ""
+}
+
+type DomainAuthCacheConfig struct {
+	TTL        time.Duration
+	MaxEntries int
+}
+
+// DomainAuthCache enriches verification results with SPF, DMARC, MTA-STS and
+// BIMI records, caching each domain's answers. Answers with a failed lookup
+// are not cached, and concurrent lookups for one domain share the queries.
+type DomainAuthCache struct {
+	config   DomainAuthCacheConfig
+	mu       sync.Mutex
+	entries  map[string]domainAuthEntry
+	inflight map[string]*domainAuthCall
+	now      func() time.Time
+}
+
+type domainAuthEntry struct {
+	auth    DomainAuth
+	expires time.Time
+}
+
+type domainAuthCall struct {
+	done chan struct{}
+	auth DomainAuth
+}
+
+func NewDomainAuthCache(config DomainAuthCacheConfig) *DomainAuthCache {
+	if config.TTL <= 0 {
+		config.TTL = defaultDomainAuthCacheTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultDomainAuthCacheMaxEntries
+	}
+
+	return &DomainAuthCache{
+		config:   config,
+		entries:  map[string]domainAuthEntry{},
+		inflight: map[string]*domainAuthCall{},
+		now:      time.Now,
+	}
+}
+
+// Lookup returns the authentication records of domain, querying resolver
+// with timeout per record on a cache miss.
+func (c *DomainAuthCache) Lookup(ctx context.Context, resolver TXTResolver, domain string, timeout time.Duration) DomainAuth {
+	key := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		if c.now().Before(entry.expires) {
+			c.mu.Unlock()
+			return entry.auth
+		}
+		delete(c.entries, key)
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.auth
+		case <-ctx.Done():
+			return DomainAuth{}
+		}
+	}
+
+	call := &domainAuthCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.auth = lookupDomainAuth(ctx, resolver, key, timeout)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if call.auth.complete() {
+		if len(c.entries) >= c.config.MaxEntries {
+			for existing := range c.entries {
+				delete(c.entries, existing)
+				break
+			}
+		}
+		c.entries[key] = domainAuthEntry{auth: call.auth, expires: c.now().Add(c.config.TTL)}
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.auth
+}
+
+// lookupDomainAuth queries the four records in parallel. DMARC is read from
+// the domain itself; organisational-domain fallback is not attempted.
+func lookupDomainAuth(ctx context.Context, resolver TXTResolver, domain string, timeout time.Duration) DomainAuth {
+	auth := DomainAuth{}
+	var wg sync.WaitGroup
+	lookup := func(name string, parse func([]string) string, target *string) {
+		defer wg.Done()
+		lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		records, err := resolver.LookupTXT(lookupCtx, name)
+		switch {
+		case err == nil:
+			*target = parse(records)
+		case isMissingTXT(err):
+			*target = AuthMissing
+		}
+	}
+
+	wg.Add(4)
+	go lookup(domain, parseSPF, &auth.SPF)
+	go lookup("_dmarc."+domain, parseDMARC, &auth.DMARC)
+	go lookup("_mta-sts."+domain, presenceParser("v=stsv1"), &auth.MTASTS)
+	go lookup("default._bimi."+domain, presenceParser("v=bimi1"), &auth.BIMI)
+	wg.Wait()
+
+	return auth
+}
+
+func isMissingTXT(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// taggedRecords returns the records starting with version, such as v=spf1.
+func taggedRecords(records []string, version string) []string {
+	matched := []string{}
+	for _, record := range records {
+		normalized := strings.ToLower(strings.TrimSpace(record))
+		if normalized == version || strings.HasPrefix(normalized, version+" ") || strings.HasPrefix(normalized, version+";") {
+			matched = append(matched, normalized)
+		}
+	}
+
+	return matched
+}
+
+func parseSPF(records []string) string {
+	matched := taggedRecords(records, "v=spf1")
+	switch len(matched) {
+	case 0:
+		return AuthMissing
+	case 1:
+	default:
+		return AuthInvalid
+	}
+
+	for _, term := range strings.Fields(matched[0])[1:] {
+		qualifier := "+"
+		if strings.ContainsAny(term[:1], "+-~?") {
+			qualifier, term = term[:1], term[1:]
+		}
+		if term != "all" {
+			continue
+		}
+		switch qualifier {
+		case "-":
+			return SPFHardfail
+		case "~":
+			return SPFSoftfail
+		case "?":
+			return SPFNeutral
+		default:
+			return SPFPass
+		}
+	}
+
+	// Without an "all" mechanism unmatched senders get a neutral result.
+	return SPFNeutral
+}
+
+func parseDMARC(records []string) string {
+	matched := taggedRecords(records, "v=dmarc1")
+	switch len(matched) {
+	case 0:
+		return AuthMissing
+	case 1:
+	default:
+		return AuthInvalid
+	}
+
+	for _, tag := range strings.Split(matched[0], ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(tag), "=")
+		if !ok || strings.TrimSpace(key) != "p" {
+			continue
+		}
+		switch value = strings.TrimSpace(value); value {
+		case DMARCNone, DMARCQuarantine, DMARCReject:
+			return value
+		}
+		return AuthInvalid
+	}
+
+	return AuthInvalid
+}
+
+func presenceParser(version string) func([]string) string {
+	return func(records []string) string {
+		if len(taggedRecords(records, version)) == 0 {
+			return AuthMissing
+		}
+
+		return AuthPresent
+	}
+}
+
+// withDomainAuth attaches the domain's authentication records to result
+// without changing its category or reason. A valid result for a domain that
+// publishes no records keeps at most medium confidence, and the records also
+// count against the score when scoring is enabled. Invalid results are not
+// looked up.
+func (p *PipelineVerifier) withDomainAuth(ctx context.Context, domain string, result Result) Result {
+	if p.config.DomainAuth == nil || result.Category == CategoryInvalid {
+		return result
+	}
+	resolver, ok := p.resolver.(TXTResolver)
+	if !ok {
+		return result
+	}
+
+	auth := p.config.DomainAuth.Lookup(ctx, resolver, domain, p.dnsTimeout())
+	result.DomainAuth = &auth
+	if result.Category == CategoryValid && auth.Missing() {
+		result.DecisionConfidence = capConfidence(result.DecisionConfidence)
+		result.EvidenceStrength = capConfidence(result.EvidenceStrength)
+		if result.Evidence != nil {
+			result.Evidence.EvidenceStrength = capConfidence(result.Evidence.EvidenceStrength)
+		}
+	}
+
+	return result
+}
+
+// capConfidence lowers a high confidence to medium and keeps the rest.
+func capConfidence(value string) string {
+	if strings.EqualFold(strings.TrimSpace(value), "high") {
+		return "medium"
+	}
+
+	return value
+}
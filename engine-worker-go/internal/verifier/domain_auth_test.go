@@ -0,0 +1,151 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeTXTResolver struct {
+	fakeResolver
+	mu      sync.Mutex
+	txt     map[string][]string
+	txtErrs map[string]error
+	calls   map[string]int
+}
+
+func (f *fakeTXTResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+	f.calls[name]++
+
+	if err, ok := f.txtErrs[name]; ok {
+		return nil, err
+	}
+	if records, ok := f.txt[name]; ok {
+		return records, nil
+	}
+
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func TestParseSPFReadsAllQualifier(t *testing.T) {
+	cases := map[string][]string{
+		SPFHardfail: {"v=spf1 include:_spf.example.com -all"},
+		SPFSoftfail: {"google-site-verification=abc", "v=spf1 mx ~all"},
+		SPFNeutral:  {"v=spf1 a mx"},
+		SPFPass:     {"V=SPF1 +all"},
+		AuthMissing: {"v=spf10 -all", "unrelated"},
+		AuthInvalid: {"v=spf1 -all", "v=spf1 ~all"},
+	}
+	for expected, records := range cases {
+		if got := parseSPF(records); got != expected {
+			t.Fatalf("expected %q for %v, got %q", expected, records, got)
+		}
+	}
+}
+
+func TestParseDMARCReadsPolicy(t *testing.T) {
+	cases := map[string][]string{
+		DMARCReject:     {"v=DMARC1; p=reject; rua=mailto:d@example.com"},
+		DMARCQuarantine: {"v=DMARC1;p=quarantine"},
+		DMARCNone:       {"v=DMARC1; p=none"},
+		AuthInvalid:     {"v=DMARC1; rua=mailto:d@example.com"},
+		AuthMissing:     {"v=spf1 -all"},
+	}
+	for expected, records := range cases {
+		if got := parseDMARC(records); got != expected {
+			t.Fatalf("expected %q for %v, got %q", expected, records, got)
+		}
+	}
+}
+
+func TestDomainAuthCacheReusesCompleteAnswers(t *testing.T) {
+	resolver := &fakeTXTResolver{txt: map[string][]string{
+		"example.com":        {"v=spf1 -all"},
+		"_dmarc.example.com": {"v=DMARC1; p=reject"},
+	}}
+	cache := NewDomainAuthCache(DomainAuthCacheConfig{})
+
+	first := cache.Lookup(context.Background(), resolver, "Example.com.", time.Second)
+	second := cache.Lookup(context.Background(), resolver, "example.com", time.Second)
+
+	expected := DomainAuth{SPF: SPFHardfail, DMARC: DMARCReject, MTASTS: AuthMissing, BIMI: AuthMissing}
+	if first != expected || second != expected {
+		t.Fatalf("expected %+v, got %+v and %+v", expected, first, second)
+	}
+	if resolver.calls["example.com"] != 1 {
+		t.Fatalf("expected one SPF lookup, got %d", resolver.calls["example.com"])
+	}
+}
+
+func TestDomainAuthCacheSkipsFailedLookups(t *testing.T) {
+	resolver := &fakeTXTResolver{
+		txt:     map[string][]string{"example.com": {"v=spf1 ~all"}},
+		txtErrs: map[string]error{"_dmarc.example.com": errors.New("servfail")},
+	}
+	cache := NewDomainAuthCache(DomainAuthCacheConfig{})
+
+	auth := cache.Lookup(context.Background(), resolver, "example.com", time.Second)
+	if auth.DMARC != "" || auth.SPF != SPFSoftfail {
+		t.Fatalf("expected unknown DMARC and softfail SPF, got %+v", auth)
+	}
+
+	cache.Lookup(context.Background(), resolver, "example.com", time.Second)
+	if resolver.calls["_dmarc.example.com"] != 2 {
+		t.Fatalf("expected incomplete answer to be looked up again, got %d lookups", resolver.calls["_dmarc.example.com"])
+	}
+}
+
+func TestPipelineKeepsOutcomeOfDomainsWithoutMailAuth(t *testing.T) {
+	config := baseConfig(1)
+	config.DomainAuth = NewDomainAuthCache(DomainAuthCacheConfig{})
+
+	resolver := &fakeTXTResolver{
+		fakeResolver: fakeResolver{records: map[string][]*net.MX{
+			"bare.test":   {{Host: "mx.bare.test.", Pref: 10}},
+			"signed.test": {{Host: "mx.signed.test.", Pref: 10}},
+			"gone.test":   {{Host: "mx.gone.test.", Pref: 10}},
+		}},
+		txt: map[string][]string{"signed.test": {"v=spf1 mx -all"}},
+	}
+	smtp := &fakeSMTP{results: map[string]Result{
+		"mx.bare.test":   {Category: CategoryValid, Reason: "smtp_connect_ok", DecisionConfidence: "high", EvidenceStrength: "high"},
+		"mx.signed.test": {Category: CategoryValid, Reason: "smtp_connect_ok", DecisionConfidence: "high", EvidenceStrength: "high"},
+		"mx.gone.test":   {Category: CategoryInvalid, Reason: "rcpt_rejected"},
+	}}
+	v := NewPipelineVerifier(config, resolver, smtp)
+
+	bare := v.Verify(context.Background(), "user@bare.test")
+	if bare.Category != CategoryValid || bare.Reason != "smtp_connect_ok" {
+		t.Fatalf("expected valid smtp_connect_ok despite missing mail auth, got %s %s", bare.Category, bare.Reason)
+	}
+	if bare.DomainAuth == nil || !bare.DomainAuth.Missing() {
+		t.Fatalf("expected missing domain auth on result, got %+v", bare.DomainAuth)
+	}
+	if bare.DecisionConfidence != "medium" || bare.EvidenceStrength != "medium" {
+		t.Fatalf("expected missing mail auth to cap confidence at medium, got %s/%s", bare.DecisionConfidence, bare.EvidenceStrength)
+	}
+
+	signed := v.Verify(context.Background(), "user@signed.test")
+	if signed.Category != CategoryValid || signed.Reason != "smtp_connect_ok" {
+		t.Fatalf("expected valid smtp_connect_ok, got %s %s", signed.Category, signed.Reason)
+	}
+	if signed.DomainAuth == nil || signed.DomainAuth.SPF != SPFHardfail || signed.DomainAuth.DMARC != AuthMissing {
+		t.Fatalf("expected hardfail SPF and missing DMARC, got %+v", signed.DomainAuth)
+	}
+	if signed.DecisionConfidence != "high" {
+		t.Fatalf("expected published mail auth to keep high confidence, got %s", signed.DecisionConfidence)
+	}
+
+	gone := v.Verify(context.Background(), "user@gone.test")
+	if gone.Category != CategoryInvalid || gone.DomainAuth != nil || resolver.calls["gone.test"] != 0 {
+		t.Fatalf("expected no domain auth lookups for an invalid result, got %+v after %d lookups", gone.DomainAuth, resolver.calls["gone.test"])
+	}
+}
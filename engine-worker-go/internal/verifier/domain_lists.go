@@ -0,0 +1,164 @@
+package verifier
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Names of the lists distributed by the control plane.
+const (
+	DomainListDisposable   = "disposable_domains"
+	DomainListRoleAccounts = "role_accounts"
+	DomainListFreeMail     = "free_mail_domains"
+)
+
+// DomainList is one immutable version of a distributed list.
+type DomainList struct {
+	Name     string
+	Version  string
+	Checksum string
+	entries  map[string]struct{}
+}
+
+// NewDomainList builds a list from raw entries, which are lower-cased,
+// de-duplicated and stripped of blanks and # comments.
+func NewDomainList(name, version string, entries []string) *DomainList {
+	set := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		set[entry] = struct{}{}
+	}
+
+	list := &DomainList{Name: name, Version: version, entries: set}
+	list.Checksum = DomainListChecksum(list.Entries())
+
+	return list
+}
+
+// ApplyDelta returns a new version with added and removed applied. The
+// receiver is left untouched so readers holding it are not affected.
+func (l *DomainList) ApplyDelta(version string, added, removed []string) *DomainList {
+	removedSet := make(map[string]struct{}, len(removed))
+	for _, entry := range removed {
+		removedSet[strings.ToLower(strings.TrimSpace(entry))] = struct{}{}
+	}
+
+	entries := make([]string, 0, l.Len()+len(added))
+	for _, entry := range l.Entries() {
+		if _, ok := removedSet[entry]; !ok {
+			entries = append(entries, entry)
+		}
+	}
+	entries = append(entries, added...)
+
+	return NewDomainList(l.Name, version, entries)
+}
+
+// Entries returns the sorted entries.
+func (l *DomainList) Entries() []string {
+	if l == nil {
+		return nil
+	}
+
+	entries := make([]string, 0, len(l.entries))
+	for entry := range l.entries {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+
+	return entries
+}
+
+func (l *DomainList) Len() int {
+	if l == nil {
+		return 0
+	}
+
+	return len(l.entries)
+}
+
+// DomainListChecksum is the hex SHA-256 of sorted entries joined by
+// newlines, matching the checksum the control plane publishes.
+func DomainListChecksum(sortedEntries []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(sortedEntries, "\n")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// DomainListSet is a consistent view of every loaded list.
+type DomainListSet map[string]*DomainList
+
+// entriesOr returns the entries of the named list, or fallback when that
+// list has not been loaded.
+func (s DomainListSet) entriesOr(name string, fallback map[string]struct{}) map[string]struct{} {
+	if list, ok := s[name]; ok && list != nil {
+		return list.entries
+	}
+
+	return fallback
+}
+
+// DomainLists holds the distributed lists shared by every verifier a worker
+// builds. Replace swaps in a new set atomically, so a verification always
+// reads one version of each list; lists never loaded fall back to the
+// embedded or configured ones.
+type DomainLists struct {
+	mu      sync.Mutex
+	current atomic.Pointer[DomainListSet]
+}
+
+func NewDomainLists() *DomainLists {
+	return &DomainLists{}
+}
+
+// Snapshot returns the current set. It is safe to call on a nil receiver.
+func (d *DomainLists) Snapshot() DomainListSet {
+	if d == nil {
+		return nil
+	}
+
+	if current := d.current.Load(); current != nil {
+		return *current
+	}
+
+	return nil
+}
+
+// Get returns the loaded list with the given name, or nil.
+func (d *DomainLists) Get(name string) *DomainList {
+	return d.Snapshot()[name]
+}
+
+// Replace installs list, keeping the other lists as they are.
+func (d *DomainLists) Replace(list *DomainList) {
+	if d == nil || list == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	next := DomainListSet{}
+	for name, existing := range d.Snapshot() {
+		next[name] = existing
+	}
+	next[list.Name] = list
+	d.current.Store(&next)
+}
+
+// Versions returns the loaded version of each list.
+func (d *DomainLists) Versions() map[string]string {
+	versions := map[string]string{}
+	for name, list := range d.Snapshot() {
+		versions[name] = list.Version
+	}
+
+	return versions
+}
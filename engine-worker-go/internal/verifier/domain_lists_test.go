@@ -0,0 +1,68 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDomainListChecksumMatchesControlPlane(t *testing.T) {
+	list := NewDomainList(DomainListDisposable, "v1", []string{" B.test", "a.test", "# comment", "a.test"})
+	if list.Checksum != "2134be35f997333d3da50f94f715a3fdfdb955a4d8fee033cd6116351fe7f8d9" {
+		t.Fatalf("expected sha256 of sorted entries, got %s", list.Checksum)
+	}
+}
+
+func TestDomainListApplyDeltaLeavesBaseUntouched(t *testing.T) {
+	base := NewDomainList(DomainListDisposable, "v1", []string{"a.test", "b.test"})
+	next := base.ApplyDelta("v2", []string{"c.test"}, []string{"a.test"})
+
+	if strings.Join(next.Entries(), ",") != "b.test,c.test" || next.Version != "v2" {
+		t.Fatalf("expected delta to be applied, got %v (%s)", next.Entries(), next.Version)
+	}
+	if strings.Join(base.Entries(), ",") != "a.test,b.test" {
+		t.Fatalf("expected base list to be unchanged, got %v", base.Entries())
+	}
+}
+
+func TestPipelineUsesDistributedListsWithEmbeddedFallback(t *testing.T) {
+	config := baseConfig(1)
+	config.DisposableDomains["embedded.test"] = struct{}{}
+	config.RoleAccounts["info"] = struct{}{}
+	config.FreeMailDomains = map[string]struct{}{"gmail.com": {}}
+	config.DomainLists = NewDomainLists()
+
+	resolver := &fakeResolver{errs: map[string]error{
+		"embedded.test": errors.New("SERVFAIL"),
+		"synced.test":   errors.New("SERVFAIL"),
+		"gmail.com":     errors.New("SERVFAIL"),
+	}}
+	v := NewPipelineVerifier(config, resolver, &fakeSMTP{})
+
+	if res := v.Verify(context.Background(), "user@embedded.test"); res.Reason != "disposable_domain" {
+		t.Fatalf("expected embedded disposable list before sync, got %s", res.Reason)
+	}
+	if res := v.Verify(context.Background(), "user@gmail.com"); !res.FreeMail {
+		t.Fatal("expected embedded free-mail list to flag gmail.com")
+	}
+
+	config.DomainLists.Replace(NewDomainList(DomainListDisposable, "v1", []string{"synced.test"}))
+	config.DomainLists.Replace(NewDomainList(DomainListFreeMail, "v1", []string{"example.net"}))
+
+	if res := v.Verify(context.Background(), "user@synced.test"); res.Reason != "disposable_domain" {
+		t.Fatalf("expected synced disposable list, got %s", res.Reason)
+	}
+	if res := v.Verify(context.Background(), "user@embedded.test"); res.Reason == "disposable_domain" {
+		t.Fatal("expected synced disposable list to replace the embedded one")
+	}
+	if res := v.Verify(context.Background(), "user@gmail.com"); res.FreeMail {
+		t.Fatal("expected synced free-mail list to replace the embedded one")
+	}
+	if res := v.Verify(context.Background(), "info@synced.test"); res.Reason != "disposable_domain" {
+		t.Fatalf("expected disposable check before role accounts, got %s", res.Reason)
+	}
+	if res := v.Verify(context.Background(), "info@gmail.com"); res.Reason != "role_account" {
+		t.Fatalf("expected configured role accounts while no role list is synced, got %s", res.Reason)
+	}
+}
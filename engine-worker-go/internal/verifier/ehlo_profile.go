@@ -0,0 +1,90 @@
+package verifier
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	DefaultEHLOProfile = "default"
+
+	HeloNameConfigured     = "configured"
+	HeloNameIdentityDomain = "identity_domain"
+	HeloNameMailFromDomain = "mail_from_domain"
+)
+
+// EHLOProfile describes how the prober greets an MX host and paces the
+// session. Profiles are selected by ProviderSessionRule.EHLOProfile.
+type EHLOProfile struct {
+	Name string
+	// Greetings lists the greeting commands in fallback order. The next
+	// command is only tried when the previous one is rejected with 5xx.
+	Greetings []string
+	// HeloNameSource picks the name announced in the greeting.
+	HeloNameSource string
+	// CommandPacing is the minimum gap between commands on one session.
+	CommandPacing time.Duration
+	// SkipCatchAll disables the random-recipient catch-all probe.
+	SkipCatchAll bool
+}
+
+var ehloProfiles = map[string]EHLOProfile{
+	DefaultEHLOProfile: {
+		Name:           DefaultEHLOProfile,
+		Greetings:      []string{"EHLO", "HELO"},
+		HeloNameSource: HeloNameConfigured,
+	},
+	"provider-safe-gmail": {
+		Name:           "provider-safe-gmail",
+		Greetings:      []string{"EHLO"},
+		HeloNameSource: HeloNameIdentityDomain,
+		CommandPacing:  100 * time.Millisecond,
+		SkipCatchAll:   true,
+	},
+	"provider-safe-microsoft": {
+		Name:           "provider-safe-microsoft",
+		Greetings:      []string{"EHLO", "HELO"},
+		HeloNameSource: HeloNameIdentityDomain,
+		CommandPacing:  250 * time.Millisecond,
+		SkipCatchAll:   true,
+	},
+	"provider-safe-yahoo": {
+		Name:           "provider-safe-yahoo",
+		Greetings:      []string{"EHLO"},
+		HeloNameSource: HeloNameIdentityDomain,
+		CommandPacing:  200 * time.Millisecond,
+	},
+	"legacy-helo": {
+		Name:           "legacy-helo",
+		Greetings:      []string{"HELO"},
+		HeloNameSource: HeloNameConfigured,
+	},
+}
+
+// LookupEHLOProfile returns the named greeting profile, falling back to the
+// default profile for unknown names.
+func LookupEHLOProfile(name string) EHLOProfile {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if profile, ok := ehloProfiles[name]; ok {
+		return profile
+	}
+
+	return ehloProfiles[DefaultEHLOProfile]
+}
+
+// heloName resolves the announced greeting name for the profile, falling
+// back to the configured name when the preferred source is empty.
+func (e EHLOProfile) heloName(configured, identityDomain, mailFrom string) string {
+	switch e.HeloNameSource {
+	case HeloNameIdentityDomain:
+		if domain := strings.ToLower(strings.TrimSpace(identityDomain)); domain != "" {
+			return domain
+		}
+	case HeloNameMailFromDomain:
+		if at := strings.LastIndex(mailFrom, "@"); at != -1 && at+1 < len(mailFrom) {
+			return strings.ToLower(strings.TrimSpace(mailFrom[at+1:]))
+		}
+	}
+
+	return configured
+}
@@ -0,0 +1,113 @@
+package verifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newProfileProber(dialer SMTPDialer, profile string) NetSMTPProber {
+	return NetSMTPProber{
+		Dialer:                   dialer,
+		ConnectTimeout:           time.Second,
+		ReadTimeout:              time.Second,
+		EhloTimeout:              time.Second,
+		HeloName:                 "helo.test",
+		MailFromAddress:          "probe@mail.test",
+		IdentityDomain:           "Identity.Test",
+		EHLOProfile:              profile,
+		CatchAllDetectionEnabled: true,
+	}
+}
+
+func TestLookupEHLOProfileFallsBackToDefault(t *testing.T) {
+	if profile := LookupEHLOProfile(" Provider-Safe-Gmail "); profile.Name != "provider-safe-gmail" {
+		t.Fatalf("expected provider-safe-gmail profile, got %q", profile.Name)
+	}
+	if profile := LookupEHLOProfile("unknown"); profile.Name != DefaultEHLOProfile {
+		t.Fatalf("expected default profile, got %q", profile.Name)
+	}
+}
+
+func TestEHLOProfileHeloNameSources(t *testing.T) {
+	identity := EHLOProfile{HeloNameSource: HeloNameIdentityDomain}
+	if name := identity.heloName("helo.test", "Identity.Test", ""); name != "identity.test" {
+		t.Fatalf("expected identity domain, got %q", name)
+	}
+	if name := identity.heloName("helo.test", "", ""); name != "helo.test" {
+		t.Fatalf("expected configured fallback, got %q", name)
+	}
+
+	mailFrom := EHLOProfile{HeloNameSource: HeloNameMailFromDomain}
+	if name := mailFrom.heloName("helo.test", "", "probe@Mail.Test"); name != "mail.test" {
+		t.Fatalf("expected mail from domain, got %q", name)
+	}
+}
+
+func TestLegacyHeloProfileOnlySendsHELO(t *testing.T) {
+	dialer := &recordingSMTPDialer{t: t, handler: func(line string) string { return "250 OK" }}
+
+	prober := newProfileProber(dialer, "legacy-helo")
+	prober.CatchAllDetectionEnabled = false
+	res := prober.Check(context.Background(), "mx.test", "user@test.com")
+
+	if res.Category != CategoryValid {
+		t.Fatalf("expected rcpt_ok valid, got %s/%s", res.Category, res.Reason)
+	}
+	if count := dialer.commandCount("EHLO"); count != 0 {
+		t.Fatalf("expected no EHLO, got %d", count)
+	}
+	if count := dialer.commandCount("HELO helo.test"); count != 1 {
+		t.Fatalf("expected HELO with configured name, got %d", count)
+	}
+	if res.Evidence == nil || res.Evidence.EHLOProfile != "legacy-helo" {
+		t.Fatalf("expected legacy-helo evidence, got %+v", res.Evidence)
+	}
+}
+
+func TestDefaultProfileFallsBackToHELOOnRejectedEHLO(t *testing.T) {
+	dialer := &recordingSMTPDialer{t: t, handler: func(line string) string {
+		if strings.HasPrefix(line, "EHLO") {
+			return "502 Command not implemented"
+		}
+		return "250 OK"
+	}}
+
+	prober := newProfileProber(dialer, "")
+	prober.CatchAllDetectionEnabled = false
+	res := prober.Check(context.Background(), "mx.test", "user@test.com")
+
+	if res.Category != CategoryValid {
+		t.Fatalf("expected rcpt_ok valid after HELO fallback, got %s/%s", res.Category, res.Reason)
+	}
+	if count := dialer.commandCount("HELO"); count != 1 {
+		t.Fatalf("expected HELO fallback, got %d", count)
+	}
+	if res.Evidence == nil || res.Evidence.EHLOProfile != DefaultEHLOProfile {
+		t.Fatalf("expected default profile evidence, got %+v", res.Evidence)
+	}
+}
+
+func TestProviderSafeProfileUsesIdentityDomainAndSkipsCatchAll(t *testing.T) {
+	dialer := &recordingSMTPDialer{t: t, handler: func(line string) string { return "250 OK" }}
+
+	prober := newProfileProber(dialer, "provider-safe-gmail")
+	started := time.Now()
+	res := prober.Check(context.Background(), "mx.test", "user@test.com")
+	elapsed := time.Since(started)
+
+	if res.Category != CategoryValid {
+		t.Fatalf("expected rcpt_ok valid, got %s/%s", res.Category, res.Reason)
+	}
+	if count := dialer.commandCount("EHLO identity.test"); count != 1 {
+		t.Fatalf("expected EHLO with identity domain, got %d", count)
+	}
+	if count := dialer.commandCount("RCPT TO"); count != 1 {
+		t.Fatalf("expected catch-all probe to be skipped, got %d RCPT TO", count)
+	}
+	// EHLO, MAIL FROM and RCPT TO are paced 100ms apart.
+	if elapsed < 200*time.Millisecond {
+		t.Fatalf("expected paced commands, finished in %s", elapsed)
+	}
+}
@@ -0,0 +1,181 @@
+package verifier
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// Reasons for domains whose MX points at listed infrastructure.
+const (
+	reasonDisposableMX = "disposable_mx"
+	reasonParkedDomain = "parked_domain"
+)
+
+var mxInfrastructureKinds = map[string]string{
+	"disposable": reasonDisposableMX,
+	"parked":     reasonParkedDomain,
+}
+
+// MXInfrastructure classifies MX hosts that belong to disposable-mail
+// backends or domain-parking services, so fresh throwaway or parked domains
+// are caught by where their mail goes rather than by their name.
+type MXInfrastructure struct {
+	hosts    map[string]string
+	networks []mxInfrastructureNetwork
+}
+
+type mxInfrastructureNetwork struct {
+	network *net.IPNet
+	reason  string
+}
+
+func NewMXInfrastructure() *MXInfrastructure {
+	return &MXInfrastructure{hosts: map[string]string{}}
+}
+
+// ParseMXInfrastructure reads "<kind> <entry>" lines, where kind is
+// disposable or parked and entry is an MX host suffix, an IP or a CIDR.
+// Blank lines, # comments and malformed lines are skipped.
+func ParseMXInfrastructure(data string) *MXInfrastructure {
+	infrastructure := NewMXInfrastructure()
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(strings.ToLower(line))
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		infrastructure.Add(fields[0], fields[1])
+	}
+
+	return infrastructure
+}
+
+// Add lists entry under kind and reports whether both were recognised.
+func (m *MXInfrastructure) Add(kind, entry string) bool {
+	reason, ok := mxInfrastructureKinds[strings.ToLower(strings.TrimSpace(kind))]
+	if !ok {
+		return false
+	}
+
+	entry = strings.Trim(strings.ToLower(strings.TrimSpace(entry)), ".")
+	if entry == "" {
+		return false
+	}
+
+	if _, network, err := net.ParseCIDR(entry); err == nil {
+		m.networks = append(m.networks, mxInfrastructureNetwork{network: network, reason: reason})
+		return true
+	}
+	if ip := net.ParseIP(entry); ip != nil {
+		bits := 8 * net.IPv4len
+		if ip.To4() == nil {
+			bits = 8 * net.IPv6len
+		}
+		m.networks = append(m.networks, mxInfrastructureNetwork{
+			network: &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)},
+			reason:  reason,
+		})
+		return true
+	}
+
+	m.hosts[entry] = reason
+	return true
+}
+
+// matchHost returns the reason for host when it or one of its parent
+// domains is listed.
+func (m *MXInfrastructure) matchHost(host string) string {
+	if m == nil {
+		return ""
+	}
+
+	host = strings.Trim(strings.ToLower(strings.TrimSpace(host)), ".")
+	for host != "" {
+		if reason, ok := m.hosts[host]; ok {
+			return reason
+		}
+		dot := strings.Index(host, ".")
+		if dot == -1 {
+			return ""
+		}
+		host = host[dot+1:]
+	}
+
+	return ""
+}
+
+// matchAddresses returns the reason for the first listed address.
+func (m *MXInfrastructure) matchAddresses(addresses []string) string {
+	if m == nil {
+		return ""
+	}
+
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			continue
+		}
+		for _, network := range m.networks {
+			if network.network.Contains(ip) {
+				return network.reason
+			}
+		}
+	}
+
+	return ""
+}
+
+func (m *MXInfrastructure) hasNetworks() bool {
+	return m != nil && len(m.networks) > 0
+}
+
+// checkMXInfrastructure classifies the MX hosts a probe would contact by
+// name and, when address ranges are listed, by their resolved addresses.
+// An empty result means no listed infrastructure was found.
+func (p *PipelineVerifier) checkMXInfrastructure(ctx context.Context, mxRecords []*net.MX) Result {
+	infrastructure := p.config.MXInfrastructure
+	if infrastructure == nil {
+		return Result{}
+	}
+
+	for _, mx := range mxRecords {
+		if reason := infrastructure.matchHost(mx.Host); reason != "" {
+			return mxInfrastructureResult(reason, mx.Host)
+		}
+	}
+
+	if !infrastructure.hasNetworks() {
+		return Result{}
+	}
+	hostResolver, ok := p.resolver.(HostResolver)
+	if !ok {
+		return Result{}
+	}
+
+	maxAttempts := p.config.MaxMXAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 2
+	}
+	for index, mx := range mxRecords {
+		if index >= maxAttempts {
+			break
+		}
+		addresses, err := p.lookupHost(ctx, hostResolver, strings.TrimSuffix(mx.Host, "."))
+		if err != nil {
+			continue
+		}
+		if reason := infrastructure.matchAddresses(addresses); reason != "" {
+			return mxInfrastructureResult(reason, mx.Host)
+		}
+	}
+
+	return Result{}
+}
+
+func mxInfrastructureResult(reason, host string) Result {
+	return Result{
+		Category: CategoryRisky,
+		Reason:   reason,
+		MXHost:   strings.TrimSuffix(host, "."),
+	}
+}
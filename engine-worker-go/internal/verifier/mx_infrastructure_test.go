@@ -0,0 +1,88 @@
+package verifier
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	workerdata "engine-worker-go/data"
+)
+
+func TestParseMXInfrastructureMatchesHostsAndNetworks(t *testing.T) {
+	infrastructure := ParseMXInfrastructure("# comment\ndisposable mailinator.com\nparked 192.0.2.0/24\nparked 2001:db8::25\nunknown example.com\nmalformed\n")
+
+	cases := map[string]string{
+		"mail.mailinator.com.": reasonDisposableMX,
+		"MAILINATOR.COM":       reasonDisposableMX,
+		"notmailinator.com":    "",
+		"mx.example.com":       "",
+	}
+	for host, expected := range cases {
+		if reason := infrastructure.matchHost(host); reason != expected {
+			t.Fatalf("expected %q for %s, got %q", expected, host, reason)
+		}
+	}
+
+	if reason := infrastructure.matchAddresses([]string{"198.51.100.1", "192.0.2.44"}); reason != reasonParkedDomain {
+		t.Fatalf("expected parked_domain for a listed range, got %q", reason)
+	}
+	if reason := infrastructure.matchAddresses([]string{"2001:db8::25"}); reason != reasonParkedDomain {
+		t.Fatalf("expected parked_domain for a listed IPv6 address, got %q", reason)
+	}
+	if reason := infrastructure.matchAddresses([]string{"2001:db8::26"}); reason != "" {
+		t.Fatalf("expected no match for an unlisted address, got %q", reason)
+	}
+}
+
+func TestEmbeddedMXInfrastructureParses(t *testing.T) {
+	infrastructure := ParseMXInfrastructure(workerdata.MXInfrastructure)
+	if infrastructure.matchHost("mx.sedoparking.com") != reasonParkedDomain || infrastructure.matchHost("mx.yopmail.net") != reasonDisposableMX {
+		t.Fatal("expected embedded infrastructure to list parking and disposable MX hosts")
+	}
+	if !infrastructure.hasNetworks() {
+		t.Fatal("expected embedded infrastructure to list parking networks")
+	}
+	if reason := infrastructure.matchAddresses([]string{"198.51.100.7", "91.195.240.94"}); reason != reasonParkedDomain {
+		t.Fatalf("expected parked_domain for a parking network address, got %q", reason)
+	}
+	if reason := infrastructure.matchAddresses([]string{"198.51.100.7"}); reason != "" {
+		t.Fatalf("expected no match for an unlisted address, got %q", reason)
+	}
+}
+
+func TestPipelineClassifiesMXInfrastructureBeforeSMTP(t *testing.T) {
+	config := baseConfig(2)
+	config.MXInfrastructure = ParseMXInfrastructure("disposable mail.throwaway-backend.test\nparked 203.0.113.0/24\n")
+
+	resolver := &fakeHostResolver{
+		fakeResolver: fakeResolver{records: map[string][]*net.MX{
+			"fresh-throwaway.test": {{Host: "mail.throwaway-backend.test.", Pref: 10}},
+			"parked.test":          {{Host: "mx.parking.test.", Pref: 10}},
+			"company.test":         {{Host: "mx.company.test.", Pref: 10}},
+		}},
+		hosts: map[string][]string{
+			"mx.parking.test": {"203.0.113.7"},
+			"mx.company.test": {"198.51.100.7"},
+			"parked-noa.test": {"203.0.113.9"},
+		},
+	}
+	smtp := &fakeSMTP{results: map[string]Result{"mx.company.test": {Category: CategoryValid, Reason: "smtp_connect_ok"}}}
+	v := NewPipelineVerifier(config, resolver, smtp)
+
+	cases := map[string]string{
+		"user@fresh-throwaway.test": reasonDisposableMX,
+		"user@parked.test":          reasonParkedDomain,
+		"user@parked-noa.test":      reasonParkedDomain,
+		"user@company.test":         "smtp_connect_ok",
+	}
+	for email, expected := range cases {
+		res := v.Verify(context.Background(), email)
+		if res.Reason != expected {
+			t.Fatalf("expected %s for %s, got %s/%s", expected, email, res.Category, res.Reason)
+		}
+	}
+
+	if smtp.calls["mail.throwaway-backend.test"] != 0 || smtp.calls["mx.parking.test"] != 0 || smtp.calls["parked-noa.test"] != 0 {
+		t.Fatalf("expected no SMTP connection to listed infrastructure, got %v", smtp.calls)
+	}
+}
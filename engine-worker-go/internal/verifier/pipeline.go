@@ -8,8 +8,6 @@ import (
 	"sort"
 	"strings"
 	"time"
-
-	"golang.org/x/net/idna"
 )
 
 type PipelineVerifier struct {
@@ -20,21 +18,30 @@ type PipelineVerifier struct {
 	config            Config
 	disposableDomains map[string]struct{}
 	roleAccounts      map[string]struct{}
+	freeMailDomains   map[string]struct{}
 	domainTypos       map[string]string
 }
 
 func NewPipelineVerifier(config Config, resolver MXResolver, smtpChecker SMTPChecker) *PipelineVerifier {
 	limiter := NewDomainLimiter(config.PerDomainConcurrency)
-	rateLimiter := NewRateLimiter(config.SMTPRateLimitPerMinute)
+	rateLimiter := config.RateLimiter
+	if rateLimiter == nil && config.SMTPRateLimitPerMinute > 0 {
+		rateLimiter = NewRateLimiter(RateLimiterConfig{PerMinute: config.SMTPRateLimitPerMinute})
+	}
+
+	if resolver == nil {
+		resolver = NetMXResolver{}
+	}
 
 	if smtpChecker == nil {
 		smtpChecker = NetSMTPChecker{
-			Dialer:         nil,
+			Dialer:         config.SMTPDialer,
 			ConnectTimeout: time.Duration(config.SMTPConnectTimeout) * time.Millisecond,
 			ReadTimeout:    time.Duration(config.SMTPReadTimeout) * time.Millisecond,
 			EhloTimeout:    time.Duration(config.SMTPEhloTimeout) * time.Millisecond,
 			HeloName:       config.HeloName,
 			RateLimiter:    rateLimiter,
+			ReplyCorpus:    config.ReplyCorpus,
 		}
 	}
 
@@ -48,6 +55,11 @@ func NewPipelineVerifier(config Config, resolver MXResolver, smtpChecker SMTPChe
 		roleAccounts = map[string]struct{}{}
 	}
 
+	freeMailDomains := config.FreeMailDomains
+	if freeMailDomains == nil {
+		freeMailDomains = map[string]struct{}{}
+	}
+
 	domainTypos := config.DomainTypos
 	if domainTypos == nil {
 		domainTypos = map[string]string{}
@@ -61,6 +73,7 @@ func NewPipelineVerifier(config Config, resolver MXResolver, smtpChecker SMTPChe
 		config:            config,
 		disposableDomains: disposableDomains,
 		roleAccounts:      roleAccounts,
+		freeMailDomains:   freeMailDomains,
 		domainTypos:       domainTypos,
 	}
 }
@@ -68,51 +81,122 @@ func NewPipelineVerifier(config Config, resolver MXResolver, smtpChecker SMTPChe
 func (p *PipelineVerifier) Verify(ctx context.Context, email string) Result {
 	parsed, parseResult := parseEmail(email)
 	if parseResult.Reason != "" {
-		return parseResult
+		return p.withScore(parseResult, scoreSignals{})
 	}
 
+	lists := p.config.DomainLists.Snapshot()
+	result := p.verifyParsed(ctx, parsed, lists)
+	result.SMTPUTF8 = parsed.smtputf8
+	_, result.FreeMail = lists.entriesOr(DomainListFreeMail, p.freeMailDomains)[parsed.domain]
+	_, roleAccount := lists.entriesOr(DomainListRoleAccounts, p.roleAccounts)[parsed.local]
+
+	return p.withScore(result, scoreSignals{roleAccount: roleAccount})
+}
+
+// verifyParsed checks parsed against one snapshot of the distributed lists,
+// so a list update mid-verification cannot mix versions.
+func (p *PipelineVerifier) verifyParsed(ctx context.Context, parsed parsedEmail, lists DomainListSet) Result {
 	if suggestion, ok := p.domainTypos[parsed.domain]; ok {
 		return Result{Category: CategoryRisky, Reason: fmt.Sprintf("domain_typo_suspected:suggest=%s", suggestion)}
 	}
 
-	if p.isDisposableDomain(parsed.domain) {
+	if isDisposableDomain(lists.entriesOr(DomainListDisposable, p.disposableDomains), parsed.domain) {
 		return Result{Category: CategoryRisky, Reason: "disposable_domain"}
 	}
 
 	behavior := strings.ToLower(strings.TrimSpace(p.config.RoleAccountsBehavior))
 	if behavior == "" || behavior == "risky" {
-		if _, ok := p.roleAccounts[parsed.local]; ok {
+		if _, ok := lists.entriesOr(DomainListRoleAccounts, p.roleAccounts)[parsed.local]; ok {
 			return Result{Category: CategoryRisky, Reason: "role_account"}
 		}
 	}
 
 	emailAddress := fmt.Sprintf("%s@%s", parsed.local, parsed.domain)
 
-	mxRecords, dnsResult := p.lookupMX(ctx, parsed.domain)
+	mxRecords, dnsResult, mxErr := p.lookupMX(ctx, parsed.domain)
+	if isNullMX(mxRecords) {
+		return p.typoOr(parsed.domain, nullMXResult(parsed.domain))
+	}
+	mxRecords = withoutNullMX(mxRecords)
+
+	if len(mxRecords) == 0 && (dnsResult.Reason == "" || isNegativeDNSAnswer(mxErr)) {
+		return p.checkImplicitMX(ctx, parsed.domain, emailAddress, dnsResult)
+	}
 	if dnsResult.Reason != "" {
 		return dnsResult
 	}
-	if len(mxRecords) == 0 {
-		return Result{Category: CategoryInvalid, Reason: "mx_missing"}
-	}
 
 	sort.Slice(mxRecords, func(i, j int) bool {
 		return mxRecords[i].Pref < mxRecords[j].Pref
 	})
 
-	return p.checkSMTP(ctx, parsed.domain, emailAddress, mxRecords)
+	if result := p.checkMXInfrastructure(ctx, mxRecords); result.Reason != "" {
+		return result
+	}
+
+	return p.withDomainAuth(ctx, parsed.domain, p.checkSMTP(ctx, parsed.domain, emailAddress, mxRecords, mxRoutePrefix))
+}
+
+// checkImplicitMX applies RFC 5321 section 5.1: a domain without MX records
+// receives mail on its own A/AAAA address. Resolvers that cannot look up
+// addresses keep the plain mx_missing outcome, as do domains without one.
+// dnsResult is returned when the MX lookup failed and no address exists.
+// A typo suggestion replaces either outcome, since only then can the domain
+// not receive mail.
+func (p *PipelineVerifier) checkImplicitMX(ctx context.Context, domain, email string, dnsResult Result) Result {
+	hostResolver, ok := p.resolver.(HostResolver)
+	if !ok {
+		if dnsResult.Reason != "" {
+			return p.typoOr(domain, dnsResult)
+		}
+		return p.typoOr(domain, Result{Category: CategoryInvalid, Reason: "mx_missing"})
+	}
+
+	addresses, err := p.lookupHost(ctx, hostResolver, domain)
+	if err != nil && !isNegativeDNSAnswer(err) {
+		return classifyDNSError(err)
+	}
+	if len(addresses) == 0 {
+		return p.typoOr(domain, Result{Category: CategoryInvalid, Reason: "mx_missing"})
+	}
+	if reason := p.config.MXInfrastructure.matchAddresses(addresses); reason != "" {
+		return mxInfrastructureResult(reason, domain)
+	}
+
+	return p.withDomainAuth(ctx, domain, p.checkSMTP(ctx, domain, email, []*net.MX{{Host: domain}}, implicitMXRoutePrefix))
 }
 
-func (p *PipelineVerifier) lookupMX(ctx context.Context, domain string) ([]*net.MX, Result) {
-	if p.resolver == nil {
-		p.resolver = NetMXResolver{}
+// typoOr returns a domain_typo_suspected result when the typo engine has a
+// suggestion for a domain that cannot receive mail, and fallback otherwise.
+func (p *PipelineVerifier) typoOr(domain string, fallback Result) Result {
+	if suggestion, ok := p.config.TypoEngine.Suggest(domain); ok {
+		return Result{Category: CategoryRisky, Reason: typoReason(suggestion)}
 	}
 
+	return fallback
+}
+
+func (p *PipelineVerifier) lookupHost(ctx context.Context, resolver HostResolver, host string) ([]string, error) {
+	lookupCtx, cancel := context.WithTimeout(ctx, p.dnsTimeout())
+	defer cancel()
+
+	return resolver.LookupHost(lookupCtx, host)
+}
+
+func (p *PipelineVerifier) dnsTimeout() time.Duration {
 	timeout := time.Duration(p.config.DNSTimeout) * time.Millisecond
 	if timeout <= 0 {
 		timeout = 2 * time.Second
 	}
 
+	return timeout
+}
+
+// lookupMX returns the MX records for domain. On failure it also returns the
+// last resolver error so callers can tell negative answers from outages.
+func (p *PipelineVerifier) lookupMX(ctx context.Context, domain string) ([]*net.MX, Result, error) {
+	timeout := p.dnsTimeout()
+
 	retries := maxInt(0, p.config.RetryableNetworkRetries)
 
 	var lastErr error
@@ -122,25 +206,25 @@ func (p *PipelineVerifier) lookupMX(ctx context.Context, domain string) ([]*net.
 		cancel()
 
 		if err == nil {
-			return records, Result{}
+			return records, Result{}, nil
 		}
 
 		lastErr = err
 		if !isRetryableDNSError(err) || attempt == retries {
-			return nil, classifyDNSError(err)
+			return nil, classifyDNSError(err), err
 		}
 
 		backoffSleep(ctx, p.config.BackoffBaseMs, attempt, 0, p.config.RetryJitterPercent)
 	}
 
 	if lastErr != nil {
-		return nil, classifyDNSError(lastErr)
+		return nil, classifyDNSError(lastErr), lastErr
 	}
 
-	return nil, Result{}
+	return nil, Result{}, nil
 }
 
-func (p *PipelineVerifier) checkSMTP(ctx context.Context, domain, email string, mxRecords []*net.MX) Result {
+func (p *PipelineVerifier) checkSMTP(ctx context.Context, domain, email string, mxRecords []*net.MX, routePrefix string) Result {
 	maxAttempts := p.config.MaxMXAttempts
 	if maxAttempts <= 0 {
 		maxAttempts = 2
@@ -149,6 +233,7 @@ func (p *PipelineVerifier) checkSMTP(ctx context.Context, domain, email string,
 	var best Result
 	attemptCounter := 0
 	fullAttemptChain := make([]AttemptEvidence, 0, maxAttempts)
+	var fullTranscript []TranscriptEntry
 
 	for i, mx := range mxRecords {
 		if i >= maxAttempts {
@@ -156,14 +241,16 @@ func (p *PipelineVerifier) checkSMTP(ctx context.Context, domain, email string,
 		}
 
 		host := strings.TrimSuffix(mx.Host, ".")
-		attemptResult := p.checkSMTPHost(ctx, domain, host, email, attemptCounter+1)
+		attemptResult := p.checkSMTPHost(ctx, domain, host, email, routePrefix, attemptCounter+1)
 		attemptCounter += len(attemptResult.AttemptChain)
 		if len(attemptResult.AttemptChain) > 0 {
 			fullAttemptChain = append(fullAttemptChain, cloneAttemptChain(attemptResult.AttemptChain)...)
 		}
+		fullTranscript = withAttemptTranscript(fullTranscript, attemptResult.Transcript, 0)
 
 		if attemptResult.Category == CategoryValid {
 			attemptResult.AttemptChain = cloneAttemptChain(fullAttemptChain)
+			attemptResult.Transcript = fullTranscript
 			return attemptResult
 		}
 
@@ -173,6 +260,7 @@ func (p *PipelineVerifier) checkSMTP(ctx context.Context, domain, email string,
 
 		if !shouldAttemptNextMX(attemptResult) {
 			attemptResult.AttemptChain = cloneAttemptChain(fullAttemptChain)
+			attemptResult.Transcript = fullTranscript
 			return attemptResult
 		}
 	}
@@ -182,35 +270,60 @@ func (p *PipelineVerifier) checkSMTP(ctx context.Context, domain, email string,
 			Category:     CategoryRisky,
 			Reason:       "smtp_timeout",
 			AttemptChain: cloneAttemptChain(fullAttemptChain),
+			Transcript:   fullTranscript,
 		}
 	}
 
 	best.AttemptChain = cloneAttemptChain(fullAttemptChain)
+	best.Transcript = fullTranscript
 
 	return best
 }
 
-func (p *PipelineVerifier) checkSMTPHost(ctx context.Context, domain, host, email string, firstAttemptNumber int) Result {
+func (p *PipelineVerifier) checkSMTPHost(ctx context.Context, domain, host, email, routePrefix string, firstAttemptNumber int) Result {
 	limiterRelease, err := p.limiter.Acquire(ctx, domain)
 	if err != nil {
 		return Result{Category: CategoryRisky, Reason: "smtp_timeout"}
 	}
 	defer limiterRelease()
 
+	hostRelease, err := p.config.HostLimiter.Acquire(ctx, smtpThrottleKey(p.config.ProviderProfile, host), p.config.SessionMaxConcurrency)
+	if err != nil {
+		return Result{Category: CategoryRisky, Reason: "smtp_timeout"}
+	}
+	defer hostRelease()
+
 	retries := maxInt(0, p.config.RetryableNetworkRetries)
 
 	var last Result
 	attemptChain := make([]AttemptEvidence, 0, retries+1)
+	var transcript []TranscriptEntry
 	if firstAttemptNumber <= 0 {
 		firstAttemptNumber = 1
 	}
 
+	// Long-tail hosts share the generic profile, so only recognised
+	// providers get a provider circuit; others are gated per MX host.
+	circuitKeys := []string{mxCircuitKey(host)}
+	if provider := detectSMTPProviderProfile(p.config.ProviderProfile, host, ""); provider != "" && provider != "generic" {
+		circuitKeys = append([]string{providerCircuitKey(provider)}, circuitKeys...)
+	}
+
 	for attempt := 0; attempt <= retries; attempt++ {
-		result := p.smtpChecker.Check(ctx, host, email)
 		attemptNumber := firstAttemptNumber + attempt
-		result = applyAttemptEvidence(result, host, attemptNumber)
+		if allowed, openKey := p.config.CircuitBreaker.Allow(circuitKeys...); !allowed {
+			result := applyAttemptEvidence(circuitOpenResult(openKey), host, routePrefix, attemptNumber)
+			result.AttemptChain = append(cloneAttemptChain(attemptChain), attemptEvidenceFromResult(result))
+			return result
+		}
+
+		result := p.smtpChecker.Check(ctx, host, email)
+		p.config.CircuitBreaker.Record(isCircuitTempfailResult(result), circuitKeys...)
+		result = applyAttemptEvidence(result, host, routePrefix, attemptNumber)
 		result.AttemptChain = append(cloneAttemptChain(attemptChain), attemptEvidenceFromResult(result))
 		attemptChain = result.AttemptChain
+		transcript = withAttemptTranscript(transcript, result.Transcript, attemptNumber)
+		result.Transcript = transcript
 
 		if result.Category == CategoryValid {
 			return result
@@ -236,6 +349,53 @@ func (p *PipelineVerifier) checkSMTPHost(ctx context.Context, domain, host, emai
 	return last
 }
 
+const (
+	mxRoutePrefix         = "mx:"
+	implicitMXRoutePrefix = "implicit_mx:"
+)
+
+// isNullMX reports whether the domain published the RFC 7505 null MX, a
+// single "." record, declaring that it accepts no mail.
+func isNullMX(records []*net.MX) bool {
+	return len(records) == 1 && records[0] != nil && isNullMXHost(records[0].Host)
+}
+
+func isNullMXHost(host string) bool {
+	return strings.TrimSpace(host) == "." || strings.TrimSpace(host) == ""
+}
+
+// withoutNullMX drops "." records mixed in with real exchanges, which RFC
+// 7505 says must not be used for delivery.
+func withoutNullMX(records []*net.MX) []*net.MX {
+	filtered := records[:0:0]
+	for _, record := range records {
+		if record == nil || isNullMXHost(record.Host) {
+			continue
+		}
+		filtered = append(filtered, record)
+	}
+
+	return filtered
+}
+
+func nullMXResult(domain string) Result {
+	result := Result{
+		Category:           CategoryInvalid,
+		Reason:             "null_mx",
+		DecisionClass:      DecisionUndeliverable,
+		ReasonCode:         "null_mx",
+		DecisionConfidence: "high",
+		RetryStrategy:      "none",
+		AttemptNumber:      1,
+		AttemptRoute:       "null_mx:" + domain,
+	}
+	result = applyAttemptEvidence(result, "", "", 0)
+	result.AttemptChain = []AttemptEvidence{attemptEvidenceFromResult(result)}
+	result.Evidence.AttemptChain = cloneAttemptChain(result.AttemptChain)
+
+	return result
+}
+
 func shouldAttemptNextMX(result Result) bool {
 	switch result.DecisionClass {
 	case DecisionRetryable, DecisionUnknown:
@@ -250,7 +410,7 @@ func shouldAttemptNextMX(result Result) bool {
 	}
 }
 
-func applyAttemptEvidence(result Result, mxHost string, attemptNumber int) Result {
+func applyAttemptEvidence(result Result, mxHost, routePrefix string, attemptNumber int) Result {
 	mxHost = strings.TrimSpace(mxHost)
 	if mxHost != "" && strings.TrimSpace(result.MXHost) == "" {
 		result.MXHost = mxHost
@@ -259,7 +419,7 @@ func applyAttemptEvidence(result Result, mxHost string, attemptNumber int) Resul
 		result.AttemptNumber = attemptNumber
 	}
 	if strings.TrimSpace(result.AttemptRoute) == "" && mxHost != "" {
-		result.AttemptRoute = "mx:" + mxHost
+		result.AttemptRoute = routePrefix + mxHost
 	}
 	if strings.TrimSpace(result.EvidenceStrength) == "" {
 		result.EvidenceStrength = normalizedEvidenceStrength(result.DecisionConfidence)
@@ -280,6 +440,9 @@ func applyAttemptEvidence(result Result, mxHost string, attemptNumber int) Resul
 	if strings.TrimSpace(result.Evidence.EvidenceStrength) == "" {
 		result.Evidence.EvidenceStrength = result.EvidenceStrength
 	}
+	if strings.TrimSpace(result.Evidence.SourceIP) == "" {
+		result.Evidence.SourceIP = result.SourceIP
+	}
 	if len(result.Evidence.AttemptChain) == 0 && len(result.AttemptChain) > 0 {
 		result.Evidence.AttemptChain = cloneAttemptChain(result.AttemptChain)
 	}
@@ -301,6 +464,7 @@ func attemptEvidenceFromResult(result Result) AttemptEvidence {
 		ProviderProfile:  strings.TrimSpace(result.ProviderProfile),
 		ConfidenceHint:   strings.TrimSpace(result.DecisionConfidence),
 		EvidenceStrength: strings.TrimSpace(result.EvidenceStrength),
+		SourceIP:         strings.TrimSpace(result.SourceIP),
 	}
 }
 
@@ -316,49 +480,39 @@ func cloneAttemptChain(chain []AttemptEvidence) []AttemptEvidence {
 }
 
 type parsedEmail struct {
-	local  string
-	domain string
+	local    string
+	domain   string
+	smtputf8 bool
 }
 
 func parseEmail(email string) (parsedEmail, Result) {
 	normalized := strings.TrimSpace(strings.ToLower(email))
-	if normalized == "" || !strings.Contains(normalized, "@") {
-		return parsedEmail{}, Result{Category: CategoryInvalid, Reason: "syntax"}
+	if normalized == "" {
+		return parsedEmail{}, Result{Category: CategoryInvalid, Reason: SyntaxInvalid}
 	}
 
-	parts := strings.SplitN(normalized, "@", 2)
-	if len(parts) != 2 {
-		return parsedEmail{}, Result{Category: CategoryInvalid, Reason: "syntax"}
-	}
-
-	local := strings.TrimSpace(parts[0])
-	domain := strings.TrimSpace(parts[1])
-	if local == "" || domain == "" {
-		return parsedEmail{}, Result{Category: CategoryInvalid, Reason: "syntax"}
-	}
-
-	asciiDomain, err := idna.Lookup.ToASCII(domain)
-	if err != nil {
-		return parsedEmail{}, Result{Category: CategoryInvalid, Reason: "syntax"}
-	}
-
-	asciiDomain = strings.ToLower(strings.TrimSuffix(asciiDomain, "."))
-	if asciiDomain == "" {
-		return parsedEmail{}, Result{Category: CategoryInvalid, Reason: "syntax"}
+	syntax, reason := ValidateAddressSyntax(normalized)
+	switch reason {
+	case "":
+	case SyntaxIPLiteral:
+		return parsedEmail{}, Result{Category: CategoryRisky, Reason: reason}
+	default:
+		return parsedEmail{}, Result{Category: CategoryInvalid, Reason: reason}
 	}
 
 	return parsedEmail{
-		local:  local,
-		domain: asciiDomain,
+		local:    syntax.Local,
+		domain:   syntax.Domain,
+		smtputf8: syntax.SMTPUTF8,
 	}, Result{}
 }
 
-func (p *PipelineVerifier) isDisposableDomain(domain string) bool {
-	if len(p.disposableDomains) == 0 || domain == "" {
+func isDisposableDomain(disposableDomains map[string]struct{}, domain string) bool {
+	if len(disposableDomains) == 0 || domain == "" {
 		return false
 	}
 
-	if _, ok := p.disposableDomains[domain]; ok {
+	if _, ok := disposableDomains[domain]; ok {
 		return true
 	}
 
@@ -368,7 +522,7 @@ func (p *PipelineVerifier) isDisposableDomain(domain string) bool {
 			return false
 		}
 		domain = domain[dot+1:]
-		if _, ok := p.disposableDomains[domain]; ok {
+		if _, ok := disposableDomains[domain]; ok {
 			return true
 		}
 	}
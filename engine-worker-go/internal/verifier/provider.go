@@ -63,6 +63,10 @@ func (p *ProviderAwareVerifier) verifierFor(key string, policy *ProviderPolicy)
 	config := p.baseConfig
 	if policy != nil {
 		config = applyProviderOverrides(config, *policy)
+		config.ProviderProfile = strings.ToLower(strings.TrimSpace(policy.Name))
+		if config.ProviderProfile != "" {
+			config.RateLimiter.SetKeyRate(providerCircuitKey(config.ProviderProfile), config.SMTPRateLimitPerMinute)
+		}
 	}
 
 	var smtpChecker SMTPChecker
@@ -82,6 +86,28 @@ func (p *ProviderAwareVerifier) verifierFor(key string, policy *ProviderPolicy)
 	return verifier
 }
 
+// ProviderKeyRates returns the connect rate each enabled provider policy
+// sets on its rate-limiter key, computed from config the way the verifier
+// of that provider is built.
+func ProviderKeyRates(config Config, policies []ProviderPolicy) map[string]int {
+	rates := map[string]int{}
+	for _, policy := range policies {
+		if !policy.Enabled || len(policy.Domains) == 0 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(policy.Name))
+		if name == "" {
+			continue
+		}
+		if _, ok := rates[providerCircuitKey(name)]; ok {
+			continue
+		}
+		rates[providerCircuitKey(name)] = applyProviderOverrides(config, policy).SMTPRateLimitPerMinute
+	}
+
+	return rates
+}
+
 func withProviderProfile(checker SMTPChecker, profile string) SMTPChecker {
 	switch typed := checker.(type) {
 	case NetSMTPProber:
@@ -179,12 +205,19 @@ func applyProviderOverrides(config Config, policy ProviderPolicy) Config {
 		session := profile.Session
 		if session.MaxConcurrency > 0 {
 			config.PerDomainConcurrency = applyMultiplier(session.MaxConcurrency, modeRule.MaxConcurrencyMultiplier)
+			config.SessionMaxConcurrency = config.PerDomainConcurrency
 		}
 		if session.ConnectsPerMinute > 0 {
 			config.SMTPRateLimitPerMinute = applyMultiplier(session.ConnectsPerMinute, modeRule.ConnectsPerMinuteMultiplier)
 		}
 		config.RetryJitterPercent = session.RetryJitterPercent
 		config.ReuseConnectionForRetries = session.ReuseConnectionForRetries
+		if session.TLSMode != "" {
+			config.SMTPTLSMode = session.TLSMode
+		}
+		if proxy := strings.TrimSpace(session.Proxy); proxy != "" {
+			config.SMTPProxy = proxy
+		}
 		config.EHLOProfile = strings.TrimSpace(session.EHLOProfile)
 		if config.EHLOProfile == "" {
 			config.EHLOProfile = "default"
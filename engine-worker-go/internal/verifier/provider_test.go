@@ -101,3 +101,25 @@ func TestApplyProviderOverridesUsesSessionPolicyAndModeMultipliers(t *testing.T)
 		t.Fatal("expected EHLO profile to be populated from provider session policy")
 	}
 }
+
+func TestProviderKeyRatesFollowsProviderOverrides(t *testing.T) {
+	connects := 15
+	config := Config{SMTPRateLimitPerMinute: 60}
+	policies := []ProviderPolicy{
+		{Name: "Gmail", Enabled: true, Domains: []string{"gmail.com"}, ConnectsPerMinute: &connects},
+		{Name: "yahoo", Enabled: true, Domains: []string{"yahoo.com"}},
+		{Name: "outlook", Enabled: false, Domains: []string{"outlook.com"}, ConnectsPerMinute: &connects},
+	}
+
+	rates := ProviderKeyRates(config, policies)
+
+	if rates[providerCircuitKey("gmail")] != connects {
+		t.Fatalf("expected gmail rate %d, got %d", connects, rates[providerCircuitKey("gmail")])
+	}
+	if rates[providerCircuitKey("yahoo")] != 60 {
+		t.Fatalf("expected yahoo to keep the base rate, got %d", rates[providerCircuitKey("yahoo")])
+	}
+	if _, ok := rates[providerCircuitKey("outlook")]; ok {
+		t.Fatal("expected disabled policies to be skipped")
+	}
+}
@@ -0,0 +1,137 @@
+package verifier
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ReplySample is one recorded SMTP reply: the stage it answered, its codes,
+// the anonymized message and the provider profile it was classified under.
+type ReplySample struct {
+	Stage        string `json:"stage"`
+	Code         int    `json:"code"`
+	EnhancedCode string `json:"enhanced_code,omitempty"`
+	Message      string `json:"message,omitempty"`
+	Provider     string `json:"provider"`
+}
+
+var (
+	corpusEmailPattern = regexp.MustCompile(`[^\s<>"'()\[\],;:]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	corpusIPv4Pattern  = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`)
+	corpusIPv6Pattern  = regexp.MustCompile(`\b(?:[0-9A-Fa-f]{1,4}:){2,7}[0-9A-Fa-f]{1,4}\b`)
+)
+
+// anonymizeReplyMessage strips email and IP addresses from a reply message,
+// keeping the wording the reply policy rules match on.
+func anonymizeReplyMessage(message string) string {
+	message = corpusEmailPattern.ReplaceAllString(message, "<email>")
+	message = corpusIPv4Pattern.ReplaceAllString(message, "<ip>")
+
+	return corpusIPv6Pattern.ReplaceAllString(message, "<ip>")
+}
+
+// ReplyCorpus appends anonymized reply samples to a JSON lines file, one
+// sample per classified reply, until maxRecords samples were written.
+type ReplyCorpus struct {
+	mu         sync.Mutex
+	out        io.WriteCloser
+	maxRecords int
+	records    int
+}
+
+// OpenReplyCorpus appends to the corpus at path. maxRecords <= 0 records
+// without limit.
+func OpenReplyCorpus(path string, maxRecords int) (*ReplyCorpus, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReplyCorpus{out: file, maxRecords: maxRecords}, nil
+}
+
+func (c *ReplyCorpus) record(stage string, reply smtpReply, provider string) {
+	if c == nil {
+		return
+	}
+
+	line, err := json.Marshal(ReplySample{
+		Stage:        stage,
+		Code:         reply.Code,
+		EnhancedCode: reply.EnhancedCode,
+		Message:      anonymizeReplyMessage(reply.Message),
+		Provider:     provider,
+	})
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxRecords > 0 && c.records >= c.maxRecords {
+		return
+	}
+	if _, err := c.out.Write(append(line, '\n')); err == nil {
+		c.records++
+	}
+}
+
+// Close closes the corpus file.
+func (c *ReplyCorpus) Close() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.out.Close()
+}
+
+// ReadReplyCorpus reads the samples of a corpus, skipping lines that are not
+// valid samples.
+func ReadReplyCorpus(r io.Reader) ([]ReplySample, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	samples := []ReplySample{}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var sample ReplySample
+		if err := json.Unmarshal([]byte(line), &sample); err != nil || sample.Stage == "" || sample.Code == 0 {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, scanner.Err()
+}
+
+// ClassifyReplySample classifies a recorded reply the way the prober would
+// under engine. Session replies that let the session continue classify as
+// an empty result.
+func ClassifyReplySample(sample ReplySample, engine *ProviderReplyPolicyEngine) Result {
+	reply := smtpReply{
+		Code:         sample.Code,
+		EnhancedCode: sample.EnhancedCode,
+		Message:      sample.Message,
+		Lines:        []string{strings.TrimSpace(formatSMTPCode(sample.Code) + " " + sample.Message)},
+	}
+
+	if sample.Stage == "rcpt_to" {
+		return classifySMTPRcptReply(reply, sample.Provider, "", true, engine, false)
+	}
+
+	result, _ := classifySMTPSessionReply(sample.Stage, reply, sample.Provider, "", engine, false)
+
+	return result
+}
@@ -0,0 +1,129 @@
+package verifier
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSMTPProberRecordsAnonymizedReplies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replies.jsonl")
+	corpus, err := OpenReplyCorpus(path, 0)
+	if err != nil {
+		t.Fatalf("failed to open corpus: %v", err)
+	}
+
+	client, server := net.Pipe()
+	go runSMTPServer(t, server, func(line string) string {
+		if strings.HasPrefix(line, "RCPT TO:") {
+			return "550 5.1.1 <user@test.com>: unknown user (from 203.0.113.7)"
+		}
+		return "250 OK"
+	})
+
+	prober := newProber(t, client)
+	prober.ReplyCorpus = corpus
+	prober.Check(context.Background(), "mx.test", "user@test.com")
+	if err := corpus.Close(); err != nil {
+		t.Fatalf("failed to close corpus: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen corpus: %v", err)
+	}
+	defer file.Close()
+	samples, err := ReadReplyCorpus(file)
+	if err != nil {
+		t.Fatalf("failed to read corpus: %v", err)
+	}
+
+	stages := []string{}
+	var rcpt ReplySample
+	for _, sample := range samples {
+		stages = append(stages, sample.Stage)
+		if sample.Stage == "rcpt_to" {
+			rcpt = sample
+		}
+	}
+	if strings.Join(stages, ",") != "banner,ehlo,mail_from,rcpt_to" {
+		t.Fatalf("expected a sample per classified reply, got %v", stages)
+	}
+	expected := ReplySample{Stage: "rcpt_to", Code: 550, EnhancedCode: "5.1.1", Message: "5.1.1 <<email>>: unknown user (from <ip>)", Provider: "generic"}
+	if rcpt != expected {
+		t.Fatalf("expected anonymized rcpt sample %+v, got %+v", expected, rcpt)
+	}
+}
+
+func TestReplyCorpusStopsAtMaxRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replies.jsonl")
+	corpus, err := OpenReplyCorpus(path, 2)
+	if err != nil {
+		t.Fatalf("failed to open corpus: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		corpus.record("rcpt_to", smtpReply{Code: 250, Message: "OK"}, "generic")
+	}
+	_ = corpus.Close()
+
+	raw, _ := os.ReadFile(path)
+	if lines := strings.Count(string(raw), "\n"); lines != 2 {
+		t.Fatalf("expected 2 recorded samples, got %d", lines)
+	}
+}
+
+func TestReplayReplyCorpusDiffsProviders(t *testing.T) {
+	candidate, err := ParseProviderReplyPolicyEngineJSON(`{
+		"version": "v5",
+		"profiles": {
+			"gmail": {
+				"message_rules": [{
+					"rule_id": "gmail-over-quota",
+					"message_contains": ["over quota"],
+					"decision_class": "retryable",
+					"category": "risky",
+					"reason": "smtp_tempfail",
+					"reason_code": "mailbox_full"
+				}]
+			}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("failed to parse candidate: %v", err)
+	}
+
+	samples := []ReplySample{
+		{Stage: "rcpt_to", Code: 552, Message: "mailbox over quota", Provider: "gmail"},
+		{Stage: "rcpt_to", Code: 552, Message: "mailbox over quota", Provider: "gmail"},
+		{Stage: "rcpt_to", Code: 250, Message: "OK", Provider: "gmail"},
+		{Stage: "rcpt_to", Code: 552, Message: "mailbox over quota", Provider: "yahoo"},
+	}
+	diffs := ReplayReplyCorpus(samples, nil, candidate)
+
+	if len(diffs) != 2 || diffs[0].Provider != "gmail" || diffs[1].Provider != "yahoo" {
+		t.Fatalf("expected gmail and yahoo diffs, got %+v", diffs)
+	}
+	gmail := diffs[0]
+	if gmail.Samples != 3 || gmail.Changed != 2 {
+		t.Fatalf("expected 2 of 3 gmail samples changed, got %+v", gmail)
+	}
+	expected := []ReplayChange{
+		{Field: ReplayFieldDecisionClass, From: DecisionUndeliverable, To: DecisionRetryable, Count: 2},
+		{Field: ReplayFieldCategory, From: CategoryInvalid, To: CategoryRisky, Count: 2},
+		{Field: ReplayFieldReasonCode, From: "rcpt_rejected", To: "mailbox_full", Count: 2},
+	}
+	if len(gmail.Changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), gmail.Changes)
+	}
+	for i := range expected {
+		if gmail.Changes[i] != expected[i] {
+			t.Fatalf("expected change %d to be %+v, got %+v", i, expected[i], gmail.Changes[i])
+		}
+	}
+	if diffs[1].Changed != 0 {
+		t.Fatalf("expected yahoo unchanged, got %+v", diffs[1])
+	}
+}
@@ -1,11 +1,32 @@
 package verifier
 
 import (
-	"encoding/json"
-	"fmt"
+	"regexp"
 	"strings"
+	"sync"
+
+	"engine-worker-go/replypolicy"
+)
+
+// The policy payload types live in replypolicy, so policylint and the
+// control plane parse payloads exactly as the worker does.
+type (
+	ProviderReplyPolicyEngine = replypolicy.Engine
+	ProviderReplyProfile      = replypolicy.Profile
+	ProviderReplyRule         = replypolicy.Rule
+	ProviderRetryPolicy       = replypolicy.RetryPolicy
+	ProviderSessionRule       = replypolicy.SessionRule
+	ProviderModeRule          = replypolicy.ModeRule
 )
 
+func DefaultProviderReplyPolicyEngine() *ProviderReplyPolicyEngine {
+	return replypolicy.Default()
+}
+
+func ParseProviderReplyPolicyEngineJSON(raw string) (*ProviderReplyPolicyEngine, error) {
+	return replypolicy.Parse(raw)
+}
+
 type ReplyEvidence struct {
 	ReasonCode       string            `json:"reason_code,omitempty"`
 	ReasonTag        string            `json:"reason_tag,omitempty"`
@@ -20,617 +41,15 @@ type ReplyEvidence struct {
 	DecisionClass    string            `json:"decision_class,omitempty"`
 	ConfidenceHint   string            `json:"confidence_hint,omitempty"`
 	SessionStrategy  string            `json:"session_strategy_id,omitempty"`
-}
-
-type ProviderReplyPolicyEngine struct {
-	Enabled       bool                            `json:"enabled"`
-	Version       string                          `json:"version,omitempty"`
-	SchemaVersion string                          `json:"schema_version,omitempty"`
-	Profiles      map[string]ProviderReplyProfile `json:"profiles"`
-	Modes         map[string]ProviderModeRule     `json:"modes,omitempty"`
-}
-
-type ProviderReplyProfile struct {
-	Name          string              `json:"name,omitempty"`
-	EnhancedRules []ProviderReplyRule `json:"enhanced_rules,omitempty"`
-	SMTPCodeRules []ProviderReplyRule `json:"smtp_code_rules,omitempty"`
-	MessageRules  []ProviderReplyRule `json:"message_rules,omitempty"`
-	Retry         ProviderRetryPolicy `json:"retry"`
-	Session       ProviderSessionRule `json:"session,omitempty"`
-}
-
-type ProviderReplyRule struct {
-	RuleID           string   `json:"rule_id,omitempty"`
-	EnhancedPrefixes []string `json:"enhanced_prefixes,omitempty"`
-	SMTPCodes        []int    `json:"smtp_codes,omitempty"`
-	MessageContains  []string `json:"message_contains,omitempty"`
-	DecisionClass    string   `json:"decision_class"`
-	Category         string   `json:"category"`
-	Reason           string   `json:"reason"`
-	ReasonCode       string   `json:"reason_code"`
-	RuleTag          string   `json:"rule_tag,omitempty"`
-	ConfidenceHint   string   `json:"confidence_hint,omitempty"`
-	ProviderScope    string   `json:"provider_scope,omitempty"`
-	RetryAfterSecond int      `json:"retry_after_seconds,omitempty"`
-}
-
-type ProviderRetryPolicy struct {
-	DefaultSeconds      int `json:"default_seconds,omitempty"`
-	TempfailSeconds     int `json:"tempfail_seconds,omitempty"`
-	GreylistSeconds     int `json:"greylist_seconds,omitempty"`
-	PolicyBlockedSecond int `json:"policy_blocked_seconds,omitempty"`
-	UnknownSeconds      int `json:"unknown_seconds,omitempty"`
-}
-
-type ProviderSessionRule struct {
-	MaxConcurrency            int    `json:"max_concurrency,omitempty"`
-	ConnectsPerMinute         int    `json:"connects_per_minute,omitempty"`
-	ReuseConnectionForRetries bool   `json:"reuse_connection_for_retries"`
-	RetryJitterPercent        int    `json:"retry_jitter_percent,omitempty"`
-	EHLOProfile               string `json:"ehlo_profile,omitempty"`
-}
-
-type ProviderModeRule struct {
-	ProbeEnabled                bool    `json:"probe_enabled"`
-	MaxConcurrencyMultiplier    float64 `json:"max_concurrency_multiplier,omitempty"`
-	ConnectsPerMinuteMultiplier float64 `json:"connects_per_minute_multiplier,omitempty"`
-}
-
-func DefaultProviderReplyPolicyEngine() *ProviderReplyPolicyEngine {
-	engine := ProviderReplyPolicyEngine{
-		Enabled:       true,
-		Version:       "v4",
-		SchemaVersion: "v4",
-		Profiles:      defaultProviderReplyProfiles(),
-		Modes:         defaultProviderModeRules(),
-	}
-
-	normalized := normalizeProviderReplyPolicyEngine(engine)
-	return &normalized
-}
-
-func ParseProviderReplyPolicyEngineJSON(raw string) (*ProviderReplyPolicyEngine, error) {
-	raw = strings.TrimSpace(raw)
-	if raw == "" {
-		return nil, fmt.Errorf("policy json is empty")
-	}
-
-	var parsed ProviderReplyPolicyEngine
-	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
-		return nil, fmt.Errorf("invalid provider reply policy json: %w", err)
-	}
-
-	normalized := normalizeProviderReplyPolicyEngine(parsed)
-	return &normalized, nil
-}
-
-func normalizeProviderReplyPolicyEngine(engine ProviderReplyPolicyEngine) ProviderReplyPolicyEngine {
-	defaults := ProviderReplyPolicyEngine{
-		Version:       "v4",
-		SchemaVersion: "v4",
-		Profiles:      defaultProviderReplyProfiles(),
-		Modes:         defaultProviderModeRules(),
-	}
-	genericDefaults := defaults.Profiles["generic"]
-	out := engine
-	if out.Profiles == nil {
-		out.Profiles = map[string]ProviderReplyProfile{}
-	}
-	if out.Modes == nil {
-		out.Modes = map[string]ProviderModeRule{}
-	}
-
-	for key, profile := range defaults.Profiles {
-		existing, ok := out.Profiles[key]
-		if !ok {
-			out.Profiles[key] = profile
-			continue
-		}
-
-		if len(existing.EnhancedRules) == 0 {
-			existing.EnhancedRules = profile.EnhancedRules
-			if len(existing.EnhancedRules) == 0 {
-				existing.EnhancedRules = genericDefaults.EnhancedRules
-			}
-		}
-		if len(existing.SMTPCodeRules) == 0 {
-			existing.SMTPCodeRules = profile.SMTPCodeRules
-			if len(existing.SMTPCodeRules) == 0 {
-				existing.SMTPCodeRules = genericDefaults.SMTPCodeRules
-			}
-		}
-		if len(existing.MessageRules) == 0 {
-			existing.MessageRules = profile.MessageRules
-			if len(existing.MessageRules) == 0 {
-				existing.MessageRules = genericDefaults.MessageRules
-			}
-		}
-		existing.EnhancedRules = normalizeProviderReplyRules(existing.EnhancedRules, key)
-		existing.SMTPCodeRules = normalizeProviderReplyRules(existing.SMTPCodeRules, key)
-		existing.MessageRules = normalizeProviderReplyRules(existing.MessageRules, key)
-		existing.Retry = normalizeRetryProfile(existing.Retry, profile.Retry)
-		existing.Session = normalizeSessionProfile(existing.Session, profile.Session)
-		if strings.TrimSpace(existing.Name) == "" {
-			existing.Name = profile.Name
-		}
-
-		out.Profiles[key] = existing
-	}
-
-	if _, ok := out.Profiles["generic"]; !ok {
-		out.Profiles["generic"] = defaults.Profiles["generic"]
-	}
-
-	if strings.TrimSpace(out.Version) == "" {
-		out.Version = defaults.Version
-	}
-	if strings.TrimSpace(out.SchemaVersion) == "" {
-		if hasV3Signals(out) {
-			out.SchemaVersion = "v3"
-		} else {
-			out.SchemaVersion = "v2"
-		}
-	}
-
-	for mode, modeRule := range defaults.Modes {
-		existing, ok := out.Modes[mode]
-		if !ok {
-			out.Modes[mode] = modeRule
-			continue
-		}
-
-		if existing.MaxConcurrencyMultiplier <= 0 {
-			existing.MaxConcurrencyMultiplier = modeRule.MaxConcurrencyMultiplier
-		}
-		if existing.ConnectsPerMinuteMultiplier <= 0 {
-			existing.ConnectsPerMinuteMultiplier = modeRule.ConnectsPerMinuteMultiplier
-		}
-		if mode == "normal" && !existing.ProbeEnabled {
-			existing.ProbeEnabled = true
-		}
-		if mode == "drain" || mode == "quarantine" {
-			existing.ProbeEnabled = false
-		}
-		out.Modes[mode] = existing
-	}
-
-	return out
-}
-
-func defaultProviderReplyProfiles() map[string]ProviderReplyProfile {
-	return map[string]ProviderReplyProfile{
-		"generic": {
-			Name: "generic",
-			EnhancedRules: []ProviderReplyRule{
-				{
-					RuleID:           "generic-enhanced-511-mailbox-not-found",
-					EnhancedPrefixes: []string{"5.1.1", "5.1.10", "5.2."},
-					DecisionClass:    DecisionUndeliverable,
-					Category:         CategoryInvalid,
-					Reason:           "rcpt_rejected",
-					ReasonCode:       "mailbox_not_found",
-					RuleTag:          "mailbox_not_found",
-				},
-				{
-					RuleID:           "generic-enhanced-57-policy-blocked",
-					EnhancedPrefixes: []string{"5.7."},
-					DecisionClass:    DecisionPolicyBlocked,
-					Category:         CategoryRisky,
-					Reason:           "smtp_tempfail",
-					ReasonCode:       "smtp_policy_blocked",
-					RuleTag:          "policy_blocked",
-				},
-				{
-					RuleID:           "generic-enhanced-42-44-retry",
-					EnhancedPrefixes: []string{"4.2.", "4.4."},
-					DecisionClass:    DecisionRetryable,
-					Category:         CategoryRisky,
-					Reason:           "smtp_tempfail",
-					ReasonCode:       "smtp_tempfail",
-					RuleTag:          "unknown_transient",
-				},
-				{
-					RuleID:           "generic-enhanced-422-mailbox-full",
-					EnhancedPrefixes: []string{"4.2.2"},
-					DecisionClass:    DecisionRetryable,
-					Category:         CategoryRisky,
-					Reason:           "smtp_tempfail",
-					ReasonCode:       "mailbox_full",
-					RuleTag:          "mailbox_full",
-				},
-			},
-			SMTPCodeRules: []ProviderReplyRule{
-				{
-					RuleID:        "generic-smtp-421-452-retry",
-					SMTPCodes:     []int{421, 450, 451, 452},
-					DecisionClass: DecisionRetryable,
-					Category:      CategoryRisky,
-					Reason:        "smtp_tempfail",
-					ReasonCode:    "smtp_tempfail",
-					RuleTag:       "unknown_transient",
-				},
-				{
-					RuleID:        "generic-smtp-550-553-undeliverable",
-					SMTPCodes:     []int{550, 551, 553},
-					DecisionClass: DecisionUndeliverable,
-					Category:      CategoryInvalid,
-					Reason:        "rcpt_rejected",
-					ReasonCode:    "rcpt_rejected",
-					RuleTag:       "mailbox_not_found",
-				},
-			},
-			MessageRules: []ProviderReplyRule{
-				{
-					RuleID:           "generic-msg-greylist-retry",
-					MessageContains:  []string{"greylist", "try again later", "temporarily deferred"},
-					DecisionClass:    DecisionRetryable,
-					Category:         CategoryRisky,
-					Reason:           "smtp_tempfail",
-					ReasonCode:       "smtp_tempfail",
-					RetryAfterSecond: 180,
-					RuleTag:          "greylist",
-				},
-				{
-					RuleID:          "generic-msg-policy-blocked",
-					MessageContains: []string{"policy", "blocked", "denied", "forbidden", "authentication", "auth"},
-					DecisionClass:   DecisionPolicyBlocked,
-					Category:        CategoryRisky,
-					Reason:          "smtp_tempfail",
-					ReasonCode:      "smtp_policy_blocked",
-					RuleTag:         "policy_blocked",
-				},
-				{
-					RuleID:          "generic-msg-mailbox-not-found",
-					MessageContains: []string{"user unknown", "no such user", "mailbox unavailable", "recipient address rejected"},
-					DecisionClass:   DecisionUndeliverable,
-					Category:        CategoryInvalid,
-					Reason:          "rcpt_rejected",
-					ReasonCode:      "mailbox_not_found",
-					RuleTag:         "mailbox_not_found",
-				},
-				{
-					RuleID:          "generic-msg-mailbox-full",
-					MessageContains: []string{"mailbox full", "quota exceeded", "mailbox over quota"},
-					DecisionClass:   DecisionRetryable,
-					Category:        CategoryRisky,
-					Reason:          "smtp_tempfail",
-					ReasonCode:      "mailbox_full",
-					RuleTag:         "mailbox_full",
-				},
-				{
-					RuleID:          "generic-msg-auth-required",
-					MessageContains: []string{"authentication required", "auth required", "sender verify failed"},
-					DecisionClass:   DecisionPolicyBlocked,
-					Category:        CategoryRisky,
-					Reason:          "smtp_tempfail",
-					ReasonCode:      "smtp_policy_blocked",
-					RuleTag:         "auth_required",
-				},
-			},
-			Retry: ProviderRetryPolicy{
-				DefaultSeconds:      60,
-				TempfailSeconds:     90,
-				GreylistSeconds:     180,
-				PolicyBlockedSecond: 300,
-				UnknownSeconds:      75,
-			},
-			Session: ProviderSessionRule{
-				MaxConcurrency:            2,
-				ConnectsPerMinute:         30,
-				ReuseConnectionForRetries: false,
-				RetryJitterPercent:        15,
-				EHLOProfile:               "default",
-			},
-		},
-		"gmail": {
-			Name: "gmail",
-			EnhancedRules: []ProviderReplyRule{
-				{
-					RuleID:           "gmail-enhanced-47-retry",
-					EnhancedPrefixes: []string{"4.7."},
-					DecisionClass:    DecisionRetryable,
-					Category:         CategoryRisky,
-					Reason:           "smtp_tempfail",
-					ReasonCode:       "smtp_tempfail",
-					RuleTag:          "rate_limit",
-				},
-			},
-			SMTPCodeRules: []ProviderReplyRule{
-				{
-					RuleID:        "gmail-smtp-421-451-retry",
-					SMTPCodes:     []int{421, 450, 451},
-					DecisionClass: DecisionRetryable,
-					Category:      CategoryRisky,
-					Reason:        "smtp_tempfail",
-					ReasonCode:    "smtp_tempfail",
-					RuleTag:       "rate_limit",
-				},
-			},
-			MessageRules: []ProviderReplyRule{
-				{
-					RuleID:          "gmail-msg-rate-limit-retry",
-					MessageContains: []string{"rate limited", "temporarily deferred", "try again later"},
-					DecisionClass:   DecisionRetryable,
-					Category:        CategoryRisky,
-					Reason:          "smtp_tempfail",
-					ReasonCode:      "smtp_tempfail",
-					RuleTag:         "rate_limit",
-				},
-			},
-			Retry: ProviderRetryPolicy{
-				DefaultSeconds:      90,
-				TempfailSeconds:     120,
-				GreylistSeconds:     240,
-				PolicyBlockedSecond: 480,
-				UnknownSeconds:      120,
-			},
-			Session: ProviderSessionRule{
-				MaxConcurrency:            2,
-				ConnectsPerMinute:         20,
-				ReuseConnectionForRetries: false,
-				RetryJitterPercent:        20,
-				EHLOProfile:               "provider-safe-gmail",
-			},
-		},
-		"microsoft": {
-			Name: "microsoft",
-			EnhancedRules: []ProviderReplyRule{
-				{
-					RuleID:           "microsoft-enhanced-47-retry",
-					EnhancedPrefixes: []string{"4.7."},
-					DecisionClass:    DecisionRetryable,
-					Category:         CategoryRisky,
-					Reason:           "smtp_tempfail",
-					ReasonCode:       "smtp_tempfail",
-					RuleTag:          "rate_limit",
-				},
-			},
-			SMTPCodeRules: []ProviderReplyRule{
-				{
-					RuleID:        "microsoft-smtp-421-451-retry",
-					SMTPCodes:     []int{421, 451, 452},
-					DecisionClass: DecisionRetryable,
-					Category:      CategoryRisky,
-					Reason:        "smtp_tempfail",
-					ReasonCode:    "smtp_tempfail",
-					RuleTag:       "rate_limit",
-				},
-			},
-			MessageRules: []ProviderReplyRule{
-				{
-					RuleID:          "microsoft-msg-deferral-retry",
-					MessageContains: []string{"temporarily deferred", "throttled", "try this again later"},
-					DecisionClass:   DecisionRetryable,
-					Category:        CategoryRisky,
-					Reason:          "smtp_tempfail",
-					ReasonCode:      "smtp_tempfail",
-					RuleTag:         "rate_limit",
-				},
-			},
-			Retry: ProviderRetryPolicy{
-				DefaultSeconds:      90,
-				TempfailSeconds:     150,
-				GreylistSeconds:     300,
-				PolicyBlockedSecond: 600,
-				UnknownSeconds:      120,
-			},
-			Session: ProviderSessionRule{
-				MaxConcurrency:            2,
-				ConnectsPerMinute:         18,
-				ReuseConnectionForRetries: false,
-				RetryJitterPercent:        20,
-				EHLOProfile:               "provider-safe-microsoft",
-			},
-		},
-		"yahoo": {
-			Name: "yahoo",
-			EnhancedRules: []ProviderReplyRule{
-				{
-					RuleID:           "yahoo-enhanced-47-retry",
-					EnhancedPrefixes: []string{"4.7."},
-					DecisionClass:    DecisionRetryable,
-					Category:         CategoryRisky,
-					Reason:           "smtp_tempfail",
-					ReasonCode:       "smtp_tempfail",
-					RuleTag:          "rate_limit",
-				},
-			},
-			SMTPCodeRules: []ProviderReplyRule{
-				{
-					RuleID:        "yahoo-smtp-421-451-retry",
-					SMTPCodes:     []int{421, 451, 452},
-					DecisionClass: DecisionRetryable,
-					Category:      CategoryRisky,
-					Reason:        "smtp_tempfail",
-					ReasonCode:    "smtp_tempfail",
-					RuleTag:       "rate_limit",
-				},
-			},
-			MessageRules: []ProviderReplyRule{
-				{
-					RuleID:          "yahoo-msg-tempfail-retry",
-					MessageContains: []string{"temporarily deferred", "temporarily unavailable", "try again later"},
-					DecisionClass:   DecisionRetryable,
-					Category:        CategoryRisky,
-					Reason:          "smtp_tempfail",
-					ReasonCode:      "smtp_tempfail",
-					RuleTag:         "rate_limit",
-				},
-			},
-			Retry: ProviderRetryPolicy{
-				DefaultSeconds:      75,
-				TempfailSeconds:     120,
-				GreylistSeconds:     240,
-				PolicyBlockedSecond: 480,
-				UnknownSeconds:      100,
-			},
-			Session: ProviderSessionRule{
-				MaxConcurrency:            2,
-				ConnectsPerMinute:         22,
-				ReuseConnectionForRetries: false,
-				RetryJitterPercent:        15,
-				EHLOProfile:               "provider-safe-yahoo",
-			},
-		},
-	}
-}
-
-func defaultProviderModeRules() map[string]ProviderModeRule {
-	return map[string]ProviderModeRule{
-		"normal": {
-			ProbeEnabled:                true,
-			MaxConcurrencyMultiplier:    1,
-			ConnectsPerMinuteMultiplier: 1,
-		},
-		"cautious": {
-			ProbeEnabled:                true,
-			MaxConcurrencyMultiplier:    0.65,
-			ConnectsPerMinuteMultiplier: 0.6,
-		},
-		"drain": {
-			ProbeEnabled:                false,
-			MaxConcurrencyMultiplier:    0,
-			ConnectsPerMinuteMultiplier: 0,
-		},
-		"quarantine": {
-			ProbeEnabled:                false,
-			MaxConcurrencyMultiplier:    0,
-			ConnectsPerMinuteMultiplier: 0,
-		},
-		"degraded_probe": {
-			ProbeEnabled:                true,
-			MaxConcurrencyMultiplier:    0.4,
-			ConnectsPerMinuteMultiplier: 0.5,
-		},
-	}
-}
-
-func normalizeRetryProfile(value ProviderRetryPolicy, fallback ProviderRetryPolicy) ProviderRetryPolicy {
-	if value.DefaultSeconds <= 0 {
-		value.DefaultSeconds = fallback.DefaultSeconds
-	}
-	if value.TempfailSeconds <= 0 {
-		value.TempfailSeconds = fallback.TempfailSeconds
-	}
-	if value.GreylistSeconds <= 0 {
-		value.GreylistSeconds = fallback.GreylistSeconds
-	}
-	if value.PolicyBlockedSecond <= 0 {
-		value.PolicyBlockedSecond = fallback.PolicyBlockedSecond
-	}
-	if value.UnknownSeconds <= 0 {
-		value.UnknownSeconds = fallback.UnknownSeconds
-	}
-
-	return value
-}
-
-func normalizeSessionProfile(value ProviderSessionRule, fallback ProviderSessionRule) ProviderSessionRule {
-	if value.MaxConcurrency <= 0 {
-		value.MaxConcurrency = fallback.MaxConcurrency
-	}
-	if value.ConnectsPerMinute <= 0 {
-		value.ConnectsPerMinute = fallback.ConnectsPerMinute
-	}
-	if value.RetryJitterPercent < 0 {
-		value.RetryJitterPercent = fallback.RetryJitterPercent
-	}
-	if value.RetryJitterPercent > 50 {
-		value.RetryJitterPercent = 50
-	}
-	if strings.TrimSpace(value.EHLOProfile) == "" {
-		value.EHLOProfile = fallback.EHLOProfile
-	}
-
-	return value
-}
-
-func normalizeProviderReplyRules(rules []ProviderReplyRule, provider string) []ProviderReplyRule {
-	output := make([]ProviderReplyRule, 0, len(rules))
-
-	for _, rule := range rules {
-		normalized := rule
-		if strings.TrimSpace(normalized.RuleTag) == "" {
-			normalized.RuleTag = inferRuleTag(normalized.ReasonCode, normalized.DecisionClass)
-		}
-		if strings.TrimSpace(normalized.ConfidenceHint) == "" {
-			normalized.ConfidenceHint = inferConfidenceHint(normalized.DecisionClass, normalized.Category)
-		}
-		if strings.TrimSpace(normalized.ProviderScope) == "" {
-			normalized.ProviderScope = provider
-		}
-		output = append(output, normalized)
-	}
-
-	return output
-}
-
-func inferRuleTag(reasonCode string, decisionClass string) string {
-	reasonCode = strings.ToLower(strings.TrimSpace(reasonCode))
-	decisionClass = strings.ToLower(strings.TrimSpace(decisionClass))
-
-	switch {
-	case strings.Contains(reasonCode, "greylist"):
-		return "greylist"
-	case strings.Contains(reasonCode, "mailbox_full"):
-		return "mailbox_full"
-	case strings.Contains(reasonCode, "policy_blocked"):
-		return "policy_blocked"
-	case strings.Contains(reasonCode, "mailbox_not_found"):
-		return "mailbox_not_found"
-	case strings.Contains(reasonCode, "auth"):
-		return "auth_required"
-	case strings.Contains(reasonCode, "rate_limit"):
-		return "rate_limit"
-	case decisionClass == DecisionPolicyBlocked:
-		return "policy_blocked"
-	case decisionClass == DecisionUndeliverable:
-		return "mailbox_not_found"
-	default:
-		return "unknown_transient"
-	}
-}
-
-func inferConfidenceHint(decisionClass string, category string) string {
-	decisionClass = strings.ToLower(strings.TrimSpace(decisionClass))
-	category = strings.ToLower(strings.TrimSpace(category))
-
-	switch decisionClass {
-	case DecisionDeliverable, DecisionUndeliverable:
-		return "high"
-	case DecisionPolicyBlocked, DecisionRetryable:
-		return "medium"
-	case DecisionUnknown:
-		return "low"
-	default:
-		if category == CategoryValid || category == CategoryInvalid {
-			return "medium"
-		}
-		return "low"
-	}
-}
-
-func hasV3Signals(engine ProviderReplyPolicyEngine) bool {
-	if len(engine.Modes) > 0 {
-		return true
-	}
-
-	for _, profile := range engine.Profiles {
-		if profile.Session.MaxConcurrency > 0 || profile.Session.ConnectsPerMinute > 0 || strings.TrimSpace(profile.Session.EHLOProfile) != "" {
-			return true
-		}
-		for _, rules := range [][]ProviderReplyRule{profile.EnhancedRules, profile.SMTPCodeRules, profile.MessageRules} {
-			for _, rule := range rules {
-				if strings.TrimSpace(rule.RuleTag) != "" ||
-					strings.TrimSpace(rule.ConfidenceHint) != "" ||
-					strings.TrimSpace(rule.ProviderScope) != "" {
-					return true
-				}
-			}
-		}
-	}
-
-	return false
+	STARTTLS         string            `json:"starttls,omitempty"`
+	TLSVersion       string            `json:"tls_version,omitempty"`
+	TLSCipher        string            `json:"tls_cipher,omitempty"`
+	TLSCertStatus    string            `json:"tls_cert_status,omitempty"`
+	EHLOProfile      string            `json:"ehlo_profile,omitempty"`
+	SourceIP         string            `json:"source_ip,omitempty"`
+	CatchAll         string            `json:"catch_all,omitempty"`
+	CatchAllSamples  string            `json:"catch_all_samples,omitempty"`
+	CatchAllCached   bool              `json:"catch_all_cached,omitempty"`
 }
 
 func lookupProviderReplyProfile(engine *ProviderReplyPolicyEngine, provider string) ProviderReplyProfile {
@@ -668,7 +87,7 @@ func lookupProviderModeRule(engine *ProviderReplyPolicyEngine, mode string) Prov
 		normalizedMode = "normal"
 	}
 
-	defaults := defaultProviderModeRules()
+	defaults := replypolicy.DefaultModeRules()
 
 	if engine == nil || len(engine.Modes) == 0 {
 		if modeRule, ok := defaults[normalizedMode]; ok {
@@ -698,43 +117,169 @@ func lookupProviderModeRule(engine *ProviderReplyPolicyEngine, mode string) Prov
 	return modeRule
 }
 
-func matchProviderReplyRule(profile ProviderReplyProfile, reply smtpReply) (ProviderReplyRule, bool) {
+// matchProviderReplyRule picks the highest-priority rule matching a reply to
+// the given SMTP command. Rules of equal priority keep the enhanced, SMTP
+// code, message order. Named groups of the matched message pattern are
+// returned as captures.
+func matchProviderReplyRule(profile ProviderReplyProfile, command string, reply smtpReply) (ProviderReplyRule, map[string]string, bool) {
 	enhanced := strings.ToLower(strings.TrimSpace(reply.EnhancedCode))
 	message := strings.ToLower(strings.TrimSpace(reply.Message))
+	stage := replyRuleStage(command)
+
+	var best ProviderReplyRule
+	var bestCaptures map[string]string
+	found := false
+	consider := func(rule ProviderReplyRule, matched bool) {
+		if !matched || (found && rule.Priority <= best.Priority) || !ruleAppliesToStage(rule, stage) {
+			return
+		}
+		captures, ok := matchReplyRulePatterns(rule, reply.Message)
+		if !ok {
+			return
+		}
+		best, bestCaptures, found = rule, captures, true
+	}
 
 	for _, rule := range profile.EnhancedRules {
+		matched := false
 		for _, prefix := range rule.EnhancedPrefixes {
 			prefix = strings.ToLower(strings.TrimSpace(prefix))
-			if prefix == "" {
-				continue
-			}
-			if strings.HasPrefix(enhanced, prefix) {
-				return rule, true
+			if prefix != "" && strings.HasPrefix(enhanced, prefix) {
+				matched = true
+				break
 			}
 		}
+		consider(rule, matched)
 	}
 
 	for _, rule := range profile.SMTPCodeRules {
+		matched := false
 		for _, code := range rule.SMTPCodes {
 			if code == reply.Code {
-				return rule, true
+				matched = true
+				break
 			}
 		}
+		consider(rule, matched)
 	}
 
 	for _, rule := range profile.MessageRules {
+		// Pattern-only message rules match on their patterns alone.
+		matched := len(rule.MessageContains) == 0 && len(rule.MessagePatterns) > 0
 		for _, token := range rule.MessageContains {
 			token = strings.ToLower(strings.TrimSpace(token))
-			if token == "" {
+			if token != "" && strings.Contains(message, token) {
+				matched = true
+				break
+			}
+		}
+		consider(rule, matched)
+	}
+
+	return best, bestCaptures, found
+}
+
+func replyRuleStage(command string) string {
+	switch command {
+	case "helo":
+		return "ehlo"
+	case "rcpt_to":
+		return "rcpt"
+	default:
+		return command
+	}
+}
+
+func ruleAppliesToStage(rule ProviderReplyRule, stage string) bool {
+	if len(rule.Stages) == 0 {
+		return true
+	}
+
+	for _, allowed := range rule.Stages {
+		if strings.ToLower(strings.TrimSpace(allowed)) == stage {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchReplyRulePatterns rejects messages matching any exclude pattern and,
+// when the rule has message patterns, requires one of them to match.
+func matchReplyRulePatterns(rule ProviderReplyRule, message string) (map[string]string, bool) {
+	message = strings.TrimSpace(message)
+	for _, pattern := range rule.ExcludePatterns {
+		compiled, err := compileReplyRulePattern(pattern)
+		if err == nil && compiled.MatchString(message) {
+			return nil, false
+		}
+	}
+	if len(rule.MessagePatterns) == 0 {
+		return nil, true
+	}
+
+	for _, pattern := range rule.MessagePatterns {
+		compiled, err := compileReplyRulePattern(pattern)
+		if err != nil {
+			continue
+		}
+		match := compiled.FindStringSubmatch(message)
+		if match == nil {
+			continue
+		}
+
+		var captures map[string]string
+		for i, name := range compiled.SubexpNames() {
+			if name == "" || match[i] == "" {
 				continue
 			}
-			if strings.Contains(message, token) {
-				return rule, true
+			if captures == nil {
+				captures = map[string]string{}
 			}
+			captures[name] = match[i]
+		}
+		return captures, true
+	}
+
+	return nil, false
+}
+
+// maxReplyRulePatterns bounds the compiled pattern cache. Policies are
+// reloaded at runtime, so patterns of replaced policies must not pile up.
+const maxReplyRulePatterns = 4096
+
+var replyRulePatterns = struct {
+	sync.RWMutex
+	compiled map[string]*regexp.Regexp
+}{compiled: map[string]*regexp.Regexp{}}
+
+// compileReplyRulePattern compiles a rule pattern case-insensitively; ^ and
+// $ anchor to the reply message. Compiled patterns are cached, dropping an
+// arbitrary entry once the cache is full.
+func compileReplyRulePattern(pattern string) (*regexp.Regexp, error) {
+	replyRulePatterns.RLock()
+	cached, ok := replyRulePatterns.compiled[pattern]
+	replyRulePatterns.RUnlock()
+	if ok {
+		return cached, nil
+	}
+
+	compiled, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	replyRulePatterns.Lock()
+	defer replyRulePatterns.Unlock()
+	if _, exists := replyRulePatterns.compiled[pattern]; !exists && len(replyRulePatterns.compiled) >= maxReplyRulePatterns {
+		for existing := range replyRulePatterns.compiled {
+			delete(replyRulePatterns.compiled, existing)
+			break
 		}
 	}
+	replyRulePatterns.compiled[pattern] = compiled
 
-	return ProviderReplyRule{}, false
+	return compiled, nil
 }
 
 func resolveAdaptiveRetryDelay(
@@ -748,7 +293,7 @@ func resolveAdaptiveRetryDelay(
 		return retryDelaySeconds(reply)
 	}
 
-	retry := normalizeRetryProfile(profile.Retry, ProviderRetryPolicy{
+	retry := replypolicy.NormalizeRetryPolicy(profile.Retry, ProviderRetryPolicy{
 		DefaultSeconds:      60,
 		TempfailSeconds:     90,
 		GreylistSeconds:     180,
@@ -1,27 +1,26 @@
 package verifier
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
-func TestDefaultProviderReplyPolicyEngineIncludesGenericProfile(t *testing.T) {
-	engine := DefaultProviderReplyPolicyEngine()
-	if engine == nil {
-		t.Fatal("expected default engine")
-	}
-
-	if _, ok := engine.Profiles["generic"]; !ok {
-		t.Fatal("expected generic profile to exist")
-	}
-}
-
-func TestParseProviderReplyPolicyEngineJSONAppliesDefaults(t *testing.T) {
+func TestProviderReplyRulePatternsCaptureAndFilterByStage(t *testing.T) {
 	engine, err := ParseProviderReplyPolicyEngineJSON(`{
 		"enabled": true,
 		"profiles": {
-			"gmail": {
-				"name": "gmail",
-				"retry": {
-					"default_seconds": 120
-				}
+			"microsoft": {
+				"message_rules": [{
+					"rule_id": "microsoft-as-block",
+					"message_patterns": ["access denied\\. AS\\((?P<as>\\d+)\\)$"],
+					"exclude_patterns": ["^5\\.4\\.1 .*recipient address rejected"],
+					"stages": ["rcpt"],
+					"priority": 10,
+					"decision_class": "policy_blocked",
+					"category": "risky",
+					"reason": "smtp_tempfail",
+					"reason_code": "policy_blocked_as"
+				}]
 			}
 		}
 	}`)
@@ -29,15 +28,61 @@ func TestParseProviderReplyPolicyEngineJSONAppliesDefaults(t *testing.T) {
 		t.Fatalf("expected json to parse, got %v", err)
 	}
 
-	gmail, ok := engine.Profiles["gmail"]
-	if !ok {
-		t.Fatal("expected gmail profile")
+	reply := smtpReply{Code: 550, EnhancedCode: "5.4.1", Message: "5.4.1 Access denied. AS(201806281)"}
+	result := classifySMTPRcptReply(reply, "microsoft", "", true, engine, false)
+	if result.MatchedRuleID != "microsoft-as-block" || result.DecisionClass != DecisionPolicyBlocked {
+		t.Fatalf("expected the prioritized pattern rule to win, got rule %q decision %q", result.MatchedRuleID, result.DecisionClass)
+	}
+	if result.RuleCaptures["as"] != "201806281" {
+		t.Fatalf("expected AS capture 201806281, got %+v", result.RuleCaptures)
+	}
+
+	if result, _ := classifySMTPSessionReply("mail_from", reply, "microsoft", "", engine, false); result.MatchedRuleID == "microsoft-as-block" {
+		t.Fatal("expected an rcpt-only rule to skip MAIL FROM replies")
+	}
+
+	excluded := smtpReply{Code: 550, EnhancedCode: "5.4.1", Message: "5.4.1 Recipient address rejected: Access denied. AS(201806281)"}
+	if result := classifySMTPRcptReply(excluded, "microsoft", "", true, engine, false); result.MatchedRuleID == "microsoft-as-block" {
+		t.Fatal("expected the exclude pattern to veto the rule")
+	}
+}
+
+func TestProviderReplyRulePriorityOverridesListOrder(t *testing.T) {
+	profile := ProviderReplyProfile{
+		EnhancedRules: []ProviderReplyRule{{RuleID: "enhanced", EnhancedPrefixes: []string{"5.1."}}},
+		SMTPCodeRules: []ProviderReplyRule{{RuleID: "code", SMTPCodes: []int{550}, Priority: 5}},
+		MessageRules:  []ProviderReplyRule{{RuleID: "message", MessageContains: []string{"unknown"}, Priority: 5}},
+	}
+	reply := smtpReply{Code: 550, EnhancedCode: "5.1.1", Message: "5.1.1 unknown user"}
+
+	rule, _, ok := matchProviderReplyRule(profile, "rcpt_to", reply)
+	if !ok || rule.RuleID != "code" {
+		t.Fatalf("expected the first rule of the highest priority, got %q", rule.RuleID)
+	}
+
+	profile.SMTPCodeRules[0].Priority = 0
+	profile.MessageRules[0].Priority = 0
+	if rule, _, _ := matchProviderReplyRule(profile, "rcpt_to", reply); rule.RuleID != "enhanced" {
+		t.Fatalf("expected list order without priorities, got %q", rule.RuleID)
 	}
-	if gmail.Retry.DefaultSeconds != 120 {
-		t.Fatalf("expected gmail default retry 120, got %d", gmail.Retry.DefaultSeconds)
+}
+
+func TestCompileReplyRulePatternBoundsCache(t *testing.T) {
+	for i := 0; i <= maxReplyRulePatterns; i++ {
+		if _, err := compileReplyRulePattern(fmt.Sprintf("^bounded pattern %d$", i)); err != nil {
+			t.Fatalf("expected pattern %d to compile, got %v", i, err)
+		}
+	}
+
+	replyRulePatterns.RLock()
+	size := len(replyRulePatterns.compiled)
+	replyRulePatterns.RUnlock()
+	if size > maxReplyRulePatterns {
+		t.Fatalf("expected at most %d cached patterns, got %d", maxReplyRulePatterns, size)
 	}
 
-	if len(gmail.EnhancedRules) == 0 {
-		t.Fatal("expected gmail enhanced rules fallback from defaults")
+	compiled, err := compileReplyRulePattern("^Bounded Pattern 1$")
+	if err != nil || !compiled.MatchString("bounded pattern 1") {
+		t.Fatalf("expected case-insensitive pattern after eviction, got %v/%v", compiled, err)
 	}
 }
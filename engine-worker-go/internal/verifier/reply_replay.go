@@ -0,0 +1,116 @@
+package verifier
+
+import (
+	"sort"
+	"strings"
+)
+
+// Replay diff fields, in the order they are reported.
+const (
+	ReplayFieldDecisionClass = "decision_class"
+	ReplayFieldCategory      = "category"
+	ReplayFieldReasonCode    = "reason_code"
+)
+
+// ReplayChange counts the samples whose field moved from one value to
+// another between two policy versions. Empty values mean the reply let the
+// session continue.
+type ReplayChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// ProviderReplayDiff summarises how a candidate policy reclassifies one
+// provider's samples.
+type ProviderReplayDiff struct {
+	Provider string         `json:"provider"`
+	Samples  int            `json:"samples"`
+	Changed  int            `json:"changed"`
+	Changes  []ReplayChange `json:"changes"`
+}
+
+// ReplayReplyCorpus classifies every sample under baseline and candidate and
+// returns the per-provider differences, sorted by provider. Both engines are
+// applied regardless of their enabled flag.
+func ReplayReplyCorpus(samples []ReplySample, baseline, candidate *ProviderReplyPolicyEngine) []ProviderReplayDiff {
+	baseline = enabledReplayEngine(baseline)
+	candidate = enabledReplayEngine(candidate)
+
+	type changeKey struct{ field, from, to string }
+	diffs := map[string]*ProviderReplayDiff{}
+	counts := map[string]map[changeKey]int{}
+
+	for _, sample := range samples {
+		provider := strings.ToLower(strings.TrimSpace(sample.Provider))
+		if provider == "" {
+			provider = "generic"
+		}
+		sample.Provider = provider
+
+		diff := diffs[provider]
+		if diff == nil {
+			diff = &ProviderReplayDiff{Provider: provider}
+			diffs[provider] = diff
+			counts[provider] = map[changeKey]int{}
+		}
+		diff.Samples++
+
+		before := ClassifyReplySample(sample, baseline)
+		after := ClassifyReplySample(sample, candidate)
+		fields := [][3]string{
+			{ReplayFieldDecisionClass, before.DecisionClass, after.DecisionClass},
+			{ReplayFieldCategory, before.Category, after.Category},
+			{ReplayFieldReasonCode, before.ReasonCode, after.ReasonCode},
+		}
+
+		changed := false
+		for _, field := range fields {
+			if field[1] != field[2] {
+				counts[provider][changeKey{field[0], field[1], field[2]}]++
+				changed = true
+			}
+		}
+		if changed {
+			diff.Changed++
+		}
+	}
+
+	fieldOrder := map[string]int{ReplayFieldDecisionClass: 0, ReplayFieldCategory: 1, ReplayFieldReasonCode: 2}
+	out := make([]ProviderReplayDiff, 0, len(diffs))
+	for provider, diff := range diffs {
+		diff.Changes = []ReplayChange{}
+		for key, count := range counts[provider] {
+			diff.Changes = append(diff.Changes, ReplayChange{Field: key.field, From: key.from, To: key.to, Count: count})
+		}
+		sort.Slice(diff.Changes, func(i, j int) bool {
+			a, b := diff.Changes[i], diff.Changes[j]
+			if a.Field != b.Field {
+				return fieldOrder[a.Field] < fieldOrder[b.Field]
+			}
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			if a.From != b.From {
+				return a.From < b.From
+			}
+			return a.To < b.To
+		})
+		out = append(out, *diff)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+
+	return out
+}
+
+func enabledReplayEngine(engine *ProviderReplyPolicyEngine) *ProviderReplyPolicyEngine {
+	if engine == nil {
+		return nil
+	}
+
+	enabled := *engine
+	enabled.Enabled = true
+
+	return &enabled
+}
@@ -0,0 +1,218 @@
+package verifier
+
+import (
+	"strings"
+
+	"engine-worker-go/replypolicy"
+)
+
+// Score factor names. Each is a key in ScoringModel.Weights.
+const (
+	ScoreFactorBase                 = replypolicy.ScoreFactorBase
+	ScoreFactorSyntaxInvalid        = replypolicy.ScoreFactorSyntaxInvalid
+	ScoreFactorIPLiteral            = replypolicy.ScoreFactorIPLiteral
+	ScoreFactorDisposable           = replypolicy.ScoreFactorDisposable
+	ScoreFactorRoleAccount          = replypolicy.ScoreFactorRoleAccount
+	ScoreFactorFreeMail             = replypolicy.ScoreFactorFreeMail
+	ScoreFactorDomainTypo           = replypolicy.ScoreFactorDomainTypo
+	ScoreFactorMXMissing            = replypolicy.ScoreFactorMXMissing
+	ScoreFactorMXImplicit           = replypolicy.ScoreFactorMXImplicit
+	ScoreFactorMXParked             = replypolicy.ScoreFactorMXParked
+	ScoreFactorMailAuthMissing      = replypolicy.ScoreFactorMailAuthMissing
+	ScoreFactorMailAuthEnforced     = replypolicy.ScoreFactorMailAuthEnforced
+	ScoreFactorSMTPConnectOK        = replypolicy.ScoreFactorSMTPConnectOK
+	ScoreFactorSMTPDeliverable      = replypolicy.ScoreFactorSMTPDeliverable
+	ScoreFactorSMTPUndeliverable    = replypolicy.ScoreFactorSMTPUndeliverable
+	ScoreFactorSMTPRetryable        = replypolicy.ScoreFactorSMTPRetryable
+	ScoreFactorSMTPPolicyBlocked    = replypolicy.ScoreFactorSMTPPolicyBlocked
+	ScoreFactorSMTPUnknown          = replypolicy.ScoreFactorSMTPUnknown
+	ScoreFactorCatchAllHigh         = replypolicy.ScoreFactorCatchAllHigh
+	ScoreFactorCatchAllMedium       = replypolicy.ScoreFactorCatchAllMedium
+	ScoreFactorCatchAllLow          = replypolicy.ScoreFactorCatchAllLow
+	ScoreFactorCatchAllInconsistent = replypolicy.ScoreFactorCatchAllInconsistent
+	ScoreFactorEvidenceLow          = replypolicy.ScoreFactorEvidenceLow
+	ScoreFactorProviderCautious     = replypolicy.ScoreFactorProviderCautious
+	ScoreFactorProviderDegraded     = replypolicy.ScoreFactorProviderDegraded
+	ScoreFactorProviderDrain        = replypolicy.ScoreFactorProviderDrain
+	ScoreFactorProviderQuarantine   = replypolicy.ScoreFactorProviderQuarantine
+)
+
+// ScoringModel turns a verification result into a 0-100 deliverability
+// score. It ships inside ProviderReplyPolicyEngine, so a new model rolls
+// out, canaries and rolls back with the policy version that carries it.
+type ScoringModel = replypolicy.ScoringModel
+
+func DefaultScoringModel() ScoringModel {
+	return replypolicy.DefaultScoringModel()
+}
+
+// ScoreFactor is one contribution to a score, in points.
+type ScoreFactor struct {
+	Name   string `json:"name"`
+	Points int    `json:"points"`
+}
+
+// defaultScoringModel scores results when no reply policy engine is
+// configured. It is never modified.
+var defaultScoringModel = DefaultScoringModel()
+
+// scoreSignals carries inputs the result itself does not record.
+type scoreSignals struct {
+	roleAccount bool
+}
+
+// scoreResult returns the 0-100 score model gives result and the factors
+// behind it, in the order they were applied.
+func scoreResult(m ScoringModel, result Result, signals scoreSignals) (int, []ScoreFactor) {
+	factors := []ScoreFactor{}
+	add := func(name string) {
+		if points := m.Weights[name]; points != 0 {
+			factors = append(factors, ScoreFactor{Name: name, Points: points})
+		}
+	}
+
+	reason := baseReason(result.Reason)
+	add(ScoreFactorBase)
+
+	if reason == "syntax_ip_literal" {
+		add(ScoreFactorIPLiteral)
+	} else if strings.HasPrefix(reason, "syntax") {
+		add(ScoreFactorSyntaxInvalid)
+		return clampScore(factors), factors
+	}
+
+	switch reason {
+	case "disposable_domain", reasonDisposableMX:
+		add(ScoreFactorDisposable)
+	case "domain_typo_suspected":
+		add(ScoreFactorDomainTypo)
+	case reasonParkedDomain:
+		add(ScoreFactorMXParked)
+	case "mx_missing", "null_mx":
+		add(ScoreFactorMXMissing)
+	}
+	if signals.roleAccount {
+		add(ScoreFactorRoleAccount)
+	}
+	if result.FreeMail {
+		add(ScoreFactorFreeMail)
+	}
+
+	if strings.HasPrefix(result.AttemptRoute, "implicit_mx:") {
+		add(ScoreFactorMXImplicit)
+	}
+	if auth := result.DomainAuth; auth != nil {
+		if auth.Missing() {
+			add(ScoreFactorMailAuthMissing)
+		} else if auth.DMARC == DMARCReject || auth.DMARC == DMARCQuarantine {
+			add(ScoreFactorMailAuthEnforced)
+		}
+	}
+
+	if smtpFactor := smtpScoreFactor(result, reason); smtpFactor != "" {
+		add(smtpFactor)
+		if normalizedEvidenceStrength(result.EvidenceStrength) == "low" {
+			add(ScoreFactorEvidenceLow)
+		}
+		switch strings.ToLower(strings.TrimSpace(result.ProviderMode)) {
+		case "cautious":
+			add(ScoreFactorProviderCautious)
+		case "degraded_probe":
+			add(ScoreFactorProviderDegraded)
+		case "drain":
+			add(ScoreFactorProviderDrain)
+		case "quarantine":
+			add(ScoreFactorProviderQuarantine)
+		}
+	}
+
+	return clampScore(factors), factors
+}
+
+// smtpScoreFactor names the SMTP outcome factor, or "" when no SMTP session
+// decided the result.
+func smtpScoreFactor(result Result, reason string) string {
+	switch reason {
+	case "catch_all_high_confidence":
+		return ScoreFactorCatchAllHigh
+	case "catch_all_medium_confidence":
+		return ScoreFactorCatchAllMedium
+	case "catch_all_low_confidence":
+		return ScoreFactorCatchAllLow
+	case reasonCatchAllInconsistent:
+		return ScoreFactorCatchAllInconsistent
+	case "smtp_connect_ok":
+		return ScoreFactorSMTPConnectOK
+	case "null_mx", "mx_missing", reasonDisposableMX, reasonParkedDomain, "disposable_domain", "role_account", "domain_typo_suspected":
+		return ""
+	}
+	if strings.TrimSpace(result.MXHost) == "" {
+		return ""
+	}
+
+	switch result.DecisionClass {
+	case DecisionDeliverable:
+		return ScoreFactorSMTPDeliverable
+	case DecisionUndeliverable:
+		return ScoreFactorSMTPUndeliverable
+	case DecisionRetryable:
+		return ScoreFactorSMTPRetryable
+	case DecisionPolicyBlocked:
+		return ScoreFactorSMTPPolicyBlocked
+	}
+
+	switch result.Category {
+	case CategoryValid:
+		return ScoreFactorSMTPDeliverable
+	case CategoryInvalid:
+		return ScoreFactorSMTPUndeliverable
+	default:
+		return ScoreFactorSMTPUnknown
+	}
+}
+
+func clampScore(factors []ScoreFactor) int {
+	score := 0
+	for _, factor := range factors {
+		score += factor.Points
+	}
+	if score < 0 {
+		return 0
+	}
+	if score > 100 {
+		return 100
+	}
+
+	return score
+}
+
+func baseReason(reason string) string {
+	reason = strings.ToLower(strings.TrimSpace(reason))
+	if base, _, ok := strings.Cut(reason, ":"); ok {
+		return base
+	}
+
+	return reason
+}
+
+// ScoringModelFor returns the model carried by engine, or the default model
+// when no engine is configured.
+func ScoringModelFor(engine *ProviderReplyPolicyEngine) ScoringModel {
+	if engine != nil && len(engine.Scoring.Weights) > 0 {
+		return engine.Scoring
+	}
+
+	return defaultScoringModel
+}
+
+func (p *PipelineVerifier) withScore(result Result, signals scoreSignals) Result {
+	if !p.config.ScoringEnabled {
+		return result
+	}
+
+	model := ScoringModelFor(p.config.ProviderReplyPolicyEngine)
+	result.Score, result.ScoreFactors = scoreResult(model, result, signals)
+	result.ScoreModel = model.Version
+
+	return result
+}
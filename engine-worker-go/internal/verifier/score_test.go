@@ -0,0 +1,126 @@
+package verifier
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"engine-worker-go/replypolicy"
+)
+
+func factorPoints(factors []ScoreFactor) map[string]int {
+	points := map[string]int{}
+	for _, factor := range factors {
+		points[factor.Name] = factor.Points
+	}
+
+	return points
+}
+
+func TestScoringModelCombinesFactors(t *testing.T) {
+	model := DefaultScoringModel()
+
+	score, factors := scoreResult(model, Result{
+		Category:         CategoryValid,
+		Reason:           "rcpt_ok",
+		DecisionClass:    DecisionDeliverable,
+		MXHost:           "mx.example.com",
+		EvidenceStrength: "high",
+		FreeMail:         true,
+	}, scoreSignals{})
+	if score != 90 {
+		t.Fatalf("expected 90 for a free-mail deliverable mailbox, got %d (%v)", score, factors)
+	}
+	if points := factorPoints(factors); points[ScoreFactorSMTPDeliverable] != 45 || points[ScoreFactorFreeMail] != -5 {
+		t.Fatalf("expected deliverable and free_mail factors, got %v", factors)
+	}
+
+	score, factors = scoreResult(model, Result{
+		Category:           CategoryRisky,
+		Reason:             "catch_all_low_confidence",
+		DecisionClass:      DecisionUnknown,
+		DecisionConfidence: "low",
+		EvidenceStrength:   "low",
+		MXHost:             "mx.example.com",
+		ProviderMode:       "cautious",
+	}, scoreSignals{roleAccount: true})
+	if score != 10 {
+		t.Fatalf("expected 10 for a low-confidence catch-all role account, got %d (%v)", score, factors)
+	}
+	for _, name := range []string{ScoreFactorCatchAllLow, ScoreFactorEvidenceLow, ScoreFactorProviderCautious, ScoreFactorRoleAccount} {
+		if _, ok := factorPoints(factors)[name]; !ok {
+			t.Fatalf("expected factor %s, got %v", name, factors)
+		}
+	}
+}
+
+func TestScoringModelStopsAtSyntaxAndClamps(t *testing.T) {
+	model := DefaultScoringModel()
+
+	score, factors := scoreResult(model, Result{Category: CategoryInvalid, Reason: "syntax_bad_char"}, scoreSignals{roleAccount: true})
+	if score != 0 || len(factors) != 2 {
+		t.Fatalf("expected syntax to score 0 from base and syntax_invalid only, got %d (%v)", score, factors)
+	}
+
+	score, _ = scoreResult(model, Result{Category: CategoryInvalid, Reason: "null_mx", DecisionClass: DecisionUndeliverable, MXHost: ""}, scoreSignals{})
+	if score != 0 {
+		t.Fatalf("expected null_mx to score 0, got %d", score)
+	}
+}
+
+func TestParseProviderReplyPolicyEngineJSONNormalizesScoringModel(t *testing.T) {
+	engine, err := ParseProviderReplyPolicyEngineJSON(`{"enabled":true,"version":"v5","scoring":{"version":"score-v2","weights":{"free_mail":-20}}}`)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	model := ScoringModelFor(engine)
+	if model.Version != "score-v2" {
+		t.Fatalf("expected score-v2, got %q", model.Version)
+	}
+	if model.Weights[ScoreFactorFreeMail] != -20 || model.Weights[ScoreFactorSMTPDeliverable] != 45 {
+		t.Fatalf("expected override merged over defaults, got %v", model.Weights)
+	}
+
+	if version := ScoringModelFor(nil).Version; version != replypolicy.DefaultScoringVersion {
+		t.Fatalf("expected default model without an engine, got %q", version)
+	}
+}
+
+func TestPipelineScoresResultsWithPolicyModel(t *testing.T) {
+	config := baseConfig(1)
+	config.ScoringEnabled = true
+	config.RoleAccountsBehavior = "allow"
+	config.RoleAccounts = map[string]struct{}{"info": {}}
+	engine, err := ParseProviderReplyPolicyEngineJSON(`{"version":"v5","scoring":{"version":"score-canary","weights":{"role_account":-30}}}`)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	config.ProviderReplyPolicyEngine = engine
+
+	resolver := &fakeResolver{records: map[string][]*net.MX{"example.com": {{Host: "mx.example.com.", Pref: 10}}}}
+	smtp := &fakeSMTP{results: map[string]Result{"mx.example.com": {Category: CategoryValid, Reason: "smtp_connect_ok", DecisionClass: DecisionDeliverable}}}
+	v := NewPipelineVerifier(config, resolver, smtp)
+
+	result := v.Verify(context.Background(), "info@example.com")
+	if result.ScoreModel != "score-canary" {
+		t.Fatalf("expected score-canary model, got %q", result.ScoreModel)
+	}
+	if points := factorPoints(result.ScoreFactors); points[ScoreFactorRoleAccount] != -30 || points[ScoreFactorSMTPConnectOK] != 25 {
+		t.Fatalf("expected role_account and smtp_connect_ok factors, got %v", result.ScoreFactors)
+	}
+	if result.Score != 40 {
+		t.Fatalf("expected score 40, got %d (%v)", result.Score, result.ScoreFactors)
+	}
+
+	invalid := v.Verify(context.Background(), "bad-email")
+	if invalid.ScoreModel != "score-canary" || invalid.Score != 0 {
+		t.Fatalf("expected syntax failure scored 0 by the canary model, got %d %q", invalid.Score, invalid.ScoreModel)
+	}
+
+	config.ScoringEnabled = false
+	unscored := NewPipelineVerifier(config, resolver, smtp).Verify(context.Background(), "info@example.com")
+	if unscored.ScoreModel != "" || unscored.Score != 0 || unscored.ScoreFactors != nil {
+		t.Fatalf("expected no score with scoring disabled, got %d %q %v", unscored.Score, unscored.ScoreModel, unscored.ScoreFactors)
+	}
+}
@@ -3,6 +3,7 @@ package verifier
 import (
 	"context"
 	"crypto/rand"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net"
@@ -28,9 +29,13 @@ type NetSMTPChecker struct {
 	ProviderProfile     string
 	ProviderMode        string
 	SessionStrategyID   string
+	Proxy               string
 	RateLimiter         *RateLimiter
 	ReplyPolicyEngine   *ProviderReplyPolicyEngine
 	AdaptiveRetryEnable bool
+	ReplyCorpus         *ReplyCorpus
+
+	sourceIP string
 }
 
 func (c NetSMTPChecker) Check(ctx context.Context, host, email string) Result {
@@ -38,7 +43,7 @@ func (c NetSMTPChecker) Check(ctx context.Context, host, email string) Result {
 		c.HeloName = host
 	}
 
-	if err := c.waitRate(ctx); err != nil {
+	if err := c.waitRate(ctx, host); err != nil {
 		return c.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_timeout"})
 	}
 
@@ -50,8 +55,11 @@ func (c NetSMTPChecker) Check(ctx context.Context, host, email string) Result {
 	connectCtx, cancel := context.WithTimeout(ctx, c.ConnectTimeout)
 	defer cancel()
 
-	conn, err := dialer.DialContext(connectCtx, "tcp", net.JoinHostPort(host, "25"))
+	conn, err := dialer.DialContext(smtpDialContext(connectCtx, c.ProviderProfile, c.Proxy), "tcp", net.JoinHostPort(host, "25"))
 	if err != nil {
+		if reason := proxyFailureReason(err); reason != "" {
+			return c.applySessionContext(Result{Category: CategoryRisky, Reason: reason})
+		}
 		if isTimeout(err) || errors.Is(connectCtx.Err(), context.DeadlineExceeded) {
 			return c.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_connect_timeout"})
 		}
@@ -59,17 +67,11 @@ func (c NetSMTPChecker) Check(ctx context.Context, host, email string) Result {
 		return c.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_connect_timeout"})
 	}
 	defer conn.Close()
+	c.sourceIP = localIP(conn)
 
 	if reply, res := readSMTPReply(conn, c.ReadTimeout); res != nil {
 		return c.applySessionContext(*res)
-	} else if result, stop := classifySMTPSessionReply(
-		"banner",
-		reply,
-		c.ProviderProfile,
-		host,
-		c.ReplyPolicyEngine,
-		c.AdaptiveRetryEnable,
-	); stop {
+	} else if result, stop := c.classifySessionReply("banner", reply, host); stop {
 		return c.applySessionContext(result)
 	}
 
@@ -82,14 +84,7 @@ func (c NetSMTPChecker) Check(ctx context.Context, host, email string) Result {
 
 	if reply, res := readSMTPReply(conn, c.EhloTimeout); res != nil {
 		return c.applySessionContext(*res)
-	} else if result, stop := classifySMTPSessionReply(
-		"ehlo",
-		reply,
-		c.ProviderProfile,
-		host,
-		c.ReplyPolicyEngine,
-		c.AdaptiveRetryEnable,
-	); stop {
+	} else if result, stop := c.classifySessionReply("ehlo", reply, host); stop {
 		return c.applySessionContext(result)
 	}
 
@@ -105,26 +100,42 @@ func (c NetSMTPChecker) Check(ctx context.Context, host, email string) Result {
 }
 
 type NetSMTPProber struct {
-	Dialer                   SMTPDialer
-	ConnectTimeout           time.Duration
-	ReadTimeout              time.Duration
-	EhloTimeout              time.Duration
-	HeloName                 string
-	ProviderProfile          string
-	ProviderMode             string
-	SessionStrategyID        string
-	MailFromAddress          string
-	RateLimiter              *RateLimiter
-	CatchAllDetectionEnabled bool
-	RandomLocalPart          func() string
-	ReplyPolicyEngine        *ProviderReplyPolicyEngine
-	AdaptiveRetryEnable      bool
+	Dialer                    SMTPDialer
+	ConnectTimeout            time.Duration
+	ReadTimeout               time.Duration
+	EhloTimeout               time.Duration
+	HeloName                  string
+	ProviderProfile           string
+	ProviderMode              string
+	SessionStrategyID         string
+	MailFromAddress           string
+	RateLimiter               *RateLimiter
+	CatchAllDetectionEnabled  bool
+	CatchAllCache             *CatchAllCache
+	CatchAllSamples           int
+	RandomLocalPart           func() string
+	ReplyPolicyEngine         *ProviderReplyPolicyEngine
+	AdaptiveRetryEnable       bool
+	SessionPool               *SMTPSessionPool
+	MaxSessionsPerHost        int
+	ReuseConnectionForRetries bool
+	TLSMode                   string
+	TLSRootCAs                *x509.CertPool
+	EHLOProfile               string
+	IdentityDomain            string
+	Proxy                     string
+	TranscriptEnabled         bool
+	ReplyCorpus               *ReplyCorpus
+
+	greeting EHLOProfile
 }
 
 func (p NetSMTPProber) Check(ctx context.Context, host, email string) Result {
 	if email == "" {
 		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_tempfail"})
 	}
+	p.greeting = LookupEHLOProfile(p.EHLOProfile)
+	p.HeloName = p.greeting.heloName(p.HeloName, p.IdentityDomain, p.MailFromAddress)
 	if p.HeloName == "" {
 		p.HeloName = host
 	}
@@ -132,10 +143,90 @@ func (p NetSMTPProber) Check(ctx context.Context, host, email string) Result {
 		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_tempfail"})
 	}
 
-	if err := p.waitRate(ctx); err != nil {
+	return p.applyGreetingEvidence(p.check(ctx, host, email))
+}
+
+func (p NetSMTPProber) check(ctx context.Context, host, email string) Result {
+	if p.SessionPool != nil {
+		return p.checkPooled(ctx, host, email)
+	}
+
+	session, result := p.openSession(ctx, host)
+	if result.Category != "" {
+		return result
+	}
+	defer session.Close()
+	session.transcript.begin()
+
+	if result := p.startTransaction(ctx, session, host); result.Category != "" {
+		return session.applySessionEvidence(result)
+	}
+
+	result = p.probeRecipient(ctx, session, host, email)
+	_ = writeSMTP(session, "QUIT", p.ReadTimeout)
+
+	return session.applySessionEvidence(result)
+}
+
+// checkPooled probes email on a pooled session for host, opening a new
+// session only when no idle one is available. Transactions are reset with
+// RSET once the pool's per-transaction RCPT budget is used up.
+func (p NetSMTPProber) checkPooled(ctx context.Context, host, email string) Result {
+	key := smtpSessionKey(host, p)
+	session, err := p.SessionPool.acquire(ctx, key, p.MaxSessionsPerHost)
+	if err != nil {
 		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_timeout"})
 	}
 
+	var result Result
+	if session == nil {
+		session, result = p.openSession(ctx, host)
+		if result.Category != "" {
+			p.SessionPool.release(key, nil)
+			return result
+		}
+	}
+	defer p.SessionPool.release(key, session)
+	session.transcript.begin()
+
+	if p.SessionPool.needsNewTransaction(session, p.MailFromAddress) {
+		if session.mailFrom != "" {
+			if result := p.resetTransaction(ctx, session, host); result.Category != "" {
+				return session.applySessionEvidence(result)
+			}
+		}
+		if result := p.startTransaction(ctx, session, host); result.Category != "" {
+			return session.applySessionEvidence(result)
+		}
+	}
+
+	return session.applySessionEvidence(p.probeRecipient(ctx, session, host, email))
+}
+
+// openSession dials host, reads the banner, greets the server and
+// negotiates STARTTLS. A non-empty result category means the session could
+// not be opened. When an opportunistic TLS handshake fails the host is
+// redialed once in plaintext.
+func (p NetSMTPProber) openSession(ctx context.Context, host string) (*smtpSession, Result) {
+	session, result, handshakeFailed := p.dialSession(ctx, host)
+	if !handshakeFailed || NormalizeSMTPTLSMode(p.TLSMode, SMTPTLSNever) != SMTPTLSOpportunistic {
+		return session, result
+	}
+
+	p.TLSMode = SMTPTLSNever
+	session, result, _ = p.dialSession(ctx, host)
+	if session != nil {
+		session.starttls = starttlsFailed
+	}
+
+	return session, result
+}
+
+func (p NetSMTPProber) dialSession(ctx context.Context, host string) (*smtpSession, Result, bool) {
+	if err := p.waitRate(ctx, host); err != nil {
+		return nil, p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_timeout"}), false
+	}
+
 	dialer := p.Dialer
 	if dialer == nil {
 		dialer = &net.Dialer{Timeout: p.ConnectTimeout}
@@ -144,210 +235,231 @@ func (p NetSMTPProber) Check(ctx context.Context, host, email string) Result {
 	connectCtx, cancel := context.WithTimeout(ctx, p.ConnectTimeout)
 	defer cancel()
 
-	conn, err := dialer.DialContext(connectCtx, "tcp", net.JoinHostPort(host, "25"))
+	conn, err := dialer.DialContext(smtpDialContext(connectCtx, p.ProviderProfile, p.Proxy), "tcp", net.JoinHostPort(host, "25"))
 	if err != nil {
+		if reason := proxyFailureReason(err); reason != "" {
+			return nil, p.applySessionContext(Result{Category: CategoryRisky, Reason: reason}), false
+		}
 		if isTimeout(err) || errors.Is(connectCtx.Err(), context.DeadlineExceeded) {
-			return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_connect_timeout"})
+			return nil, p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_connect_timeout"}), false
 		}
 
-		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_connect_timeout"})
+		return nil, p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_connect_timeout"}), false
+	}
+	session := newSMTPSession(conn)
+	if p.TranscriptEnabled {
+		session.transcript = newSMTPTranscript()
 	}
-	defer conn.Close()
 
-	if reply, res := readSMTPReply(conn, p.ReadTimeout); res != nil {
-		return p.applySessionContext(*res)
-	} else if result, stop := classifySMTPSessionReply(
-		"banner",
-		reply,
-		p.ProviderProfile,
-		host,
-		p.ReplyPolicyEngine,
-		p.AdaptiveRetryEnable,
-	); stop {
-		return p.applySessionContext(result)
+	if reply, res := readSMTPReply(session, p.ReadTimeout); res != nil {
+		_ = session.Close()
+		return nil, session.applySessionEvidence(p.applySessionContext(*res)), false
+	} else if result, stop := p.classifySessionReply("banner", reply, host); stop {
+		_ = session.Close()
+		return nil, session.applySessionEvidence(p.applySessionContext(result)), false
 	}
 
-	if result := p.sayHello(conn, host); result.Category != "" {
+	if result := p.sayHello(ctx, session, host); result.Category != "" {
+		_ = session.Close()
+		return nil, session.applySessionEvidence(p.applySessionContext(result)), false
+	}
+
+	result, handshakeFailed := p.negotiateTLS(ctx, session, host)
+	if result.Category == "" && !handshakeFailed && session.starttls == starttlsNegotiated {
+		// RFC 3207: the client must discard prior knowledge and greet
+		// again over the encrypted channel.
+		result = p.sayHello(ctx, session, host)
+	}
+	if result.Category != "" || handshakeFailed {
+		result = session.applySessionEvidence(result)
+		_ = session.Close()
+		if result.Category != "" {
+			result = p.applySessionContext(result)
+		}
+		return nil, result, handshakeFailed
+	}
+	session.transcript.endHandshake()
+
+	return session, Result{}, false
+}
+
+func (p NetSMTPProber) startTransaction(ctx context.Context, session *smtpSession, host string) Result {
+	command := fmt.Sprintf("MAIL FROM:<%s>", p.MailFromAddress)
+	if session.supports("SMTPUTF8") {
+		// RFC 6531: declaring SMTPUTF8 lets the transaction carry UTF-8
+		// recipients; it is harmless for ASCII ones.
+		command += " SMTPUTF8"
+	}
+	if err := p.send(ctx, session, command, p.ReadTimeout); err != nil {
+		session.broken = true
+		if isTimeout(err) {
+			return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_timeout"})
+		}
+		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_tempfail"})
+	}
+
+	if reply, res := readSMTPReply(session, p.ReadTimeout); res != nil {
+		session.broken = true
+		return p.applySessionContext(*res)
+	} else if result, stop := p.classifySessionReply("mail_from", reply, host); stop {
+		session.discard = true
 		return p.applySessionContext(result)
 	}
 
-	if err := writeSMTP(conn, fmt.Sprintf("MAIL FROM:<%s>", p.MailFromAddress), p.ReadTimeout); err != nil {
+	session.mailFrom = p.MailFromAddress
+	session.batchRcpts = 0
+
+	return Result{}
+}
+
+func (p NetSMTPProber) resetTransaction(ctx context.Context, session *smtpSession, host string) Result {
+	if err := p.send(ctx, session, "RSET", p.ReadTimeout); err != nil {
+		session.broken = true
 		if isTimeout(err) {
 			return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_timeout"})
 		}
 		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_tempfail"})
 	}
 
-	if reply, res := readSMTPReply(conn, p.ReadTimeout); res != nil {
+	if reply, res := readSMTPReply(session, p.ReadTimeout); res != nil {
+		session.broken = true
 		return p.applySessionContext(*res)
-	} else if result, stop := classifySMTPSessionReply(
-		"mail_from",
-		reply,
-		p.ProviderProfile,
-		host,
-		p.ReplyPolicyEngine,
-		p.AdaptiveRetryEnable,
-	); stop {
+	} else if result, stop := p.classifySessionReply("rset", reply, host); stop {
+		session.discard = true
 		return p.applySessionContext(result)
 	}
 
-	rcptResult := p.checkRcpt(conn, host, email, true)
+	session.mailFrom = ""
+	session.batchRcpts = 0
+
+	return Result{}
+}
+
+func (p NetSMTPProber) probeRecipient(ctx context.Context, session *smtpSession, host, email string) Result {
+	if requiresSMTPUTF8(email) && !session.supports("SMTPUTF8") {
+		return p.applySessionContext(Result{
+			Category:        CategoryRisky,
+			Reason:          reasonSMTPUTF8Unsupported,
+			ReasonCode:      reasonSMTPUTF8Unsupported,
+			DecisionClass:   DecisionUnknown,
+			ProviderProfile: detectSMTPProviderProfile(p.ProviderProfile, host, ""),
+		})
+	}
+
+	rcptResult := p.checkRcpt(ctx, session, host, email, true)
 	if rcptResult.Category != CategoryValid {
-		_ = writeSMTP(conn, "QUIT", p.ReadTimeout)
 		return p.applySessionContext(rcptResult)
 	}
 
-	if p.CatchAllDetectionEnabled {
-		catchAllResult := p.checkCatchAll(conn, host, email)
-		_ = writeSMTP(conn, "QUIT", p.ReadTimeout)
-		return p.applySessionContext(catchAllResult)
+	if p.CatchAllDetectionEnabled && !p.greeting.SkipCatchAll {
+		return p.checkCatchAll(ctx, session, host, email, rcptResult)
 	}
 
-	_ = writeSMTP(conn, "QUIT", p.ReadTimeout)
 	return p.applySessionContext(rcptResult)
 }
 
-func (p NetSMTPProber) waitRate(ctx context.Context) error {
+func (p NetSMTPProber) waitRate(ctx context.Context, host string) error {
 	if p.RateLimiter == nil {
 		return nil
 	}
 
-	return p.RateLimiter.Wait(ctx)
+	return p.RateLimiter.Wait(ctx, smtpThrottleKey(p.ProviderProfile, host))
 }
 
-func (p NetSMTPProber) sayHello(conn net.Conn, host string) Result {
-	if err := writeSMTP(conn, fmt.Sprintf("EHLO %s", p.HeloName), p.EhloTimeout); err != nil {
-		if isTimeout(err) {
-			return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_timeout"})
-		}
-		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_tempfail"})
+// sayHello greets the server with the profile's greeting commands in order,
+// moving to the next command only when the previous one is rejected with a
+// permanent failure.
+func (p NetSMTPProber) sayHello(ctx context.Context, session *smtpSession, host string) Result {
+	greetings := p.greeting.Greetings
+	if len(greetings) == 0 {
+		greetings = LookupEHLOProfile(DefaultEHLOProfile).Greetings
 	}
 
-	if reply, res := readSMTPReply(conn, p.EhloTimeout); res != nil {
-		return p.applySessionContext(*res)
-	} else if reply.Code >= 500 {
-		if err := writeSMTP(conn, fmt.Sprintf("HELO %s", p.HeloName), p.EhloTimeout); err != nil {
+	for index, greeting := range greetings {
+		if err := p.send(ctx, session, fmt.Sprintf("%s %s", greeting, p.HeloName), p.EhloTimeout); err != nil {
+			session.broken = true
 			if isTimeout(err) {
 				return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_timeout"})
 			}
 			return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_tempfail"})
 		}
-		if heloReply, res := readSMTPReply(conn, p.EhloTimeout); res != nil {
+
+		reply, res := readSMTPReply(session, p.EhloTimeout)
+		if res != nil {
+			session.broken = true
 			return p.applySessionContext(*res)
-		} else if result, stop := classifySMTPSessionReply(
-			"helo",
-			heloReply,
-			p.ProviderProfile,
-			host,
-			p.ReplyPolicyEngine,
-			p.AdaptiveRetryEnable,
-		); stop {
+		}
+		if reply.Code >= 500 && index+1 < len(greetings) {
+			continue
+		}
+
+		if result, stop := p.classifySessionReply(strings.ToLower(greeting), reply, host); stop {
 			return p.applySessionContext(result)
 		}
-	} else if result, stop := classifySMTPSessionReply(
-		"ehlo",
-		reply,
-		p.ProviderProfile,
-		host,
-		p.ReplyPolicyEngine,
-		p.AdaptiveRetryEnable,
-	); stop {
-		return p.applySessionContext(result)
+
+		session.extensions = nil
+		if greeting == "EHLO" {
+			session.extensions = parseEHLOExtensions(reply.Lines)
+		}
+
+		return Result{}
 	}
 
 	return Result{}
 }
 
-func (p NetSMTPProber) checkRcpt(conn net.Conn, host, email string, allowValid bool) Result {
-	if err := writeSMTP(conn, fmt.Sprintf("RCPT TO:<%s>", email), p.ReadTimeout); err != nil {
-		if isTimeout(err) {
-			return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_timeout"})
-		}
-		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_tempfail"})
+// send writes command on session after waiting out the greeting profile's
+// command pacing. It gives up when ctx is done before the wait is over.
+func (p NetSMTPProber) send(ctx context.Context, session *smtpSession, command string, timeout time.Duration) error {
+	if err := session.pace(ctx, p.greeting.CommandPacing); err != nil {
+		return err
 	}
+	return writeSMTP(session, command, timeout)
+}
 
-	reply, res := readSMTPReply(conn, p.ReadTimeout)
-	if res != nil {
-		return p.applySessionContext(*res)
+func (p NetSMTPProber) applyGreetingEvidence(result Result) Result {
+	if p.greeting.Name == "" {
+		return result
 	}
 
-	return p.applySessionContext(classifySMTPRcptReply(
-		reply,
-		p.ProviderProfile,
-		host,
-		allowValid,
-		p.ReplyPolicyEngine,
-		p.AdaptiveRetryEnable,
-	))
-}
-
-func (p NetSMTPProber) checkCatchAll(conn net.Conn, host, email string) Result {
-	domain := ""
-	if at := strings.LastIndex(email, "@"); at != -1 && at+1 < len(email) {
-		domain = email[at+1:]
+	if result.Evidence == nil {
+		result.Evidence = &ReplyEvidence{}
 	}
-	if domain == "" {
-		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_tempfail"})
+	if result.Evidence.EHLOProfile == "" {
+		result.Evidence.EHLOProfile = p.greeting.Name
 	}
 
-	randomLocal := p.randomLocalPart()
-	randomEmail := fmt.Sprintf("%s@%s", randomLocal, domain)
+	return result
+}
 
-	result := p.checkRcpt(conn, host, randomEmail, true)
-	if result.Category == CategoryValid {
-		return p.applySessionContext(Result{
-			Category:           CategoryRisky,
-			Reason:             "catch_all_high_confidence",
-			ReasonCode:         "catch_all_high_confidence",
-			DecisionClass:      DecisionUnknown,
-			DecisionConfidence: "high",
-			RetryStrategy:      "none",
-			ProviderProfile:    result.ProviderProfile,
-			SMTPCode:           result.SMTPCode,
-			EnhancedCode:       result.EnhancedCode,
-		})
+func (p NetSMTPProber) checkRcpt(ctx context.Context, session *smtpSession, host, email string, allowValid bool) Result {
+	if err := p.send(ctx, session, fmt.Sprintf("RCPT TO:<%s>", email), p.ReadTimeout); err != nil {
+		session.broken = true
+		if isTimeout(err) {
+			return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_timeout"})
+		}
+		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_tempfail"})
 	}
 
-	if result.Category == CategoryInvalid {
-		return p.applySessionContext(Result{
-			Category:           CategoryValid,
-			Reason:             "rcpt_ok",
-			ReasonCode:         "rcpt_ok",
-			DecisionClass:      DecisionDeliverable,
-			DecisionConfidence: "high",
-			RetryStrategy:      "none",
-			ProviderProfile:    result.ProviderProfile,
-		})
+	reply, res := readSMTPReply(session, p.ReadTimeout)
+	if res != nil {
+		session.broken = true
+		return p.applySessionContext(*res)
 	}
 
-	if result.DecisionClass == DecisionRetryable || result.DecisionClass == DecisionPolicyBlocked || result.DecisionClass == DecisionUnknown {
-		return p.applySessionContext(Result{
-			Category:           CategoryRisky,
-			Reason:             "catch_all_medium_confidence",
-			ReasonCode:         "catch_all_medium_confidence",
-			DecisionClass:      DecisionUnknown,
-			DecisionConfidence: "medium",
-			RetryStrategy:      result.RetryStrategy,
-			ProviderProfile:    result.ProviderProfile,
-			SMTPCode:           result.SMTPCode,
-			EnhancedCode:       result.EnhancedCode,
-			RetryAfterSecond:   result.RetryAfterSecond,
-			Evidence:           result.Evidence,
-		})
+	session.batchRcpts++
+	session.totalRcpts++
+
+	result := p.classifyRcptReply(reply, host, allowValid)
+	if reply.Code == 421 {
+		session.discard = true
+	} else if result.DecisionClass == DecisionRetryable && !p.ReuseConnectionForRetries {
+		// The retry should land on a fresh connection, which may reach a
+		// different server behind the MX.
+		session.discard = true
 	}
 
-	return p.applySessionContext(Result{
-		Category:           CategoryRisky,
-		Reason:             "catch_all_low_confidence",
-		ReasonCode:         "catch_all_low_confidence",
-		DecisionClass:      DecisionUnknown,
-		DecisionConfidence: "low",
-		RetryStrategy:      "none",
-		ProviderProfile:    result.ProviderProfile,
-		SMTPCode:           result.SMTPCode,
-		EnhancedCode:       result.EnhancedCode,
-		Evidence:           result.Evidence,
-	})
+	return p.applySessionContext(result)
 }
 
 func (p NetSMTPProber) randomLocalPart() string {
@@ -370,21 +482,43 @@ func (p NetSMTPProber) randomLocalPart() string {
 }
 
 func (c NetSMTPChecker) applySessionContext(result Result) Result {
-	return applySessionContextResult(result, c.ProviderMode, c.SessionStrategyID)
+	return withSourceIP(applySessionContextResult(result, c.ProviderMode, c.SessionStrategyID), c.sourceIP)
+}
+
+// classifySessionReply records reply in the corpus, if any, and classifies
+// it.
+func (c NetSMTPChecker) classifySessionReply(stage string, reply smtpReply, host string) (Result, bool) {
+	c.ReplyCorpus.record(stage, reply, detectSMTPProviderProfile(c.ProviderProfile, host, reply.Message))
+
+	return classifySMTPSessionReply(stage, reply, c.ProviderProfile, host, c.ReplyPolicyEngine, c.AdaptiveRetryEnable)
 }
 
-func (c NetSMTPChecker) waitRate(ctx context.Context) error {
+func (c NetSMTPChecker) waitRate(ctx context.Context, host string) error {
 	if c.RateLimiter == nil {
 		return nil
 	}
 
-	return c.RateLimiter.Wait(ctx)
+	return c.RateLimiter.Wait(ctx, smtpThrottleKey(c.ProviderProfile, host))
 }
 
 func (p NetSMTPProber) applySessionContext(result Result) Result {
 	return applySessionContextResult(result, p.ProviderMode, p.SessionStrategyID)
 }
 
+// classifySessionReply records reply in the corpus, if any, and classifies
+// it.
+func (p NetSMTPProber) classifySessionReply(stage string, reply smtpReply, host string) (Result, bool) {
+	p.ReplyCorpus.record(stage, reply, detectSMTPProviderProfile(p.ProviderProfile, host, reply.Message))
+
+	return classifySMTPSessionReply(stage, reply, p.ProviderProfile, host, p.ReplyPolicyEngine, p.AdaptiveRetryEnable)
+}
+
+func (p NetSMTPProber) classifyRcptReply(reply smtpReply, host string, allowValid bool) Result {
+	p.ReplyCorpus.record("rcpt_to", reply, detectSMTPProviderProfile(p.ProviderProfile, host, reply.Message))
+
+	return classifySMTPRcptReply(reply, p.ProviderProfile, host, allowValid, p.ReplyPolicyEngine, p.AdaptiveRetryEnable)
+}
+
 func applySessionContextResult(result Result, providerMode string, sessionStrategyID string) Result {
 	if strings.TrimSpace(providerMode) == "" {
 		providerMode = "normal"
@@ -431,6 +565,7 @@ func applySessionContextResult(result Result, providerMode string, sessionStrate
 }
 
 func writeSMTP(conn net.Conn, command string, timeout time.Duration) error {
+	transcriptOf(conn).command(command)
 	_ = conn.SetWriteDeadline(time.Now().Add(timeout))
 	_, err := fmt.Fprintf(conn, "%s\r\n", command)
 	return err
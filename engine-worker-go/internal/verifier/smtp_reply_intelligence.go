@@ -1,13 +1,14 @@
 package verifier
 
 import (
-	"bufio"
 	"errors"
 	"net"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"engine-worker-go/replypolicy"
 )
 
 type smtpReply struct {
@@ -21,10 +22,11 @@ var enhancedStatusPattern = regexp.MustCompile(`\b([245]\.\d\.\d+)\b`)
 
 func readSMTPReply(conn net.Conn, timeout time.Duration) (smtpReply, *Result) {
 	_ = conn.SetReadDeadline(time.Now().Add(timeout))
-	reader := bufio.NewReader(conn)
+	reader := smtpReplyReader(conn)
 
 	line, err := reader.ReadString('\n')
 	if err != nil {
+		transcriptOf(conn).reply(nil, err)
 		if isTimeout(err) {
 			result := Result{
 				Category:      CategoryRisky,
@@ -71,6 +73,7 @@ func readSMTPReply(conn net.Conn, timeout time.Duration) (smtpReply, *Result) {
 
 	reply.Message = pickReplyMessage(reply.Lines)
 	reply.EnhancedCode = extractEnhancedStatus(reply.Lines)
+	transcriptOf(conn).reply(reply.Lines, nil)
 
 	if reply.Code == 0 {
 		result := Result{
@@ -316,7 +319,7 @@ func classifySMTPWithPolicyEngine(
 	}
 
 	replyProfile := lookupProviderReplyProfile(engine, profile)
-	rule, ok := matchProviderReplyRule(replyProfile, reply)
+	rule, captures, ok := matchProviderReplyRule(replyProfile, command, reply)
 	if !ok {
 		return Result{}, false
 	}
@@ -365,14 +368,14 @@ func classifySMTPWithPolicyEngine(
 		reply,
 		profile,
 		retryAfter,
-		withPolicyContext(engine, rule),
+		withPolicyContext(engine, rule, captures),
 		withConfidenceHint(strings.TrimSpace(rule.ConfidenceHint)),
 	), true
 }
 
 func smtpResult(category, reason, reasonCode, reasonTag, decisionClass string, reply smtpReply, profile string, retryAfter int, options ...func(*Result)) Result {
 	if strings.TrimSpace(reasonTag) == "" && strings.TrimSpace(decisionClass) != DecisionDeliverable {
-		reasonTag = inferRuleTag(reasonCode, decisionClass)
+		reasonTag = replypolicy.InferRuleTag(reasonCode, decisionClass)
 	}
 	confidence := normalizedEvidenceStrength(decisionConfidenceFor(decisionClass, category, reply))
 	result := Result{
@@ -411,7 +414,7 @@ func smtpResult(category, reason, reasonCode, reasonTag, decisionClass string, r
 	return result
 }
 
-func withPolicyContext(engine *ProviderReplyPolicyEngine, rule ProviderReplyRule) func(*Result) {
+func withPolicyContext(engine *ProviderReplyPolicyEngine, rule ProviderReplyRule, captures map[string]string) func(*Result) {
 	return func(result *Result) {
 		if result == nil {
 			return
@@ -429,6 +432,7 @@ func withPolicyContext(engine *ProviderReplyPolicyEngine, rule ProviderReplyRule
 			ruleID = strings.TrimSpace(rule.DecisionClass)
 		}
 		result.MatchedRuleID = ruleID
+		result.RuleCaptures = captures
 		if strings.TrimSpace(rule.RuleTag) != "" {
 			result.ReasonTag = strings.TrimSpace(rule.RuleTag)
 			if result.Evidence != nil {
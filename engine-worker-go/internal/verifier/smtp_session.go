@@ -0,0 +1,322 @@
+package verifier
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSMTPSessionMaxRcpts            = 100
+	defaultSMTPSessionRcptsPerTransaction = 20
+	defaultSMTPSessionIdleTimeout         = 30 * time.Second
+	defaultSMTPSessionsPerHost            = 1
+)
+
+type SMTPSessionPoolConfig struct {
+	MaxRcptsPerSession     int
+	RcptsPerTransaction    int
+	IdleTimeout            time.Duration
+	DefaultSessionsPerHost int
+}
+
+// SMTPSessionPool keeps greeted SMTP sessions per MX host so consecutive
+// probes against the same host send RCPT TO on an open connection instead
+// of dialing again. A caller waits while the host has as many sessions as
+// the limit it passes, counting both idle and in-use sessions, so a changed
+// limit applies from the next acquire. Hosts without sessions are dropped
+// by EvictIdle.
+type SMTPSessionPool struct {
+	mu     sync.Mutex
+	config SMTPSessionPoolConfig
+	hosts  map[string]*smtpHostSessions
+	now    func() time.Time
+}
+
+type smtpHostSessions struct {
+	inUse   int
+	waiting int
+	// released is closed and replaced whenever a slot frees up.
+	released chan struct{}
+	idle     []*smtpSession
+}
+
+// smtpSession wraps a connection together with the buffered reader used for
+// every reply on it, so bytes read ahead of one reply are not dropped before
+// the next command.
+type smtpSession struct {
+	net.Conn
+	reader     *bufio.Reader
+	mailFrom   string
+	batchRcpts int
+	totalRcpts int
+	lastUsed   time.Time
+	broken     bool
+	discard    bool
+	lastSent   time.Time
+
+	extensions    map[string]struct{}
+	starttls      string
+	tlsVersion    string
+	tlsCipher     string
+	tlsCertStatus string
+	sourceIP      string
+
+	transcript *smtpTranscript
+}
+
+func newSMTPSession(conn net.Conn) *smtpSession {
+	return &smtpSession{Conn: conn, reader: bufio.NewReader(conn), sourceIP: localIP(conn)}
+}
+
+// applySessionEvidence copies the session's source address and STARTTLS
+// outcome into the result evidence, and attaches the session transcript
+// when one is recorded.
+func (s *smtpSession) applySessionEvidence(result Result) Result {
+	if s == nil {
+		return result
+	}
+
+	result = s.applyTLSEvidence(withSourceIP(result, s.sourceIP))
+	if result.Transcript == nil {
+		result.Transcript = s.transcript.snapshot()
+	}
+
+	return result
+}
+
+// pace waits until at least gap has passed since the previous command sent
+// on the session, or returns ctx's error when ctx is done first.
+func (s *smtpSession) pace(ctx context.Context, gap time.Duration) error {
+	if gap > 0 && !s.lastSent.IsZero() {
+		if wait := gap - time.Since(s.lastSent); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	s.lastSent = time.Now()
+
+	return nil
+}
+
+func NewSMTPSessionPool(config SMTPSessionPoolConfig) *SMTPSessionPool {
+	if config.MaxRcptsPerSession <= 0 {
+		config.MaxRcptsPerSession = defaultSMTPSessionMaxRcpts
+	}
+	if config.RcptsPerTransaction <= 0 {
+		config.RcptsPerTransaction = defaultSMTPSessionRcptsPerTransaction
+	}
+	if config.RcptsPerTransaction > config.MaxRcptsPerSession {
+		config.RcptsPerTransaction = config.MaxRcptsPerSession
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaultSMTPSessionIdleTimeout
+	}
+	if config.DefaultSessionsPerHost <= 0 {
+		config.DefaultSessionsPerHost = defaultSMTPSessionsPerHost
+	}
+
+	return &SMTPSessionPool{
+		config: config,
+		hosts:  map[string]*smtpHostSessions{},
+		now:    time.Now,
+	}
+}
+
+// acquire waits for a session slot on key and returns an idle session when
+// one is available. A nil session means the caller owns the slot and must
+// open a new connection. Every successful acquire must be paired with a
+// release.
+func (p *SMTPSessionPool) acquire(ctx context.Context, key string, limit int) (*smtpSession, error) {
+	if limit <= 0 {
+		limit = p.config.DefaultSessionsPerHost
+	}
+
+	p.mu.Lock()
+	host, ok := p.hosts[key]
+	if !ok {
+		host = &smtpHostSessions{released: make(chan struct{})}
+		p.hosts[key] = host
+	}
+	for host.inUse >= limit {
+		released := host.released
+		host.waiting++
+		p.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			p.mu.Lock()
+			host.waiting--
+			p.mu.Unlock()
+			return nil, ctx.Err()
+		}
+
+		p.mu.Lock()
+		host.waiting--
+	}
+	host.inUse++
+
+	var expired []*smtpSession
+	var session *smtpSession
+	now := p.now()
+	for len(host.idle) > 0 {
+		last := len(host.idle) - 1
+		candidate := host.idle[last]
+		host.idle = host.idle[:last]
+
+		if now.Sub(candidate.lastUsed) >= p.config.IdleTimeout {
+			expired = append(expired, candidate)
+			continue
+		}
+
+		session = candidate
+		break
+	}
+	p.mu.Unlock()
+
+	closeSMTPSessions(expired)
+
+	return session, nil
+}
+
+// release frees the slot taken by acquire and pools the session when it is
+// still usable.
+func (p *SMTPSessionPool) release(key string, session *smtpSession) {
+	p.mu.Lock()
+	host := p.hosts[key]
+	reusable := session != nil && !session.broken && !session.discard && session.totalRcpts < p.config.MaxRcptsPerSession
+	if reusable {
+		session.lastUsed = p.now()
+		host.idle = append(host.idle, session)
+	}
+
+	host.inUse--
+	close(host.released)
+	host.released = make(chan struct{})
+	p.mu.Unlock()
+
+	if !reusable {
+		closeSMTPSession(session)
+	}
+}
+
+func (p *SMTPSessionPool) needsNewTransaction(session *smtpSession, mailFrom string) bool {
+	return session.mailFrom != mailFrom || session.batchRcpts >= p.config.RcptsPerTransaction
+}
+
+// EvictIdle closes idle sessions that have not been used within the idle
+// timeout and forgets hosts left with no sessions and no waiters.
+func (p *SMTPSessionPool) EvictIdle() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	var expired []*smtpSession
+	now := p.now()
+	for key, host := range p.hosts {
+		kept := host.idle[:0]
+		for _, session := range host.idle {
+			if now.Sub(session.lastUsed) >= p.config.IdleTimeout {
+				expired = append(expired, session)
+				continue
+			}
+			kept = append(kept, session)
+		}
+		host.idle = kept
+		if len(host.idle) == 0 && host.inUse == 0 && host.waiting == 0 {
+			delete(p.hosts, key)
+		}
+	}
+	p.mu.Unlock()
+
+	closeSMTPSessions(expired)
+}
+
+// Close sends QUIT on and closes every idle session.
+func (p *SMTPSessionPool) Close() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	var idle []*smtpSession
+	for _, host := range p.hosts {
+		idle = append(idle, host.idle...)
+		host.idle = nil
+	}
+	p.mu.Unlock()
+
+	closeSMTPSessions(idle)
+}
+
+// IdleSessions returns the number of pooled sessions waiting for reuse.
+func (p *SMTPSessionPool) IdleSessions() int {
+	if p == nil {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	total := 0
+	for _, host := range p.hosts {
+		total += len(host.idle)
+	}
+
+	return total
+}
+
+func closeSMTPSessions(sessions []*smtpSession) {
+	for _, session := range sessions {
+		closeSMTPSession(session)
+	}
+}
+
+func closeSMTPSession(session *smtpSession) {
+	if session == nil {
+		return
+	}
+	if !session.broken {
+		_ = writeSMTP(session, "QUIT", time.Second)
+	}
+	_ = session.Close()
+}
+
+// smtpSessionKey separates pooled sessions by everything fixed when the
+// session is opened: the host, greeting name and profile, TLS mode, egress
+// proxy and whether the session is transcribed.
+func smtpSessionKey(host string, p NetSMTPProber) string {
+	transcript := "no_transcript"
+	if p.TranscriptEnabled {
+		transcript = "transcript"
+	}
+
+	return strings.Join([]string{
+		strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), ".")),
+		strings.ToLower(strings.TrimSpace(p.HeloName)),
+		strings.ToLower(strings.TrimSpace(p.EHLOProfile)),
+		NormalizeSMTPTLSMode(p.TLSMode, SMTPTLSNever),
+		strings.ToLower(strings.TrimSpace(p.Proxy)),
+		transcript,
+	}, "|")
+}
+
+// smtpReplyReader returns the buffered reader owned by a pooled session, or
+// a fresh reader for one-shot connections.
+func smtpReplyReader(conn net.Conn) *bufio.Reader {
+	if session, ok := conn.(*smtpSession); ok {
+		return session.reader
+	}
+
+	return bufio.NewReader(conn)
+}
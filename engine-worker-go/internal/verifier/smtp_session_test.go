@@ -0,0 +1,283 @@
+package verifier
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingSMTPDialer struct {
+	t       *testing.T
+	handler func(line string) string
+	mu      sync.Mutex
+	dials   int
+	lines   []string
+}
+
+func (d *recordingSMTPDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+
+	d.mu.Lock()
+	d.dials++
+	d.mu.Unlock()
+
+	go runSMTPServer(d.t, server, func(line string) string {
+		d.mu.Lock()
+		d.lines = append(d.lines, line)
+		d.mu.Unlock()
+
+		return d.handler(line)
+	})
+
+	return client, nil
+}
+
+func (d *recordingSMTPDialer) dialCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.dials
+}
+
+func (d *recordingSMTPDialer) commandCount(prefix string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	count := 0
+	for _, line := range d.lines {
+		if strings.HasPrefix(line, prefix) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func newPooledProber(dialer SMTPDialer, pool *SMTPSessionPool) NetSMTPProber {
+	return NetSMTPProber{
+		Dialer:          dialer,
+		ConnectTimeout:  time.Second,
+		ReadTimeout:     time.Second,
+		EhloTimeout:     time.Second,
+		HeloName:        "helo.test",
+		MailFromAddress: "probe@helo.test",
+		SessionPool:     pool,
+	}
+}
+
+func TestPooledProberSendsSeveralRcptsOnOneSession(t *testing.T) {
+	dialer := &recordingSMTPDialer{t: t, handler: func(line string) string {
+		if strings.HasPrefix(line, "RCPT TO:<bad") {
+			return "550 No such user"
+		}
+		return "250 OK"
+	}}
+	pool := NewSMTPSessionPool(SMTPSessionPoolConfig{})
+	defer pool.Close()
+
+	prober := newPooledProber(dialer, pool)
+	expected := map[string]string{
+		"a@test.com":   CategoryValid,
+		"bad@test.com": CategoryInvalid,
+		"c@test.com":   CategoryValid,
+	}
+	for _, email := range []string{"a@test.com", "bad@test.com", "c@test.com"} {
+		res := prober.Check(context.Background(), "mx.test", email)
+		if res.Category != expected[email] {
+			t.Fatalf("expected %s for %s, got %s/%s", expected[email], email, res.Category, res.Reason)
+		}
+	}
+
+	if dials := dialer.dialCount(); dials != 1 {
+		t.Fatalf("expected one connection, got %d", dials)
+	}
+	if count := dialer.commandCount("MAIL FROM"); count != 1 {
+		t.Fatalf("expected one MAIL FROM transaction, got %d", count)
+	}
+	if count := dialer.commandCount("RCPT TO"); count != 3 {
+		t.Fatalf("expected three RCPT TO commands, got %d", count)
+	}
+	if idle := pool.IdleSessions(); idle != 1 {
+		t.Fatalf("expected session to be pooled, got %d idle", idle)
+	}
+}
+
+func TestPooledProberResetsBetweenBatches(t *testing.T) {
+	dialer := &recordingSMTPDialer{t: t, handler: func(line string) string { return "250 OK" }}
+	pool := NewSMTPSessionPool(SMTPSessionPoolConfig{MaxRcptsPerSession: 3, RcptsPerTransaction: 2})
+	defer pool.Close()
+
+	prober := newPooledProber(dialer, pool)
+	for i := 0; i < 4; i++ {
+		prober.Check(context.Background(), "mx.test", "user@test.com")
+	}
+
+	if dials := dialer.dialCount(); dials != 2 {
+		t.Fatalf("expected session to be replaced after 3 RCPTs, got %d dials", dials)
+	}
+	if count := dialer.commandCount("RSET"); count != 1 {
+		t.Fatalf("expected one RSET between batches, got %d", count)
+	}
+	if count := dialer.commandCount("MAIL FROM"); count != 3 {
+		t.Fatalf("expected three MAIL FROM transactions, got %d", count)
+	}
+}
+
+func TestPooledProberDiscardsSessionAfterTempfailUnlessReuseEnabled(t *testing.T) {
+	for _, reuse := range []bool{false, true} {
+		dialer := &recordingSMTPDialer{t: t, handler: func(line string) string {
+			if strings.HasPrefix(line, "RCPT TO:<grey") {
+				return "451 4.7.1 Try again later"
+			}
+			return "250 OK"
+		}}
+		pool := NewSMTPSessionPool(SMTPSessionPoolConfig{})
+
+		prober := newPooledProber(dialer, pool)
+		prober.ReuseConnectionForRetries = reuse
+
+		res := prober.Check(context.Background(), "mx.test", "grey@test.com")
+		if res.Category != CategoryRisky {
+			t.Fatalf("expected risky tempfail, got %s/%s", res.Category, res.Reason)
+		}
+		prober.Check(context.Background(), "mx.test", "user@test.com")
+
+		expectedDials := 2
+		if reuse {
+			expectedDials = 1
+		}
+		if dials := dialer.dialCount(); dials != expectedDials {
+			t.Fatalf("reuse=%v: expected %d dials, got %d", reuse, expectedDials, dials)
+		}
+		pool.Close()
+	}
+}
+
+func TestPooledProberRespectsSessionsPerHost(t *testing.T) {
+	release := make(chan struct{})
+	dialer := &recordingSMTPDialer{t: t, handler: func(line string) string {
+		if strings.HasPrefix(line, "RCPT TO") {
+			<-release
+		}
+		return "250 OK"
+	}}
+	pool := NewSMTPSessionPool(SMTPSessionPoolConfig{})
+	defer pool.Close()
+
+	prober := newPooledProber(dialer, pool)
+	prober.MaxSessionsPerHost = 2
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prober.Check(context.Background(), "mx.test", "user@test.com")
+		}()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for dialer.commandCount("RCPT TO") < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if dials := dialer.dialCount(); dials != 2 {
+		t.Fatalf("expected 2 concurrent sessions, got %d", dials)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if dials := dialer.dialCount(); dials != 2 {
+		t.Fatalf("expected queued probes to reuse sessions, got %d dials", dials)
+	}
+}
+
+func TestSMTPSessionPoolAppliesEachCallersLimit(t *testing.T) {
+	pool := NewSMTPSessionPool(SMTPSessionPoolConfig{})
+	if _, err := pool.acquire(context.Background(), "mx.test", 1); err != nil {
+		t.Fatalf("unexpected acquire error: %v", err)
+	}
+
+	if _, err := pool.acquire(context.Background(), "mx.test", 2); err != nil {
+		t.Fatalf("expected a caller with a higher limit to get a second slot, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.acquire(ctx, "mx.test", 2); err == nil {
+		t.Fatal("expected a third caller to wait at a limit of 2")
+	}
+
+	pool.release("mx.test", nil)
+	pool.release("mx.test", nil)
+	pool.EvictIdle()
+	if hosts := len(pool.hosts); hosts != 0 {
+		t.Fatalf("expected the released host to be dropped, got %d hosts", hosts)
+	}
+}
+
+func TestPooledProberKeepsSessionConfigsApart(t *testing.T) {
+	dialer := &recordingSMTPDialer{t: t, handler: func(line string) string { return "250 OK" }}
+	pool := NewSMTPSessionPool(SMTPSessionPoolConfig{})
+	defer pool.Close()
+
+	prober := newPooledProber(dialer, pool)
+	prober.Check(context.Background(), "mx.test", "user@test.com")
+
+	transcribed := prober
+	transcribed.TranscriptEnabled = true
+	transcribed.Check(context.Background(), "mx.test", "user@test.com")
+
+	proxied := prober
+	proxied.Proxy = "eu"
+	proxied.Check(context.Background(), "mx.test", "user@test.com")
+
+	prober.Check(context.Background(), "mx.test", "user@test.com")
+	if dials := dialer.dialCount(); dials != 3 {
+		t.Fatalf("expected one session per config, got %d dials", dials)
+	}
+}
+
+func TestSMTPSessionPoolEvictsIdleSessions(t *testing.T) {
+	dialer := &recordingSMTPDialer{t: t, handler: func(line string) string { return "250 OK" }}
+	pool := NewSMTPSessionPool(SMTPSessionPoolConfig{IdleTimeout: time.Minute})
+	clock := time.Unix(1700000000, 0)
+	pool.now = func() time.Time { return clock }
+
+	prober := newPooledProber(dialer, pool)
+	prober.Check(context.Background(), "mx.test", "user@test.com")
+	if idle := pool.IdleSessions(); idle != 1 {
+		t.Fatalf("expected one idle session, got %d", idle)
+	}
+
+	clock = clock.Add(2 * time.Minute)
+	pool.EvictIdle()
+	if idle := pool.IdleSessions(); idle != 0 {
+		t.Fatalf("expected idle session to be evicted, got %d", idle)
+	}
+
+	prober.Check(context.Background(), "mx.test", "user@test.com")
+	if dials := dialer.dialCount(); dials != 2 {
+		t.Fatalf("expected a new connection after eviction, got %d dials", dials)
+	}
+	pool.Close()
+}
+
+func TestSMTPSessionPaceStopsWhenContextIsDone(t *testing.T) {
+	session := &smtpSession{lastSent: time.Now()}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	err := session.pace(ctx, time.Minute)
+	if err == nil || !isTimeout(err) {
+		t.Fatalf("expected the context deadline to end pacing, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("expected pacing to stop with the context, waited %s", elapsed)
+	}
+}
@@ -0,0 +1,195 @@
+package verifier
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"time"
+
+	"engine-worker-go/replypolicy"
+)
+
+const (
+	SMTPTLSNever         = replypolicy.TLSNever
+	SMTPTLSOpportunistic = replypolicy.TLSOpportunistic
+	SMTPTLSRequired      = replypolicy.TLSRequired
+
+	starttlsNegotiated = "negotiated"
+	starttlsOffered    = "offered"
+	starttlsNotOffered = "not_offered"
+	starttlsFailed     = "failed"
+)
+
+// NormalizeSMTPTLSMode maps a configured TLS mode onto never, opportunistic
+// or required. Unknown values fall back to fallback.
+func NormalizeSMTPTLSMode(mode, fallback string) string {
+	return replypolicy.NormalizeTLSMode(mode, fallback)
+}
+
+// negotiateTLS upgrades session with STARTTLS according to the prober's TLS
+// mode. A non-empty result category stops the probe. handshakeFailed is set
+// when the TLS handshake itself failed, which leaves the connection unusable.
+func (p NetSMTPProber) negotiateTLS(ctx context.Context, session *smtpSession, host string) (result Result, handshakeFailed bool) {
+	mode := NormalizeSMTPTLSMode(p.TLSMode, SMTPTLSNever)
+
+	if !session.supports("STARTTLS") {
+		session.starttls = starttlsNotOffered
+		if mode == SMTPTLSRequired {
+			return p.applySessionContext(tlsUnavailableResult("starttls_unavailable")), false
+		}
+		return Result{}, false
+	}
+	if mode == SMTPTLSNever {
+		session.starttls = starttlsOffered
+		return Result{}, false
+	}
+
+	if err := p.send(ctx, session, "STARTTLS", p.EhloTimeout); err != nil {
+		session.broken = true
+		if isTimeout(err) {
+			return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_timeout"}), false
+		}
+		return p.applySessionContext(Result{Category: CategoryRisky, Reason: "smtp_tempfail"}), false
+	}
+
+	reply, res := readSMTPReply(session, p.EhloTimeout)
+	if res != nil {
+		session.broken = true
+		return p.applySessionContext(*res), false
+	}
+	if reply.Code != 220 {
+		session.starttls = starttlsFailed
+		if mode == SMTPTLSRequired {
+			return p.applySessionContext(tlsUnavailableResult("starttls_rejected")), false
+		}
+		return Result{}, false
+	}
+
+	tlsConn := tls.Client(session.Conn, &tls.Config{
+		ServerName: host,
+		MinVersion: tls.VersionTLS10,
+		// Certificates are verified separately so opportunistic TLS still
+		// succeeds against self-signed MX hosts while the outcome is
+		// recorded as evidence.
+		InsecureSkipVerify: true,
+	})
+
+	handshakeCtx, cancel := context.WithTimeout(ctx, p.EhloTimeout)
+	defer cancel()
+	_ = tlsConn.SetDeadline(time.Now().Add(p.EhloTimeout))
+	if err := tlsConn.HandshakeContext(handshakeCtx); err != nil {
+		session.broken = true
+		session.starttls = starttlsFailed
+		if mode == SMTPTLSRequired {
+			return p.applySessionContext(tlsUnavailableResult("starttls_failed")), true
+		}
+		return Result{}, true
+	}
+	_ = tlsConn.SetDeadline(time.Time{})
+
+	state := tlsConn.ConnectionState()
+	session.Conn = tlsConn
+	session.reader = bufio.NewReader(tlsConn)
+	session.extensions = nil
+	session.starttls = starttlsNegotiated
+	session.tlsVersion = smtpTLSVersionName(state.Version)
+	session.tlsCipher = tls.CipherSuiteName(state.CipherSuite)
+	session.tlsCertStatus = smtpCertificateStatus(state, host, p.TLSRootCAs, time.Now())
+
+	return Result{}, false
+}
+
+func tlsUnavailableResult(reasonCode string) Result {
+	return Result{
+		Category:           CategoryRisky,
+		Reason:             "smtp_tls_unavailable",
+		ReasonCode:         reasonCode,
+		DecisionClass:      DecisionUnknown,
+		DecisionConfidence: "low",
+		RetryStrategy:      "none",
+	}
+}
+
+func smtpTLSVersionName(version uint16) string {
+	return strings.ToLower(strings.ReplaceAll(tls.VersionName(version), " ", ""))
+}
+
+// smtpCertificateStatus reports whether the MX certificate would pass
+// verification: valid, expired, hostname_mismatch, untrusted or missing.
+func smtpCertificateStatus(state tls.ConnectionState, host string, roots *x509.CertPool, now time.Time) string {
+	if len(state.PeerCertificates) == 0 {
+		return "missing"
+	}
+
+	leaf := state.PeerCertificates[0]
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return "expired"
+	}
+	if err := leaf.VerifyHostname(host); err != nil {
+		return "hostname_mismatch"
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	}); err != nil {
+		return "untrusted"
+	}
+
+	return "valid"
+}
+
+// parseEHLOExtensions returns the upper-cased extension keywords advertised
+// in an EHLO reply, skipping the greeting line.
+func parseEHLOExtensions(lines []string) map[string]struct{} {
+	extensions := map[string]struct{}{}
+	for index, line := range lines {
+		if index == 0 || len(line) < 4 {
+			continue
+		}
+
+		fields := strings.Fields(line[4:])
+		if len(fields) == 0 {
+			continue
+		}
+		extensions[strings.ToUpper(fields[0])] = struct{}{}
+	}
+
+	return extensions
+}
+
+func (s *smtpSession) supports(extension string) bool {
+	if s == nil || s.extensions == nil {
+		return false
+	}
+
+	_, ok := s.extensions[strings.ToUpper(extension)]
+	return ok
+}
+
+// applyTLSEvidence copies the session's STARTTLS outcome into the result
+// evidence.
+func (s *smtpSession) applyTLSEvidence(result Result) Result {
+	if s == nil || s.starttls == "" {
+		return result
+	}
+
+	if result.Evidence == nil {
+		result.Evidence = &ReplyEvidence{}
+	}
+	if result.Evidence.STARTTLS == "" {
+		result.Evidence.STARTTLS = s.starttls
+		result.Evidence.TLSVersion = s.tlsVersion
+		result.Evidence.TLSCipher = s.tlsCipher
+		result.Evidence.TLSCertStatus = s.tlsCertStatus
+	}
+
+	return result
+}
@@ -0,0 +1,264 @@
+package verifier
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestCertificate(t *testing.T, host string) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: host},
+		DNSNames:              []string{host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, roots
+}
+
+type tlsSMTPServer struct {
+	certificate tls.Certificate
+	offerTLS    bool
+	breakTLS    bool
+	mu          sync.Mutex
+	dials       int
+	commands    []string
+}
+
+func (s *tlsSMTPServer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+
+	s.mu.Lock()
+	s.dials++
+	breakTLS := s.breakTLS && s.dials == 1
+	s.mu.Unlock()
+
+	go s.serve(server, breakTLS)
+
+	return client, nil
+}
+
+func (s *tlsSMTPServer) serve(conn net.Conn, breakTLS bool) {
+	defer func() { _ = conn.Close() }()
+
+	var current net.Conn = conn
+	reader := bufio.NewReader(current)
+	write := func(reply string) bool {
+		_, err := current.Write([]byte(reply + "\r\n"))
+		return err == nil
+	}
+
+	if !write("220 mx.test ESMTP") {
+		return
+	}
+
+	encrypted := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+
+		switch {
+		case strings.HasPrefix(line, "EHLO"):
+			reply := "250-mx.test\r\n250-PIPELINING\r\n250 8BITMIME"
+			if s.offerTLS && !encrypted {
+				reply = "250-mx.test\r\n250-STARTTLS\r\n250 8BITMIME"
+			}
+			if !write(reply) {
+				return
+			}
+		case line == "STARTTLS":
+			if !write("220 Ready to start TLS") {
+				return
+			}
+			if breakTLS {
+				return
+			}
+
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{s.certificate}})
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			current = tlsConn
+			reader = bufio.NewReader(current)
+			encrypted = true
+		case line == "QUIT":
+			// net.Pipe is unbuffered and the prober does not wait for the
+			// QUIT reply, so close without answering.
+			return
+		default:
+			if !write("250 OK") {
+				return
+			}
+		}
+	}
+}
+
+func (s *tlsSMTPServer) sawCommand(command string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, line := range s.commands {
+		if strings.HasPrefix(line, command) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func newTLSProber(server *tlsSMTPServer, mode string, roots *x509.CertPool) NetSMTPProber {
+	return NetSMTPProber{
+		Dialer:          server,
+		ConnectTimeout:  time.Second,
+		ReadTimeout:     time.Second,
+		EhloTimeout:     time.Second,
+		HeloName:        "helo.test",
+		MailFromAddress: "probe@helo.test",
+		TLSMode:         mode,
+		TLSRootCAs:      roots,
+	}
+}
+
+func TestSMTPProberNegotiatesOpportunisticSTARTTLS(t *testing.T) {
+	certificate, roots := newTestCertificate(t, "mx.test")
+	server := &tlsSMTPServer{certificate: certificate, offerTLS: true}
+
+	res := newTLSProber(server, SMTPTLSOpportunistic, roots).Check(context.Background(), "mx.test", "user@test.com")
+
+	if res.Category != CategoryValid || res.Reason != "rcpt_ok" {
+		t.Fatalf("expected rcpt_ok valid, got %s/%s", res.Category, res.Reason)
+	}
+	if res.Evidence == nil || res.Evidence.STARTTLS != "negotiated" {
+		t.Fatalf("expected negotiated STARTTLS evidence, got %+v", res.Evidence)
+	}
+	if !strings.HasPrefix(res.Evidence.TLSVersion, "tls1.") || res.Evidence.TLSCipher == "" {
+		t.Fatalf("expected TLS version and cipher evidence, got %+v", res.Evidence)
+	}
+	if res.Evidence.TLSCertStatus != "valid" {
+		t.Fatalf("expected valid certificate, got %q", res.Evidence.TLSCertStatus)
+	}
+	if count := server.sawCommand("EHLO"); count != 2 {
+		t.Fatalf("expected EHLO to be repeated after STARTTLS, got %d", count)
+	}
+}
+
+func TestSMTPProberRecordsUntrustedCertificate(t *testing.T) {
+	certificate, _ := newTestCertificate(t, "other.test")
+	server := &tlsSMTPServer{certificate: certificate, offerTLS: true}
+
+	res := newTLSProber(server, SMTPTLSOpportunistic, x509.NewCertPool()).Check(context.Background(), "mx.test", "user@test.com")
+
+	if res.Category != CategoryValid {
+		t.Fatalf("expected opportunistic TLS to tolerate bad certificates, got %s/%s", res.Category, res.Reason)
+	}
+	if res.Evidence.TLSCertStatus != "hostname_mismatch" {
+		t.Fatalf("expected hostname_mismatch certificate status, got %q", res.Evidence.TLSCertStatus)
+	}
+}
+
+func TestSMTPProberRequiredTLSFailsWithoutSTARTTLS(t *testing.T) {
+	server := &tlsSMTPServer{offerTLS: false}
+
+	res := newTLSProber(server, SMTPTLSRequired, nil).Check(context.Background(), "mx.test", "user@test.com")
+
+	if res.Category != CategoryRisky || res.Reason != "smtp_tls_unavailable" || res.ReasonCode != "starttls_unavailable" {
+		t.Fatalf("expected smtp_tls_unavailable risky, got %s/%s/%s", res.Category, res.Reason, res.ReasonCode)
+	}
+	if res.Evidence == nil || res.Evidence.STARTTLS != "not_offered" {
+		t.Fatalf("expected not_offered STARTTLS evidence, got %+v", res.Evidence)
+	}
+	if count := server.sawCommand("MAIL FROM"); count != 0 {
+		t.Fatalf("expected no MAIL FROM without TLS, got %d", count)
+	}
+}
+
+func TestSMTPProberNeverModeSkipsSTARTTLS(t *testing.T) {
+	certificate, _ := newTestCertificate(t, "mx.test")
+	server := &tlsSMTPServer{certificate: certificate, offerTLS: true}
+
+	res := newTLSProber(server, SMTPTLSNever, nil).Check(context.Background(), "mx.test", "user@test.com")
+
+	if res.Category != CategoryValid {
+		t.Fatalf("expected rcpt_ok valid, got %s/%s", res.Category, res.Reason)
+	}
+	if res.Evidence.STARTTLS != "offered" {
+		t.Fatalf("expected offered STARTTLS evidence, got %q", res.Evidence.STARTTLS)
+	}
+	if count := server.sawCommand("STARTTLS"); count != 0 {
+		t.Fatalf("expected STARTTLS not to be sent, got %d", count)
+	}
+}
+
+func TestSMTPProberFallsBackToPlaintextAfterHandshakeFailure(t *testing.T) {
+	certificate, _ := newTestCertificate(t, "mx.test")
+	server := &tlsSMTPServer{certificate: certificate, offerTLS: true, breakTLS: true}
+
+	res := newTLSProber(server, SMTPTLSOpportunistic, nil).Check(context.Background(), "mx.test", "user@test.com")
+
+	if res.Category != CategoryValid {
+		t.Fatalf("expected plaintext fallback to succeed, got %s/%s", res.Category, res.Reason)
+	}
+	if res.Evidence.STARTTLS != "failed" {
+		t.Fatalf("expected failed STARTTLS evidence, got %q", res.Evidence.STARTTLS)
+	}
+	if server.dials != 2 {
+		t.Fatalf("expected a plaintext redial, got %d dials", server.dials)
+	}
+}
+
+func TestParseEHLOExtensions(t *testing.T) {
+	extensions := parseEHLOExtensions([]string{"250-mx.test greets you", "250-SIZE 35882577", "250-starttls", "250 SMTPUTF8"})
+
+	for _, keyword := range []string{"SIZE", "STARTTLS", "SMTPUTF8"} {
+		if _, ok := extensions[keyword]; !ok {
+			t.Fatalf("expected %s extension, got %v", keyword, extensions)
+		}
+	}
+	if _, ok := extensions["MX.TEST"]; ok {
+		t.Fatalf("expected greeting line to be skipped, got %v", extensions)
+	}
+}
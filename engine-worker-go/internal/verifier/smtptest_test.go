@@ -0,0 +1,117 @@
+package verifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"engine-worker-go/internal/smtptest"
+)
+
+func newSMTPTestPipeline(t *testing.T, script smtptest.Script, catchAll bool) *PipelineVerifier {
+	t.Helper()
+
+	server, err := smtptest.NewServer(script)
+	if err != nil {
+		t.Fatalf("failed to start smtp server: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	config := baseConfig(1)
+	config.DNSTimeout = 1000
+	config.CatchAllDetectionEnabled = catchAll
+	prober := NetSMTPProber{
+		Dialer:                   server.Dialer(),
+		ConnectTimeout:           time.Second,
+		ReadTimeout:              200 * time.Millisecond,
+		EhloTimeout:              time.Second,
+		HeloName:                 config.HeloName,
+		MailFromAddress:          config.MailFromAddress,
+		CatchAllDetectionEnabled: catchAll,
+	}
+
+	return NewPipelineVerifier(config, smtptest.Resolver{NXDomains: []string{"gone.test"}}, prober)
+}
+
+func TestPipelineProbesScriptedSMTPServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   smtptest.Script
+		catchAll bool
+		email    string
+		category string
+		reason   string
+	}{
+		{
+			name:     "known mailbox",
+			script:   smtptest.Script{Mailboxes: []string{"user@example.test"}},
+			email:    "user@example.test",
+			category: CategoryValid,
+			reason:   "rcpt_ok",
+		},
+		{
+			name:     "unknown mailbox",
+			script:   smtptest.Script{Mailboxes: []string{"user@example.test"}},
+			email:    "nobody@example.test",
+			category: CategoryInvalid,
+			reason:   "rcpt_rejected",
+		},
+		{
+			name:     "catch-all domain",
+			script:   smtptest.Script{CatchAll: true},
+			catchAll: true,
+			email:    "user@example.test",
+			category: CategoryRisky,
+			reason:   "catch_all",
+		},
+		{
+			name:     "greylisted recipient",
+			script:   smtptest.Script{CatchAll: true, Greylist: time.Minute},
+			email:    "user@example.test",
+			category: CategoryRisky,
+			reason:   "smtp_tempfail",
+		},
+		{
+			name: "multi-line banner",
+			script: smtptest.Script{
+				Banner:    smtptest.Reply{Code: 220, Lines: []string{"mx.example.test ESMTP", "no UCE"}},
+				Mailboxes: []string{"user@example.test"},
+			},
+			email:    "user@example.test",
+			category: CategoryValid,
+			reason:   "rcpt_ok",
+		},
+		{
+			name:     "tarpitted banner",
+			script:   smtptest.Script{Banner: smtptest.Reply{Code: 220, Lines: []string{"slow"}, Delay: time.Second}},
+			email:    "user@example.test",
+			category: CategoryRisky,
+			reason:   "smtp_timeout",
+		},
+		{
+			name:     "dropped after MAIL FROM",
+			script:   smtptest.Script{Mail: smtptest.Reply{Drop: true}},
+			email:    "user@example.test",
+			category: CategoryRisky,
+			reason:   "smtp_",
+		},
+		{
+			name:     "missing domain",
+			email:    "user@gone.test",
+			category: CategoryInvalid,
+			reason:   "mx_missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newSMTPTestPipeline(t, tt.script, tt.catchAll)
+			res := v.Verify(context.Background(), tt.email)
+
+			if res.Category != tt.category || !strings.HasPrefix(res.Reason, tt.reason) {
+				t.Fatalf("expected %s/%s*, got %s/%s", tt.category, tt.reason, res.Category, res.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strings"
+	"sync"
+)
+
+const (
+	SourceSelectionRoundRobin = "round_robin"
+	SourceSelectionSticky     = "sticky"
+
+	IPPreferenceAny  = "any"
+	IPPreferenceIPv4 = "ipv4"
+	IPPreferenceIPv6 = "ipv6"
+)
+
+type SourceAddressDialerConfig struct {
+	// Addresses are the local IPs probes may originate from.
+	Addresses []string
+	// ProviderAddresses restricts a provider profile to a subset of
+	// addresses. Providers without an entry use Addresses.
+	ProviderAddresses map[string][]string
+	// Selection is round_robin (default) or sticky. Sticky keeps each MX
+	// host on the same source address.
+	Selection string
+	// Preference orders address families: any (default), ipv4 or ipv6.
+	Preference string
+	// Resolver looks up MX host addresses. Nil uses net.DefaultResolver.
+	Resolver *net.Resolver
+}
+
+// SourceAddressDialer dials SMTP hosts from a configured set of local
+// addresses so each probe's originating IP can be chosen and traced.
+type SourceAddressDialer struct {
+	addresses         []net.IP
+	providerAddresses map[string][]net.IP
+	selection         string
+	preference        string
+	resolver          *net.Resolver
+	dial              func(ctx context.Context, dialer *net.Dialer, network, address string) (net.Conn, error)
+
+	mu      sync.Mutex
+	cursors map[string]int
+}
+
+type smtpDialProviderKey struct{}
+
+// withSMTPDialProvider tells a provider-aware dialer which provider profile
+// the connection is for.
+func withSMTPDialProvider(ctx context.Context, provider string) context.Context {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if provider == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, smtpDialProviderKey{}, provider)
+}
+
+func smtpDialProviderFrom(ctx context.Context) string {
+	provider, _ := ctx.Value(smtpDialProviderKey{}).(string)
+	return provider
+}
+
+func NewSourceAddressDialer(config SourceAddressDialerConfig) (*SourceAddressDialer, error) {
+	addresses, err := parseSourceAddresses(config.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, errors.New("source address dialer needs at least one address")
+	}
+
+	providerAddresses := make(map[string][]net.IP, len(config.ProviderAddresses))
+	for provider, values := range config.ProviderAddresses {
+		parsed, err := parseSourceAddresses(values)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", provider, err)
+		}
+		if len(parsed) > 0 {
+			providerAddresses[strings.ToLower(strings.TrimSpace(provider))] = parsed
+		}
+	}
+
+	selection := strings.ToLower(strings.TrimSpace(config.Selection))
+	if selection != SourceSelectionSticky {
+		selection = SourceSelectionRoundRobin
+	}
+
+	preference := strings.ToLower(strings.TrimSpace(config.Preference))
+	if preference != IPPreferenceIPv4 && preference != IPPreferenceIPv6 {
+		preference = IPPreferenceAny
+	}
+
+	resolver := config.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return &SourceAddressDialer{
+		addresses:         addresses,
+		providerAddresses: providerAddresses,
+		selection:         selection,
+		preference:        preference,
+		resolver:          resolver,
+		dial: func(ctx context.Context, dialer *net.Dialer, network, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		},
+		cursors: map[string]int{},
+	}, nil
+}
+
+// DialContext resolves the target host and connects to it from a source
+// address of a matching family, trying the preferred family first.
+func (d *SourceAddressDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := d.lookupTargets(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.dialTargets(ctx, network, host, port, targets)
+}
+
+func (d *SourceAddressDialer) dialTargets(ctx context.Context, network, host, port string, targets []net.IP) (net.Conn, error) {
+	provider := smtpDialProviderFrom(ctx)
+	if provider == "" {
+		provider = detectSMTPProviderProfile("", host, "")
+	}
+	sources := d.sourcesFor(provider)
+
+	var lastErr error
+	for _, family := range d.familyOrder() {
+		familySources := filterIPFamily(sources, family)
+		familyTargets := filterIPFamily(targets, family)
+		if len(familySources) == 0 || len(familyTargets) == 0 {
+			continue
+		}
+
+		source := d.pick(provider, host, familySources)
+		dialer := &net.Dialer{LocalAddr: &net.TCPAddr{IP: source}}
+		for _, target := range familyTargets {
+			conn, err := d.dial(ctx, dialer, network, net.JoinHostPort(target.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no source address matches the address family of %s", host)
+	}
+
+	return nil, lastErr
+}
+
+func (d *SourceAddressDialer) lookupTargets(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	addresses, err := d.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]net.IP, 0, len(addresses))
+	for _, address := range addresses {
+		targets = append(targets, address.IP)
+	}
+
+	return targets, nil
+}
+
+func (d *SourceAddressDialer) sourcesFor(provider string) []net.IP {
+	if addresses, ok := d.providerAddresses[provider]; ok {
+		return addresses
+	}
+
+	return d.addresses
+}
+
+func (d *SourceAddressDialer) familyOrder() []string {
+	if d.preference == IPPreferenceIPv6 {
+		return []string{IPPreferenceIPv6, IPPreferenceIPv4}
+	}
+
+	return []string{IPPreferenceIPv4, IPPreferenceIPv6}
+}
+
+// pick chooses a source address. Round robin rotates per provider and
+// family; sticky hashes the MX host so it always sees the same address.
+func (d *SourceAddressDialer) pick(provider, host string, sources []net.IP) net.IP {
+	if len(sources) == 1 {
+		return sources[0]
+	}
+
+	if d.selection == SourceSelectionSticky {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(strings.ToLower(host)))
+		return sources[int(hash.Sum32()%uint32(len(sources)))]
+	}
+
+	key := provider + "|" + ipFamily(sources[0])
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	index := d.cursors[key] % len(sources)
+	d.cursors[key] = index + 1
+
+	return sources[index]
+}
+
+func parseSourceAddresses(values []string) ([]net.IP, error) {
+	addresses := make([]net.IP, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source address %q", value)
+		}
+		addresses = append(addresses, ip)
+	}
+
+	return addresses, nil
+}
+
+func filterIPFamily(addresses []net.IP, family string) []net.IP {
+	filtered := make([]net.IP, 0, len(addresses))
+	for _, address := range addresses {
+		if ipFamily(address) == family {
+			filtered = append(filtered, address)
+		}
+	}
+
+	return filtered
+}
+
+func ipFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return IPPreferenceIPv4
+	}
+
+	return IPPreferenceIPv6
+}
+
+// localIP returns the local IP of conn, or an empty string when it is not a
+// TCP connection or goes through a proxy.
+func localIP(conn net.Conn) string {
+	if conn == nil {
+		return ""
+	}
+	if _, proxied := conn.(*proxiedConn); proxied {
+		return ""
+	}
+
+	if address, ok := conn.LocalAddr().(*net.TCPAddr); ok && address.IP != nil {
+		return address.IP.String()
+	}
+
+	return ""
+}
+
+// withSourceIP records the local address a probe was sent from.
+func withSourceIP(result Result, sourceIP string) Result {
+	if sourceIP == "" {
+		return result
+	}
+
+	if strings.TrimSpace(result.SourceIP) == "" {
+		result.SourceIP = sourceIP
+	}
+	if result.Evidence == nil {
+		result.Evidence = &ReplyEvidence{}
+	}
+	if strings.TrimSpace(result.Evidence.SourceIP) == "" {
+		result.Evidence.SourceIP = result.SourceIP
+	}
+
+	return result
+}
@@ -0,0 +1,228 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// localAddrConn reports the source address the dialer bound to, which a
+// net.Pipe connection cannot.
+type localAddrConn struct {
+	net.Conn
+	local net.Addr
+}
+
+func (c localAddrConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+type sourceDial struct {
+	source string
+	target string
+}
+
+type fakeSourceDials struct {
+	mu      sync.Mutex
+	dials   []sourceDial
+	refuse  map[string]bool
+	handler func(t *testing.T) net.Conn
+}
+
+func (f *fakeSourceDials) install(t *testing.T, dialer *SourceAddressDialer) {
+	dialer.dial = func(ctx context.Context, netDialer *net.Dialer, network, address string) (net.Conn, error) {
+		source := netDialer.LocalAddr.(*net.TCPAddr)
+		host, _, _ := net.SplitHostPort(address)
+
+		f.mu.Lock()
+		f.dials = append(f.dials, sourceDial{source: source.IP.String(), target: host})
+		f.mu.Unlock()
+
+		if f.refuse[host] {
+			return nil, errors.New("connection refused")
+		}
+		if f.handler == nil {
+			client, server := net.Pipe()
+			_ = server.Close()
+			return localAddrConn{Conn: client, local: source}, nil
+		}
+
+		return localAddrConn{Conn: f.handler(t), local: source}, nil
+	}
+}
+
+func (f *fakeSourceDials) sources() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sources := make([]string, 0, len(f.dials))
+	for _, dial := range f.dials {
+		sources = append(sources, dial.source)
+	}
+
+	return sources
+}
+
+func newTestSourceDialer(t *testing.T, config SourceAddressDialerConfig) (*SourceAddressDialer, *fakeSourceDials) {
+	t.Helper()
+
+	dialer, err := NewSourceAddressDialer(config)
+	if err != nil {
+		t.Fatalf("expected valid dialer config, got %v", err)
+	}
+	fake := &fakeSourceDials{refuse: map[string]bool{}}
+	fake.install(t, dialer)
+
+	return dialer, fake
+}
+
+func TestSourceAddressDialerRoundRobinsAddresses(t *testing.T) {
+	dialer, fake := newTestSourceDialer(t, SourceAddressDialerConfig{
+		Addresses: []string{"192.0.2.1", "192.0.2.2", "2001:db8::1"},
+	})
+
+	for i := 0; i < 4; i++ {
+		conn, err := dialer.DialContext(context.Background(), "tcp", "198.51.100.7:25")
+		if err != nil {
+			t.Fatalf("expected dial to succeed, got %v", err)
+		}
+		_ = conn.Close()
+	}
+
+	got := strings.Join(fake.sources(), ",")
+	if got != "192.0.2.1,192.0.2.2,192.0.2.1,192.0.2.2" {
+		t.Fatalf("expected IPv4 sources to rotate, got %s", got)
+	}
+}
+
+func TestSourceAddressDialerStickyKeepsHostOnOneAddress(t *testing.T) {
+	dialer, fake := newTestSourceDialer(t, SourceAddressDialerConfig{
+		Addresses: []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"},
+		Selection: SourceSelectionSticky,
+	})
+
+	for i := 0; i < 3; i++ {
+		conn, err := dialer.DialContext(context.Background(), "tcp", "198.51.100.7:25")
+		if err != nil {
+			t.Fatalf("expected dial to succeed, got %v", err)
+		}
+		_ = conn.Close()
+	}
+
+	sources := fake.sources()
+	if sources[0] != sources[1] || sources[1] != sources[2] {
+		t.Fatalf("expected sticky selection to reuse one source, got %v", sources)
+	}
+}
+
+func TestSourceAddressDialerPrefersIPv6AndFallsBackToIPv4(t *testing.T) {
+	dialer, fake := newTestSourceDialer(t, SourceAddressDialerConfig{
+		Addresses:  []string{"192.0.2.1", "2001:db8::1"},
+		Preference: IPPreferenceIPv6,
+	})
+	targets := []net.IP{net.ParseIP("198.51.100.7"), net.ParseIP("2001:db8:ff::7")}
+
+	conn, err := dialer.dialTargets(context.Background(), "tcp", "mx.test", "25", targets)
+	if err != nil {
+		t.Fatalf("expected dial to succeed, got %v", err)
+	}
+	_ = conn.Close()
+	if got := fake.dials[0]; got.source != "2001:db8::1" || got.target != "2001:db8:ff::7" {
+		t.Fatalf("expected IPv6 to be tried first, got %+v", got)
+	}
+
+	fake.refuse["2001:db8:ff::7"] = true
+	conn, err = dialer.dialTargets(context.Background(), "tcp", "mx.test", "25", targets)
+	if err != nil {
+		t.Fatalf("expected IPv4 fallback to succeed, got %v", err)
+	}
+	_ = conn.Close()
+	if got := fake.dials[len(fake.dials)-1]; got.source != "192.0.2.1" || got.target != "198.51.100.7" {
+		t.Fatalf("expected IPv4 fallback, got %+v", got)
+	}
+}
+
+func TestSourceAddressDialerUsesProviderAddresses(t *testing.T) {
+	dialer, fake := newTestSourceDialer(t, SourceAddressDialerConfig{
+		Addresses:         []string{"192.0.2.1"},
+		ProviderAddresses: map[string][]string{"Gmail": {"192.0.2.50"}},
+	})
+
+	ctx := withSMTPDialProvider(context.Background(), "gmail")
+	conn, err := dialer.DialContext(ctx, "tcp", "198.51.100.7:25")
+	if err != nil {
+		t.Fatalf("expected dial to succeed, got %v", err)
+	}
+	_ = conn.Close()
+
+	conn, err = dialer.DialContext(context.Background(), "tcp", "198.51.100.8:25")
+	if err != nil {
+		t.Fatalf("expected dial to succeed, got %v", err)
+	}
+	_ = conn.Close()
+
+	if got := strings.Join(fake.sources(), ","); got != "192.0.2.50,192.0.2.1" {
+		t.Fatalf("expected provider subset then default pool, got %s", got)
+	}
+}
+
+func TestSourceAddressDialerRejectsMismatchedFamily(t *testing.T) {
+	dialer, _ := newTestSourceDialer(t, SourceAddressDialerConfig{Addresses: []string{"2001:db8::1"}})
+
+	if _, err := dialer.DialContext(context.Background(), "tcp", "198.51.100.7:25"); err == nil {
+		t.Fatal("expected an IPv4-only target to fail without an IPv4 source")
+	}
+}
+
+func TestNewSourceAddressDialerValidatesAddresses(t *testing.T) {
+	if _, err := NewSourceAddressDialer(SourceAddressDialerConfig{}); err == nil {
+		t.Fatal("expected an error without addresses")
+	}
+	if _, err := NewSourceAddressDialer(SourceAddressDialerConfig{Addresses: []string{"not-an-ip"}}); err == nil {
+		t.Fatal("expected an error for an invalid address")
+	}
+	if _, err := NewSourceAddressDialer(SourceAddressDialerConfig{
+		Addresses:         []string{"192.0.2.1"},
+		ProviderAddresses: map[string][]string{"gmail": {"bad"}},
+	}); err == nil {
+		t.Fatal("expected an error for an invalid provider address")
+	}
+}
+
+func TestProberRecordsSourceIPInEvidence(t *testing.T) {
+	dialer, fake := newTestSourceDialer(t, SourceAddressDialerConfig{Addresses: []string{"192.0.2.9"}})
+	fake.handler = func(t *testing.T) net.Conn {
+		client, server := net.Pipe()
+		go runSMTPServer(t, server, func(line string) string {
+			if strings.HasPrefix(line, "RCPT TO") {
+				return "250 2.1.5 OK"
+			}
+			return "250 OK"
+		})
+		return client
+	}
+
+	prober := NetSMTPProber{
+		Dialer:          dialer,
+		ConnectTimeout:  time.Second,
+		ReadTimeout:     time.Second,
+		EhloTimeout:     time.Second,
+		HeloName:        "helo.test",
+		MailFromAddress: "probe@helo.test",
+	}
+
+	result := prober.Check(context.Background(), "198.51.100.7", "user@example.com")
+	if result.SourceIP != "192.0.2.9" {
+		t.Fatalf("expected source IP 192.0.2.9, got %q", result.SourceIP)
+	}
+	if result.Evidence == nil || result.Evidence.SourceIP != "192.0.2.9" {
+		t.Fatalf("expected source IP in evidence, got %+v", result.Evidence)
+	}
+	if attempt := attemptEvidenceFromResult(result); attempt.SourceIP != "192.0.2.9" {
+		t.Fatalf("expected source IP in attempt evidence, got %q", attempt.SourceIP)
+	}
+}
@@ -0,0 +1,239 @@
+package verifier
+
+import (
+	"net"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	maxAddressLength     = 254
+	maxLocalPartLength   = 64
+	maxDomainLength      = 253
+	maxDomainLabelLength = 63
+)
+
+// Syntax reason codes. Every code other than syntax_ip_literal makes the
+// address invalid.
+const (
+	SyntaxInvalid       = "syntax"
+	SyntaxTooLong       = "syntax_too_long"
+	SyntaxLocalTooLong  = "syntax_local_too_long"
+	SyntaxDotPosition   = "syntax_dot_position"
+	SyntaxBadChar       = "syntax_bad_char"
+	SyntaxBadQuote      = "syntax_bad_quote"
+	SyntaxDomainInvalid = "syntax_domain_invalid"
+	SyntaxDomainTooLong = "syntax_domain_too_long"
+	SyntaxLabelTooLong  = "syntax_label_too_long"
+	SyntaxIPLiteral     = "syntax_ip_literal"
+)
+
+// reasonSMTPUTF8Unsupported is returned when a UTF-8 address is probed on a
+// server that does not offer SMTPUTF8. The mailbox was never asked about, so
+// the result is risky rather than invalid.
+const reasonSMTPUTF8Unsupported = "smtputf8_unsupported"
+
+// AddressSyntax is an address that passed ValidateAddressSyntax. Domain is
+// in its ASCII (A-label) form.
+type AddressSyntax struct {
+	Local  string
+	Domain string
+	// SMTPUTF8 is set when the local part holds non-ASCII characters, so
+	// the address can only be delivered by servers offering RFC 6531
+	// SMTPUTF8.
+	SMTPUTF8 bool
+	// IPLiteral is set for domains written as [192.0.2.1] or [IPv6:...].
+	IPLiteral bool
+}
+
+// ValidateAddressSyntax checks address against the RFC 5322 addr-spec
+// grammar, extended with RFC 6531 UTF-8 characters, and the RFC 5321 length
+// limits. It returns a syntax reason code when the address is rejected.
+func ValidateAddressSyntax(address string) (AddressSyntax, string) {
+	address = strings.TrimSpace(address)
+	at := strings.LastIndex(address, "@")
+	if at <= 0 || at == len(address)-1 {
+		return AddressSyntax{}, SyntaxInvalid
+	}
+	if !utf8.ValidString(address) {
+		return AddressSyntax{}, SyntaxBadChar
+	}
+
+	local := address[:at]
+	domain := strings.TrimSuffix(address[at+1:], ".")
+
+	if len(local) > maxLocalPartLength {
+		return AddressSyntax{}, SyntaxLocalTooLong
+	}
+	if reason := validateLocalPart(local); reason != "" {
+		return AddressSyntax{}, reason
+	}
+
+	syntax := AddressSyntax{Local: local, SMTPUTF8: !isASCII(local)}
+
+	if strings.HasPrefix(domain, "[") {
+		if !validIPLiteral(domain) {
+			return AddressSyntax{}, SyntaxDomainInvalid
+		}
+		syntax.Domain = domain
+		syntax.IPLiteral = true
+		return syntax, SyntaxIPLiteral
+	}
+
+	asciiDomain, reason := validateDomain(domain)
+	if reason != "" {
+		return AddressSyntax{}, reason
+	}
+	syntax.Domain = asciiDomain
+
+	if len(local)+1+len(asciiDomain) > maxAddressLength {
+		return AddressSyntax{}, SyntaxTooLong
+	}
+
+	return syntax, ""
+}
+
+func validateLocalPart(local string) string {
+	if strings.HasPrefix(local, `"`) {
+		return validateQuotedLocalPart(local)
+	}
+
+	if strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		return SyntaxDotPosition
+	}
+	for _, r := range local {
+		if r != '.' && !isAtext(r) {
+			return SyntaxBadChar
+		}
+	}
+
+	return ""
+}
+
+// validateQuotedLocalPart checks an RFC 5322 quoted-string local part such
+// as "john smith" or "a\"b".
+func validateQuotedLocalPart(local string) string {
+	if len(local) < 2 || !strings.HasSuffix(local, `"`) {
+		return SyntaxBadQuote
+	}
+
+	content := local[1 : len(local)-1]
+	escaped := false
+	for _, r := range content {
+		switch {
+		case escaped:
+			if r < ' ' || r == 0x7f {
+				return SyntaxBadChar
+			}
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			return SyntaxBadQuote
+		case r == ' ' || r == '\t' || isQtext(r):
+		default:
+			return SyntaxBadChar
+		}
+	}
+	if escaped {
+		return SyntaxBadQuote
+	}
+
+	return ""
+}
+
+// validateDomain converts domain to its A-label form and checks RFC 1035
+// label rules on the result.
+func validateDomain(domain string) (string, string) {
+	if domain == "" {
+		return "", SyntaxInvalid
+	}
+
+	asciiDomain, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", SyntaxDomainInvalid
+	}
+	asciiDomain = strings.ToLower(asciiDomain)
+
+	if len(asciiDomain) > maxDomainLength {
+		return "", SyntaxDomainTooLong
+	}
+
+	labels := strings.Split(asciiDomain, ".")
+	if len(labels) < 2 {
+		return "", SyntaxDomainInvalid
+	}
+	for _, label := range labels {
+		if len(label) > maxDomainLabelLength {
+			return "", SyntaxLabelTooLong
+		}
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", SyntaxDomainInvalid
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' {
+				return "", SyntaxDomainInvalid
+			}
+		}
+	}
+
+	return asciiDomain, ""
+}
+
+func validIPLiteral(domain string) bool {
+	if !strings.HasSuffix(domain, "]") {
+		return false
+	}
+
+	literal := strings.ToLower(domain[1 : len(domain)-1])
+	if strings.HasPrefix(literal, "ipv6:") {
+		ip := net.ParseIP(literal[len("ipv6:"):])
+		return ip != nil && ip.To4() == nil
+	}
+
+	ip := net.ParseIP(literal)
+	return ip != nil && ip.To4() != nil
+}
+
+// isAtext reports whether r may appear in an RFC 5322 atom, including the
+// RFC 6531 UTF8-non-ascii extension.
+func isAtext(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case r >= utf8.RuneSelf:
+		return r != utf8.RuneError
+	}
+
+	return strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r)
+}
+
+func isQtext(r rune) bool {
+	if r >= utf8.RuneSelf {
+		return r != utf8.RuneError
+	}
+
+	return r == 33 || (r >= 35 && r <= 91) || (r >= 93 && r <= 126)
+}
+
+func isASCII(value string) bool {
+	for index := 0; index < len(value); index++ {
+		if value[index] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+// requiresSMTPUTF8 reports whether email has a non-ASCII local part.
+func requiresSMTPUTF8(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return !isASCII(email)
+	}
+
+	return !isASCII(email[:at])
+}
@@ -0,0 +1,107 @@
+package verifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateAddressSyntax(t *testing.T) {
+	cases := []struct {
+		address string
+		reason  string
+	}{
+		{"user@example.com", ""},
+		{"first.last+tag@sub.example.co.uk", ""},
+		{"o'brien@example.com", ""},
+		{`"john smith"@example.com`, ""},
+		{`"a\"b@c"@example.com`, ""},
+		{"user@bücher.de", ""},
+		{"用户@例子.广告", ""},
+		{"bad-email", SyntaxInvalid},
+		{"@example.com", SyntaxInvalid},
+		{"user@", SyntaxInvalid},
+		{strings.Repeat("a", 65) + "@example.com", SyntaxLocalTooLong},
+		{".user@example.com", SyntaxDotPosition},
+		{"user.@example.com", SyntaxDotPosition},
+		{"first..last@example.com", SyntaxDotPosition},
+		{"us er@example.com", SyntaxBadChar},
+		{"user(comment)@example.com", SyntaxBadChar},
+		{"user\xff@example.com", SyntaxBadChar},
+		{`"unterminated@example.com`, SyntaxBadQuote},
+		{`"a"b"@example.com`, SyntaxBadQuote},
+		{"user@-example.com", SyntaxDomainInvalid},
+		{"user@exa_mple.com", SyntaxDomainInvalid},
+		{"user@localhost", SyntaxDomainInvalid},
+		{"user@example..com", SyntaxDomainInvalid},
+		{"user@" + strings.Repeat("a", 64) + ".com", SyntaxLabelTooLong},
+		{"user@" + strings.Repeat(strings.Repeat("a", 60)+".", 5) + "com", SyntaxDomainTooLong},
+		{strings.Repeat("a", 64) + "@" + strings.Repeat(strings.Repeat("a", 60)+".", 4) + "com", SyntaxTooLong},
+		{"user@[192.0.2.1]", SyntaxIPLiteral},
+		{"user@[IPv6:2001:db8::1]", SyntaxIPLiteral},
+		{"user@[300.0.0.1]", SyntaxDomainInvalid},
+	}
+
+	for _, tc := range cases {
+		_, reason := ValidateAddressSyntax(tc.address)
+		if reason != tc.reason {
+			t.Fatalf("expected %q for %q, got %q", tc.reason, tc.address, reason)
+		}
+	}
+}
+
+func TestValidateAddressSyntaxFlagsSMTPUTF8(t *testing.T) {
+	syntax, reason := ValidateAddressSyntax("用户@bücher.de")
+	if reason != "" {
+		t.Fatalf("expected valid UTF-8 address, got %q", reason)
+	}
+	if !syntax.SMTPUTF8 || syntax.Domain != "xn--bcher-kva.de" {
+		t.Fatalf("expected SMTPUTF8 flag and A-label domain, got %+v", syntax)
+	}
+
+	syntax, _ = ValidateAddressSyntax("user@bücher.de")
+	if syntax.SMTPUTF8 {
+		t.Fatal("expected an ASCII local part with an IDN domain not to need SMTPUTF8")
+	}
+}
+
+func TestPipelineReturnsGranularSyntaxReasons(t *testing.T) {
+	v := newVerifier(&fakeResolver{}, &fakeSMTP{}, 1)
+
+	res := v.Verify(context.Background(), "first..last@example.com")
+	if res.Category != CategoryInvalid || res.Reason != SyntaxDotPosition {
+		t.Fatalf("expected invalid syntax_dot_position, got %s/%s", res.Category, res.Reason)
+	}
+
+	res = v.Verify(context.Background(), "user@[192.0.2.1]")
+	if res.Category != CategoryRisky || res.Reason != SyntaxIPLiteral {
+		t.Fatalf("expected risky syntax_ip_literal, got %s/%s", res.Category, res.Reason)
+	}
+}
+
+func TestProberDeclaresOrRequiresSMTPUTF8(t *testing.T) {
+	for _, offered := range []bool{true, false} {
+		dialer := &recordingSMTPDialer{t: t, handler: func(line string) string {
+			if strings.HasPrefix(line, "EHLO") && offered {
+				return "250-mx.test\r\n250 SMTPUTF8"
+			}
+			return ""
+		}}
+		prober := newPooledProber(dialer, nil)
+
+		result := prober.Check(context.Background(), "mx.test", "用户@example.com")
+		if offered {
+			if dialer.commandCount("MAIL FROM:<probe@helo.test> SMTPUTF8") != 1 || dialer.commandCount("RCPT TO:<用户@example.com>") != 1 {
+				t.Fatalf("expected SMTPUTF8 transaction with the UTF-8 recipient, got %s", result.Reason)
+			}
+			continue
+		}
+
+		if result.Category != CategoryRisky || result.Reason != reasonSMTPUTF8Unsupported || result.DecisionClass != DecisionUnknown {
+			t.Fatalf("expected risky smtputf8_unsupported without the extension, got %s/%s (%s)", result.Category, result.Reason, result.DecisionClass)
+		}
+		if dialer.commandCount("RCPT TO") != 0 {
+			t.Fatal("expected no RCPT for a UTF-8 address on a server without SMTPUTF8")
+		}
+	}
+}
@@ -6,15 +6,123 @@ import (
 	"time"
 )
 
+const defaultHostLimiterIdleTimeout = 10 * time.Minute
+
+// keyedSemaphores hands out per-key concurrency slots. Entries track how many
+// callers hold or wait on them so idle keys can be dropped without letting a
+// waiter slip past the limit on a fresh semaphore.
+type keyedSemaphores struct {
+	mu      sync.Mutex
+	entries map[string]*semaphoreEntry
+	now     func() time.Time
+	// dropReleased removes a key as soon as its last holder releases it.
+	dropReleased bool
+}
+
+type semaphoreEntry struct {
+	key     string
+	inUse   int
+	holders int
+	// released is closed and replaced whenever a slot frees up.
+	released chan struct{}
+	lastUsed time.Time
+}
+
+func newKeyedSemaphores(dropReleased bool) *keyedSemaphores {
+	return &keyedSemaphores{
+		entries:      make(map[string]*semaphoreEntry),
+		now:          time.Now,
+		dropReleased: dropReleased,
+	}
+}
+
+// acquire waits until fewer than limit slots on key are taken. Each caller
+// applies its own limit, so callers sharing a key with different limits each
+// get theirs and a changed limit takes effect on the next acquire.
+func (k *keyedSemaphores) acquire(ctx context.Context, key string, limit int) (func(), error) {
+	k.mu.Lock()
+	entry, ok := k.entries[key]
+	if !ok {
+		entry = &semaphoreEntry{key: key, released: make(chan struct{})}
+		k.entries[key] = entry
+	}
+	entry.holders++
+	for entry.inUse >= limit {
+		released := entry.released
+		k.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			k.mu.Lock()
+			k.doneLocked(entry)
+			k.mu.Unlock()
+			return nil, ctx.Err()
+		}
+
+		k.mu.Lock()
+	}
+	entry.inUse++
+	k.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			k.mu.Lock()
+			entry.inUse--
+			close(entry.released)
+			entry.released = make(chan struct{})
+			k.doneLocked(entry)
+			k.mu.Unlock()
+		})
+	}, nil
+}
+
+func (k *keyedSemaphores) doneLocked(entry *semaphoreEntry) {
+	entry.holders--
+	entry.lastUsed = k.now()
+	if k.dropReleased && entry.holders == 0 {
+		delete(k.entries, entry.key)
+	}
+}
+
+// evictIdle drops keys nobody holds or waits on that have been idle for at
+// least idleTimeout.
+func (k *keyedSemaphores) evictIdle(idleTimeout time.Duration) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	evicted := 0
+	for key, entry := range k.entries {
+		if entry.holders > 0 || now.Sub(entry.lastUsed) < idleTimeout {
+			continue
+		}
+		delete(k.entries, key)
+		evicted++
+	}
+
+	return evicted
+}
+
+func (k *keyedSemaphores) len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return len(k.entries)
+}
+
+// DomainLimiter caps concurrent probes per recipient domain. It lives for a
+// single chunk verifier, so unused domains are dropped as soon as they are
+// released.
 type DomainLimiter struct {
-	mu         sync.Mutex
-	semaphores map[string]chan struct{}
+	semaphores *keyedSemaphores
 	perDomain  int
 }
 
 func NewDomainLimiter(perDomain int) *DomainLimiter {
 	return &DomainLimiter{
-		semaphores: make(map[string]chan struct{}),
+		semaphores: newKeyedSemaphores(true),
 		perDomain:  perDomain,
 	}
 }
@@ -24,51 +132,355 @@ func (d *DomainLimiter) Acquire(ctx context.Context, domain string) (func(), err
 		return func() {}, nil
 	}
 
-	d.mu.Lock()
-	sem, ok := d.semaphores[domain]
-	if !ok {
-		sem = make(chan struct{}, d.perDomain)
-		d.semaphores[domain] = sem
+	return d.semaphores.acquire(ctx, domain, d.perDomain)
+}
+
+// HostLimiter caps concurrent probes per resolved MX host or provider
+// profile, so vanity domains hosted by the same provider share one limit.
+// It is shared across chunks; EvictIdle drops keys that have gone quiet.
+type HostLimiter struct {
+	semaphores   *keyedSemaphores
+	defaultLimit int
+	idleTimeout  time.Duration
+}
+
+func NewHostLimiter(defaultLimit int, idleTimeout time.Duration) *HostLimiter {
+	if idleTimeout <= 0 {
+		idleTimeout = defaultHostLimiterIdleTimeout
+	}
+
+	return &HostLimiter{
+		semaphores:   newKeyedSemaphores(false),
+		defaultLimit: defaultLimit,
+		idleTimeout:  idleTimeout,
 	}
-	d.mu.Unlock()
+}
 
-	select {
-	case sem <- struct{}{}:
-		return func() { <-sem }, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
+// Acquire waits for a slot on key. limit overrides the default limit for
+// this call; a non-positive result disables limiting.
+func (h *HostLimiter) Acquire(ctx context.Context, key string, limit int) (func(), error) {
+	if limit <= 0 && h != nil {
+		limit = h.defaultLimit
+	}
+	if h == nil || limit <= 0 || key == "" {
+		return func() {}, nil
 	}
+
+	return h.semaphores.acquire(ctx, key, limit)
+}
+
+// EvictIdle drops keys that have been idle for longer than the idle timeout.
+func (h *HostLimiter) EvictIdle() int {
+	if h == nil {
+		return 0
+	}
+
+	return h.semaphores.evictIdle(h.idleTimeout)
+}
+
+// Len returns the number of tracked keys.
+func (h *HostLimiter) Len() int {
+	if h == nil {
+		return 0
+	}
+
+	return h.semaphores.len()
+}
+
+// smtpThrottleKey groups hosts by provider profile when one is recognised
+// and by MX host otherwise. Concurrency and rate limits share the key.
+func smtpThrottleKey(providerProfile, host string) string {
+	provider := detectSMTPProviderProfile(providerProfile, host, "")
+	if provider != "" && provider != "generic" {
+		return providerCircuitKey(provider)
+	}
+
+	return mxCircuitKey(host)
+}
+
+const defaultRateLimiterIdleTimeout = 10 * time.Minute
+
+// globalRateLimiterKey names the bucket shared by every key. It cannot clash
+// with provider: or mx: keys.
+const globalRateLimiterKey = "global"
+
+type RateLimiterConfig struct {
+	// PerMinute is the default refill rate for keys without an override.
+	// Zero or less leaves those keys unlimited.
+	PerMinute int
+	// GlobalPerMinute caps connections across every key together. Zero or
+	// less disables the shared cap.
+	GlobalPerMinute int
+	// Burst is the bucket capacity. It defaults to one token.
+	Burst       int
+	IdleTimeout time.Duration
+}
+
+// RateLimiterStats summarises how long callers waited for a token.
+type RateLimiterStats struct {
+	Waits     int64
+	Throttled int64
+	TotalWait time.Duration
+	MaxWait   time.Duration
 }
 
+// RateLimiter is a token-bucket limiter with one bucket per provider or MX
+// key and a global bucket every key also draws from. Rates can change at any
+// time; buckets keep their tokens and refill at the new rate from then on.
 type RateLimiter struct {
-	tick *time.Ticker
+	mu          sync.Mutex
+	perMinute   int
+	global      int
+	burst       int
+	idleTimeout time.Duration
+	overrides   map[string]int
+	buckets     map[string]*tokenBucket
+	stats       RateLimiterStats
+	now         func() time.Time
 }
 
-func NewRateLimiter(perMinute int) *RateLimiter {
-	if perMinute <= 0 {
-		return nil
+type tokenBucket struct {
+	tokens   float64
+	updated  time.Time
+	lastUsed time.Time
+}
+
+func NewRateLimiter(config RateLimiterConfig) *RateLimiter {
+	if config.Burst <= 0 {
+		config.Burst = 1
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaultRateLimiterIdleTimeout
 	}
 
-	interval := time.Minute / time.Duration(perMinute)
-	return &RateLimiter{tick: time.NewTicker(interval)}
+	return &RateLimiter{
+		perMinute:   config.PerMinute,
+		global:      config.GlobalPerMinute,
+		burst:       config.Burst,
+		idleTimeout: config.IdleTimeout,
+		overrides:   map[string]int{},
+		buckets:     map[string]*tokenBucket{},
+		now:         time.Now,
+	}
 }
 
-func (r *RateLimiter) Wait(ctx context.Context) error {
+// SetRate changes the default rate and the burst for every bucket.
+func (r *RateLimiter) SetRate(perMinute, burst int) {
 	if r == nil {
-		return nil
+		return
 	}
 
-	select {
-	case <-r.tick.C:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refillAll()
+	r.perMinute = perMinute
+	if burst > 0 {
+		r.burst = burst
+	}
+}
+
+// SetGlobalRate changes the cap shared by every key. Zero or less removes
+// it.
+func (r *RateLimiter) SetGlobalRate(perMinute int) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refillAll()
+	r.global = perMinute
+}
+
+// SetKeyRates replaces every per-key override with rates, so keys missing
+// from rates fall back to the default rate. Rates of zero or less are skipped.
+func (r *RateLimiter) SetKeyRates(rates map[string]int) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refillAll()
+	r.overrides = make(map[string]int, len(rates))
+	for key, perMinute := range rates {
+		if key == "" || perMinute <= 0 {
+			continue
+		}
+		r.overrides[key] = perMinute
+	}
+}
+
+// SetKeyRate overrides the rate for one key. Zero or less removes the
+// override so the key falls back to the default rate.
+func (r *RateLimiter) SetKeyRate(key string, perMinute int) {
+	if r == nil || key == "" {
+		return
 	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if bucket, ok := r.buckets[key]; ok {
+		r.refill(key, bucket, r.now())
+	}
+	if perMinute <= 0 {
+		delete(r.overrides, key)
+		return
+	}
+	r.overrides[key] = perMinute
 }
 
-func (r *RateLimiter) Stop() {
+// Wait blocks until a token is available for key or ctx is done.
+func (r *RateLimiter) Wait(ctx context.Context, key string) error {
 	if r == nil {
+		return nil
+	}
+
+	started := r.now()
+	throttled := false
+	for {
+		r.mu.Lock()
+		delay, limited := r.take(key)
+		r.mu.Unlock()
+
+		if !limited || delay <= 0 {
+			r.recordWait(throttled, r.now().Sub(started))
+			return nil
+		}
+
+		throttled = true
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			r.recordWait(throttled, r.now().Sub(started))
+			return ctx.Err()
+		}
+	}
+}
+
+// take consumes a token from key's bucket and the global bucket when both
+// have one and otherwise returns how long until they do. limited is false
+// when neither bucket is limited.
+func (r *RateLimiter) take(key string) (time.Duration, bool) {
+	now := r.now()
+	limited := false
+	var delay time.Duration
+	buckets := make([]*tokenBucket, 0, 2)
+	for _, bucketKey := range []string{key, globalRateLimiterKey} {
+		perMinute := r.rateFor(bucketKey)
+		if perMinute <= 0 {
+			continue
+		}
+		limited = true
+
+		bucket, ok := r.buckets[bucketKey]
+		if !ok {
+			bucket = &tokenBucket{tokens: float64(r.burst), updated: now}
+			r.buckets[bucketKey] = bucket
+		}
+		r.refill(bucketKey, bucket, now)
+		bucket.lastUsed = now
+		buckets = append(buckets, bucket)
+
+		if bucket.tokens < 1 {
+			interval := time.Minute / time.Duration(perMinute)
+			if wait := time.Duration((1 - bucket.tokens) * float64(interval)); wait > delay {
+				delay = wait
+			}
+		}
+	}
+
+	if !limited || delay > 0 {
+		return delay, limited
+	}
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+
+	return 0, true
+}
+
+func (r *RateLimiter) rateFor(key string) int {
+	if key == globalRateLimiterKey {
+		return r.global
+	}
+	if perMinute, ok := r.overrides[key]; ok {
+		return perMinute
+	}
+
+	return r.perMinute
+}
+
+func (r *RateLimiter) refill(key string, bucket *tokenBucket, now time.Time) {
+	elapsed := now.Sub(bucket.updated)
+	bucket.updated = now
+	if perMinute := r.rateFor(key); perMinute > 0 && elapsed > 0 {
+		bucket.tokens += elapsed.Minutes() * float64(perMinute)
+	}
+	if bucket.tokens > float64(r.burst) {
+		bucket.tokens = float64(r.burst)
+	}
+}
+
+func (r *RateLimiter) refillAll() {
+	now := r.now()
+	for key, bucket := range r.buckets {
+		r.refill(key, bucket, now)
+	}
+}
+
+func (r *RateLimiter) recordWait(throttled bool, waited time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Waits++
+	if !throttled {
 		return
 	}
-	r.tick.Stop()
+	r.stats.Throttled++
+	r.stats.TotalWait += waited
+	if waited > r.stats.MaxWait {
+		r.stats.MaxWait = waited
+	}
+}
+
+// Stats returns the wait statistics gathered since the limiter was created.
+func (r *RateLimiter) Stats() RateLimiterStats {
+	if r == nil {
+		return RateLimiterStats{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.stats
+}
+
+// EvictIdle drops buckets that have not been used within the idle timeout.
+// A dropped bucket starts full again, as it would have refilled while idle.
+func (r *RateLimiter) EvictIdle() int {
+	if r == nil {
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	evicted := 0
+	for key, bucket := range r.buckets {
+		if now.Sub(bucket.lastUsed) < r.idleTimeout {
+			continue
+		}
+		delete(r.buckets, key)
+		evicted++
+	}
+
+	return evicted
 }
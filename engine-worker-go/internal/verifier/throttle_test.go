@@ -0,0 +1,280 @@
+package verifier
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type concurrencySMTP struct {
+	active  int32
+	peak    int32
+	release chan struct{}
+}
+
+func (c *concurrencySMTP) Check(ctx context.Context, host, email string) Result {
+	active := atomic.AddInt32(&c.active, 1)
+	for {
+		peak := atomic.LoadInt32(&c.peak)
+		if active <= peak || atomic.CompareAndSwapInt32(&c.peak, peak, active) {
+			break
+		}
+	}
+	<-c.release
+	atomic.AddInt32(&c.active, -1)
+
+	return Result{Category: CategoryValid, Reason: "smtp_connect_ok"}
+}
+
+func TestPipelineSharesHostLimitAcrossVanityDomains(t *testing.T) {
+	mx := []*net.MX{{Host: "aspmx.l.google.com.", Pref: 1}}
+	resolver := &fakeResolver{records: map[string][]*net.MX{
+		"alpha.test": mx,
+		"beta.test":  mx,
+		"gamma.test": mx,
+		"delta.test": mx,
+	}}
+	smtp := &concurrencySMTP{release: make(chan struct{})}
+
+	config := baseConfig(1)
+	config.PerDomainConcurrency = 2
+	config.HostLimiter = NewHostLimiter(2, time.Minute)
+	pipeline := NewPipelineVerifier(config, resolver, smtp)
+
+	var wg sync.WaitGroup
+	for _, domain := range []string{"alpha.test", "beta.test", "gamma.test", "delta.test"} {
+		wg.Add(1)
+		go func(domain string) {
+			defer wg.Done()
+			pipeline.Verify(context.Background(), "user@"+domain)
+		}(domain)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&smtp.active) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(smtp.release)
+	wg.Wait()
+
+	if peak := atomic.LoadInt32(&smtp.peak); peak != 2 {
+		t.Fatalf("expected shared host limit of 2, got peak %d", peak)
+	}
+}
+
+func TestHostLimiterKeysByProviderOrMXHost(t *testing.T) {
+	if key := smtpThrottleKey("", "acme-com.mail.protection.outlook.com"); key != "provider:microsoft" {
+		t.Fatalf("expected provider:microsoft, got %q", key)
+	}
+	if key := smtpThrottleKey("", "MX1.Example.NET."); key != "mx:mx1.example.net" {
+		t.Fatalf("expected mx:mx1.example.net, got %q", key)
+	}
+}
+
+func TestHostLimiterEvictsIdleKeys(t *testing.T) {
+	limiter := NewHostLimiter(1, time.Minute)
+	clock := time.Unix(1700000000, 0)
+	limiter.semaphores.now = func() time.Time { return clock }
+
+	release, err := limiter.Acquire(context.Background(), "mx:a.test", 0)
+	if err != nil {
+		t.Fatalf("unexpected acquire error: %v", err)
+	}
+	if evicted := limiter.EvictIdle(); evicted != 0 {
+		t.Fatalf("expected held key to survive eviction, got %d evicted", evicted)
+	}
+
+	release()
+	clock = clock.Add(30 * time.Second)
+	if evicted := limiter.EvictIdle(); evicted != 0 {
+		t.Fatalf("expected recently used key to survive eviction, got %d evicted", evicted)
+	}
+
+	clock = clock.Add(time.Minute)
+	if evicted := limiter.EvictIdle(); evicted != 1 || limiter.Len() != 0 {
+		t.Fatalf("expected idle key to be evicted, got %d evicted and %d left", evicted, limiter.Len())
+	}
+}
+
+func TestHostLimiterAppliesEachCallersLimit(t *testing.T) {
+	limiter := NewHostLimiter(8, time.Minute)
+
+	first, err := limiter.Acquire(context.Background(), "mx:a.test", 2)
+	if err != nil {
+		t.Fatalf("unexpected acquire error: %v", err)
+	}
+	if _, err := limiter.Acquire(context.Background(), "mx:a.test", 2); err != nil {
+		t.Fatalf("unexpected acquire error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "mx:a.test", 2); err == nil {
+		t.Fatal("expected a caller with limit 2 to wait while 2 slots are held")
+	}
+	if _, err := limiter.Acquire(context.Background(), "mx:a.test", 0); err != nil {
+		t.Fatalf("expected a caller with the default limit of 8 to get a slot, got %v", err)
+	}
+
+	first()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "mx:a.test", 2); err == nil {
+		t.Fatal("expected the lower limit to keep counting every holder on the key")
+	}
+}
+
+func TestHostLimiterReleasesWaiterOnContextCancel(t *testing.T) {
+	limiter := NewHostLimiter(1, time.Minute)
+	release, _ := limiter.Acquire(context.Background(), "mx:a.test", 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "mx:a.test", 0); err == nil {
+		t.Fatal("expected acquire to fail once the context expires")
+	}
+
+	release()
+	limiter.semaphores.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if evicted := limiter.EvictIdle(); evicted != 1 {
+		t.Fatalf("expected abandoned waiter not to pin the key, got %d evicted", evicted)
+	}
+}
+
+func TestDomainLimiterDropsReleasedDomains(t *testing.T) {
+	limiter := NewDomainLimiter(1)
+	for _, domain := range []string{"a.test", "b.test", "c.test"} {
+		release, err := limiter.Acquire(context.Background(), domain)
+		if err != nil {
+			t.Fatalf("unexpected acquire error: %v", err)
+		}
+		release()
+	}
+
+	if size := limiter.semaphores.len(); size != 0 {
+		t.Fatalf("expected released domains to be dropped, got %d", size)
+	}
+}
+
+func TestRateLimiterAllowsBurstThenRefills(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterConfig{PerMinute: 3000, Burst: 3})
+
+	started := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background(), "mx:a.test"); err != nil {
+			t.Fatalf("unexpected wait error: %v", err)
+		}
+	}
+	if elapsed := time.Since(started); elapsed > 10*time.Millisecond {
+		t.Fatalf("expected burst to pass without waiting, took %s", elapsed)
+	}
+
+	if err := limiter.Wait(context.Background(), "mx:a.test"); err != nil {
+		t.Fatalf("unexpected wait error: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed < 15*time.Millisecond {
+		t.Fatalf("expected fourth token to wait for a refill, took %s", elapsed)
+	}
+
+	stats := limiter.Stats()
+	if stats.Waits != 4 || stats.Throttled != 1 || stats.MaxWait <= 0 {
+		t.Fatalf("unexpected wait stats: %+v", stats)
+	}
+}
+
+func TestRateLimiterKeepsKeysIndependent(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterConfig{PerMinute: 1})
+
+	if err := limiter.Wait(context.Background(), "mx:a.test"); err != nil {
+		t.Fatalf("unexpected wait error: %v", err)
+	}
+	if err := limiter.Wait(context.Background(), "mx:b.test"); err != nil {
+		t.Fatalf("expected a separate bucket for another key, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "mx:a.test"); err == nil {
+		t.Fatal("expected exhausted bucket to block until the context expires")
+	}
+}
+
+func TestRateLimiterAppliesRateChanges(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterConfig{PerMinute: 1})
+	_ = limiter.Wait(context.Background(), "provider:gmail")
+
+	limiter.SetKeyRate("provider:gmail", 6000)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := limiter.Wait(ctx, "provider:gmail"); err != nil {
+		t.Fatalf("expected key override to speed up refills, got %v", err)
+	}
+
+	limiter.SetRate(0, 0)
+	_ = limiter.Wait(context.Background(), "mx:a.test")
+	if err := limiter.Wait(ctx, "mx:a.test"); err != nil {
+		t.Fatalf("expected zero default rate to disable limiting, got %v", err)
+	}
+}
+
+func TestRateLimiterGlobalRateCapsKeysTogether(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterConfig{PerMinute: 6000, GlobalPerMinute: 1})
+
+	if err := limiter.Wait(context.Background(), "mx:a.test"); err != nil {
+		t.Fatalf("unexpected wait error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "mx:b.test"); err == nil {
+		t.Fatal("expected a second key to wait for the global bucket")
+	}
+
+	limiter.SetGlobalRate(0)
+	if err := limiter.Wait(context.Background(), "mx:b.test"); err != nil {
+		t.Fatalf("expected keys to run independently without a global rate, got %v", err)
+	}
+}
+
+func TestRateLimiterSetKeyRatesReplacesOverrides(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterConfig{PerMinute: 1})
+	limiter.SetKeyRate("provider:gmail", 6000)
+	limiter.SetKeyRate("provider:yahoo", 6000)
+	limiter.SetKeyRates(map[string]int{"provider:gmail": 6000})
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := limiter.Wait(ctx, "provider:gmail")
+		cancel()
+		if err != nil {
+			t.Fatalf("expected the kept override to still apply, got %v", err)
+		}
+	}
+
+	_ = limiter.Wait(context.Background(), "provider:yahoo")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "provider:yahoo"); err == nil {
+		t.Fatal("expected the dropped key to fall back to the default rate")
+	}
+}
+
+func TestRateLimiterEvictsIdleBuckets(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterConfig{PerMinute: 60, IdleTimeout: time.Minute})
+	clock := time.Unix(1700000000, 0)
+	limiter.now = func() time.Time { return clock }
+
+	_ = limiter.Wait(context.Background(), "mx:a.test")
+	if evicted := limiter.EvictIdle(); evicted != 0 {
+		t.Fatalf("expected fresh bucket to be kept, got %d evicted", evicted)
+	}
+
+	clock = clock.Add(2 * time.Minute)
+	if evicted := limiter.EvictIdle(); evicted != 1 {
+		t.Fatalf("expected idle bucket to be evicted, got %d", evicted)
+	}
+}
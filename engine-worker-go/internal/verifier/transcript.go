@@ -0,0 +1,174 @@
+package verifier
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"strings"
+	"time"
+)
+
+// maxTranscriptEntries bounds the entries kept for one probe, so a session
+// sampling many catch-all recipients cannot grow a transcript unchecked.
+const maxTranscriptEntries = 64
+
+// TranscriptEntry is one command and the reply it got. The banner has no
+// command. Recipient addresses are replaced with their sha256 hash.
+type TranscriptEntry struct {
+	Attempt   int      `json:"attempt,omitempty"`
+	AtMS      int64    `json:"at_ms"`
+	Command   string   `json:"command,omitempty"`
+	Reply     []string `json:"reply,omitempty"`
+	LatencyMS int64    `json:"latency_ms,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// EmailHash returns the hex sha256 of the trimmed, lower-cased address, the
+// same hash Laravel stores for decision traces.
+func EmailHash(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+// smtpTranscript records the commands and replies on one session. The
+// handshake (banner, greeting, STARTTLS) is kept for the session's lifetime
+// so every probe on a pooled session carries it; entries hold the current
+// probe only.
+type smtpTranscript struct {
+	opened     time.Time
+	handshake  []TranscriptEntry
+	entries    []TranscriptEntry
+	recipients []string
+	sentAt     time.Time
+	now        func() time.Time
+}
+
+func newSMTPTranscript() *smtpTranscript {
+	return &smtpTranscript{opened: time.Now(), now: time.Now}
+}
+
+// transcriptOf returns the recorder of conn when it is a recording session.
+func transcriptOf(conn net.Conn) *smtpTranscript {
+	if session, ok := conn.(*smtpSession); ok {
+		return session.transcript
+	}
+
+	return nil
+}
+
+func (t *smtpTranscript) command(command string) {
+	if t == nil {
+		return
+	}
+
+	if strings.HasPrefix(strings.ToUpper(command), "RCPT TO:<") {
+		if end := strings.Index(command, ">"); end > len("RCPT TO:<") {
+			recipient := command[len("RCPT TO:<"):end]
+			t.recipients = append(t.recipients, recipient)
+			command = command[:len("RCPT TO:<")] + "sha256:" + EmailHash(recipient) + command[end:]
+		}
+	}
+
+	t.sentAt = t.now()
+	t.append(TranscriptEntry{AtMS: t.sentAt.Sub(t.opened).Milliseconds(), Command: command})
+}
+
+// reply attaches reply lines, or the read error, to the pending command, or
+// records them on their own when no command is waiting (the banner).
+func (t *smtpTranscript) reply(lines []string, err error) {
+	if t == nil {
+		return
+	}
+
+	now := t.now()
+	redacted := make([]string, 0, len(lines))
+	for _, line := range lines {
+		redacted = append(redacted, t.redact(line))
+	}
+
+	if last := len(t.entries) - 1; last >= 0 && t.entries[last].Command != "" && t.entries[last].Reply == nil && t.entries[last].Error == "" {
+		t.entries[last].Reply = redacted
+		t.entries[last].LatencyMS = now.Sub(t.sentAt).Milliseconds()
+		if err != nil {
+			t.entries[last].Error = err.Error()
+		}
+		return
+	}
+
+	entry := TranscriptEntry{AtMS: now.Sub(t.opened).Milliseconds(), Reply: redacted}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	t.append(entry)
+}
+
+func (t *smtpTranscript) append(entry TranscriptEntry) {
+	if len(t.entries) < maxTranscriptEntries {
+		t.entries = append(t.entries, entry)
+	}
+}
+
+// redact replaces recipient addresses echoed by the server with their hash.
+func (t *smtpTranscript) redact(line string) string {
+	for _, recipient := range t.recipients {
+		lower := strings.ToLower(line)
+		target := strings.ToLower(recipient)
+		for index := strings.Index(lower, target); index >= 0; index = strings.Index(lower, target) {
+			hashed := "sha256:" + EmailHash(recipient)
+			line = line[:index] + hashed + line[index+len(recipient):]
+			lower = lower[:index] + hashed + lower[index+len(recipient):]
+		}
+	}
+
+	return line
+}
+
+// endHandshake keeps the entries so far as the session's handshake.
+func (t *smtpTranscript) endHandshake() {
+	if t == nil {
+		return
+	}
+
+	t.handshake = append(t.handshake, t.entries...)
+	t.entries = nil
+}
+
+// begin starts the transcript of a new probe on the session.
+func (t *smtpTranscript) begin() {
+	if t == nil {
+		return
+	}
+
+	t.entries = nil
+	t.recipients = nil
+}
+
+func (t *smtpTranscript) snapshot() []TranscriptEntry {
+	if t == nil || len(t.handshake)+len(t.entries) == 0 {
+		return nil
+	}
+
+	entries := make([]TranscriptEntry, 0, len(t.handshake)+len(t.entries))
+	entries = append(entries, t.handshake...)
+
+	return append(entries, t.entries...)
+}
+
+// withAttemptTranscript numbers the entries of one attempt's transcript and
+// appends them to the transcript of the earlier attempts.
+func withAttemptTranscript(earlier, attempt []TranscriptEntry, attemptNumber int) []TranscriptEntry {
+	if len(earlier)+len(attempt) == 0 {
+		return nil
+	}
+
+	combined := make([]TranscriptEntry, 0, len(earlier)+len(attempt))
+	combined = append(combined, earlier...)
+	for _, entry := range attempt {
+		if entry.Attempt <= 0 {
+			entry.Attempt = attemptNumber
+		}
+		combined = append(combined, entry)
+	}
+
+	return combined
+}
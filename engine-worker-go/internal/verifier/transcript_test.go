@@ -0,0 +1,89 @@
+package verifier
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestSMTPProberRecordsRedactedTranscript(t *testing.T) {
+	client, server := net.Pipe()
+	go runSMTPServer(t, server, func(line string) string {
+		if strings.HasPrefix(line, "RCPT TO:") {
+			return "550 5.1.1 <User@Test.com>: Recipient address rejected"
+		}
+		return "250 OK"
+	})
+
+	prober := newProber(t, client)
+	prober.TranscriptEnabled = true
+	res := prober.Check(context.Background(), "mx.test", "user@test.com")
+
+	if len(res.Transcript) < 4 {
+		t.Fatalf("expected banner, EHLO, MAIL FROM and RCPT entries, got %+v", res.Transcript)
+	}
+	banner := res.Transcript[0]
+	if banner.Command != "" || len(banner.Reply) != 1 || banner.Reply[0] != "220 test.local ESMTP" {
+		t.Fatalf("expected the banner as the first entry, got %+v", banner)
+	}
+
+	hashed := "sha256:" + EmailHash("user@test.com")
+	var rcpt *TranscriptEntry
+	for i, entry := range res.Transcript {
+		if strings.Contains(strings.ToLower(entry.Command+strings.Join(entry.Reply, " ")), "user@test.com") {
+			t.Fatalf("expected the recipient redacted everywhere, got %+v", entry)
+		}
+		if strings.HasPrefix(entry.Command, "RCPT TO:") {
+			rcpt = &res.Transcript[i]
+		}
+	}
+	if rcpt == nil || rcpt.Command != "RCPT TO:<"+hashed+">" {
+		t.Fatalf("expected a hashed RCPT command, got %+v", rcpt)
+	}
+	if len(rcpt.Reply) != 1 || !strings.Contains(rcpt.Reply[0], "<"+hashed+">") {
+		t.Fatalf("expected the echoed recipient hashed in the reply, got %+v", rcpt.Reply)
+	}
+}
+
+func TestSMTPProberOmitsTranscriptWhenDisabled(t *testing.T) {
+	client, server := net.Pipe()
+	go runSMTPServer(t, server, func(line string) string { return "250 OK" })
+
+	res := newProber(t, client).Check(context.Background(), "mx.test", "user@test.com")
+	if res.Transcript != nil {
+		t.Fatalf("expected no transcript, got %+v", res.Transcript)
+	}
+}
+
+func TestPooledProberTranscriptKeepsHandshake(t *testing.T) {
+	dialer := &recordingSMTPDialer{t: t, handler: func(line string) string { return "250 OK" }}
+	pool := NewSMTPSessionPool(SMTPSessionPoolConfig{})
+	defer pool.Close()
+
+	prober := newPooledProber(dialer, pool)
+	prober.TranscriptEnabled = true
+	prober.Check(context.Background(), "mx.test", "a@test.com")
+	second := prober.Check(context.Background(), "mx.test", "b@test.com")
+
+	if len(second.Transcript) == 0 || second.Transcript[0].Reply[0] != "220 test.local ESMTP" {
+		t.Fatalf("expected the pooled probe to carry the handshake, got %+v", second.Transcript)
+	}
+	for _, entry := range second.Transcript {
+		if entry.Command == "RCPT TO:<sha256:"+EmailHash("a@test.com")+">" {
+			t.Fatalf("expected the earlier probe's commands dropped, got %+v", second.Transcript)
+		}
+	}
+}
+
+func TestWithAttemptTranscriptNumbersEntries(t *testing.T) {
+	first := withAttemptTranscript(nil, []TranscriptEntry{{Command: "EHLO helo.test"}}, 1)
+	combined := withAttemptTranscript(first, []TranscriptEntry{{Command: "EHLO helo.test"}, {Command: "QUIT"}}, 2)
+
+	if len(combined) != 3 || combined[0].Attempt != 1 || combined[1].Attempt != 2 || combined[2].Attempt != 2 {
+		t.Fatalf("expected entries numbered by attempt, got %+v", combined)
+	}
+	if withAttemptTranscript(nil, nil, 1) != nil {
+		t.Fatal("expected no transcript without entries")
+	}
+}
@@ -0,0 +1,267 @@
+package verifier
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TypoEngine suggests corrections for recipient domains that look like
+// misspellings of popular mailbox domains or well-known TLDs. Distances are
+// keyboard aware: hitting a neighbouring key or doubling a letter costs
+// half an edit.
+type TypoEngine struct {
+	domains []string
+	known   map[string]struct{}
+	tlds    map[string]struct{}
+	tldList []string
+}
+
+type TypoSuggestion struct {
+	Domain     string
+	Confidence float64
+}
+
+// NewTypoEngine builds an engine from popular domains, most used first, and
+// TLDs with or without the leading dot.
+func NewTypoEngine(domains []string, tlds []string) *TypoEngine {
+	engine := &TypoEngine{
+		known: map[string]struct{}{},
+		tlds:  map[string]struct{}{},
+	}
+
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" {
+			continue
+		}
+		if _, ok := engine.known[domain]; ok {
+			continue
+		}
+		engine.known[domain] = struct{}{}
+		engine.domains = append(engine.domains, domain)
+	}
+
+	for _, tld := range tlds {
+		tld = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(tld)), ".")
+		if tld == "" {
+			continue
+		}
+		if _, ok := engine.tlds[tld]; ok {
+			continue
+		}
+		engine.tlds[tld] = struct{}{}
+		engine.tldList = append(engine.tldList, tld)
+	}
+
+	return engine
+}
+
+// ParseTypoEngine reads one entry per line: domains in rank order and TLDs
+// prefixed with a dot. Blank lines and # comments are skipped.
+func ParseTypoEngine(data string) *TypoEngine {
+	domains, tlds := SplitTypoEntries(strings.Split(data, "\n"))
+
+	return NewTypoEngine(domains, tlds)
+}
+
+// SplitTypoEntries separates ranked domains from dot-prefixed TLDs.
+func SplitTypoEntries(entries []string) ([]string, []string) {
+	domains := []string{}
+	tlds := []string{}
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "", strings.HasPrefix(entry, "#"):
+		case strings.HasPrefix(entry, "."):
+			tlds = append(tlds, entry)
+		default:
+			domains = append(domains, entry)
+		}
+	}
+
+	return domains, tlds
+}
+
+// Len returns the number of ranked domains.
+func (e *TypoEngine) Len() int {
+	if e == nil {
+		return 0
+	}
+
+	return len(e.domains)
+}
+
+// Suggest returns the most likely intended domain. Popular domains are
+// tried first; otherwise a misspelled TLD is corrected.
+func (e *TypoEngine) Suggest(domain string) (TypoSuggestion, bool) {
+	domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	if e == nil || domain == "" {
+		return TypoSuggestion{}, false
+	}
+	if _, ok := e.known[domain]; ok {
+		return TypoSuggestion{}, false
+	}
+
+	if suggestion, ok := e.suggestDomain(domain); ok {
+		return suggestion, true
+	}
+
+	return e.suggestTLD(domain)
+}
+
+func (e *TypoEngine) suggestDomain(domain string) (TypoSuggestion, bool) {
+	best := ""
+	bestDistance := math.MaxFloat64
+	bestRank := 0
+	bestLimit := 0.0
+
+	for rank, candidate := range e.domains {
+		limit := typoDistanceLimit(candidate)
+		if math.Abs(float64(len(candidate)-len(domain))) > limit {
+			continue
+		}
+
+		distance := keyboardDistance(domain, candidate)
+		if distance > limit || distance >= bestDistance {
+			continue
+		}
+
+		best, bestDistance, bestRank, bestLimit = candidate, distance, rank, limit
+	}
+
+	if best == "" {
+		return TypoSuggestion{}, false
+	}
+
+	confidence := 1 - bestDistance/(bestLimit+1)
+	confidence *= 1 - 0.2*float64(bestRank)/float64(len(e.domains))
+
+	return TypoSuggestion{Domain: best, Confidence: roundConfidence(confidence)}, true
+}
+
+func (e *TypoEngine) suggestTLD(domain string) (TypoSuggestion, bool) {
+	dot := strings.LastIndex(domain, ".")
+	if dot <= 0 || dot == len(domain)-1 {
+		return TypoSuggestion{}, false
+	}
+
+	name, tld := domain[:dot], domain[dot+1:]
+	if _, ok := e.tlds[tld]; ok {
+		return TypoSuggestion{}, false
+	}
+
+	best := ""
+	bestDistance := math.MaxFloat64
+	for _, candidate := range e.tldList {
+		distance := keyboardDistance(tld, candidate)
+		if distance > 1 || distance >= bestDistance {
+			continue
+		}
+		best, bestDistance = candidate, distance
+	}
+
+	if best == "" {
+		return TypoSuggestion{}, false
+	}
+
+	return TypoSuggestion{Domain: name + "." + best, Confidence: roundConfidence(0.8 - bestDistance*0.3)}, true
+}
+
+// typoDistanceLimit allows one edit for short domains and two for longer
+// ones.
+func typoDistanceLimit(domain string) float64 {
+	if len(domain) <= 8 {
+		return 1
+	}
+
+	return 2
+}
+
+func roundConfidence(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// typoReason formats the risky reason for a suggestion.
+func typoReason(suggestion TypoSuggestion) string {
+	return fmt.Sprintf("domain_typo_suspected:suggest=%s;typo_confidence=%.2f", suggestion.Domain, suggestion.Confidence)
+}
+
+// keyboardDistance is an optimal string alignment distance where
+// substituting a neighbouring key and repeating the previous letter cost
+// half an edit.
+func keyboardDistance(from, to string) float64 {
+	a, b := []rune(from), []rune(to)
+	rows := make([][]float64, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]float64, len(b)+1)
+		rows[i][0] = float64(i)
+	}
+	for j := range rows[0] {
+		rows[0][j] = float64(j)
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			substitution := 0.0
+			if a[i-1] != b[j-1] {
+				substitution = 1
+				if keysAdjacent(a[i-1], b[j-1]) {
+					substitution = 0.5
+				}
+			}
+
+			deletion := 1.0
+			if i > 1 && a[i-1] == a[i-2] {
+				deletion = 0.5
+			}
+			insertion := 1.0
+			if j > 1 && b[j-1] == b[j-2] {
+				insertion = 0.5
+			}
+
+			best := math.Min(rows[i-1][j]+deletion, rows[i][j-1]+insertion)
+			best = math.Min(best, rows[i-1][j-1]+substitution)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				best = math.Min(best, rows[i-2][j-2]+1)
+			}
+			rows[i][j] = best
+		}
+	}
+
+	return rows[len(a)][len(b)]
+}
+
+var qwertyRows = []string{"1234567890-", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// qwertyRowOffsets stagger the rows the way a physical keyboard does.
+var qwertyRowOffsets = []float64{0, 0.5, 0.75, 1.25}
+
+type keyPosition struct {
+	x float64
+	y float64
+}
+
+var qwertyPositions = func() map[rune]keyPosition {
+	positions := map[rune]keyPosition{}
+	for row, keys := range qwertyRows {
+		for column, key := range keys {
+			positions[key] = keyPosition{x: float64(column) + qwertyRowOffsets[row], y: float64(row)}
+		}
+	}
+
+	return positions
+}()
+
+func keysAdjacent(a, b rune) bool {
+	first, ok := qwertyPositions[a]
+	if !ok {
+		return false
+	}
+	second, ok := qwertyPositions[b]
+	if !ok {
+		return false
+	}
+
+	return math.Abs(first.y-second.y) <= 1 && math.Abs(first.x-second.x) <= 1
+}
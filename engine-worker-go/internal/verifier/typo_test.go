@@ -0,0 +1,135 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestKeyboardDistanceDiscountsAdjacentKeysAndDoubledLetters(t *testing.T) {
+	cases := []struct {
+		from     string
+		to       string
+		expected float64
+	}{
+		{"gmail.com", "gmail.com", 0},
+		{"gmsil.com", "gmail.com", 0.5},
+		{"gmpil.com", "gmail.com", 1},
+		{"gmaill.com", "gmail.com", 0.5},
+		{"gmial.com", "gmail.com", 1},
+		{"gmal.com", "gmail.com", 1},
+	}
+
+	for _, tc := range cases {
+		if distance := keyboardDistance(tc.from, tc.to); distance != tc.expected {
+			t.Fatalf("expected distance %.1f from %s to %s, got %.1f", tc.expected, tc.from, tc.to, distance)
+		}
+	}
+}
+
+func TestTypoEngineSuggest(t *testing.T) {
+	engine := NewTypoEngine([]string{"gmail.com", "yahoo.com", "hotmail.com", "outlook.com"}, []string{".com", ".net", "org"})
+
+	cases := map[string]string{
+		"gmial.com":   "gmail.com",
+		"gmail.con":   "gmail.com",
+		"GMAIL.CMO":   "gmail.com",
+		"yahooo.com":  "yahoo.com",
+		"hotmial.com": "hotmail.com",
+		"company.cmo": "company.com",
+		"company.nte": "company.net",
+	}
+	for domain, expected := range cases {
+		suggestion, ok := engine.Suggest(domain)
+		if !ok || suggestion.Domain != expected {
+			t.Fatalf("expected %s for %s, got %+v (%t)", expected, domain, suggestion, ok)
+		}
+		if suggestion.Confidence <= 0 || suggestion.Confidence > 1 {
+			t.Fatalf("expected confidence in (0, 1] for %s, got %.2f", domain, suggestion.Confidence)
+		}
+	}
+
+	for _, domain := range []string{"gmail.com", "company.com", "example.org", "zzzzzz.qqq", ""} {
+		if suggestion, ok := engine.Suggest(domain); ok {
+			t.Fatalf("expected no suggestion for %q, got %+v", domain, suggestion)
+		}
+	}
+
+	var missing *TypoEngine
+	if _, ok := missing.Suggest("gmial.com"); ok {
+		t.Fatal("expected a nil engine to suggest nothing")
+	}
+}
+
+func TestTypoEngineConfidenceFavoursCloserAndHigherRankedDomains(t *testing.T) {
+	engine := NewTypoEngine([]string{"gmail.com", "yahoo.com"}, nil)
+
+	adjacent, _ := engine.Suggest("gmsil.com")
+	distant, _ := engine.Suggest("gmpil.com")
+	if adjacent.Confidence <= distant.Confidence {
+		t.Fatalf("expected adjacent-key typo to score higher, got %.2f <= %.2f", adjacent.Confidence, distant.Confidence)
+	}
+
+	top, _ := engine.Suggest("gmpil.com")
+	lower, _ := engine.Suggest("yahxo.com")
+	if top.Confidence <= lower.Confidence {
+		t.Fatalf("expected higher ranked domain to score higher, got %.2f <= %.2f", top.Confidence, lower.Confidence)
+	}
+}
+
+func TestParseTypoEngineSkipsCommentsAndSplitsTLDs(t *testing.T) {
+	engine := ParseTypoEngine("# popular\ngmail.com\n\n.com\n.net\n")
+	if engine.Len() != 1 {
+		t.Fatalf("expected one ranked domain, got %d", engine.Len())
+	}
+	if _, ok := engine.tlds["net"]; !ok {
+		t.Fatal("expected .net to be parsed as a TLD")
+	}
+}
+
+func TestPipelineSuggestsTypoOnlyWithoutMX(t *testing.T) {
+	config := baseConfig(1)
+	config.TypoEngine = NewTypoEngine([]string{"gmail.com"}, []string{".com"})
+
+	resolver := &fakeResolver{
+		records: map[string][]*net.MX{"gmial.com.au": {{Host: "mx.gmial.com.au", Pref: 10}}},
+		errs:    map[string]error{"gmaul.com": errors.New("SERVFAIL")},
+	}
+	v := NewPipelineVerifier(config, resolver, &fakeSMTP{})
+
+	res := v.Verify(context.Background(), "user@gmial.com")
+	if res.Category != CategoryRisky || !strings.HasPrefix(res.Reason, "domain_typo_suspected:suggest=gmail.com;typo_confidence=") {
+		t.Fatalf("expected typo suggestion for a domain without MX, got %s/%s", res.Category, res.Reason)
+	}
+
+	res = v.Verify(context.Background(), "user@gmial.com.au")
+	if strings.HasPrefix(res.Reason, "domain_typo_suspected") {
+		t.Fatalf("expected no typo suggestion for a domain with MX, got %s", res.Reason)
+	}
+
+	res = v.Verify(context.Background(), "user@gmaul.com")
+	if strings.HasPrefix(res.Reason, "domain_typo_suspected") {
+		t.Fatalf("expected no typo suggestion during a DNS outage, got %s", res.Reason)
+	}
+}
+
+func TestPipelineSuggestsTypoOnlyWhenImplicitMXFails(t *testing.T) {
+	config := baseConfig(1)
+	config.TypoEngine = NewTypoEngine([]string{"gmail.com"}, []string{".com"})
+
+	resolver := &fakeHostResolver{hosts: map[string][]string{"gmial.com": {"192.0.2.10"}}}
+	smtp := &fakeSMTP{results: map[string]Result{"gmial.com": {Category: CategoryValid, Reason: "rcpt_ok"}}}
+	v := NewPipelineVerifier(config, resolver, smtp)
+
+	res := v.Verify(context.Background(), "user@gmial.com")
+	if res.Category != CategoryValid || res.Reason != "rcpt_ok" {
+		t.Fatalf("expected a domain with an A record to be probed instead of flagged, got %s/%s", res.Category, res.Reason)
+	}
+
+	res = v.Verify(context.Background(), "user@gmaol.com")
+	if res.Category != CategoryRisky || !strings.HasPrefix(res.Reason, "domain_typo_suspected:suggest=gmail.com;typo_confidence=") {
+		t.Fatalf("expected typo suggestion once the address lookup also fails, got %s/%s", res.Category, res.Reason)
+	}
+}
@@ -1,17 +1,21 @@
 package verifier
 
-import "context"
+import (
+	"context"
+
+	"engine-worker-go/replypolicy"
+)
 
 const (
-	CategoryValid   = "valid"
-	CategoryInvalid = "invalid"
-	CategoryRisky   = "risky"
+	CategoryValid   = replypolicy.CategoryValid
+	CategoryInvalid = replypolicy.CategoryInvalid
+	CategoryRisky   = replypolicy.CategoryRisky
 
-	DecisionDeliverable   = "deliverable"
-	DecisionUndeliverable = "undeliverable"
-	DecisionRetryable     = "retryable"
-	DecisionPolicyBlocked = "policy_blocked"
-	DecisionUnknown       = "unknown"
+	DecisionDeliverable   = replypolicy.DecisionDeliverable
+	DecisionUndeliverable = replypolicy.DecisionUndeliverable
+	DecisionRetryable     = replypolicy.DecisionRetryable
+	DecisionPolicyBlocked = replypolicy.DecisionPolicyBlocked
+	DecisionUnknown       = replypolicy.DecisionUnknown
 )
 
 type Result struct {
@@ -33,8 +37,17 @@ type Result struct {
 	RetryAfterSecond   int
 	PolicyVersion      string
 	MatchedRuleID      string
+	RuleCaptures       map[string]string
 	DecisionConfidence string
 	RetryStrategy      string
+	SourceIP           string
+	SMTPUTF8           bool
+	FreeMail           bool
+	DomainAuth         *DomainAuth
+	Score              int
+	ScoreFactors       []ScoreFactor
+	ScoreModel         string
+	Transcript         []TranscriptEntry
 	Evidence           *ReplyEvidence
 }
 
@@ -51,6 +64,7 @@ type AttemptEvidence struct {
 	ProviderProfile  string `json:"provider_profile,omitempty"`
 	ConfidenceHint   string `json:"confidence_hint,omitempty"`
 	EvidenceStrength string `json:"evidence_strength,omitempty"`
+	SourceIP         string `json:"source_ip,omitempty"`
 }
 
 type Verifier interface {
@@ -76,9 +90,30 @@ type Config struct {
 	DisposableDomains           map[string]struct{}
 	RoleAccounts                map[string]struct{}
 	RoleAccountsBehavior        string
+	FreeMailDomains             map[string]struct{}
+	DomainLists                 *DomainLists
+	MXInfrastructure            *MXInfrastructure
+	DomainAuth                  *DomainAuthCache
 	CatchAllDetectionEnabled    bool
+	CatchAllCache               *CatchAllCache
+	CatchAllSamples             int
 	DomainTypos                 map[string]string
+	TypoEngine                  *TypoEngine
 	ProviderPolicyEngineEnabled bool
 	AdaptiveRetryEnabled        bool
 	ProviderReplyPolicyEngine   *ProviderReplyPolicyEngine
+	ProviderProfile             string
+	CircuitBreaker              *CircuitBreaker
+	HostLimiter                 *HostLimiter
+	RateLimiter                 *RateLimiter
+	SMTPSessionPool             *SMTPSessionPool
+	SMTPDialer                  SMTPDialer
+	SMTPProxy                   string
+	SessionMaxConcurrency       int
+	SMTPTLSMode                 string
+	IdentityDomain              string
+	SMTPTranscriptEnabled       bool
+	ReplyCorpus                 *ReplyCorpus
+	// ScoringEnabled attaches a deliverability score to every result.
+	ScoringEnabled bool
 }
@@ -22,6 +22,20 @@ func (f *fakeResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX,
 	return []*net.MX{}, nil
 }
 
+// fakeHostResolver adds address lookups to fakeResolver so the pipeline can
+// fall back to implicit MX delivery.
+type fakeHostResolver struct {
+	fakeResolver
+	hosts map[string][]string
+}
+
+func (f *fakeHostResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addresses, ok := f.hosts[host]; ok {
+		return addresses, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
 type fakeSMTP struct {
 	results map[string]Result
 	calls   map[string]int
@@ -86,6 +100,75 @@ func TestPipelineMXMissing(t *testing.T) {
 	}
 }
 
+func TestPipelineNullMX(t *testing.T) {
+	resolver := &fakeResolver{records: map[string][]*net.MX{
+		"nullmx.local": {{Host: ".", Pref: 0}},
+	}}
+	smtp := &fakeSMTP{}
+	v := newVerifier(resolver, smtp, 1)
+
+	res := v.Verify(context.Background(), "test@nullmx.local")
+	if res.Category != CategoryInvalid || res.Reason != "null_mx" {
+		t.Fatalf("expected null_mx invalid, got %s/%s", res.Category, res.Reason)
+	}
+	if len(res.AttemptChain) != 1 || res.AttemptChain[0].AttemptRoute != "null_mx:nullmx.local" {
+		t.Fatalf("expected null_mx attempt in chain, got %+v", res.AttemptChain)
+	}
+	if len(smtp.calls) != 0 {
+		t.Fatalf("expected no SMTP probe for null MX, got %v", smtp.calls)
+	}
+}
+
+func TestPipelineIgnoresNullMXMixedWithRealExchanges(t *testing.T) {
+	resolver := &fakeResolver{records: map[string][]*net.MX{
+		"mixed.local": {{Host: ".", Pref: 0}, {Host: "mx.mixed.local", Pref: 10}},
+	}}
+	smtp := &fakeSMTP{results: map[string]Result{
+		"mx.mixed.local": {Category: CategoryValid, Reason: "smtp_connect_ok"},
+	}}
+	v := newVerifier(resolver, smtp, 1)
+
+	res := v.Verify(context.Background(), "test@mixed.local")
+	if res.Category != CategoryValid || smtp.calls["mx.mixed.local"] != 1 {
+		t.Fatalf("expected the real exchange to be probed, got %s/%s and %v", res.Category, res.Reason, smtp.calls)
+	}
+}
+
+func TestPipelineImplicitMXFallsBackToAddressRecords(t *testing.T) {
+	resolver := &fakeHostResolver{
+		fakeResolver: fakeResolver{errs: map[string]error{
+			"implicit.local": &net.DNSError{Err: "no such host", Name: "implicit.local", IsNotFound: true},
+		}},
+		hosts: map[string][]string{"implicit.local": {"192.0.2.10"}},
+	}
+	smtp := &fakeSMTP{results: map[string]Result{
+		"implicit.local": {Category: CategoryValid, Reason: "smtp_connect_ok"},
+	}}
+	v := newVerifier(resolver, smtp, 1)
+
+	res := v.Verify(context.Background(), "test@implicit.local")
+	if res.Category != CategoryValid || res.Reason != "smtp_connect_ok" {
+		t.Fatalf("expected implicit MX probe to succeed, got %s/%s", res.Category, res.Reason)
+	}
+	if len(res.AttemptChain) != 1 || res.AttemptChain[0].AttemptRoute != "implicit_mx:implicit.local" {
+		t.Fatalf("expected implicit_mx attempt in chain, got %+v", res.AttemptChain)
+	}
+}
+
+func TestPipelineImplicitMXWithoutAddressIsMissing(t *testing.T) {
+	resolver := &fakeHostResolver{fakeResolver: fakeResolver{records: map[string][]*net.MX{}}}
+	smtp := &fakeSMTP{}
+	v := newVerifier(resolver, smtp, 1)
+
+	res := v.Verify(context.Background(), "test@nowhere.local")
+	if res.Category != CategoryInvalid || res.Reason != "mx_missing" {
+		t.Fatalf("expected mx_missing invalid, got %s/%s", res.Category, res.Reason)
+	}
+	if len(smtp.calls) != 0 {
+		t.Fatalf("expected no SMTP probe without an address, got %v", smtp.calls)
+	}
+}
+
 func TestPipelineDNSTimeout(t *testing.T) {
 	resolver := &fakeResolver{errs: map[string]error{"timeout.local": timeoutError{}}}
 	v := newVerifier(resolver, &fakeSMTP{}, 1)
@@ -0,0 +1,129 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"engine-worker-go/internal/verifier"
+)
+
+const maxChunkParallelism = 256
+
+// verifyChunkLines verifies every line with up to parallelism concurrent
+// workers and returns the results in input order. Lines are dispatched
+// round-robin across recipient domains so a chunk dominated by one domain
+// cannot park every worker on that domain's DomainLimiter semaphore. Once
+// ctx is done no further lines are dispatched and their results are left
+// empty; the chunk is not completed in that case.
+func verifyChunkLines(
+	ctx context.Context,
+	lines []string,
+	engineVerifier verifier.Verifier,
+	parallelism int,
+) []verifier.Result {
+	results := make([]verifier.Result, len(lines))
+	if len(lines) == 0 {
+		return results
+	}
+
+	parallelism = normalizeChunkParallelism(parallelism)
+	if parallelism > len(lines) {
+		parallelism = len(lines)
+	}
+
+	if parallelism == 1 {
+		for index, line := range lines {
+			if ctx.Err() != nil {
+				break
+			}
+			results[index] = engineVerifier.Verify(ctx, line)
+		}
+
+		return results
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for index := range jobs {
+				results[index] = engineVerifier.Verify(ctx, lines[index])
+			}
+		}()
+	}
+
+	dispatchChunkLines(ctx, jobs, domainInterleavedOrder(lines))
+	close(jobs)
+
+	wg.Wait()
+
+	return results
+}
+
+// dispatchChunkLines queues each index of order on jobs, stopping once ctx
+// is done instead of waiting for a worker to take the next one.
+func dispatchChunkLines(ctx context.Context, jobs chan<- int, order []int) {
+	for _, index := range order {
+		select {
+		case jobs <- index:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// domainInterleavedOrder returns line indexes ordered so that consecutive
+// entries rotate through recipient domains, preserving input order within
+// each domain.
+func domainInterleavedOrder(lines []string) []int {
+	buckets := map[string][]int{}
+	domains := make([]string, 0)
+
+	for index, line := range lines {
+		domain := lineDomain(line)
+		if _, ok := buckets[domain]; !ok {
+			domains = append(domains, domain)
+		}
+		buckets[domain] = append(buckets[domain], index)
+	}
+
+	order := make([]int, 0, len(lines))
+	for round := 0; len(domains) > 0; round++ {
+		remaining := domains[:0]
+		for _, domain := range domains {
+			bucket := buckets[domain]
+			order = append(order, bucket[round])
+			if round+1 < len(bucket) {
+				remaining = append(remaining, domain)
+			}
+		}
+		domains = remaining
+	}
+
+	return order
+}
+
+func lineDomain(line string) string {
+	at := strings.LastIndex(line, "@")
+	if at == -1 || at+1 >= len(line) {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSpace(line[at+1:]))
+}
+
+func normalizeChunkParallelism(value int) int {
+	if value < 1 {
+		return 1
+	}
+	if value > maxChunkParallelism {
+		return maxChunkParallelism
+	}
+
+	return value
+}
@@ -0,0 +1,196 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"engine-worker-go/internal/verifier"
+)
+
+type concurrencyTrackingVerifier struct {
+	active    int64
+	maxActive int64
+}
+
+func (v *concurrencyTrackingVerifier) Verify(_ context.Context, email string) verifier.Result {
+	current := atomic.AddInt64(&v.active, 1)
+	for {
+		observed := atomic.LoadInt64(&v.maxActive)
+		if current <= observed || atomic.CompareAndSwapInt64(&v.maxActive, observed, current) {
+			break
+		}
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	atomic.AddInt64(&v.active, -1)
+
+	category := verifier.CategoryValid
+	if strings.HasPrefix(email, "bad") {
+		category = verifier.CategoryInvalid
+	}
+
+	return verifier.Result{Category: category, Reason: "checked:" + email}
+}
+
+func TestVerifyChunkLinesPreservesInputOrder(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{
+		"a@one.test",
+		"bad-b@one.test",
+		"c@two.test",
+		"d@three.test",
+		"bad-e@two.test",
+		"f@one.test",
+	}
+
+	tracking := &concurrencyTrackingVerifier{}
+	results := verifyChunkLines(context.Background(), lines, tracking, 3)
+
+	if len(results) != len(lines) {
+		t.Fatalf("expected %d results, got %d", len(lines), len(results))
+	}
+	for index, line := range lines {
+		if results[index].Reason != "checked:"+line {
+			t.Fatalf("expected result %d to belong to %q, got %q", index, line, results[index].Reason)
+		}
+	}
+	if max := atomic.LoadInt64(&tracking.maxActive); max > 3 {
+		t.Fatalf("expected at most 3 concurrent verifications, got %d", max)
+	}
+	if max := atomic.LoadInt64(&tracking.maxActive); max < 2 {
+		t.Fatalf("expected verifications to overlap, got max concurrency %d", max)
+	}
+}
+
+func TestVerifyChunkLinesSequentialWhenParallelismDisabled(t *testing.T) {
+	t.Parallel()
+
+	tracking := &concurrencyTrackingVerifier{}
+	verifyChunkLines(context.Background(), []string{"a@one.test", "b@two.test", "c@three.test"}, tracking, 0)
+
+	if max := atomic.LoadInt64(&tracking.maxActive); max != 1 {
+		t.Fatalf("expected sequential verification, got max concurrency %d", max)
+	}
+}
+
+// contextBlockingVerifier holds every verification until ctx is done.
+type contextBlockingVerifier struct {
+	calls int64
+}
+
+func (v *contextBlockingVerifier) Verify(ctx context.Context, email string) verifier.Result {
+	atomic.AddInt64(&v.calls, 1)
+	<-ctx.Done()
+
+	return verifier.Result{Category: verifier.CategoryRisky, Reason: "smtp_timeout"}
+}
+
+func TestVerifyChunkLinesStopsDispatchingWhenContextIsDone(t *testing.T) {
+	t.Parallel()
+
+	lines := make([]string, 50)
+	for index := range lines {
+		lines[index] = "user@one.test"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	blocking := &contextBlockingVerifier{}
+	done := make(chan struct{})
+	go func() {
+		verifyChunkLines(ctx, lines, blocking, 2)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected verifyChunkLines to return once the context is done")
+	}
+	if calls := atomic.LoadInt64(&blocking.calls); calls >= int64(len(lines)) {
+		t.Fatalf("expected lines left undispatched after cancellation, got %d verifications", calls)
+	}
+}
+
+func TestDomainInterleavedOrderRotatesDomains(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{
+		"a@big.test",
+		"b@big.test",
+		"c@big.test",
+		"d@small.test",
+		"e@other.test",
+		"f@small.test",
+	}
+
+	order := domainInterleavedOrder(lines)
+	expected := []int{0, 3, 4, 1, 5, 2}
+	if len(order) != len(expected) {
+		t.Fatalf("expected %d indexes, got %d", len(expected), len(order))
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected order %v, got %v", expected, order)
+		}
+	}
+}
+
+type recordingVerifier struct {
+	mu    sync.Mutex
+	seen  []string
+	delay time.Duration
+}
+
+func (v *recordingVerifier) Verify(_ context.Context, email string) verifier.Result {
+	time.Sleep(v.delay)
+	v.mu.Lock()
+	v.seen = append(v.seen, email)
+	v.mu.Unlock()
+
+	return verifier.Result{Category: verifier.CategoryRisky, Reason: "smtp_tempfail"}
+}
+
+func TestBuildOutputsWithParallelismKeepsRowOrder(t *testing.T) {
+	t.Parallel()
+
+	input := "email\nfirst@one.test\nsecond@two.test\nthird@one.test\n"
+	outputs, err := buildOutputs(context.Background(), strings.NewReader(input), &recordingVerifier{delay: time.Millisecond}, chunkOutputOptions{Parallelism: 4})
+	if err != nil {
+		t.Fatalf("buildOutputs returned error: %v", err)
+	}
+
+	if outputs.EmailCount != 3 || outputs.RiskyCount != 3 {
+		t.Fatalf("expected 3 risky emails, got count=%d risky=%d", outputs.EmailCount, outputs.RiskyCount)
+	}
+
+	rows := strings.Split(strings.TrimSpace(string(outputs.RiskyData)), "\n")
+	expected := []string{"email,reason", "first@one.test,smtp_tempfail", "second@two.test,smtp_tempfail", "third@one.test,smtp_tempfail"}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %d: %q", len(expected), len(rows), rows)
+	}
+	for i := range expected {
+		if rows[i] != expected[i] {
+			t.Fatalf("expected row %d to be %q, got %q", i, expected[i], rows[i])
+		}
+	}
+}
+
+func TestBuildOutputsReturnsContextErrorWhenCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	outputs, err := buildOutputs(ctx, strings.NewReader("first@one.test\nsecond@two.test\n"), &recordingVerifier{}, chunkOutputOptions{Parallelism: 2})
+	if !errors.Is(err, context.Canceled) || outputs != nil {
+		t.Fatalf("expected a cancelled chunk to produce no outputs, got %v %+v", err, outputs)
+	}
+}
@@ -0,0 +1,71 @@
+package worker
+
+import "engine-worker-go/internal/verifier"
+
+// chunkAddresses groups chunk rows by canonical address so each mailbox is
+// probed once and its result fanned out to every row naming it. With only
+// Probes set, every row is probed itself.
+type chunkAddresses struct {
+	// Probes holds the first row seen for each canonical address.
+	Probes []string
+	// Canonical holds the canonical address of each row.
+	Canonical []string
+	// ProbeIndex maps each row to its entry in Probes.
+	ProbeIndex []int
+	// FirstRow maps each entry in Probes back to the row it came from.
+	FirstRow []int
+}
+
+func dedupeChunkLines(lines []string, engine *verifier.ProviderReplyPolicyEngine) chunkAddresses {
+	if engine == nil {
+		engine = verifier.DefaultProviderReplyPolicyEngine()
+	}
+
+	addresses := chunkAddresses{
+		Probes:     make([]string, 0, len(lines)),
+		Canonical:  make([]string, len(lines)),
+		ProbeIndex: make([]int, len(lines)),
+		FirstRow:   make([]int, 0, len(lines)),
+	}
+	seen := make(map[string]int, len(lines))
+
+	for index, line := range lines {
+		canonical, _ := verifier.CanonicalEmail(engine, line)
+		addresses.Canonical[index] = canonical
+
+		probe, exists := seen[canonical]
+		if !exists {
+			probe = len(addresses.Probes)
+			seen[canonical] = probe
+			addresses.Probes = append(addresses.Probes, line)
+			addresses.FirstRow = append(addresses.FirstRow, index)
+		}
+		addresses.ProbeIndex[index] = probe
+	}
+
+	return addresses
+}
+
+// probeFor returns the entry in Probes whose result applies to row.
+func (a chunkAddresses) probeFor(row int) int {
+	if a.ProbeIndex == nil {
+		return row
+	}
+
+	return a.ProbeIndex[row]
+}
+
+// duplicateOf returns the row the given row's result was taken from, or ""
+// when the row was probed itself.
+func (a chunkAddresses) duplicateOf(row int) string {
+	if a.ProbeIndex == nil {
+		return ""
+	}
+
+	first := a.FirstRow[a.ProbeIndex[row]]
+	if first == row {
+		return ""
+	}
+
+	return a.Probes[a.ProbeIndex[row]]
+}
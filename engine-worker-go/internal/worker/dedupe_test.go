@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"engine-worker-go/internal/verifier"
+)
+
+func TestBuildOutputsProbesCanonicalAddressesOnce(t *testing.T) {
+	t.Parallel()
+
+	input := "email\nJohn.Doe+promo@gmail.com\njohndoe@gmail.com\nother@example.com\nJOHNDOE@GMAIL.COM\nother@example.com\n"
+	engineVerifier := &recordingVerifier{}
+	outputs, err := buildOutputs(context.Background(), strings.NewReader(input), engineVerifier, chunkOutputOptions{Parallelism: 2, Dedupe: true})
+	if err != nil {
+		t.Fatalf("buildOutputs returned error: %v", err)
+	}
+
+	if len(engineVerifier.seen) != 2 {
+		t.Fatalf("expected one probe per canonical address, got %q", engineVerifier.seen)
+	}
+	if outputs.EmailCount != 5 || outputs.RiskyCount != 5 || outputs.DuplicateCount != 3 {
+		t.Fatalf("expected 5 risky rows with 3 duplicates, got count=%d risky=%d duplicates=%d", outputs.EmailCount, outputs.RiskyCount, outputs.DuplicateCount)
+	}
+
+	rows := strings.Split(strings.TrimSpace(string(outputs.RiskyData)), "\n")
+	expected := []string{
+		"email,reason,canonical_email,duplicate_of",
+		"John.Doe+promo@gmail.com,smtp_tempfail,johndoe@gmail.com,",
+		"johndoe@gmail.com,smtp_tempfail,johndoe@gmail.com,John.Doe+promo@gmail.com",
+		"other@example.com,smtp_tempfail,other@example.com,",
+		"JOHNDOE@GMAIL.COM,smtp_tempfail,johndoe@gmail.com,John.Doe+promo@gmail.com",
+		"other@example.com,smtp_tempfail,other@example.com,other@example.com",
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %d: %q", len(expected), len(rows), rows)
+	}
+	for i := range expected {
+		if rows[i] != expected[i] {
+			t.Fatalf("expected row %d to be %q, got %q", i, expected[i], rows[i])
+		}
+	}
+}
+
+func TestDedupeChunkLinesFollowsPolicyCanonicalization(t *testing.T) {
+	t.Parallel()
+
+	engine, err := verifier.ParseProviderReplyPolicyEngineJSON(`{"profiles": {"gmail": {"canonicalization": {"domains": ["gmail.com"], "plus_addressing": true}}}}`)
+	if err != nil {
+		t.Fatalf("expected payload to parse, got %v", err)
+	}
+
+	addresses := dedupeChunkLines([]string{"j.doe@gmail.com", "jdoe@gmail.com", "J.Doe@gmail.com"}, engine)
+	if len(addresses.Probes) != 2 || addresses.duplicateOf(2) != "j.doe@gmail.com" || addresses.duplicateOf(1) != "" {
+		t.Fatalf("expected dots to stay significant once the policy drops ignore_dots, got %+v", addresses)
+	}
+}
+
+func TestBuildOutputsWritesTranscriptForEveryDuplicateRow(t *testing.T) {
+	t.Parallel()
+
+	input := "probed@gmail.com\nprobed+tag@gmail.com\nPROBED@gmail.com\n"
+	outputs, err := buildOutputs(context.Background(), strings.NewReader(input), transcriptVerifier{}, chunkOutputOptions{Parallelism: 1, Dedupe: true})
+	if err != nil {
+		t.Fatalf("buildOutputs returned error: %v", err)
+	}
+
+	hashes := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(string(outputs.TranscriptData)), "\n") {
+		var record transcriptRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("expected JSON transcript line, got %v", err)
+		}
+		hashes[record.EmailHash] = true
+	}
+	if len(hashes) != 2 || !hashes[verifier.EmailHash("probed@gmail.com")] || !hashes[verifier.EmailHash("probed+tag@gmail.com")] {
+		t.Fatalf("expected one transcript per distinct row hash, got %v", hashes)
+	}
+}
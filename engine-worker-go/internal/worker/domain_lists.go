@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"engine-worker-go/internal/api"
+	"engine-worker-go/internal/verifier"
+)
+
+// domainListSource is the part of the control-plane client used to sync the
+// distributed lists.
+type domainListSource interface {
+	DomainLists(ctx context.Context) (*api.ControlPlaneDomainListsResponse, error)
+	DomainList(ctx context.Context, name string, since string) (*api.ControlPlaneDomainListResponse, error)
+}
+
+var syncedDomainLists = map[string]struct{}{
+	verifier.DomainListDisposable:   {},
+	verifier.DomainListRoleAccounts: {},
+	verifier.DomainListFreeMail:     {},
+}
+
+// syncDomainLists brings every advertised list up to its published version.
+// Lists that fail to fetch or verify keep their current version, which is
+// the embedded or configured list until a first sync succeeds.
+func syncDomainLists(ctx context.Context, source domainListSource, lists *verifier.DomainLists) {
+	manifest, err := source.DomainLists(ctx)
+	if err != nil {
+		fmt.Printf("domain lists fetch error: %v\n", err)
+		return
+	}
+
+	for _, entry := range manifest.Data.Lists {
+		name := strings.ToLower(strings.TrimSpace(entry.Name))
+		if _, ok := syncedDomainLists[name]; !ok {
+			continue
+		}
+
+		current := lists.Get(name)
+		if current != nil && current.Version == entry.Version && current.Checksum == entry.Checksum {
+			continue
+		}
+
+		updated, err := fetchDomainList(ctx, source, name, current)
+		if err != nil && current != nil {
+			updated, err = fetchDomainList(ctx, source, name, nil)
+		}
+		if err != nil {
+			fmt.Printf("domain list %s sync error: %v\n", name, err)
+			continue
+		}
+
+		lists.Replace(updated)
+	}
+}
+
+// fetchDomainList fetches a delta from current, or the full list when
+// current is nil, and checks the result against the published checksum.
+func fetchDomainList(ctx context.Context, source domainListSource, name string, current *verifier.DomainList) (*verifier.DomainList, error) {
+	since := ""
+	if current != nil {
+		since = current.Version
+	}
+
+	resp, err := source.DomainList(ctx, name, since)
+	if err != nil {
+		return nil, err
+	}
+
+	data := resp.Data
+	var list *verifier.DomainList
+	switch data.Mode {
+	case "delta":
+		if current == nil || data.BaseVersion != current.Version {
+			return nil, fmt.Errorf("delta from %q does not apply to %q", data.BaseVersion, since)
+		}
+		list = current.ApplyDelta(data.Version, data.Added, data.Removed)
+	default:
+		list = verifier.NewDomainList(name, data.Version, data.Entries)
+	}
+
+	if list.Checksum != data.Checksum {
+		return nil, fmt.Errorf("checksum mismatch for version %s", data.Version)
+	}
+
+	return list, nil
+}
+
+// domainListTags reports the loaded list versions as heartbeat tags, e.g.
+// domain_list:disposable_domains=3f2a9c1d0b7e.
+func domainListTags(lists *verifier.DomainLists) []string {
+	tags := []string{}
+	for name, version := range lists.Versions() {
+		tags = append(tags, fmt.Sprintf("domain_list:%s=%s", name, version))
+	}
+	sort.Strings(tags)
+
+	return tags
+}
@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"engine-worker-go/internal/api"
+	"engine-worker-go/internal/verifier"
+)
+
+type fakeDomainListSource struct {
+	manifest []api.ControlPlaneDomainListManifest
+	lists    map[string]*api.ControlPlaneDomainListResponse
+	deltas   map[string]*api.ControlPlaneDomainListResponse
+	requests []string
+}
+
+func (f *fakeDomainListSource) DomainLists(ctx context.Context) (*api.ControlPlaneDomainListsResponse, error) {
+	resp := &api.ControlPlaneDomainListsResponse{}
+	resp.Data.Lists = f.manifest
+	return resp, nil
+}
+
+func (f *fakeDomainListSource) DomainList(ctx context.Context, name string, since string) (*api.ControlPlaneDomainListResponse, error) {
+	f.requests = append(f.requests, name+"@"+since)
+	if since != "" {
+		if resp, ok := f.deltas[name]; ok {
+			return resp, nil
+		}
+	}
+	if resp, ok := f.lists[name]; ok {
+		return resp, nil
+	}
+	return nil, errors.New("not found")
+}
+
+// domainListResponse builds a response whose checksum covers final, the
+// entries a worker should hold after applying it.
+func domainListResponse(name, version, mode string, final []string) *api.ControlPlaneDomainListResponse {
+	resp := &api.ControlPlaneDomainListResponse{}
+	resp.Data.Name = name
+	resp.Data.Version = version
+	resp.Data.Checksum = verifier.NewDomainList(name, version, final).Checksum
+	resp.Data.Mode = mode
+	if mode == "full" {
+		resp.Data.Entries = final
+	}
+	return resp
+}
+
+func TestSyncDomainListsAppliesFullThenDelta(t *testing.T) {
+	lists := verifier.NewDomainLists()
+	full := domainListResponse(verifier.DomainListDisposable, "v1", "full", []string{"kept.test", "old.test"})
+	source := &fakeDomainListSource{
+		manifest: []api.ControlPlaneDomainListManifest{full.Data.ControlPlaneDomainListManifest, {Name: "unknown_list", Version: "v1"}},
+		lists:    map[string]*api.ControlPlaneDomainListResponse{verifier.DomainListDisposable: full},
+	}
+
+	syncDomainLists(context.Background(), source, lists)
+	if got := lists.Get(verifier.DomainListDisposable); got == nil || got.Version != "v1" {
+		t.Fatalf("expected full list v1 to be installed, got %+v", got)
+	}
+
+	syncDomainLists(context.Background(), source, lists)
+	if len(source.requests) != 1 {
+		t.Fatalf("expected an unchanged version not to be fetched again, got %v", source.requests)
+	}
+
+	delta := domainListResponse(verifier.DomainListDisposable, "v2", "delta", []string{"kept.test", "new.test"})
+	delta.Data.BaseVersion = "v1"
+	delta.Data.Added = []string{"new.test"}
+	delta.Data.Removed = []string{"old.test"}
+	source.manifest = []api.ControlPlaneDomainListManifest{delta.Data.ControlPlaneDomainListManifest}
+	source.deltas = map[string]*api.ControlPlaneDomainListResponse{verifier.DomainListDisposable: delta}
+
+	syncDomainLists(context.Background(), source, lists)
+	got := lists.Get(verifier.DomainListDisposable)
+	if got.Version != "v2" || strings.Join(got.Entries(), ",") != "kept.test,new.test" {
+		t.Fatalf("expected delta v2 to be applied, got %s %v", got.Version, got.Entries())
+	}
+	if source.requests[len(source.requests)-1] != verifier.DomainListDisposable+"@v1" {
+		t.Fatalf("expected delta request since v1, got %v", source.requests)
+	}
+	if tags := domainListTags(lists); len(tags) != 1 || tags[0] != "domain_list:disposable_domains=v2" {
+		t.Fatalf("unexpected heartbeat tags %v", tags)
+	}
+}
+
+func TestSyncDomainListsKeepsCurrentListOnChecksumMismatch(t *testing.T) {
+	lists := verifier.NewDomainLists()
+	lists.Replace(verifier.NewDomainList(verifier.DomainListRoleAccounts, "v1", []string{"info"}))
+
+	corrupt := domainListResponse(verifier.DomainListRoleAccounts, "v2", "full", []string{"info", "sales"})
+	corrupt.Data.Checksum = "bad"
+	source := &fakeDomainListSource{
+		manifest: []api.ControlPlaneDomainListManifest{corrupt.Data.ControlPlaneDomainListManifest},
+		lists:    map[string]*api.ControlPlaneDomainListResponse{verifier.DomainListRoleAccounts: corrupt},
+	}
+
+	syncDomainLists(context.Background(), source, lists)
+	if got := lists.Get(verifier.DomainListRoleAccounts); got.Version != "v1" {
+		t.Fatalf("expected v1 to be kept after a checksum mismatch, got %s", got.Version)
+	}
+	if len(source.requests) != 2 || source.requests[1] != verifier.DomainListRoleAccounts+"@" {
+		t.Fatalf("expected a full refetch after the failed update, got %v", source.requests)
+	}
+}
+
+func TestWorkerSyncsDomainListsWhenPolicyFetchFails(t *testing.T) {
+	laravelServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer laravelServer.Close()
+
+	list := domainListResponse(verifier.DomainListDisposable, "v1", "full", []string{"temp.test"})
+	controlPlaneServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/domain-lists":
+			manifest := &api.ControlPlaneDomainListsResponse{}
+			manifest.Data.Lists = []api.ControlPlaneDomainListManifest{list.Data.ControlPlaneDomainListManifest}
+			_ = json.NewEncoder(w).Encode(manifest)
+		case "/api/domain-lists/" + verifier.DomainListDisposable:
+			_ = json.NewEncoder(w).Encode(list)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer controlPlaneServer.Close()
+
+	w := New(api.NewClient(laravelServer.URL, ""), Config{
+		PolicyRefresh:                 time.Minute,
+		ControlPlaneClient:            api.NewControlPlaneClient(controlPlaneServer.URL, ""),
+		ControlPlanePolicySyncEnabled: true,
+	})
+
+	now := time.Now()
+	w.refreshPolicyIfNeeded(context.Background(), now)
+	w.syncDomainListsIfNeeded(context.Background(), now)
+
+	if got := w.domainLists.Get(verifier.DomainListDisposable); got == nil || got.Version != "v1" {
+		t.Fatalf("expected lists to sync while the policy fetch fails, got %+v", got)
+	}
+}
@@ -0,0 +1,260 @@
+package worker
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"engine-worker-go/internal/verifier"
+)
+
+const (
+	// defaultGreylistWait applies when a greylisted reply carried no retry
+	// delay; it matches the reply policy engine's default greylist_seconds.
+	defaultGreylistWait = 180 * time.Second
+	// greylistLeaseMargin is kept free at the end of the lease for uploading
+	// outputs and completing the chunk.
+	greylistLeaseMargin = 30 * time.Second
+)
+
+// greylistReprobe parks greylisted addresses and verifies them a second time
+// once their greylist window has passed, as long as the window ends within
+// maxWait and before the chunk's lease runs out. With renew set, the lease
+// is renewed while waiting instead of bounding the windows.
+type greylistReprobe struct {
+	maxWait  time.Duration
+	deadline time.Time
+	now      func() time.Time
+	wait     func(ctx context.Context, d time.Duration) bool
+	renew    func(ctx context.Context) (time.Time, error)
+}
+
+// greylistOutcome counts the greylisted addresses of one chunk. Parked
+// addresses that were not re-probed, or were greylisted again, are deferred.
+type greylistOutcome struct {
+	Parked    int
+	Recovered int
+	Deferred  int
+}
+
+type parkedAddress struct {
+	index   int
+	readyAt time.Time
+}
+
+// newGreylistReprobe returns a scheduler bounded by maxWait and the lease
+// deadline. A zero deadline means the lease is unknown and only maxWait
+// applies.
+func newGreylistReprobe(maxWait time.Duration, deadline time.Time) *greylistReprobe {
+	return &greylistReprobe{
+		maxWait:  maxWait,
+		deadline: deadline,
+		now:      time.Now,
+		wait:     sleepContext,
+	}
+}
+
+// leaseDeadline returns when the chunk's lease expires, from the claim's
+// lease_expires_at or else the requested lease length.
+func leaseDeadline(expiresAt string, leaseSeconds *int, now time.Time) time.Time {
+	if parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(expiresAt)); err == nil {
+		return parsed
+	}
+	if leaseSeconds != nil && *leaseSeconds > 0 {
+		return now.Add(time.Duration(*leaseSeconds) * time.Second)
+	}
+
+	return time.Time{}
+}
+
+// run re-probes the greylisted entries of results in place, folding each
+// second attempt into the first attempt's chain.
+func (g *greylistReprobe) run(
+	ctx context.Context,
+	lines []string,
+	results []verifier.Result,
+	engineVerifier verifier.Verifier,
+	parallelism int,
+) greylistOutcome {
+	outcome := greylistOutcome{}
+	if g == nil {
+		return outcome
+	}
+
+	start := g.now()
+	pending := make([]parkedAddress, 0)
+	for index, result := range results {
+		if !isGreylisted(result) {
+			continue
+		}
+		outcome.Parked++
+
+		wait := time.Duration(result.RetryAfterSecond) * time.Second
+		if wait <= 0 {
+			wait = defaultGreylistWait
+		}
+		readyAt := start.Add(wait)
+		if wait > g.maxWait || (g.renew == nil && !g.deadline.IsZero() && readyAt.After(g.deadline.Add(-greylistLeaseMargin))) {
+			continue
+		}
+		pending = append(pending, parkedAddress{index: index, readyAt: readyAt})
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].readyAt.Before(pending[j].readyAt)
+	})
+
+	for len(pending) > 0 {
+		if !g.waitUntil(ctx, pending[0].readyAt) {
+			break
+		}
+
+		now := g.now()
+		ready := 0
+		for ready < len(pending) && !pending[ready].readyAt.After(now) {
+			ready++
+		}
+		batch := pending[:ready]
+		pending = pending[ready:]
+
+		batchLines := make([]string, len(batch))
+		for i, parked := range batch {
+			batchLines[i] = lines[parked.index]
+		}
+		reprobed := verifyChunkLines(ctx, batchLines, engineVerifier, parallelism)
+		if ctx.Err() != nil {
+			break
+		}
+		for i, parked := range batch {
+			// A line the pool never dispatched keeps its first result.
+			if reprobed[i].Category == "" {
+				continue
+			}
+			results[parked.index] = foldReprobe(results[parked.index], reprobed[i])
+			if recoveredFromGreylist(reprobed[i]) {
+				outcome.Recovered++
+			}
+		}
+	}
+	outcome.Deferred = outcome.Parked - outcome.Recovered
+
+	return outcome
+}
+
+// waitUntil waits for readyAt, renewing the lease each time it would run out
+// within greylistLeaseMargin first. It reports false when ctx is done or the
+// lease cannot be held until readyAt.
+func (g *greylistReprobe) waitUntil(ctx context.Context, readyAt time.Time) bool {
+	for {
+		now := g.now()
+		if !readyAt.After(now) {
+			return true
+		}
+
+		until := readyAt
+		if !g.deadline.IsZero() && readyAt.After(g.deadline.Add(-greylistLeaseMargin)) {
+			if !g.renewLease(ctx) {
+				return false
+			}
+			if leaseEnd := g.deadline.Add(-greylistLeaseMargin); leaseEnd.Before(readyAt) {
+				if !leaseEnd.After(now) {
+					return false
+				}
+				until = leaseEnd
+			}
+		}
+
+		if !g.wait(ctx, until.Sub(now)) {
+			return false
+		}
+	}
+}
+
+// renewLease extends the chunk's lease, reporting false when there is no
+// renewer or the lease did not move past the current deadline.
+func (g *greylistReprobe) renewLease(ctx context.Context) bool {
+	if g.renew == nil {
+		return false
+	}
+
+	deadline, err := g.renew(ctx)
+	if err != nil || !deadline.After(g.deadline) {
+		return false
+	}
+	g.deadline = deadline
+
+	return true
+}
+
+func isGreylisted(result verifier.Result) bool {
+	if result.Category != verifier.CategoryRisky {
+		return false
+	}
+
+	return strings.EqualFold(strings.TrimSpace(result.RetryStrategy), "greylist") ||
+		strings.EqualFold(strings.TrimSpace(result.ReasonTag), "greylist")
+}
+
+// recoveredFromGreylist reports whether a re-probe got past the greylist,
+// i.e. was neither greylisted nor tempfailed again.
+func recoveredFromGreylist(result verifier.Result) bool {
+	return !isGreylisted(result) && result.DecisionClass != verifier.DecisionRetryable
+}
+
+// foldReprobe returns second with first's attempts prepended to its attempt
+// chain and transcript, numbering the re-probe's attempts after the original
+// ones.
+func foldReprobe(first, second verifier.Result) verifier.Result {
+	offset := len(first.AttemptChain)
+	chain := make([]verifier.AttemptEvidence, 0, offset+len(second.AttemptChain))
+	chain = append(chain, first.AttemptChain...)
+	for _, attempt := range second.AttemptChain {
+		if attempt.AttemptNumber > 0 {
+			attempt.AttemptNumber += offset
+		}
+		chain = append(chain, attempt)
+	}
+	if second.AttemptNumber > 0 {
+		second.AttemptNumber += offset
+	}
+	second.AttemptChain = chain
+	second.Transcript = foldTranscript(first.Transcript, second.Transcript, offset)
+
+	if second.Evidence != nil {
+		evidence := *second.Evidence
+		evidence.AttemptNumber = second.AttemptNumber
+		evidence.AttemptChain = append([]verifier.AttemptEvidence(nil), chain...)
+		second.Evidence = &evidence
+	}
+
+	return second
+}
+
+func foldTranscript(first, second []verifier.TranscriptEntry, offset int) []verifier.TranscriptEntry {
+	if len(first)+len(second) == 0 {
+		return nil
+	}
+
+	transcript := make([]verifier.TranscriptEntry, 0, len(first)+len(second))
+	transcript = append(transcript, first...)
+	for _, entry := range second {
+		if entry.Attempt > 0 {
+			entry.Attempt += offset
+		}
+		transcript = append(transcript, entry)
+	}
+
+	return transcript
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
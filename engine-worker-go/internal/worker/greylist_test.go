@@ -0,0 +1,232 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"engine-worker-go/internal/verifier"
+)
+
+// greylistingVerifier greylists every address on its first probe. Addresses
+// in recover are accepted on the second probe; the rest stay greylisted.
+type greylistingVerifier struct {
+	mu         sync.Mutex
+	probes     map[string]int
+	retryAfter int
+	recover    map[string]bool
+}
+
+func (v *greylistingVerifier) Verify(_ context.Context, email string) verifier.Result {
+	v.mu.Lock()
+	v.probes[email]++
+	probe := v.probes[email]
+	v.mu.Unlock()
+
+	greylisted := verifier.Result{
+		Category:         verifier.CategoryRisky,
+		Reason:           "smtp_tempfail",
+		DecisionClass:    verifier.DecisionRetryable,
+		RetryStrategy:    "greylist",
+		RetryAfterSecond: v.retryAfter,
+		MXHost:           "mx.test",
+		AttemptNumber:    1,
+		AttemptChain: []verifier.AttemptEvidence{
+			{AttemptNumber: 1, MXHost: "mx.test", ReasonCode: "smtp_tempfail", RetryStrategy: "greylist"},
+		},
+	}
+	if probe == 1 || !v.recover[email] {
+		return greylisted
+	}
+
+	return verifier.Result{
+		Category:      verifier.CategoryValid,
+		Reason:        "rcpt_ok",
+		DecisionClass: verifier.DecisionDeliverable,
+		MXHost:        "mx.test",
+		AttemptNumber: 1,
+		AttemptChain: []verifier.AttemptEvidence{
+			{AttemptNumber: 1, MXHost: "mx.test", ReasonCode: "rcpt_ok"},
+		},
+		Evidence: &verifier.ReplyEvidence{AttemptNumber: 1},
+	}
+}
+
+func newTestGreylistReprobe(maxWait time.Duration, deadline time.Time, clock *time.Time) (*greylistReprobe, *[]time.Duration) {
+	waits := []time.Duration{}
+	reprobe := newGreylistReprobe(maxWait, deadline)
+	reprobe.now = func() time.Time { return *clock }
+	reprobe.wait = func(_ context.Context, d time.Duration) bool {
+		waits = append(waits, d)
+		*clock = clock.Add(d)
+		return true
+	}
+
+	return reprobe, &waits
+}
+
+func TestGreylistReprobeRecoversAddressesAfterWindow(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	reprobe, waits := newTestGreylistReprobe(10*time.Minute, clock.Add(time.Hour), &clock)
+	engineVerifier := &greylistingVerifier{
+		probes:     map[string]int{},
+		retryAfter: 240,
+		recover:    map[string]bool{"ok@test.com": true},
+	}
+
+	input := "email\nok@test.com\nstuck@test.com\n"
+	outputs, err := buildOutputs(context.Background(), strings.NewReader(input), engineVerifier, chunkOutputOptions{Parallelism: 1, ProbeAttemptChainEnabled: true, Greylist: reprobe})
+	if err != nil {
+		t.Fatalf("buildOutputs returned error: %v", err)
+	}
+
+	if outputs.Greylist != (greylistOutcome{Parked: 2, Recovered: 1, Deferred: 1}) {
+		t.Fatalf("expected 2 parked, 1 recovered and 1 deferred, got %+v", outputs.Greylist)
+	}
+	if len(*waits) != 1 || (*waits)[0] != 240*time.Second {
+		t.Fatalf("expected a single 240s wait for the greylist window, got %v", *waits)
+	}
+	if outputs.ValidCount != 1 || outputs.RiskyCount != 1 {
+		t.Fatalf("expected the recovered address valid and the other risky, got valid=%d risky=%d", outputs.ValidCount, outputs.RiskyCount)
+	}
+
+	rows := strings.Split(strings.TrimSpace(string(outputs.ValidData)), "\n")
+	if len(rows) != 2 || !strings.Contains(rows[1], "attempt=2") {
+		t.Fatalf("expected recovered row numbered as the second attempt, got %v", rows)
+	}
+}
+
+func TestGreylistReprobeFoldsSecondAttemptIntoChain(t *testing.T) {
+	first := verifier.Result{AttemptChain: []verifier.AttemptEvidence{
+		{AttemptNumber: 1, MXHost: "mx1.test"},
+		{AttemptNumber: 2, MXHost: "mx2.test"},
+	}}
+	second := verifier.Result{
+		AttemptNumber: 1,
+		AttemptChain:  []verifier.AttemptEvidence{{AttemptNumber: 1, MXHost: "mx1.test", ReasonCode: "rcpt_ok"}},
+		Evidence:      &verifier.ReplyEvidence{AttemptNumber: 1},
+	}
+
+	folded := foldReprobe(first, second)
+	if len(folded.AttemptChain) != 3 || folded.AttemptChain[2].AttemptNumber != 3 || folded.AttemptChain[2].ReasonCode != "rcpt_ok" {
+		t.Fatalf("expected re-probe appended as attempt 3, got %+v", folded.AttemptChain)
+	}
+	if folded.AttemptNumber != 3 || folded.Evidence.AttemptNumber != 3 || len(folded.Evidence.AttemptChain) != 3 {
+		t.Fatalf("expected attempt 3 in result and evidence, got %d %+v", folded.AttemptNumber, folded.Evidence)
+	}
+	if second.Evidence.AttemptNumber != 1 {
+		t.Fatalf("expected the re-probe's evidence to be copied, got %d", second.Evidence.AttemptNumber)
+	}
+}
+
+func TestGreylistReprobeSkipsWindowsBeyondLease(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	reprobe, waits := newTestGreylistReprobe(10*time.Minute, clock.Add(4*time.Minute), &clock)
+	engineVerifier := &greylistingVerifier{
+		probes:     map[string]int{},
+		retryAfter: 240,
+		recover:    map[string]bool{"ok@test.com": true},
+	}
+
+	lines := []string{"ok@test.com"}
+	results := []verifier.Result{engineVerifier.Verify(context.Background(), "ok@test.com")}
+	outcome := reprobe.run(context.Background(), lines, results, engineVerifier, 1)
+
+	if outcome != (greylistOutcome{Parked: 1, Deferred: 1}) || len(*waits) != 0 {
+		t.Fatalf("expected the address deferred without waiting past the lease, got %+v waits=%v", outcome, *waits)
+	}
+	if engineVerifier.probes["ok@test.com"] != 1 {
+		t.Fatalf("expected no re-probe, got %d probes", engineVerifier.probes["ok@test.com"])
+	}
+}
+
+func TestGreylistReprobeRenewsLeaseWhileWaiting(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	reprobe, waits := newTestGreylistReprobe(10*time.Minute, clock.Add(4*time.Minute), &clock)
+	renewals := 0
+	reprobe.renew = func(context.Context) (time.Time, error) {
+		renewals++
+		return clock.Add(5 * time.Minute), nil
+	}
+	engineVerifier := &greylistingVerifier{
+		probes:     map[string]int{},
+		retryAfter: 480,
+		recover:    map[string]bool{"ok@test.com": true},
+	}
+
+	lines := []string{"ok@test.com"}
+	results := []verifier.Result{engineVerifier.Verify(context.Background(), "ok@test.com")}
+	outcome := reprobe.run(context.Background(), lines, results, engineVerifier, 1)
+
+	if outcome != (greylistOutcome{Parked: 1, Recovered: 1}) {
+		t.Fatalf("expected the address recovered past the first lease, got %+v", outcome)
+	}
+	// Each renewal holds the lease for 5 minutes, so the 8 minute window is
+	// waited out in two parts, stopping short of each lease's margin.
+	if renewals != 2 || len(*waits) != 2 || (*waits)[0] != 270*time.Second || (*waits)[1] != 210*time.Second {
+		t.Fatalf("expected two renewals splitting the wait into 270s and 210s, got %d renewals waits=%v", renewals, *waits)
+	}
+}
+
+func TestGreylistReprobeDefersWhenLeaseRenewalFails(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	reprobe, waits := newTestGreylistReprobe(10*time.Minute, clock.Add(4*time.Minute), &clock)
+	reprobe.renew = func(context.Context) (time.Time, error) {
+		return time.Time{}, errors.New("chunk is claimed by another worker")
+	}
+	engineVerifier := &greylistingVerifier{
+		probes:     map[string]int{},
+		retryAfter: 240,
+		recover:    map[string]bool{"ok@test.com": true},
+	}
+
+	lines := []string{"ok@test.com"}
+	results := []verifier.Result{engineVerifier.Verify(context.Background(), "ok@test.com")}
+	outcome := reprobe.run(context.Background(), lines, results, engineVerifier, 1)
+
+	if outcome != (greylistOutcome{Parked: 1, Deferred: 1}) || len(*waits) != 0 {
+		t.Fatalf("expected the address deferred without waiting, got %+v waits=%v", outcome, *waits)
+	}
+}
+
+func TestLeaseDeadlinePrefersClaimExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	leaseSeconds := 300
+
+	if deadline := leaseDeadline("2026-01-01T00:10:00Z", &leaseSeconds, now); !deadline.Equal(now.Add(10 * time.Minute)) {
+		t.Fatalf("expected lease_expires_at deadline, got %s", deadline)
+	}
+	if deadline := leaseDeadline("", &leaseSeconds, now); !deadline.Equal(now.Add(5 * time.Minute)) {
+		t.Fatalf("expected requested lease deadline, got %s", deadline)
+	}
+	if deadline := leaseDeadline("", nil, now); !deadline.IsZero() {
+		t.Fatalf("expected no deadline, got %s", deadline)
+	}
+}
+
+func TestGreylistReprobeKeepsFirstResultsWhenCancelled(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	reprobe, _ := newTestGreylistReprobe(10*time.Minute, clock.Add(time.Hour), &clock)
+	ctx, cancel := context.WithCancel(context.Background())
+	reprobe.wait = func(_ context.Context, d time.Duration) bool {
+		clock = clock.Add(d)
+		cancel()
+		return true
+	}
+	engineVerifier := &greylistingVerifier{
+		probes:     map[string]int{},
+		retryAfter: 240,
+		recover:    map[string]bool{"ok@test.com": true},
+	}
+
+	lines := []string{"ok@test.com"}
+	results := []verifier.Result{engineVerifier.Verify(ctx, "ok@test.com")}
+	outcome := reprobe.run(ctx, lines, results, engineVerifier, 1)
+
+	if outcome.Recovered != 0 || results[0].Reason != "smtp_tempfail" || results[0].AttemptNumber != 1 {
+		t.Fatalf("expected the first result kept once the context is done, got %+v %s attempt=%d", outcome, results[0].Reason, results[0].AttemptNumber)
+	}
+}
@@ -11,26 +11,40 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
 
 	"engine-worker-go/internal/api"
 	"engine-worker-go/internal/verifier"
 )
 
 type Config struct {
-	PollInterval                  time.Duration
-	HeartbeatInterval             time.Duration
-	LeaseSeconds                  *int
-	MaxConcurrency                int
-	PolicyRefresh                 time.Duration
-	Server                        api.EngineServerPayload
-	WorkerID                      string
-	WorkerCapability              string
-	BaseVerifierConfig            verifier.Config
+	PollInterval            time.Duration
+	HeartbeatInterval       time.Duration
+	LeaseSeconds            *int
+	MaxConcurrency          int
+	ChunkParallelism        int
+	SMTPSessionMaxRcpts     int
+	PerMXConcurrency        int
+	SMTPRateLimitBurst      int
+	MXCacheMaxEntries       int
+	MXCacheNegativeTTL      time.Duration
+	CatchAllCacheTTL        time.Duration
+	CatchAllUndeterminedTTL time.Duration
+	GreylistReprobeMaxWait  time.Duration
+	PolicyRefresh           time.Duration
+	Server                  api.EngineServerPayload
+	WorkerID                string
+	WorkerCapability        string
+	BaseVerifierConfig      verifier.Config
+	// MXResolver answers the worker's MX lookups; nil uses the system
+	// resolver.
+	MXResolver                    verifier.MXResolver
 	ControlPlaneClient            *api.ControlPlaneClient
 	ControlPlaneHeartbeatEnabled  bool
 	LaravelHeartbeatEnabled       bool
@@ -38,6 +52,9 @@ type Config struct {
 	ControlPlanePolicySyncEnabled bool
 	ProbeAttemptChainEnabled      bool
 	UnknownReasonTaxonomyEnabled  bool
+	// ChunkDedupeEnabled probes each canonical address once per chunk and
+	// adds canonical_email and duplicate_of output columns.
+	ChunkDedupeEnabled bool
 }
 
 type Worker struct {
@@ -50,8 +67,16 @@ type Worker struct {
 	policyMu        sync.RWMutex
 	policy          policyState
 	lastPolicyFetch time.Time
+	lastListSync    time.Time
 	desiredState    atomic.Value
 	telemetry       *workerTelemetry
+	circuitBreakers map[string]*verifier.CircuitBreaker
+	smtpSessions    *verifier.SMTPSessionPool
+	hostLimiter     *verifier.HostLimiter
+	rateLimiters    map[string]*verifier.RateLimiter
+	mxCache         *verifier.CachingMXResolver
+	domainLists     *verifier.DomainLists
+	catchAllCache   *verifier.CatchAllCache
 }
 
 type policyState struct {
@@ -60,6 +85,7 @@ type policyState struct {
 	enhancedModeEnabled  bool
 	roleAccountsBehavior string
 	roleAccounts         map[string]struct{}
+	typoEngine           *verifier.TypoEngine
 	heloName             string
 	mailFromAddress      string
 	identityDomain       string
@@ -80,6 +106,7 @@ type policyConfig struct {
 	SMTPReadTimeoutMs          int
 	MaxMXAttempts              int
 	MaxConcurrencyDefault      int
+	ChunkParallelism           int
 	PerDomainConcurrency       int
 	CatchAllDetectionEnabled   bool
 	GlobalConnectsPerMinute    *int
@@ -88,15 +115,23 @@ type policyConfig struct {
 }
 
 type chunkOutputs struct {
-	ValidData    []byte
-	InvalidData  []byte
-	RiskyData    []byte
-	EmailCount   int
-	ValidCount   int
-	InvalidCount int
-	RiskyCount   int
-	ReasonCounts map[string]int
-	ReasonTags   map[string]int
+	ValidData   []byte
+	InvalidData []byte
+	RiskyData   []byte
+	// TranscriptData holds one JSON line per probed email when SMTP
+	// transcripts are recorded, and is empty otherwise.
+	TranscriptData []byte
+	EmailCount     int
+	ValidCount     int
+	InvalidCount   int
+	RiskyCount     int
+	// DuplicateCount is the number of rows that share a canonical address
+	// with an earlier row and reuse its result instead of being probed.
+	DuplicateCount int
+	ReasonCounts   map[string]int
+	ReasonTags     map[string]int
+	EHLOProfiles   map[string]*ehloProfileCounts
+	Greylist       greylistOutcome
 }
 
 func (c *chunkOutputs) baseReasonCount(reason string) int {
@@ -107,6 +142,30 @@ func (c *chunkOutputs) baseReasonCount(reason string) int {
 	return c.ReasonCounts[reason]
 }
 
+func (c *chunkOutputs) recordEHLOProfile(result verifier.Result, baseReason string) {
+	if result.Evidence == nil || strings.TrimSpace(result.Evidence.EHLOProfile) == "" {
+		return
+	}
+
+	profile := strings.ToLower(strings.TrimSpace(result.Evidence.EHLOProfile))
+	counts := c.EHLOProfiles[profile]
+	if counts == nil {
+		counts = &ehloProfileCounts{}
+		c.EHLOProfiles[profile] = counts
+	}
+
+	counts.Processed++
+	if result.Category == verifier.CategoryInvalid {
+		counts.Reject++
+	}
+	if baseReason == "smtp_tempfail" {
+		counts.Tempfail++
+	}
+	if result.DecisionClass == verifier.DecisionPolicyBlocked {
+		counts.PolicyBlocked++
+	}
+}
+
 func (c *chunkOutputs) baseReasonPrefixCount(prefix string) int {
 	if c == nil || c.ReasonCounts == nil {
 		return 0
@@ -138,6 +197,42 @@ func New(client *api.Client, cfg Config) *Worker {
 		cfg:            cfg,
 		maxConcurrency: int64(max),
 		telemetry:      newWorkerTelemetry(),
+		circuitBreakers: map[string]*verifier.CircuitBreaker{
+			"standard": verifier.NewCircuitBreaker(verifier.CircuitBreakerConfig{}),
+			"enhanced": verifier.NewCircuitBreaker(verifier.CircuitBreakerConfig{}),
+		},
+		hostLimiter: verifier.NewHostLimiter(cfg.PerMXConcurrency, 0),
+		domainLists: verifier.NewDomainLists(),
+		rateLimiters: map[string]*verifier.RateLimiter{
+			"standard": verifier.NewRateLimiter(verifier.RateLimiterConfig{
+				PerMinute:       cfg.BaseVerifierConfig.SMTPRateLimitPerMinute,
+				GlobalPerMinute: cfg.BaseVerifierConfig.SMTPRateLimitPerMinute,
+				Burst:           cfg.SMTPRateLimitBurst,
+			}),
+			"enhanced": verifier.NewRateLimiter(verifier.RateLimiterConfig{
+				PerMinute:       cfg.BaseVerifierConfig.SMTPRateLimitPerMinute,
+				GlobalPerMinute: cfg.BaseVerifierConfig.SMTPRateLimitPerMinute,
+				Burst:           cfg.SMTPRateLimitBurst,
+			}),
+		},
+	}
+	if cfg.SMTPSessionMaxRcpts > 1 {
+		w.smtpSessions = verifier.NewSMTPSessionPool(verifier.SMTPSessionPoolConfig{
+			MaxRcptsPerSession: cfg.SMTPSessionMaxRcpts,
+		})
+	}
+	if cfg.MXCacheMaxEntries > 0 {
+		w.mxCache = verifier.NewCachingMXResolver(w.baseMXResolver(), verifier.MXCacheConfig{
+			MaxEntries:    cfg.MXCacheMaxEntries,
+			NegativeTTL:   cfg.MXCacheNegativeTTL,
+			LookupTimeout: time.Duration(cfg.BaseVerifierConfig.DNSTimeout) * time.Millisecond,
+		})
+	}
+	if cfg.CatchAllCacheTTL > 0 {
+		w.catchAllCache = verifier.NewCatchAllCache(verifier.CatchAllCacheConfig{
+			TTL:             cfg.CatchAllCacheTTL,
+			UndeterminedTTL: cfg.CatchAllUndeterminedTTL,
+		})
 	}
 	w.cfg.LaravelHeartbeatEveryN = laravelHeartbeatEveryN
 	w.desiredState.Store("running")
@@ -153,6 +248,7 @@ func (w *Worker) Run(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			w.wg.Wait()
+			w.smtpSessions.Close()
 			return ctx.Err()
 		default:
 		}
@@ -164,6 +260,17 @@ func (w *Worker) Run(ctx context.Context) error {
 		}
 
 		w.refreshPolicyIfNeeded(ctx, now)
+		w.syncDomainListsIfNeeded(ctx, now)
+		w.smtpSessions.EvictIdle()
+		w.hostLimiter.EvictIdle()
+		for _, limiter := range w.rateLimiters {
+			limiter.EvictIdle()
+		}
+		for _, breaker := range w.circuitBreakers {
+			breaker.EvictIdle()
+		}
+		w.mxCache.EvictExpired()
+		w.catchAllCache.EvictExpired()
 
 		if w.enginePaused() {
 			time.Sleep(w.cfg.PollInterval)
@@ -329,14 +436,26 @@ func (w *Worker) processChunk(ctx context.Context, claim *api.ClaimNextResponse)
 	} else {
 		engineVerifier = w.verifierForMode(mode, policy, hasPolicy)
 	}
-	outputs, err := buildOutputs(
-		ctx,
-		reader,
-		engineVerifier,
-		w.cfg.ProbeAttemptChainEnabled,
-		w.cfg.UnknownReasonTaxonomyEnabled,
-	)
+	var greylist *greylistReprobe
+	if w.cfg.GreylistReprobeMaxWait > 0 {
+		greylist = newGreylistReprobe(w.cfg.GreylistReprobeMaxWait, leaseDeadline(claim.Data.LeaseExpiresAt, w.cfg.LeaseSeconds, time.Now()))
+		greylist.renew = w.chunkLeaseRenewer(chunkID)
+	}
+	outputs, err := buildOutputs(ctx, reader, engineVerifier, chunkOutputOptions{
+		Parallelism:                  w.chunkParallelism(policy, hasPolicy),
+		ProbeAttemptChainEnabled:     w.cfg.ProbeAttemptChainEnabled,
+		UnknownReasonTaxonomyEnabled: w.cfg.UnknownReasonTaxonomyEnabled,
+		DomainAuthColumns:            w.cfg.BaseVerifierConfig.DomainAuth != nil,
+		Dedupe:                       w.cfg.ChunkDedupeEnabled,
+		ReplyPolicy:                  w.policySnapshot().replyPolicyEngine,
+		Greylist:                     greylist,
+	})
 	if err != nil {
+		if ctx.Err() != nil {
+			// Hand the unfinished chunk back instead of waiting for its lease
+			// to expire; the worker's own context is already done.
+			return w.failChunk(context.WithoutCancel(ctx), chunkID, processingStage, "chunk interrupted", err, true)
+		}
 		return w.failChunk(ctx, chunkID, processingStage, "failed to parse input", err, false)
 	}
 
@@ -345,15 +464,22 @@ func (w *Worker) processChunk(ctx context.Context, claim *api.ClaimNextResponse)
 		return w.failChunk(ctx, chunkID, processingStage, "failed to fetch output urls", err, true)
 	}
 
-	if err := uploadSigned(ctx, outputURLs.Data.Targets.Valid.URL, outputs.ValidData); err != nil {
+	if err := uploadSigned(ctx, outputURLs.Data.Targets.Valid.URL, "text/csv", outputs.ValidData); err != nil {
 		return w.failChunk(ctx, chunkID, processingStage, "failed to upload valid output", err, true)
 	}
-	if err := uploadSigned(ctx, outputURLs.Data.Targets.Invalid.URL, outputs.InvalidData); err != nil {
+	if err := uploadSigned(ctx, outputURLs.Data.Targets.Invalid.URL, "text/csv", outputs.InvalidData); err != nil {
 		return w.failChunk(ctx, chunkID, processingStage, "failed to upload invalid output", err, true)
 	}
-	if err := uploadSigned(ctx, outputURLs.Data.Targets.Risky.URL, outputs.RiskyData); err != nil {
+	if err := uploadSigned(ctx, outputURLs.Data.Targets.Risky.URL, "text/csv", outputs.RiskyData); err != nil {
 		return w.failChunk(ctx, chunkID, processingStage, "failed to upload risky output", err, true)
 	}
+	transcriptKey := ""
+	if len(outputs.TranscriptData) > 0 && outputURLs.Data.Targets.Transcript.URL != "" {
+		if err := uploadSigned(ctx, outputURLs.Data.Targets.Transcript.URL, "application/x-ndjson", outputs.TranscriptData); err != nil {
+			return w.failChunk(ctx, chunkID, processingStage, "failed to upload transcript output", err, true)
+		}
+		transcriptKey = outputURLs.Data.Targets.Transcript.Key
+	}
 
 	completePayload := map[string]interface{}{
 		"output_disk":   outputURLs.Data.Disk,
@@ -365,6 +491,9 @@ func (w *Worker) processChunk(ctx context.Context, claim *api.ClaimNextResponse)
 		"invalid_count": outputs.InvalidCount,
 		"risky_count":   outputs.RiskyCount,
 	}
+	if transcriptKey != "" {
+		completePayload["transcript_key"] = transcriptKey
+	}
 
 	if err := w.client.CompleteChunk(ctx, chunkID, completePayload); err != nil {
 		return w.failChunk(ctx, chunkID, processingStage, "failed to complete chunk", err, true)
@@ -382,6 +511,7 @@ func (w *Worker) processChunk(ctx context.Context, claim *api.ClaimNextResponse)
 			"valid_count":      outputs.ValidCount,
 			"invalid_count":    outputs.InvalidCount,
 			"risky_count":      outputs.RiskyCount,
+			"duplicate_count":  outputs.DuplicateCount,
 			"processing_stage": processingStage,
 			"routing_provider": claim.Data.RoutingProvider,
 			"preferred_pool":   claim.Data.PreferredPool,
@@ -412,6 +542,23 @@ func (w *Worker) failChunk(ctx context.Context, chunkID, stage, message string,
 	return err
 }
 
+// chunkLeaseRenewer returns a function that extends this worker's lease on
+// chunkID and reports the new expiry.
+func (w *Worker) chunkLeaseRenewer(chunkID string) func(ctx context.Context) (time.Time, error) {
+	return func(ctx context.Context) (time.Time, error) {
+		resp, err := w.client.ExtendChunkLease(ctx, chunkID, api.ChunkLeaseRequest{
+			WorkerID:     w.cfg.WorkerID,
+			LeaseSeconds: w.cfg.LeaseSeconds,
+		})
+		if err != nil {
+			fmt.Printf("chunk lease renew error: %v\n", err)
+			return time.Time{}, err
+		}
+
+		return leaseDeadline(resp.Data.LeaseExpiresAt, w.cfg.LeaseSeconds, time.Now()), nil
+	}
+}
+
 func downloadStream(ctx context.Context, url string) (io.ReadCloser, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -431,13 +578,13 @@ func downloadStream(ctx context.Context, url string) (io.ReadCloser, error) {
 	return resp.Body, nil
 }
 
-func uploadSigned(ctx context.Context, url string, data []byte) error {
+func uploadSigned(ctx context.Context, url, contentType string, data []byte) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(data))
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Content-Type", contentType)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -452,12 +599,26 @@ func uploadSigned(ctx context.Context, url string, data []byte) error {
 	return nil
 }
 
+// chunkOutputOptions controls how buildOutputs verifies a chunk and shapes
+// its output rows.
+type chunkOutputOptions struct {
+	Parallelism                  int
+	ProbeAttemptChainEnabled     bool
+	UnknownReasonTaxonomyEnabled bool
+	DomainAuthColumns            bool
+	// Dedupe probes each canonical address once, using ReplyPolicy's
+	// provider canonicalization, and adds canonical_email and duplicate_of
+	// columns.
+	Dedupe      bool
+	ReplyPolicy *verifier.ProviderReplyPolicyEngine
+	Greylist    *greylistReprobe
+}
+
 func buildOutputs(
 	ctx context.Context,
 	reader io.Reader,
 	engineVerifier verifier.Verifier,
-	probeAttemptChainEnabled bool,
-	unknownReasonTaxonomyEnabled bool,
+	options chunkOutputOptions,
 ) (*chunkOutputs, error) {
 	if engineVerifier == nil {
 		return nil, fmt.Errorf("verifier not configured")
@@ -472,6 +633,12 @@ func buildOutputs(
 	riskyWriter := csv.NewWriter(riskyBuf)
 
 	header := []string{"email", "reason"}
+	if options.DomainAuthColumns {
+		header = append(header, "spf", "dmarc", "mta_sts", "bimi")
+	}
+	if options.Dedupe {
+		header = append(header, "canonical_email", "duplicate_of")
+	}
 	_ = validWriter.Write(header)
 	_ = invalidWriter.Write(header)
 	_ = riskyWriter.Write(header)
@@ -482,7 +649,9 @@ func buildOutputs(
 	output := &chunkOutputs{}
 	output.ReasonCounts = map[string]int{}
 	output.ReasonTags = map[string]int{}
+	output.EHLOProfiles = map[string]*ehloProfileCounts{}
 
+	lines := make([]string, 0)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" {
@@ -493,35 +662,78 @@ func buildOutputs(
 			continue
 		}
 
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	addresses := chunkAddresses{Probes: lines}
+	if options.Dedupe {
+		addresses = dedupeChunkLines(lines, options.ReplyPolicy)
+	}
+	results := verifyChunkLines(ctx, addresses.Probes, engineVerifier, options.Parallelism)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	output.Greylist = options.Greylist.run(ctx, addresses.Probes, results, engineVerifier, options.Parallelism)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	transcriptBuf := &bytes.Buffer{}
+	transcriptEncoder := json.NewEncoder(transcriptBuf)
+	transcriptHashes := map[string]bool{}
+
+	for index, line := range lines {
+		result := results[addresses.probeFor(index)]
+		duplicateOf := addresses.duplicateOf(index)
 		output.EmailCount++
-		result := engineVerifier.Verify(ctx, line)
-		reason := reasonWithEvidence(result, probeAttemptChainEnabled, unknownReasonTaxonomyEnabled)
+		reason := reasonWithEvidence(result, options.ProbeAttemptChainEnabled, options.UnknownReasonTaxonomyEnabled)
 		baseReason := baseReasonOnly(reason)
 		output.ReasonCounts[baseReason]++
 		if reasonTag := reasonTagFrom(reason); reasonTag != "" {
 			output.ReasonTags[reasonTag]++
 		}
+		if duplicateOf != "" {
+			output.DuplicateCount++
+		} else {
+			output.recordEHLOProfile(result, baseReason)
+		}
+		// Duplicate rows share the probe's transcript under their own hash,
+		// so every row that was answered by a probe can be traced.
+		if emailHash := verifier.EmailHash(line); len(result.Transcript) > 0 && !transcriptHashes[emailHash] {
+			transcriptHashes[emailHash] = true
+			if err := transcriptEncoder.Encode(transcriptRecord{EmailHash: emailHash, Transcript: result.Transcript}); err != nil {
+				return nil, err
+			}
+		}
+
+		row := []string{line, reason}
+		if options.DomainAuthColumns {
+			row = append(row, domainAuthColumnValues(result.DomainAuth)...)
+		}
+		if options.Dedupe {
+			row = append(row, addresses.Canonical[index], duplicateOf)
+		}
 
 		switch result.Category {
 		case verifier.CategoryInvalid:
 			output.InvalidCount++
-			_ = invalidWriter.Write([]string{line, reason})
+			_ = invalidWriter.Write(row)
 		case verifier.CategoryRisky:
 			output.RiskyCount++
-			_ = riskyWriter.Write([]string{line, reason})
+			_ = riskyWriter.Write(row)
 		case verifier.CategoryValid:
 			output.ValidCount++
-			_ = validWriter.Write([]string{line, reason})
+			_ = validWriter.Write(row)
 		default:
 			output.RiskyCount++
-			_ = riskyWriter.Write([]string{line, reason})
+			_ = riskyWriter.Write(row)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
 	validWriter.Flush()
 	invalidWriter.Flush()
 	riskyWriter.Flush()
@@ -533,6 +745,7 @@ func buildOutputs(
 	output.ValidData = validBuf.Bytes()
 	output.InvalidData = invalidBuf.Bytes()
 	output.RiskyData = riskyBuf.Bytes()
+	output.TranscriptData = transcriptBuf.Bytes()
 
 	return output, nil
 }
@@ -622,6 +835,9 @@ func reasonWithEvidence(
 	}
 	if rule := strings.TrimSpace(result.MatchedRuleID); rule != "" {
 		segments = append(segments, "rule="+rule)
+		if captures := encodeRuleCaptures(result.RuleCaptures); captures != "" {
+			segments = append(segments, "rule_captures="+captures)
+		}
 	}
 	reasonTag := strings.TrimSpace(result.ReasonTag)
 	if unknownReasonTaxonomyEnabled {
@@ -651,6 +867,42 @@ func reasonWithEvidence(
 	if evidenceStrength := strings.TrimSpace(result.EvidenceStrength); evidenceStrength != "" {
 		segments = append(segments, "evidence="+evidenceStrength)
 	}
+	if result.Evidence != nil && strings.TrimSpace(result.Evidence.EHLOProfile) != "" {
+		segments = append(segments, "ehlo="+strings.TrimSpace(result.Evidence.EHLOProfile))
+	}
+	if sourceIP := strings.TrimSpace(result.SourceIP); sourceIP != "" {
+		segments = append(segments, "source_ip="+sourceIP)
+	}
+	if result.SMTPUTF8 {
+		segments = append(segments, "smtputf8=required")
+	}
+	if result.FreeMail {
+		segments = append(segments, "free_mail=true")
+	}
+	if result.DomainAuth != nil && result.DomainAuth.Missing() {
+		segments = append(segments, "mail_auth=missing")
+	}
+	if model := strings.TrimSpace(result.ScoreModel); model != "" {
+		segments = append(segments, "score="+strconv.Itoa(result.Score), "score_model="+model)
+		if factors := encodeScoreFactors(result.ScoreFactors); factors != "" {
+			segments = append(segments, "score_factors="+factors)
+		}
+	}
+	if result.Evidence != nil && result.Evidence.STARTTLS != "" {
+		segments = append(segments, "starttls="+result.Evidence.STARTTLS)
+		if version := strings.TrimSpace(result.Evidence.TLSVersion); version != "" {
+			segments = append(segments, "tls="+version)
+		}
+		if certStatus := strings.TrimSpace(result.Evidence.TLSCertStatus); certStatus != "" {
+			segments = append(segments, "tls_cert="+certStatus)
+		}
+	}
+	if result.Evidence != nil && result.Evidence.CatchAll != "" {
+		segments = append(segments, "catch_all="+result.Evidence.CatchAll, "catch_all_samples="+result.Evidence.CatchAllSamples)
+		if result.Evidence.CatchAllCached {
+			segments = append(segments, "catch_all_cache=hit")
+		}
+	}
 	if probeAttemptChainEnabled {
 		if attemptChain := encodeAttemptChain(result.AttemptChain); attemptChain != "" {
 			segments = append(segments, "attempt_chain="+attemptChain)
@@ -660,7 +912,11 @@ func reasonWithEvidence(
 	if len(segments) == 0 {
 		return reason
 	}
-
+	// Reasons such as domain_typo_suspected already carry a metadata
+	// segment; consumers split once on ":" so further segments join with ";".
+	if strings.Contains(reason, ":") {
+		return reason + ";" + strings.Join(segments, ";")
+	}
 	return reason + ":" + strings.Join(segments, ";")
 }
 
@@ -677,6 +933,41 @@ func encodeAttemptChain(chain []verifier.AttemptEvidence) string {
 	return base64.RawURLEncoding.EncodeToString(encoded)
 }
 
+// encodeRuleCaptures renders named pattern captures as sorted name:value
+// pairs, e.g. as:201806281. Characters that would break the metadata
+// become underscores.
+func encodeRuleCaptures(captures map[string]string) string {
+	names := make([]string, 0, len(captures))
+	for name := range captures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.Map(func(r rune) rune {
+			if r == ';' || r == ',' || r == ':' || r == '"' || unicode.IsSpace(r) {
+				return '_'
+			}
+			return r
+		}, captures[name])
+		parts = append(parts, name+":"+value)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// encodeScoreFactors renders factors as name:points pairs, e.g.
+// base:50,smtp_deliverable:45,free_mail:-5.
+func encodeScoreFactors(factors []verifier.ScoreFactor) string {
+	parts := make([]string, 0, len(factors))
+	for _, factor := range factors {
+		parts = append(parts, factor.Name+":"+strconv.Itoa(factor.Points))
+	}
+
+	return strings.Join(parts, ",")
+}
+
 func normalizedReasonTag(result verifier.Result, baseReason string) string {
 	currentTag := strings.ToLower(strings.TrimSpace(result.ReasonTag))
 	baseReason = strings.ToLower(strings.TrimSpace(baseReason))
@@ -692,6 +983,7 @@ func normalizedReasonTag(result verifier.Result, baseReason string) string {
 		currentTag == "auth_required":
 		return "identity_rejected"
 	case strings.Contains(baseReason, "connect_timeout"),
+		strings.HasPrefix(baseReason, "smtp_proxy_"),
 		strings.Contains(baseReason, "read_error"),
 		baseReason == "smtp_timeout":
 		return "connection_unstable"
@@ -732,6 +1024,23 @@ func normalizedReasonTag(result verifier.Result, baseReason string) string {
 	return "other_unknown"
 }
 
+// transcriptRecord is one line of the transcript sidecar, keyed by the same
+// email hash as Laravel's decision traces.
+type transcriptRecord struct {
+	EmailHash  string                     `json:"email_hash"`
+	Transcript []verifier.TranscriptEntry `json:"transcript"`
+}
+
+// domainAuthColumnValues returns the spf, dmarc, mta_sts and bimi columns,
+// left empty when the records were not looked up.
+func domainAuthColumnValues(auth *verifier.DomainAuth) []string {
+	if auth == nil {
+		return []string{"", "", "", ""}
+	}
+
+	return []string{auth.SPF, auth.DMARC, auth.MTASTS, auth.BIMI}
+}
+
 func firstError(errors ...error) error {
 	for _, err := range errors {
 		if err != nil {
@@ -776,6 +1085,24 @@ func (w *Worker) refreshPolicyIfNeeded(ctx context.Context, now time.Time) {
 	w.policyMu.Unlock()
 
 	w.updateMaxConcurrency(state)
+	w.updateCircuitBreakers(state)
+	w.updateRateLimiters(state)
+}
+
+// syncDomainListsIfNeeded syncs the control-plane domain lists on the policy
+// refresh interval. It runs apart from the Laravel policy fetch so lists stay
+// current while Laravel is unreachable.
+func (w *Worker) syncDomainListsIfNeeded(ctx context.Context, now time.Time) {
+	if w.cfg.PolicyRefresh <= 0 || !w.cfg.ControlPlanePolicySyncEnabled || w.cfg.ControlPlaneClient == nil {
+		return
+	}
+
+	if !w.lastListSync.IsZero() && now.Sub(w.lastListSync) < w.cfg.PolicyRefresh {
+		return
+	}
+
+	w.lastListSync = now
+	syncDomainLists(ctx, w.cfg.ControlPlaneClient, w.domainLists)
 }
 
 type policyRuntimeState struct {
@@ -854,6 +1181,9 @@ func policyStateFrom(resp *api.PolicyResponse) policyState {
 		roleAccounts:         mapFromSlice(resp.Data.RoleAccountsList),
 		providerPolicies:     providerPoliciesFrom(resp.Data.ProviderPolicies),
 	}
+	if len(resp.Data.TypoDomains) > 0 {
+		state.typoEngine = verifier.NewTypoEngine(verifier.SplitTypoEntries(resp.Data.TypoDomains))
+	}
 
 	if policy, ok := resp.Data.Policies["standard"]; ok {
 		state.standard = policyConfigFrom(policy)
@@ -873,6 +1203,7 @@ func policyConfigFrom(policy api.Policy) policyConfig {
 		SMTPReadTimeoutMs:          policy.SMTPReadTimeoutMs,
 		MaxMXAttempts:              policy.MaxMXAttempts,
 		MaxConcurrencyDefault:      policy.MaxConcurrencyDefault,
+		ChunkParallelism:           policy.ChunkParallelism,
 		PerDomainConcurrency:       policy.PerDomainConcurrency,
 		CatchAllDetectionEnabled:   policy.CatchAllDetectionEnabled,
 		GlobalConnectsPerMinute:    policy.GlobalConnectsPerMinute,
@@ -1032,63 +1363,118 @@ func (w *Worker) isHeartbeatIdentityMissing() bool {
 }
 
 func (w *Worker) verifierForMode(mode string, policy policyConfig, hasPolicy bool) verifier.Verifier {
-	config := w.cfg.BaseVerifierConfig
 	state := w.policySnapshot()
-	config = applyGlobalOverrides(config, state)
-
-	if hasPolicy {
-		config = applyPolicy(config, policy)
-	}
-
-	config.ProviderPolicyEngineEnabled = state.policyEngineEnabled
-	config.AdaptiveRetryEnabled = state.adaptiveRetryEnabled
-	config.ProviderModes = cloneProviderModes(state.providerModes)
-	if state.replyPolicyEngine != nil {
-		config.ProviderReplyPolicyEngine = cloneProviderReplyPolicyEngine(state.replyPolicyEngine)
-	} else {
-		config.ProviderReplyPolicyEngine = cloneProviderReplyPolicyEngine(config.ProviderReplyPolicyEngine)
-	}
-
-	if config.ProviderReplyPolicyEngine != nil {
-		config.ProviderReplyPolicyEngine.Enabled = config.ProviderPolicyEngineEnabled
-	}
+	config := w.verifierConfigForMode(mode, state, policy, hasPolicy)
 
 	var smtpFactory verifier.SMTPCheckerFactory
 	if mode == "enhanced" {
 		smtpFactory = func(cfg verifier.Config) verifier.SMTPChecker {
 			return verifier.NetSMTPProber{
-				Dialer:                   nil,
-				ConnectTimeout:           time.Duration(cfg.SMTPConnectTimeout) * time.Millisecond,
-				ReadTimeout:              time.Duration(cfg.SMTPReadTimeout) * time.Millisecond,
-				EhloTimeout:              time.Duration(cfg.SMTPEhloTimeout) * time.Millisecond,
-				HeloName:                 cfg.HeloName,
-				MailFromAddress:          cfg.MailFromAddress,
-				ProviderMode:             "normal",
-				SessionStrategyID:        "generic:normal",
-				RateLimiter:              verifier.NewRateLimiter(cfg.SMTPRateLimitPerMinute),
-				CatchAllDetectionEnabled: cfg.CatchAllDetectionEnabled,
-				ReplyPolicyEngine:        cfg.ProviderReplyPolicyEngine,
-				AdaptiveRetryEnable:      cfg.AdaptiveRetryEnabled,
+				Dialer:                    cfg.SMTPDialer,
+				ConnectTimeout:            time.Duration(cfg.SMTPConnectTimeout) * time.Millisecond,
+				ReadTimeout:               time.Duration(cfg.SMTPReadTimeout) * time.Millisecond,
+				EhloTimeout:               time.Duration(cfg.SMTPEhloTimeout) * time.Millisecond,
+				HeloName:                  cfg.HeloName,
+				MailFromAddress:           cfg.MailFromAddress,
+				ProviderMode:              "normal",
+				SessionStrategyID:         "generic:normal",
+				RateLimiter:               cfg.RateLimiter,
+				CatchAllDetectionEnabled:  cfg.CatchAllDetectionEnabled,
+				CatchAllCache:             cfg.CatchAllCache,
+				CatchAllSamples:           cfg.CatchAllSamples,
+				ReplyPolicyEngine:         cfg.ProviderReplyPolicyEngine,
+				AdaptiveRetryEnable:       cfg.AdaptiveRetryEnabled,
+				SessionPool:               cfg.SMTPSessionPool,
+				MaxSessionsPerHost:        sessionsPerHost(cfg),
+				ReuseConnectionForRetries: cfg.ReuseConnectionForRetries,
+				TLSMode:                   cfg.SMTPTLSMode,
+				EHLOProfile:               cfg.EHLOProfile,
+				IdentityDomain:            cfg.IdentityDomain,
+				Proxy:                     cfg.SMTPProxy,
+				TranscriptEnabled:         cfg.SMTPTranscriptEnabled,
+				ReplyCorpus:               cfg.ReplyCorpus,
 			}
 		}
 	} else {
 		smtpFactory = func(cfg verifier.Config) verifier.SMTPChecker {
 			return verifier.NetSMTPChecker{
-				Dialer:              nil,
+				Dialer:              cfg.SMTPDialer,
 				ConnectTimeout:      time.Duration(cfg.SMTPConnectTimeout) * time.Millisecond,
 				ReadTimeout:         time.Duration(cfg.SMTPReadTimeout) * time.Millisecond,
 				EhloTimeout:         time.Duration(cfg.SMTPEhloTimeout) * time.Millisecond,
 				HeloName:            cfg.HeloName,
 				ProviderMode:        "normal",
 				SessionStrategyID:   "generic:normal",
-				RateLimiter:         verifier.NewRateLimiter(cfg.SMTPRateLimitPerMinute),
+				Proxy:               cfg.SMTPProxy,
+				RateLimiter:         cfg.RateLimiter,
 				ReplyPolicyEngine:   cfg.ProviderReplyPolicyEngine,
 				AdaptiveRetryEnable: cfg.AdaptiveRetryEnabled,
+				ReplyCorpus:         cfg.ReplyCorpus,
 			}
 		}
 	}
 
-	return verifier.NewProviderAwareVerifier(config, verifier.NetMXResolver{}, smtpFactory, state.providerPolicies)
+	return verifier.NewProviderAwareVerifier(config, w.mxResolver(), smtpFactory, state.providerPolicies)
+}
+
+// verifierConfigForMode builds the verifier config of a mode from the base
+// config, the global overrides and the mode policy held in state.
+func (w *Worker) verifierConfigForMode(mode string, state policyState, policy policyConfig, hasPolicy bool) verifier.Config {
+	config := w.cfg.BaseVerifierConfig
+	config = applyGlobalOverrides(config, state)
+
+	if hasPolicy {
+		config = applyPolicy(config, policy)
+	}
+	config.CircuitBreaker = w.circuitBreakerFor(mode)
+	config.SMTPSessionPool = w.smtpSessions
+	config.HostLimiter = w.hostLimiter
+	config.RateLimiter = w.rateLimiterFor(mode)
+	config.DomainLists = w.domainLists
+	config.CatchAllCache = w.catchAllCache
+
+	config.ProviderPolicyEngineEnabled = state.policyEngineEnabled
+	config.AdaptiveRetryEnabled = state.adaptiveRetryEnabled
+	config.ProviderModes = cloneProviderModes(state.providerModes)
+	if state.replyPolicyEngine != nil {
+		config.ProviderReplyPolicyEngine = cloneProviderReplyPolicyEngine(state.replyPolicyEngine)
+	} else {
+		config.ProviderReplyPolicyEngine = cloneProviderReplyPolicyEngine(config.ProviderReplyPolicyEngine)
+	}
+
+	if config.ProviderReplyPolicyEngine != nil {
+		config.ProviderReplyPolicyEngine.Enabled = config.ProviderPolicyEngineEnabled
+	}
+
+	return config
+}
+
+// mxResolver returns the worker-wide MX cache so answers outlive a single
+// chunk, or a plain resolver when caching is disabled.
+func (w *Worker) mxResolver() verifier.MXResolver {
+	if w.mxCache == nil {
+		return w.baseMXResolver()
+	}
+
+	return w.mxCache
+}
+
+func (w *Worker) baseMXResolver() verifier.MXResolver {
+	if w.cfg.MXResolver != nil {
+		return w.cfg.MXResolver
+	}
+
+	return verifier.NetMXResolver{}
+}
+
+// sessionsPerHost caps pooled SMTP sessions per MX host using the provider
+// session rule when one applies and the per-domain concurrency otherwise.
+func sessionsPerHost(cfg verifier.Config) int {
+	if cfg.SessionMaxConcurrency > 0 {
+		return cfg.SessionMaxConcurrency
+	}
+
+	return cfg.PerDomainConcurrency
 }
 
 func applyGlobalOverrides(config verifier.Config, state policyState) verifier.Config {
@@ -1098,12 +1484,18 @@ func applyGlobalOverrides(config verifier.Config, state policyState) verifier.Co
 	if state.mailFromAddress != "" {
 		config.MailFromAddress = state.mailFromAddress
 	}
+	if state.identityDomain != "" {
+		config.IdentityDomain = state.identityDomain
+	}
 	if state.roleAccountsBehavior != "" {
 		config.RoleAccountsBehavior = state.roleAccountsBehavior
 	}
 	if state.roleAccounts != nil {
 		config.RoleAccounts = state.roleAccounts
 	}
+	if state.typoEngine != nil {
+		config.TypoEngine = state.typoEngine
+	}
 
 	return config
 }
@@ -1186,6 +1578,105 @@ func (w *Worker) updateMaxConcurrency(state policyState) {
 	atomic.StoreInt64(&w.maxConcurrency, int64(max))
 }
 
+func (w *Worker) chunkParallelism(policy policyConfig, hasPolicy bool) int {
+	parallelism := w.cfg.ChunkParallelism
+	if hasPolicy && policy.ChunkParallelism > 0 {
+		parallelism = policy.ChunkParallelism
+	}
+
+	return normalizeChunkParallelism(parallelism)
+}
+
+func (w *Worker) updateCircuitBreakers(state policyState) {
+	for mode, policy := range map[string]policyConfig{"standard": state.standard, "enhanced": state.enhanced} {
+		rate := 0.0
+		if policy.CircuitBreakerTempfailRate != nil {
+			rate = *policy.CircuitBreakerTempfailRate
+		}
+
+		w.circuitBreakerFor(mode).SetTempfailRate(rate)
+	}
+}
+
+// updateRateLimiters applies the global connect rate of each mode to the
+// shared token buckets, so chunks already running pick up a new policy.
+// Provider overrides are replaced with the rates of the new provider
+// policies, so overrides the policy dropped do not linger.
+func (w *Worker) updateRateLimiters(state policyState) {
+	for mode, policy := range map[string]policyConfig{"standard": state.standard, "enhanced": state.enhanced} {
+		global := w.cfg.BaseVerifierConfig.SMTPRateLimitPerMinute
+		if policy.GlobalConnectsPerMinute != nil {
+			global = *policy.GlobalConnectsPerMinute
+		}
+
+		limiter := w.rateLimiterFor(mode)
+		config := w.verifierConfigForMode(mode, state, policy, state.loaded)
+		limiter.SetKeyRates(verifier.ProviderKeyRates(config, state.providerPolicies))
+		limiter.SetRate(w.cfg.BaseVerifierConfig.SMTPRateLimitPerMinute, w.cfg.SMTPRateLimitBurst)
+		limiter.SetGlobalRate(global)
+	}
+}
+
+func (w *Worker) rateLimiterFor(mode string) *verifier.RateLimiter {
+	if w.rateLimiters == nil {
+		return nil
+	}
+
+	return w.rateLimiters[normalizeVerificationMode(mode)]
+}
+
+func (w *Worker) workerMetrics() *api.ControlPlaneWorkerMetrics {
+	if w.mxCache == nil {
+		return nil
+	}
+
+	return &api.ControlPlaneWorkerMetrics{CacheHitRate: w.mxCache.HitRate()}
+}
+
+func (w *Worker) rateLimiterStats() verifier.RateLimiterStats {
+	var total verifier.RateLimiterStats
+	for _, limiter := range w.rateLimiters {
+		stats := limiter.Stats()
+		total.Waits += stats.Waits
+		total.Throttled += stats.Throttled
+		total.TotalWait += stats.TotalWait
+		if stats.MaxWait > total.MaxWait {
+			total.MaxWait = stats.MaxWait
+		}
+	}
+
+	return total
+}
+
+func (w *Worker) circuitBreakerFor(mode string) *verifier.CircuitBreaker {
+	if w.circuitBreakers == nil {
+		return nil
+	}
+
+	return w.circuitBreakers[normalizeVerificationMode(mode)]
+}
+
+func (w *Worker) collectCircuitBreakerEvents() {
+	events := make([]api.ControlPlaneCircuitBreakerEvent, 0)
+
+	for _, mode := range []string{"standard", "enhanced"} {
+		for _, transition := range w.circuitBreakerFor(mode).DrainTransitions() {
+			events = append(events, api.ControlPlaneCircuitBreakerEvent{
+				Mode:         mode,
+				Key:          transition.Key,
+				Scope:        transition.Scope,
+				From:         transition.From,
+				To:           transition.To,
+				TempfailRate: transition.TempfailRate,
+				Samples:      transition.Samples,
+				At:           transition.At.UTC().Format(time.RFC3339),
+			})
+		}
+	}
+
+	w.telemetry.queueCircuitBreakerEvents(events)
+}
+
 func (w *Worker) activeCount() int64 {
 	return atomic.LoadInt64(&w.active)
 }
@@ -1291,7 +1782,9 @@ func normalizeVerificationMode(value string) string {
 
 func (w *Worker) sendHeartbeats(ctx context.Context) {
 	if w.cfg.ControlPlaneHeartbeatEnabled && w.cfg.ControlPlaneClient != nil {
+		w.collectCircuitBreakerEvents()
 		snapshot := w.telemetry.snapshot()
+		applyRateLimiterStats(snapshot.sessionMetrics, w.rateLimiterStats())
 		payload := api.ControlPlaneHeartbeatRequest{
 			WorkerID:  w.cfg.WorkerID,
 			Host:      w.cfg.Server.Name,
@@ -1303,9 +1796,11 @@ func (w *Worker) sendHeartbeats(ctx context.Context) {
 				fmt.Sprintf("policy_sync:%t", w.cfg.ControlPlanePolicySyncEnabled),
 			},
 			Status:                w.currentDesiredState(),
+			Metrics:               w.workerMetrics(),
 			StageMetrics:          snapshot.stageMetrics,
 			SMTPMetrics:           snapshot.smtpMetrics,
 			ProviderMetrics:       snapshot.providerMetrics,
+			EHLOProfileMetrics:    snapshot.ehloProfileMetrics,
 			RoutingMetrics:        snapshot.routingMetrics,
 			SessionMetrics:        snapshot.sessionMetrics,
 			AttemptRouteMetrics:   snapshot.attemptRouteMetrics,
@@ -1313,11 +1808,22 @@ func (w *Worker) sendHeartbeats(ctx context.Context) {
 			UnknownReasonTags:     snapshot.unknownReasonTags,
 			SessionStrategyID:     w.currentSessionStrategyID(),
 			ReasonTagCounts:       snapshot.reasonTagCounts,
+			CircuitBreakerEvents:  w.telemetry.takeCircuitBreakerEvents(),
+		}
+		payload.Tags = append(payload.Tags, domainListTags(w.domainLists)...)
+		if w.cfg.BaseVerifierConfig.ScoringEnabled {
+			payload.Tags = append(payload.Tags, "score_model:"+w.currentScoreModel())
 		}
 
 		response, err := w.cfg.ControlPlaneClient.Heartbeat(ctx, payload)
 		if err != nil {
 			fmt.Printf("control-plane heartbeat error: %v\n", err)
+			// A payload the control plane rejected would be rejected again,
+			// so only keep the events for transient failures.
+			var apiErr api.APIError
+			if !errors.As(err, &apiErr) || apiErr.Status >= 500 {
+				w.telemetry.queueCircuitBreakerEvents(payload.CircuitBreakerEvents)
+			}
 		} else {
 			w.applyControlPlaneHeartbeat(response)
 		}
@@ -1395,6 +1901,17 @@ func (w *Worker) currentSessionStrategyID() string {
 	return fmt.Sprintf("%s:%s", mode, version)
 }
 
+// currentScoreModel names the scoring model chunks are verified with, which
+// follows the active policy version.
+func (w *Worker) currentScoreModel() string {
+	engine := w.policySnapshot().replyPolicyEngine
+	if engine == nil {
+		engine = w.cfg.BaseVerifierConfig.ProviderReplyPolicyEngine
+	}
+
+	return verifier.ScoringModelFor(engine).Version
+}
+
 func (w *Worker) providerModeForRuntime(provider string) string {
 	provider = normalizeProviderForRuntime(provider)
 
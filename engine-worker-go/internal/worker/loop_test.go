@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"engine-worker-go/internal/api"
 	"engine-worker-go/internal/verifier"
@@ -388,6 +389,97 @@ func TestReasonWithEvidenceIncludesAttemptMetadata(t *testing.T) {
 	}
 }
 
+func TestReasonWithEvidenceIncludesScore(t *testing.T) {
+	t.Parallel()
+
+	reason := reasonWithEvidence(verifier.Result{
+		Category:   verifier.CategoryValid,
+		Reason:     "rcpt_ok",
+		Score:      90,
+		ScoreModel: "score-v1",
+		ScoreFactors: []verifier.ScoreFactor{
+			{Name: verifier.ScoreFactorBase, Points: 50},
+			{Name: verifier.ScoreFactorSMTPDeliverable, Points: 45},
+			{Name: verifier.ScoreFactorFreeMail, Points: -5},
+		},
+	}, false, false)
+
+	if value := parseReasonMetadataValue(reason, "score"); value != "90" {
+		t.Fatalf("expected score 90, got %q in %q", value, reason)
+	}
+	if value := parseReasonMetadataValue(reason, "score_model"); value != "score-v1" {
+		t.Fatalf("expected score_model score-v1, got %q", value)
+	}
+	if value := parseReasonMetadataValue(reason, "score_factors"); value != "base:50,smtp_deliverable:45,free_mail:-5" {
+		t.Fatalf("expected encoded score factors, got %q", value)
+	}
+
+	if unscored := reasonWithEvidence(verifier.Result{Reason: "smtp_probe_disabled"}, false, false); strings.Contains(unscored, "score=") {
+		t.Fatalf("expected unscored results to omit score metadata, got %q", unscored)
+	}
+
+	typo := reasonWithEvidence(verifier.Result{Category: verifier.CategoryRisky, Reason: "domain_typo_suspected:suggest=gmail.com", FreeMail: true, Score: 40, ScoreModel: "score-v1"}, false, false)
+	if typo != "domain_typo_suspected:suggest=gmail.com;free_mail=true;score=40;score_model=score-v1" {
+		t.Fatalf("expected metadata appended to the existing segment, got %q", typo)
+	}
+	if value := parseReasonMetadataValue(typo, "score"); value != "40" {
+		t.Fatalf("expected score parsed from a typo reason, got %q", value)
+	}
+}
+
+func TestReasonWithEvidenceIncludesRuleCaptures(t *testing.T) {
+	t.Parallel()
+
+	reason := reasonWithEvidence(verifier.Result{
+		Category:      verifier.CategoryRisky,
+		Reason:        "smtp_tempfail",
+		MatchedRuleID: "microsoft-as-block",
+		RuleCaptures:  map[string]string{"as": "201806281", "host": "mx 1;a"},
+	}, false, false)
+
+	if value := parseReasonMetadataValue(reason, "rule_captures"); value != "as:201806281,host:mx_1_a" {
+		t.Fatalf("expected sorted, escaped rule captures, got %q in %q", value, reason)
+	}
+}
+
+func TestReasonWithEvidenceIncludesCatchAllVerdict(t *testing.T) {
+	t.Parallel()
+
+	reason := reasonWithEvidence(verifier.Result{
+		Category: verifier.CategoryRisky,
+		Reason:   "catch_all_inconsistent",
+		Evidence: &verifier.ReplyEvidence{
+			CatchAll:        verifier.CatchAllInconsistent,
+			CatchAllSamples: "1/3",
+			CatchAllCached:  true,
+		},
+	}, false, false)
+
+	if value := parseReasonMetadataValue(reason, "catch_all"); value != "inconsistent" {
+		t.Fatalf("expected catch_all inconsistent, got %q in %q", value, reason)
+	}
+	if value := parseReasonMetadataValue(reason, "catch_all_samples"); value != "1/3" {
+		t.Fatalf("expected catch_all_samples 1/3, got %q", value)
+	}
+	if value := parseReasonMetadataValue(reason, "catch_all_cache"); value != "hit" {
+		t.Fatalf("expected catch_all_cache hit, got %q", value)
+	}
+}
+
+func TestReasonWithEvidenceFlagsMissingMailAuth(t *testing.T) {
+	t.Parallel()
+
+	missing := &verifier.DomainAuth{SPF: verifier.AuthMissing, DMARC: verifier.AuthMissing, MTASTS: verifier.AuthMissing, BIMI: verifier.AuthMissing}
+	if reason := reasonWithEvidence(verifier.Result{Category: verifier.CategoryValid, Reason: "rcpt_ok", DomainAuth: missing}, false, false); reason != "rcpt_ok:mail_auth=missing" {
+		t.Fatalf("expected mail_auth=missing metadata on the kept reason, got %q", reason)
+	}
+
+	signed := &verifier.DomainAuth{SPF: verifier.SPFHardfail, DMARC: verifier.AuthMissing, MTASTS: verifier.AuthMissing, BIMI: verifier.AuthMissing}
+	if reason := reasonWithEvidence(verifier.Result{Category: verifier.CategoryValid, Reason: "rcpt_ok", DomainAuth: signed}, false, false); strings.Contains(reason, "mail_auth=") {
+		t.Fatalf("expected no mail_auth metadata when a record exists, got %q", reason)
+	}
+}
+
 func TestReasonWithEvidenceNormalizesUnknownReasonTags(t *testing.T) {
 	t.Parallel()
 
@@ -457,3 +549,180 @@ func parseReasonMetadataValue(reason, key string) string {
 
 	return ""
 }
+
+func TestUpdateRateLimitersAppliesPolicyConnectRate(t *testing.T) {
+	t.Parallel()
+
+	w := New(nil, Config{SMTPRateLimitBurst: 1})
+	unlimited := 0
+	perMinute := 1
+	w.updateRateLimiters(policyState{
+		standard: policyConfig{GlobalConnectsPerMinute: &unlimited},
+		enhanced: policyConfig{GlobalConnectsPerMinute: &perMinute},
+	})
+
+	standard := w.rateLimiterFor("standard")
+	for i := 0; i < 3; i++ {
+		if err := standard.Wait(context.Background(), "mx:a.test"); err != nil {
+			t.Fatalf("expected standard mode to be unlimited, got %v", err)
+		}
+	}
+
+	enhanced := w.rateLimiterFor("enhanced")
+	_ = enhanced.Wait(context.Background(), "mx:a.test")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := enhanced.Wait(ctx, "mx:a.test"); err == nil {
+		t.Fatal("expected enhanced mode to be throttled by the policy rate")
+	}
+
+	stats := w.rateLimiterStats()
+	if stats.Waits != 5 || stats.Throttled != 1 {
+		t.Fatalf("unexpected combined wait stats: %+v", stats)
+	}
+}
+
+func TestUpdateRateLimitersKeepsGlobalCapAcrossKeys(t *testing.T) {
+	t.Parallel()
+
+	w := New(nil, Config{SMTPRateLimitBurst: 1})
+	perMinute := 1
+	w.rateLimiterFor("enhanced").SetKeyRate("provider:gmail", 6000)
+	w.updateRateLimiters(policyState{enhanced: policyConfig{GlobalConnectsPerMinute: &perMinute}})
+
+	enhanced := w.rateLimiterFor("enhanced")
+	if err := enhanced.Wait(context.Background(), "mx:a.test"); err != nil {
+		t.Fatalf("unexpected wait error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := enhanced.Wait(ctx, "provider:gmail"); err == nil {
+		t.Fatal("expected another key to share the policy's global rate")
+	}
+}
+
+func TestWorkerMetricsReportsMXCacheHitRate(t *testing.T) {
+	t.Parallel()
+
+	if metrics := New(nil, Config{}).workerMetrics(); metrics != nil {
+		t.Fatalf("expected no worker metrics without an MX cache, got %+v", metrics)
+	}
+
+	w := New(nil, Config{MXCacheMaxEntries: 10})
+	if _, ok := w.mxResolver().(*verifier.CachingMXResolver); !ok {
+		t.Fatalf("expected verifiers to share the MX cache, got %T", w.mxResolver())
+	}
+	if metrics := w.workerMetrics(); metrics == nil || metrics.CacheHitRate != 0 {
+		t.Fatalf("expected empty cache hit rate, got %+v", metrics)
+	}
+}
+
+type domainAuthVerifier struct{}
+
+func (domainAuthVerifier) Verify(_ context.Context, email string) verifier.Result {
+	if strings.HasSuffix(email, "@signed.test") {
+		return verifier.Result{
+			Category:   verifier.CategoryValid,
+			Reason:     "smtp_connect_ok",
+			DomainAuth: &verifier.DomainAuth{SPF: verifier.SPFHardfail, DMARC: verifier.DMARCReject, MTASTS: verifier.AuthPresent, BIMI: verifier.AuthMissing},
+		}
+	}
+
+	return verifier.Result{Category: verifier.CategoryValid, Reason: "smtp_connect_ok"}
+}
+
+func TestBuildOutputsAppendsDomainAuthColumns(t *testing.T) {
+	t.Parallel()
+
+	input := "user@signed.test\nuser@unknown.test\n"
+	outputs, err := buildOutputs(context.Background(), strings.NewReader(input), domainAuthVerifier{}, chunkOutputOptions{Parallelism: 1, DomainAuthColumns: true})
+	if err != nil {
+		t.Fatalf("buildOutputs returned error: %v", err)
+	}
+
+	rows := strings.Split(strings.TrimSpace(string(outputs.ValidData)), "\n")
+	expected := []string{
+		"email,reason,spf,dmarc,mta_sts,bimi",
+		"user@signed.test,smtp_connect_ok,hardfail,reject,present,missing",
+		"user@unknown.test,smtp_connect_ok,,,,",
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %d: %q", len(expected), len(rows), rows)
+	}
+	for i := range expected {
+		if rows[i] != expected[i] {
+			t.Fatalf("expected row %d to be %q, got %q", i, expected[i], rows[i])
+		}
+	}
+}
+
+type transcriptVerifier struct{}
+
+func (transcriptVerifier) Verify(_ context.Context, email string) verifier.Result {
+	if strings.HasPrefix(strings.ToLower(email), "probed@") {
+		return verifier.Result{
+			Category:   verifier.CategoryValid,
+			Reason:     "rcpt_ok",
+			Transcript: []verifier.TranscriptEntry{{Command: "RCPT TO:<sha256:" + verifier.EmailHash(email) + ">", Reply: []string{"250 OK"}}},
+		}
+	}
+
+	return verifier.Result{Category: verifier.CategoryInvalid, Reason: "syntax"}
+}
+
+func TestBuildOutputsWritesTranscriptSidecar(t *testing.T) {
+	t.Parallel()
+
+	input := "Probed@test.com\nnot-an-email\n"
+	outputs, err := buildOutputs(context.Background(), strings.NewReader(input), transcriptVerifier{}, chunkOutputOptions{Parallelism: 1})
+	if err != nil {
+		t.Fatalf("buildOutputs returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(outputs.TranscriptData)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected one transcript line, got %q", lines)
+	}
+	var record transcriptRecord
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("expected JSON transcript line, got %v", err)
+	}
+	if record.EmailHash != verifier.EmailHash("probed@test.com") || len(record.Transcript) != 1 {
+		t.Fatalf("expected transcript keyed by the email hash, got %+v", record)
+	}
+}
+
+func TestUpdateRateLimitersReappliesProviderConnectRates(t *testing.T) {
+	t.Parallel()
+
+	w := New(nil, Config{
+		BaseVerifierConfig: verifier.Config{SMTPRateLimitPerMinute: 1},
+		SMTPRateLimitBurst: 1,
+	})
+	unlimited := 0
+	connects := 6000
+	w.updateRateLimiters(policyState{
+		loaded:   true,
+		standard: policyConfig{GlobalConnectsPerMinute: &unlimited},
+		providerPolicies: []verifier.ProviderPolicy{
+			{Name: "gmail", Enabled: true, Domains: []string{"gmail.com"}, ConnectsPerMinute: &connects},
+		},
+	})
+
+	standard := w.rateLimiterFor("standard")
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := standard.Wait(ctx, "provider:gmail")
+		cancel()
+		if err != nil {
+			t.Fatalf("expected the provider rate to survive the policy refresh, got %v", err)
+		}
+	}
+
+	_ = standard.Wait(context.Background(), "mx:a.test")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := standard.Wait(ctx, "mx:a.test"); err == nil {
+		t.Fatal("expected other keys to keep the default rate")
+	}
+}
@@ -0,0 +1,164 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"engine-worker-go/internal/api"
+	"engine-worker-go/internal/smtptest"
+	"engine-worker-go/internal/verifier"
+)
+
+// fakeLaravel serves one smtp_probe chunk through the verifier API and keeps
+// the uploaded outputs and the completion payload.
+type fakeLaravel struct {
+	input     string
+	mu        sync.Mutex
+	claimed   bool
+	uploads   map[string]string
+	completed map[string]any
+	done      chan struct{}
+}
+
+func (f *fakeLaravel) handler(baseURL *string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/verifier/chunks/claim-next", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		claimed := f.claimed
+		f.claimed = true
+		f.mu.Unlock()
+		if claimed {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"chunk_id":          "chunk-1",
+			"job_id":            "job-1",
+			"chunk_no":          1,
+			"verification_mode": "enhanced",
+			"processing_stage":  "smtp_probe",
+		}})
+	})
+	mux.HandleFunc("/api/verifier/chunks/chunk-1", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"chunk_id": "chunk-1", "status": "processing"}})
+	})
+	mux.HandleFunc("/api/verifier/chunks/chunk-1/input-url", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"url": *baseURL + "/storage/input.csv"}})
+	})
+	mux.HandleFunc("/api/verifier/chunks/chunk-1/output-urls", func(w http.ResponseWriter, r *http.Request) {
+		targets := map[string]any{}
+		for _, name := range []string{"valid", "invalid", "risky"} {
+			targets[name] = map[string]string{"key": name + ".csv", "url": *baseURL + "/storage/" + name + ".csv"}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"disk": "local", "targets": targets}})
+	})
+	mux.HandleFunc("/api/verifier/chunks/chunk-1/log", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/api/verifier/chunks/chunk-1/complete", func(w http.ResponseWriter, r *http.Request) {
+		payload := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		f.mu.Lock()
+		f.completed = payload
+		f.mu.Unlock()
+		close(f.done)
+		_, _ = w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/storage/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/storage/")
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(f.input))
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.uploads[name] = string(body)
+		f.mu.Unlock()
+	})
+
+	return mux
+}
+
+func TestWorkerProbesChunkAgainstScriptedSMTPServer(t *testing.T) {
+	smtpServer, err := smtptest.NewServer(smtptest.Script{
+		Mailboxes: []string{"alice@example.test"},
+		Banner:    smtptest.Reply{Code: 220, Lines: []string{"mx.example.test ESMTP", "scripted"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to start smtp server: %v", err)
+	}
+	defer smtpServer.Close()
+
+	laravel := &fakeLaravel{
+		input:   "email\nalice@example.test\nbob@example.test\ncarol@gone.test\n",
+		uploads: map[string]string{},
+		done:    make(chan struct{}),
+	}
+	var baseURL string
+	httpServer := httptest.NewServer(laravel.handler(&baseURL))
+	defer httpServer.Close()
+	baseURL = httpServer.URL
+
+	w := New(api.NewClient(httpServer.URL, ""), Config{
+		PollInterval:      10 * time.Millisecond,
+		HeartbeatInterval: time.Hour,
+		MaxConcurrency:    1,
+		WorkerID:          "worker-1",
+		MXResolver:        smtptest.Resolver{NXDomains: []string{"gone.test"}},
+		BaseVerifierConfig: verifier.Config{
+			DNSTimeout:         1000,
+			SMTPConnectTimeout: 1000,
+			SMTPReadTimeout:    1000,
+			SMTPEhloTimeout:    1000,
+			MaxMXAttempts:      1,
+			HeloName:           "worker.test",
+			MailFromAddress:    "probe@worker.test",
+			SMTPDialer:         smtpServer.Dialer(),
+		},
+	})
+	w.policy = policyState{
+		loaded:              true,
+		enhancedModeEnabled: true,
+		enhanced:            policyConfig{Enabled: true},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = w.Run(ctx) }()
+
+	select {
+	case <-laravel.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the worker to complete the chunk")
+	}
+	for deadline := time.Now().Add(time.Second); w.activeCount() > 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	laravel.mu.Lock()
+	defer laravel.mu.Unlock()
+	if laravel.completed["valid_count"] != float64(1) || laravel.completed["invalid_count"] != float64(2) {
+		t.Fatalf("expected 1 valid and 2 invalid, got %+v", laravel.completed)
+	}
+	if !strings.Contains(laravel.uploads["valid.csv"], `alice@example.test,rcpt_ok:`) {
+		t.Fatalf("expected alice accepted by RCPT, got %q", laravel.uploads["valid.csv"])
+	}
+	invalid := laravel.uploads["invalid.csv"]
+	if !strings.Contains(invalid, `bob@example.test,rcpt_rejected:`) || !strings.Contains(invalid, `carol@gone.test,mx_missing`) {
+		t.Fatalf("expected bob rejected and carol without MX, got %q", invalid)
+	}
+	if count := smtpServer.CommandCount("RCPT TO"); count != 2 {
+		t.Fatalf("expected two RCPTs against the scripted server, got %q", smtpServer.Commands())
+	}
+}
@@ -1,10 +1,13 @@
 package worker
 
 import (
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"engine-worker-go/internal/api"
+	"engine-worker-go/internal/verifier"
 )
 
 type workerTelemetry struct {
@@ -22,6 +25,7 @@ type workerTelemetry struct {
 	smtpCatchAll int64
 
 	provider          map[string]*providerCounters
+	ehloProfile       map[string]*ehloProfileCounts
 	reasonTagCounters map[string]int64
 
 	retryClaimsTotal              int64
@@ -35,6 +39,12 @@ type workerTelemetry struct {
 	sessionRetryNewConnTotal  int64
 	throttleAppliedTotal      int64
 	mxFallbackAttemptsTotal   int64
+
+	greylistParkedTotal    int64
+	greylistRecoveredTotal int64
+	greylistDeferredTotal  int64
+
+	circuitBreakerEvents []api.ControlPlaneCircuitBreakerEvent
 }
 
 type providerCounters struct {
@@ -45,10 +55,18 @@ type providerCounters struct {
 	CatchAll  int64
 }
 
+type ehloProfileCounts struct {
+	Processed     int64
+	Tempfail      int64
+	Reject        int64
+	PolicyBlocked int64
+}
+
 type telemetrySnapshot struct {
 	stageMetrics          *api.ControlPlaneStageMetrics
 	smtpMetrics           *api.ControlPlaneSMTPMetrics
 	providerMetrics       []api.ControlPlaneProviderMetric
+	ehloProfileMetrics    []api.ControlPlaneEHLOProfileMetric
 	routingMetrics        *api.ControlPlaneRoutingMetrics
 	sessionMetrics        *api.ControlPlaneSessionMetrics
 	attemptRouteMetrics   *api.ControlPlaneAttemptRouteMetrics
@@ -60,6 +78,7 @@ type telemetrySnapshot struct {
 func newWorkerTelemetry() *workerTelemetry {
 	return &workerTelemetry{
 		provider:          map[string]*providerCounters{},
+		ehloProfile:       map[string]*ehloProfileCounts{},
 		reasonTagCounters: map[string]int64{},
 	}
 }
@@ -76,6 +95,10 @@ func (t *workerTelemetry) recordChunkSuccess(
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	t.greylistParkedTotal += int64(outputs.Greylist.Parked)
+	t.greylistRecoveredTotal += int64(outputs.Greylist.Recovered)
+	t.greylistDeferredTotal += int64(outputs.Greylist.Deferred)
+
 	switch stage {
 	case "smtp_probe":
 		t.smtpProcessed += int64(outputs.EmailCount)
@@ -95,6 +118,17 @@ func (t *workerTelemetry) recordChunkSuccess(
 		counters.Unknown += int64(outputs.RiskyCount)
 		counters.Tempfail += int64(outputs.baseReasonCount("smtp_tempfail"))
 		counters.CatchAll += int64(outputs.baseReasonPrefixCount("catch_all"))
+		for profile, chunkCounts := range outputs.EHLOProfiles {
+			profileCounters := t.ehloProfile[profile]
+			if profileCounters == nil {
+				profileCounters = &ehloProfileCounts{}
+				t.ehloProfile[profile] = profileCounters
+			}
+			profileCounters.Processed += chunkCounts.Processed
+			profileCounters.Tempfail += chunkCounts.Tempfail
+			profileCounters.Reject += chunkCounts.Reject
+			profileCounters.PolicyBlocked += chunkCounts.PolicyBlocked
+		}
 		for reasonTag, count := range outputs.ReasonTags {
 			normalizedTag := strings.ToLower(strings.TrimSpace(reasonTag))
 			if normalizedTag == "" {
@@ -182,6 +216,32 @@ func (t *workerTelemetry) recordClaimRouting(snapshot claimRoutingSnapshot) {
 	}
 }
 
+const maxQueuedCircuitBreakerEvents = 200
+
+func (t *workerTelemetry) queueCircuitBreakerEvents(events []api.ControlPlaneCircuitBreakerEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.circuitBreakerEvents = append(t.circuitBreakerEvents, events...)
+	if overflow := len(t.circuitBreakerEvents) - maxQueuedCircuitBreakerEvents; overflow > 0 {
+		t.circuitBreakerEvents = append([]api.ControlPlaneCircuitBreakerEvent(nil), t.circuitBreakerEvents[overflow:]...)
+	}
+}
+
+func (t *workerTelemetry) takeCircuitBreakerEvents() []api.ControlPlaneCircuitBreakerEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	events := t.circuitBreakerEvents
+	t.circuitBreakerEvents = nil
+
+	return events
+}
+
 func (t *workerTelemetry) snapshot() telemetrySnapshot {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -223,10 +283,30 @@ func (t *workerTelemetry) snapshot() telemetrySnapshot {
 		})
 	}
 
+	ehloProfileMetrics := make([]api.ControlPlaneEHLOProfileMetric, 0, len(t.ehloProfile))
+	for profile, counters := range t.ehloProfile {
+		if counters == nil || counters.Processed <= 0 {
+			continue
+		}
+
+		denominator := float64(counters.Processed)
+		ehloProfileMetrics = append(ehloProfileMetrics, api.ControlPlaneEHLOProfileMetric{
+			Profile:         profile,
+			Processed:       counters.Processed,
+			TempfailRate:    float64(counters.Tempfail) / denominator,
+			RejectRate:      float64(counters.Reject) / denominator,
+			PolicyBlockRate: float64(counters.PolicyBlocked) / denominator,
+		})
+	}
+	sort.Slice(ehloProfileMetrics, func(i, j int) bool {
+		return ehloProfileMetrics[i].Profile < ehloProfileMetrics[j].Profile
+	})
+
 	return telemetrySnapshot{
-		stageMetrics:    stageMetrics,
-		smtpMetrics:     smtpMetrics,
-		providerMetrics: providerMetrics,
+		stageMetrics:       stageMetrics,
+		smtpMetrics:        smtpMetrics,
+		providerMetrics:    providerMetrics,
+		ehloProfileMetrics: ehloProfileMetrics,
 		routingMetrics: &api.ControlPlaneRoutingMetrics{
 			RetryClaimsTotal:              t.retryClaimsTotal,
 			RetryAntiAffinitySuccessTotal: t.retryAntiAffinitySuccessTotal,
@@ -245,6 +325,9 @@ func (t *workerTelemetry) snapshot() telemetrySnapshot {
 			AttemptsTotal:           t.smtpProcessed,
 			RetryAttemptsTotal:      t.retryClaimsTotal,
 			MXFallbackAttemptsTotal: t.mxFallbackAttemptsTotal,
+			GreylistParkedTotal:     t.greylistParkedTotal,
+			GreylistRecoveredTotal:  t.greylistRecoveredTotal,
+			GreylistDeferredTotal:   t.greylistDeferredTotal,
 		},
 		retryAntiAffinityHits: t.retryAntiAffinitySuccessTotal,
 		unknownReasonTags:     unknownReasonTagCounters(t.reasonTagCounters),
@@ -252,6 +335,21 @@ func (t *workerTelemetry) snapshot() telemetrySnapshot {
 	}
 }
 
+// applyRateLimiterStats copies token-bucket wait statistics into the session
+// metrics sent with heartbeats.
+func applyRateLimiterStats(metrics *api.ControlPlaneSessionMetrics, stats verifier.RateLimiterStats) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.RateLimitWaitsTotal = stats.Waits
+	metrics.RateLimitThrottledTotal = stats.Throttled
+	metrics.RateLimitWaitMaxMS = float64(stats.MaxWait) / float64(time.Millisecond)
+	if stats.Throttled > 0 {
+		metrics.RateLimitWaitAvgMS = float64(stats.TotalWait) / float64(time.Millisecond) / float64(stats.Throttled)
+	}
+}
+
 func cloneReasonTagCounters(source map[string]int64) map[string]int64 {
 	if len(source) == 0 {
 		return map[string]int64{}
@@ -1,6 +1,14 @@
 package worker
 
-import "testing"
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"engine-worker-go/internal/api"
+	"engine-worker-go/internal/verifier"
+)
 
 func TestRecordClaimRoutingCounters(t *testing.T) {
 	t.Parallel()
@@ -92,3 +100,101 @@ func TestUnknownReasonTagCountersNormalizesTaxonomy(t *testing.T) {
 		t.Fatalf("mailbox_not_found should not be included in unknown-reason tags")
 	}
 }
+
+func TestCircuitBreakerEventsQueueAndDrain(t *testing.T) {
+	t.Parallel()
+
+	w := New(nil, Config{})
+	breaker := w.circuitBreakerFor("enhanced")
+	breaker.SetTempfailRate(0.5)
+	for i := 0; i < 10; i++ {
+		breaker.Record(true, "provider:gmail")
+	}
+
+	w.collectCircuitBreakerEvents()
+	events := w.telemetry.takeCircuitBreakerEvents()
+	if len(events) != 1 {
+		t.Fatalf("expected one circuit breaker event, got %d", len(events))
+	}
+	if events[0].Mode != "enhanced" || events[0].Key != "provider:gmail" || events[0].To != "open" {
+		t.Fatalf("unexpected circuit breaker event: %+v", events[0])
+	}
+
+	w.telemetry.queueCircuitBreakerEvents(events)
+	if requeued := w.telemetry.takeCircuitBreakerEvents(); len(requeued) != 1 {
+		t.Fatalf("expected requeued event to be returned, got %d", len(requeued))
+	}
+	if remaining := w.telemetry.takeCircuitBreakerEvents(); len(remaining) != 0 {
+		t.Fatalf("expected queue to be empty after take, got %d", len(remaining))
+	}
+}
+
+func TestSendHeartbeatsDropsCircuitBreakerEventsRejectedByControlPlane(t *testing.T) {
+	t.Parallel()
+
+	status := http.StatusServiceUnavailable
+	controlPlaneServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer controlPlaneServer.Close()
+
+	w := New(nil, Config{
+		ControlPlaneClient:           api.NewControlPlaneClient(controlPlaneServer.URL, ""),
+		ControlPlaneHeartbeatEnabled: true,
+	})
+	event := api.ControlPlaneCircuitBreakerEvent{Key: "mx:mx.test", Scope: "mx", From: "closed", To: "open"}
+
+	w.telemetry.queueCircuitBreakerEvents([]api.ControlPlaneCircuitBreakerEvent{event})
+	w.sendHeartbeats(context.Background())
+	if queued := w.telemetry.takeCircuitBreakerEvents(); len(queued) != 1 {
+		t.Fatalf("expected events kept after a server error, got %d", len(queued))
+	}
+
+	status = http.StatusBadRequest
+	w.telemetry.queueCircuitBreakerEvents([]api.ControlPlaneCircuitBreakerEvent{event})
+	w.sendHeartbeats(context.Background())
+	if queued := w.telemetry.takeCircuitBreakerEvents(); len(queued) != 0 {
+		t.Fatalf("expected events dropped after the payload was rejected, got %d", len(queued))
+	}
+}
+
+func TestEHLOProfileMetricsFromChunkOutputs(t *testing.T) {
+	t.Parallel()
+
+	outputs := &chunkOutputs{EmailCount: 3, EHLOProfiles: map[string]*ehloProfileCounts{}}
+	evidence := &verifier.ReplyEvidence{EHLOProfile: "provider-safe-gmail"}
+	outputs.recordEHLOProfile(verifier.Result{Category: verifier.CategoryValid, Evidence: evidence}, "rcpt_ok")
+	outputs.recordEHLOProfile(verifier.Result{Category: verifier.CategoryInvalid, Evidence: evidence}, "rcpt_rejected")
+	outputs.recordEHLOProfile(verifier.Result{Category: verifier.CategoryRisky, Evidence: evidence}, "smtp_tempfail")
+	outputs.recordEHLOProfile(verifier.Result{Category: verifier.CategoryValid}, "rcpt_ok")
+
+	telemetry := newWorkerTelemetry()
+	telemetry.recordChunkSuccess("smtp_probe", "gmail", outputs)
+
+	metrics := telemetry.snapshot().ehloProfileMetrics
+	if len(metrics) != 1 || metrics[0].Profile != "provider-safe-gmail" {
+		t.Fatalf("expected provider-safe-gmail metrics, got %+v", metrics)
+	}
+	if metrics[0].Processed != 3 {
+		t.Fatalf("expected processed=3, got %d", metrics[0].Processed)
+	}
+	if metrics[0].RejectRate < 0.33 || metrics[0].RejectRate > 0.34 {
+		t.Fatalf("expected reject rate of one third, got %f", metrics[0].RejectRate)
+	}
+	if metrics[0].TempfailRate < 0.33 || metrics[0].TempfailRate > 0.34 {
+		t.Fatalf("expected tempfail rate of one third, got %f", metrics[0].TempfailRate)
+	}
+}
+
+func TestGreylistReprobeOutcomesInAttemptRouteMetrics(t *testing.T) {
+	t.Parallel()
+
+	telemetry := newWorkerTelemetry()
+	telemetry.recordChunkSuccess("smtp_probe", "generic", &chunkOutputs{EmailCount: 5, Greylist: greylistOutcome{Parked: 3, Recovered: 2, Deferred: 1}})
+	telemetry.recordChunkSuccess("smtp_probe", "generic", &chunkOutputs{EmailCount: 2, Greylist: greylistOutcome{Parked: 1, Deferred: 1}})
+
+	metrics := telemetry.snapshot().attemptRouteMetrics
+	if metrics.GreylistParkedTotal != 4 || metrics.GreylistRecoveredTotal != 2 || metrics.GreylistDeferredTotal != 2 {
+		t.Fatalf("expected 4 parked, 2 recovered and 2 deferred, got %+v", metrics)
+	}
+}
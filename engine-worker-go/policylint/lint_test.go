@@ -0,0 +1,166 @@
+package policylint
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"engine-worker-go/replypolicy"
+)
+
+func v2Payload(profiles map[string]any) []byte {
+	if _, ok := profiles["generic"]; !ok {
+		profiles["generic"] = map[string]any{}
+	}
+	generic := profiles["generic"].(map[string]any)
+	if _, ok := generic["retry"]; !ok {
+		generic["retry"] = map[string]any{
+			"default_seconds":        60,
+			"tempfail_seconds":       90,
+			"greylist_seconds":       180,
+			"policy_blocked_seconds": 300,
+			"unknown_seconds":        75,
+		}
+	}
+
+	raw, _ := json.Marshal(map[string]any{"enabled": true, "version": "v2.9.0", "profiles": profiles})
+	return raw
+}
+
+func findingsByCode(report Report) map[string][]string {
+	out := map[string][]string{}
+	for _, finding := range report.Findings {
+		out[finding.Code] = append(out[finding.Code], finding.Path)
+	}
+
+	return out
+}
+
+func TestLintAcceptsMinimalPayload(t *testing.T) {
+	report := Lint(v2Payload(map[string]any{}))
+	if !report.Valid || len(report.Findings) != 0 {
+		t.Fatalf("expected a clean report, got %+v", report)
+	}
+}
+
+func TestLintReportsSchemaViolations(t *testing.T) {
+	raw := []byte(`{
+		"enabled": "yes",
+		"version": "v4.2.0",
+		"schema_version": "v4",
+		"profiles": {
+			"generic": {
+				"retry": {"default_seconds": 60},
+				"session": {"max_concurrency": 2, "connects_per_minute": 30, "reuse_connection_for_retries": true, "retry_jitter_percent": 10, "ehlo_profile": "default", "tls_mode": "always"},
+				"canonicalization": {"domains": ["example.com", 7], "ignore_dots": "yes"},
+				"message_rules": [{
+					"rule_tag": "custom", "confidence_hint": "high", "provider_scope": "generic",
+					"message_patterns": ["AS\\((\\d+"],
+					"stages": ["data"],
+					"decision_class": "maybe"
+				}]
+			}
+		},
+		"scoring": {"version": "score-v2", "weights": {"free_mail": 150, "fre_mail": 5}}
+	}`)
+
+	report := Lint(raw)
+	if report.Valid {
+		t.Fatal("expected schema violations to make the payload invalid")
+	}
+
+	expected := []string{
+		"enabled: must be a boolean",
+		"profiles.generic.retry.tempfail_seconds: must be present and numeric",
+		"profiles.generic.session.tls_mode: must be one of never, opportunistic, required",
+		"profiles.generic.canonicalization.domains: must be an array of strings",
+		"profiles.generic.canonicalization.ignore_dots: must be a boolean",
+		`profiles.generic.message_rules[0].rule_tag: has unsupported value "custom" (expected greylist, rate_limit, mailbox_full, policy_blocked, mailbox_not_found, auth_required, unknown_transient)`,
+		`profiles.generic.message_rules[0].decision_class: has unsupported value "maybe" (expected deliverable, undeliverable, retryable, policy_blocked, unknown)`,
+		"profiles.generic.message_rules[0].message_patterns[0]: error parsing regexp: missing closing ): `AS\\((\\d+`",
+		`profiles.generic.message_rules[0].stages[0]: unknown stage "data" (expected banner, ehlo, mail_from, rcpt)`,
+		"modes: is required for schema v4",
+		"scoring.weights.free_mail: must be an integer between -100 and 100",
+		"scoring.weights.fre_mail: is not a known score factor",
+	}
+	messages := map[string]bool{}
+	for _, finding := range report.Findings {
+		messages[finding.String()] = true
+	}
+	for _, message := range expected {
+		if !messages[message] {
+			t.Fatalf("expected finding %q, got %+v", message, report.Findings)
+		}
+	}
+}
+
+func TestLintReportsRuleProblems(t *testing.T) {
+	report := Lint(v2Payload(map[string]any{
+		"generic": map[string]any{
+			"enhanced_rules": []any{
+				map[string]any{"rule_id": "retry-42", "enhanced_prefixes": []string{"4.2."}, "decision_class": "retryable"},
+				map[string]any{"rule_id": "mailbox-full", "enhanced_prefixes": []string{"4.2.2"}, "decision_class": "retryable"},
+				map[string]any{"rule_id": "mailbox-full-priority", "enhanced_prefixes": []string{"4.2.2"}, "decision_class": "retryable", "priority": 5},
+			},
+			"smtp_code_rules": []any{
+				map[string]any{"rule_id": "reject", "smtp_codes": []int{550, 553}, "decision_class": "undeliverable"},
+				map[string]any{"rule_id": "blocked", "smtp_codes": []int{550, 554}, "decision_class": "policy_blocked"},
+				map[string]any{"rule_id": "blocked-as", "smtp_codes": []int{550}, "message_patterns": []string{`AS\(\d+\)`}, "decision_class": "policy_blocked", "priority": 10},
+				map[string]any{"rule_id": "reject", "smtp_codes": []int{551}, "decision_class": "undeliverable", "retry_after_seconds": 5},
+			},
+			"message_rules": []any{
+				map[string]any{"rule_id": "no-criteria", "decision_class": "unknown"},
+			},
+		},
+		"acme": map[string]any{
+			"message_rules": []any{
+				map[string]any{"rule_id": "acme-greylist", "message_contains": []string{"greylist"}, "decision_class": "retryable"},
+			},
+			"retry": map[string]any{"tempfail_seconds": 7200},
+		},
+	}))
+
+	if report.Valid || report.Errors != 1 {
+		t.Fatalf("expected the duplicate rule_id to be the only error, got %+v", report)
+	}
+
+	expected := map[string][]string{
+		CodeDuplicateRuleID:          {"profiles.generic.smtp_code_rules[3]"},
+		CodeShadowedRule:             {"profiles.generic.enhanced_rules[1]"},
+		CodeConflictingDecisionClass: {"profiles.generic.smtp_code_rules[1]"},
+		CodeUnreachableRule:          {"profiles.generic.message_rules[0]"},
+		CodeRetryOutOfBounds:         {"profiles.acme.retry.tempfail_seconds", "profiles.generic.smtp_code_rules[3].retry_after_seconds"},
+		CodeProfileWithoutFallback:   {"profiles.acme.smtp_code_rules"},
+	}
+	got := findingsByCode(report)
+	for code, paths := range expected {
+		if fmt.Sprint(got[code]) != fmt.Sprint(paths) {
+			t.Fatalf("expected %s at %v, got %v (all findings %+v)", code, paths, got[code], report.Findings)
+		}
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected only %d finding codes, got %v", len(expected), got)
+	}
+}
+
+func TestSchemaScoringFactorsMatchWorker(t *testing.T) {
+	weights := replypolicy.DefaultScoringModel().Weights
+	factors := Definition().ScoringFactors
+	if len(factors) != len(weights) {
+		t.Fatalf("expected %d schema score factors, got %d", len(weights), len(factors))
+	}
+	for _, factor := range factors {
+		if _, ok := weights[factor]; !ok {
+			t.Fatalf("expected the worker to know schema score factor %q", factor)
+		}
+	}
+}
+
+func TestSchemaRuleStagesMatchWorker(t *testing.T) {
+	for _, stage := range Definition().RuleStages {
+		raw := fmt.Sprintf(`{"profiles": {"generic": {"smtp_code_rules": [{"smtp_codes": [550], "stages": [%q]}]}}}`, stage)
+		if _, err := replypolicy.Parse(raw); err != nil {
+			t.Fatalf("expected the worker to accept schema stage %q, got %v", stage, err)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package policylint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"engine-worker-go/replypolicy"
+)
+
+type lintRule struct {
+	path       string
+	collection string
+	index      int
+	rule       replypolicy.Rule
+}
+
+func (r lintRule) describe() string {
+	if id := strings.TrimSpace(r.rule.RuleID); id != "" {
+		return fmt.Sprintf("%s (%s)", r.path, id)
+	}
+
+	return r.path
+}
+
+// conditional reports whether the rule also depends on its message, so it
+// may not match every reply its codes or prefixes select.
+func (r lintRule) conditional() bool {
+	return len(r.rule.MessagePatterns) > 0 || len(r.rule.ExcludePatterns) > 0
+}
+
+// appliesBefore mirrors the matcher: higher priority first, then list order.
+func (r lintRule) appliesBefore(other lintRule) bool {
+	if r.rule.Priority != other.rule.Priority {
+		return r.rule.Priority > other.rule.Priority
+	}
+
+	return r.index < other.index
+}
+
+func (l *linter) checkProfileRules(path string, profile replypolicy.Profile) {
+	lists := map[string][]replypolicy.Rule{
+		"enhanced_rules":  profile.EnhancedRules,
+		"smtp_code_rules": profile.SMTPCodeRules,
+		"message_rules":   profile.MessageRules,
+	}
+
+	ruleIDs := map[string]string{}
+	for _, collection := range schema.RuleCollections {
+		rules := make([]lintRule, 0, len(lists[collection]))
+		for i, rule := range lists[collection] {
+			current := lintRule{path: fmt.Sprintf("%s.%s[%d]", path, collection, i), collection: collection, index: i, rule: rule}
+
+			if id := strings.TrimSpace(rule.RuleID); id != "" {
+				if first, exists := ruleIDs[id]; exists {
+					l.add(SeverityError, CodeDuplicateRuleID, current.path, "rule_id %q is already used by %s", id, first)
+				} else {
+					ruleIDs[id] = current.path
+				}
+			}
+
+			if missing := missingCriterion(current); missing != "" {
+				l.add(SeverityWarning, CodeUnreachableRule, current.path, "has no %s and never matches", missing)
+				continue
+			}
+			rules = append(rules, current)
+		}
+
+		for _, rule := range rules {
+			shadowed := false
+			for _, other := range rules {
+				if other.index == rule.index || !other.appliesBefore(rule) || !covers(other, rule) {
+					continue
+				}
+				l.add(SeverityWarning, CodeShadowedRule, rule.path, "never matches: %s matches every reply it does and applies first", other.describe())
+				shadowed = true
+				break
+			}
+			if shadowed {
+				continue
+			}
+
+			for _, other := range rules {
+				if other.index >= rule.index {
+					break
+				}
+				if conflict := decisionConflict(other, rule); conflict != "" {
+					l.add(SeverityWarning, CodeConflictingDecisionClass, rule.path, "%s is classified %s by %s but %s here",
+						conflict, other.rule.DecisionClass, other.describe(), rule.rule.DecisionClass)
+				}
+			}
+		}
+	}
+}
+
+func missingCriterion(r lintRule) string {
+	switch r.collection {
+	case "enhanced_rules":
+		if len(nonEmpty(r.rule.EnhancedPrefixes)) == 0 {
+			return "enhanced_prefixes"
+		}
+	case "smtp_code_rules":
+		if len(r.rule.SMTPCodes) == 0 {
+			return "smtp_codes"
+		}
+	case "message_rules":
+		if len(nonEmpty(r.rule.MessageContains)) == 0 && len(r.rule.MessagePatterns) == 0 {
+			return "message_contains or message_patterns"
+		}
+	}
+
+	return ""
+}
+
+// covers reports whether a matches every reply b matches. Rules with message
+// patterns are never treated as covering, since their patterns cannot be
+// compared.
+func covers(a, b lintRule) bool {
+	if a.conditional() || !stagesCover(a.rule.Stages, b.rule.Stages) {
+		return false
+	}
+
+	switch a.collection {
+	case "enhanced_rules":
+		return everyMatched(nonEmpty(b.rule.EnhancedPrefixes), nonEmpty(a.rule.EnhancedPrefixes), strings.HasPrefix)
+	case "smtp_code_rules":
+		for _, code := range b.rule.SMTPCodes {
+			if !containsCode(a.rule.SMTPCodes, code) {
+				return false
+			}
+		}
+		return true
+	case "message_rules":
+		tokens := nonEmpty(b.rule.MessageContains)
+		if len(tokens) == 0 {
+			return false
+		}
+		return everyMatched(tokens, nonEmpty(a.rule.MessageContains), strings.Contains)
+	}
+
+	return false
+}
+
+// decisionConflict names the SMTP code or enhanced prefix two unconditional
+// rules both select while disagreeing on the decision class.
+func decisionConflict(a, b lintRule) string {
+	if a.conditional() || b.conditional() || !stagesOverlap(a.rule.Stages, b.rule.Stages) {
+		return ""
+	}
+	classA := strings.ToLower(strings.TrimSpace(a.rule.DecisionClass))
+	classB := strings.ToLower(strings.TrimSpace(b.rule.DecisionClass))
+	if classA == "" || classB == "" || classA == classB {
+		return ""
+	}
+
+	switch a.collection {
+	case "smtp_code_rules":
+		for _, code := range b.rule.SMTPCodes {
+			if containsCode(a.rule.SMTPCodes, code) {
+				return fmt.Sprintf("smtp code %d", code)
+			}
+		}
+	case "enhanced_rules":
+		prefixes := nonEmpty(a.rule.EnhancedPrefixes)
+		for _, prefix := range nonEmpty(b.rule.EnhancedPrefixes) {
+			if contains(prefixes, prefix) {
+				return fmt.Sprintf("enhanced code %s", prefix)
+			}
+		}
+	}
+
+	return ""
+}
+
+func stagesCover(a, b []string) bool {
+	if len(a) == 0 {
+		return true
+	}
+	if len(b) == 0 {
+		return false
+	}
+
+	allowed := nonEmpty(a)
+	for _, stage := range nonEmpty(b) {
+		if !contains(allowed, stage) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func stagesOverlap(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+
+	allowed := nonEmpty(a)
+	for _, stage := range nonEmpty(b) {
+		if contains(allowed, stage) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// everyMatched reports whether each value is matched by some candidate.
+func everyMatched(values, candidates []string, match func(value, candidate string) bool) bool {
+	for _, value := range values {
+		matched := false
+		for _, candidate := range candidates {
+			if match(value, candidate) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func nonEmpty(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" {
+			out = append(out, value)
+		}
+	}
+
+	return out
+}
+
+func containsCode(codes []int, code int) bool {
+	for _, candidate := range codes {
+		if candidate == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isInteger(value any) bool {
+	number, ok := value.(float64)
+	return ok && number == float64(int64(number))
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,68 @@
+package replypolicy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultIncludesGenericProfile(t *testing.T) {
+	engine := Default()
+	if engine == nil {
+		t.Fatal("expected default engine")
+	}
+
+	if _, ok := engine.Profiles["generic"]; !ok {
+		t.Fatal("expected generic profile to exist")
+	}
+}
+
+func TestParseAppliesDefaults(t *testing.T) {
+	engine, err := Parse(`{
+		"enabled": true,
+		"profiles": {
+			"gmail": {
+				"name": "gmail",
+				"retry": {
+					"default_seconds": 120
+				}
+			}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("expected json to parse, got %v", err)
+	}
+
+	gmail, ok := engine.Profiles["gmail"]
+	if !ok {
+		t.Fatal("expected gmail profile")
+	}
+	if gmail.Retry.DefaultSeconds != 120 {
+		t.Fatalf("expected gmail default retry 120, got %d", gmail.Retry.DefaultSeconds)
+	}
+
+	if len(gmail.EnhancedRules) == 0 {
+		t.Fatal("expected gmail enhanced rules fallback from defaults")
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	cases := map[string]string{
+		`{"profiles": {"microsoft": {"message_rules": [{}, {"message_patterns": ["ok", "AS\\((\\d+"]}]}}}`: "invalid provider reply policy: profiles.microsoft.message_rules[1].message_patterns[1]: error parsing regexp: missing closing ): `AS\\((\\d+`",
+		`{"profiles": {"gmail": {"enhanced_rules": [{"exclude_patterns": [" "]}]}}}`:                       "invalid provider reply policy: profiles.gmail.enhanced_rules[0].exclude_patterns[0]: pattern is empty",
+		`{"profiles": {"yahoo": {"smtp_code_rules": [{"stages": ["rcpt", "data"]}]}}}`:                     `invalid provider reply policy: profiles.yahoo.smtp_code_rules[0].stages[1]: unknown stage "data" (expected banner, ehlo, mail_from or rcpt)`,
+	}
+
+	for raw, expected := range cases {
+		_, err := Parse(raw)
+		if err == nil || err.Error() != expected {
+			t.Fatalf("expected error %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestParseRejectsUnknownScoreFactors(t *testing.T) {
+	_, err := Parse(`{"scoring":{"version":"score-v2","weights":{"free_mail":-10,"fre_mail":-10}}}`)
+	if err == nil || !strings.Contains(err.Error(), "scoring.weights.fre_mail: unknown score factor") {
+		t.Fatalf("expected an unknown score factor error, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package replypolicy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultScoringVersion names the scoring model the worker ships with.
+const DefaultScoringVersion = "score-v1"
+
+// Score factor names. Each is a key in ScoringModel.Weights.
+const (
+	ScoreFactorBase                 = "base"
+	ScoreFactorSyntaxInvalid        = "syntax_invalid"
+	ScoreFactorIPLiteral            = "ip_literal"
+	ScoreFactorDisposable           = "disposable"
+	ScoreFactorRoleAccount          = "role_account"
+	ScoreFactorFreeMail             = "free_mail"
+	ScoreFactorDomainTypo           = "domain_typo"
+	ScoreFactorMXMissing            = "mx_missing"
+	ScoreFactorMXImplicit           = "mx_implicit"
+	ScoreFactorMXParked             = "mx_parked"
+	ScoreFactorMailAuthMissing      = "mail_auth_missing"
+	ScoreFactorMailAuthEnforced     = "mail_auth_enforced"
+	ScoreFactorSMTPConnectOK        = "smtp_connect_ok"
+	ScoreFactorSMTPDeliverable      = "smtp_deliverable"
+	ScoreFactorSMTPUndeliverable    = "smtp_undeliverable"
+	ScoreFactorSMTPRetryable        = "smtp_retryable"
+	ScoreFactorSMTPPolicyBlocked    = "smtp_policy_blocked"
+	ScoreFactorSMTPUnknown          = "smtp_unknown"
+	ScoreFactorCatchAllHigh         = "catch_all_high"
+	ScoreFactorCatchAllMedium       = "catch_all_medium"
+	ScoreFactorCatchAllLow          = "catch_all_low"
+	ScoreFactorCatchAllInconsistent = "catch_all_inconsistent"
+	ScoreFactorEvidenceLow          = "evidence_low"
+	ScoreFactorProviderCautious     = "provider_cautious"
+	ScoreFactorProviderDegraded     = "provider_degraded_probe"
+	ScoreFactorProviderDrain        = "provider_drain"
+	ScoreFactorProviderQuarantine   = "provider_quarantine"
+)
+
+// ScoringModel turns a verification result into a 0-100 deliverability
+// score. It ships inside Engine, so a new model rolls
+// out, canaries and rolls back with the policy version that carries it.
+type ScoringModel struct {
+	Version string         `json:"version,omitempty"`
+	Weights map[string]int `json:"weights,omitempty"`
+}
+
+// DefaultScoringModel returns the model the worker ships with.
+func DefaultScoringModel() ScoringModel {
+	return ScoringModel{
+		Version: DefaultScoringVersion,
+		Weights: defaultScoringWeights(),
+	}
+}
+
+func defaultScoringWeights() map[string]int {
+	return map[string]int{
+		ScoreFactorBase:                 50,
+		ScoreFactorSyntaxInvalid:        -50,
+		ScoreFactorIPLiteral:            -20,
+		ScoreFactorDisposable:           -40,
+		ScoreFactorRoleAccount:          -15,
+		ScoreFactorFreeMail:             -5,
+		ScoreFactorDomainTypo:           -35,
+		ScoreFactorMXMissing:            -50,
+		ScoreFactorMXImplicit:           -10,
+		ScoreFactorMXParked:             -35,
+		ScoreFactorMailAuthMissing:      -10,
+		ScoreFactorMailAuthEnforced:     5,
+		ScoreFactorSMTPConnectOK:        25,
+		ScoreFactorSMTPDeliverable:      45,
+		ScoreFactorSMTPUndeliverable:    -50,
+		ScoreFactorSMTPRetryable:        -10,
+		ScoreFactorSMTPPolicyBlocked:    -15,
+		ScoreFactorSMTPUnknown:          -10,
+		ScoreFactorCatchAllHigh:         -5,
+		ScoreFactorCatchAllMedium:       -10,
+		ScoreFactorCatchAllLow:          -15,
+		ScoreFactorCatchAllInconsistent: -20,
+		ScoreFactorEvidenceLow:          -5,
+		ScoreFactorProviderCautious:     -5,
+		ScoreFactorProviderDegraded:     -10,
+		ScoreFactorProviderDrain:        -10,
+		ScoreFactorProviderQuarantine:   -15,
+	}
+}
+
+// validateScoringWeights rejects weights for factors the model does not
+// know, which would otherwise be ignored silently.
+func validateScoringWeights(model ScoringModel) error {
+	known := defaultScoringWeights()
+	names := make([]string, 0, len(model.Weights))
+	for name := range model.Weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := known[strings.ToLower(strings.TrimSpace(name))]; !ok {
+			return fmt.Errorf("scoring.weights.%s: unknown score factor", name)
+		}
+	}
+
+	return nil
+}
+
+// normalizeScoringModel fills in the default version and any weight the
+// model does not set, so a payload can override single weights.
+func normalizeScoringModel(model ScoringModel) ScoringModel {
+	out := ScoringModel{
+		Version: strings.TrimSpace(model.Version),
+		Weights: defaultScoringWeights(),
+	}
+	if out.Version == "" {
+		out.Version = DefaultScoringVersion
+	}
+	for name, weight := range model.Weights {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		out.Weights[name] = weight
+	}
+
+	return out
+}
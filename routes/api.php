@@ -16,6 +16,7 @@
 use App\Http\Controllers\Api\Verifier\VerifierChunkDetailsController;
 use App\Http\Controllers\Api\Verifier\VerifierChunkFailController;
 use App\Http\Controllers\Api\Verifier\VerifierChunkInputUrlController;
+use App\Http\Controllers\Api\Verifier\VerifierChunkLeaseController;
 use App\Http\Controllers\Api\Verifier\VerifierChunkLogController;
 use App\Http\Controllers\Api\Verifier\VerifierChunkOutputUrlsController;
 use App\Http\Controllers\Api\Verifier\VerifierHeartbeatController;
@@ -80,6 +81,9 @@
             Route::post('{chunk}/fail', VerifierChunkFailController::class)
                 ->whereUuid('chunk')
                 ->name('fail');
+            Route::post('{chunk}/lease', VerifierChunkLeaseController::class)
+                ->whereUuid('chunk')
+                ->name('lease');
             Route::post('{chunk}/complete', VerifierChunkCompleteController::class)
                 ->whereUuid('chunk')
                 ->name('complete');
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	domainListsKey = "control_plane:domain_lists"
+
+	domainListHistoryLimit = 10
+)
+
+// supportedDomainLists are the lists workers know how to apply.
+var supportedDomainLists = []string{"disposable_domains", "role_accounts", "free_mail_domains"}
+
+type domainListRecord struct {
+	DomainListManifest
+	Entries []string `json:"entries"`
+}
+
+// PublishDomainList stores entries as the current version of a list. The
+// version is derived from the checksum, so publishing identical entries
+// keeps the current version.
+func (s *Store) PublishDomainList(ctx context.Context, name string, entries []string) (DomainListManifest, error) {
+	name = normalizeDomainListName(name)
+	if name == "" {
+		return DomainListManifest{}, fmt.Errorf("unsupported domain list")
+	}
+
+	entries = normalizeDomainListEntries(entries)
+	checksum := domainListChecksum(entries)
+	record := domainListRecord{
+		DomainListManifest: DomainListManifest{
+			Name:      name,
+			Version:   checksum[:12],
+			Checksum:  checksum,
+			Count:     len(entries),
+			UpdatedAt: time.Now().UTC().Format(time.RFC3339),
+		},
+		Entries: entries,
+	}
+
+	current, found, err := s.getDomainListRecord(ctx, name)
+	if err != nil {
+		return DomainListManifest{}, err
+	}
+	if found && current.Checksum == checksum {
+		return current.DomainListManifest, nil
+	}
+
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return DomainListManifest{}, err
+	}
+	entriesPayload, err := json.Marshal(entries)
+	if err != nil {
+		return DomainListManifest{}, err
+	}
+
+	versionsKey := domainListKey(name, "versions")
+	historyKey := domainListKey(name, "history")
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, versionsKey, record.Version, entriesPayload)
+	pipe.LRem(ctx, historyKey, 0, record.Version)
+	pipe.LPush(ctx, historyKey, record.Version)
+	pipe.HSet(ctx, domainListsKey, name, payload)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return DomainListManifest{}, err
+	}
+
+	expired, err := s.rdb.LRange(ctx, historyKey, domainListHistoryLimit, -1).Result()
+	if err == nil && len(expired) > 0 {
+		pipe = s.rdb.TxPipeline()
+		pipe.HDel(ctx, versionsKey, expired...)
+		pipe.LTrim(ctx, historyKey, 0, domainListHistoryLimit-1)
+		_, _ = pipe.Exec(ctx)
+	}
+
+	return record.DomainListManifest, nil
+}
+
+// GetDomainListManifests returns the current version of every published
+// list, ordered by name.
+func (s *Store) GetDomainListManifests(ctx context.Context) ([]DomainListManifest, error) {
+	values, err := s.rdb.HGetAll(ctx, domainListsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := make([]DomainListManifest, 0, len(values))
+	for _, payload := range values {
+		record := domainListRecord{}
+		if unmarshalErr := json.Unmarshal([]byte(payload), &record); unmarshalErr != nil {
+			continue
+		}
+		manifests = append(manifests, record.DomainListManifest)
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].Name < manifests[j].Name
+	})
+
+	return manifests, nil
+}
+
+// GetDomainList returns the current list, as a delta from since when that
+// version is still in the history and as the full list otherwise.
+func (s *Store) GetDomainList(ctx context.Context, name string, since string) (DomainListPayload, bool, error) {
+	name = normalizeDomainListName(name)
+	if name == "" {
+		return DomainListPayload{}, false, nil
+	}
+
+	current, found, err := s.getDomainListRecord(ctx, name)
+	if err != nil || !found {
+		return DomainListPayload{}, found, err
+	}
+
+	since = strings.TrimSpace(since)
+	if since == "" {
+		return buildDomainListPayload(current, "", nil, false), true, nil
+	}
+
+	basePayload, err := s.rdb.HGet(ctx, domainListKey(name, "versions"), since).Result()
+	if err == redis.Nil || basePayload == "" {
+		return buildDomainListPayload(current, "", nil, false), true, nil
+	}
+	if err != nil {
+		return DomainListPayload{}, false, err
+	}
+
+	base := []string{}
+	if unmarshalErr := json.Unmarshal([]byte(basePayload), &base); unmarshalErr != nil {
+		return buildDomainListPayload(current, "", nil, false), true, nil
+	}
+
+	return buildDomainListPayload(current, since, base, true), true, nil
+}
+
+func (s *Store) getDomainListRecord(ctx context.Context, name string) (domainListRecord, bool, error) {
+	payload, err := s.rdb.HGet(ctx, domainListsKey, name).Result()
+	if err == redis.Nil || payload == "" {
+		return domainListRecord{}, false, nil
+	}
+	if err != nil {
+		return domainListRecord{}, false, err
+	}
+
+	record := domainListRecord{}
+	if unmarshalErr := json.Unmarshal([]byte(payload), &record); unmarshalErr != nil {
+		return domainListRecord{}, false, unmarshalErr
+	}
+
+	return record, true, nil
+}
+
+func buildDomainListPayload(current domainListRecord, baseVersion string, base []string, delta bool) DomainListPayload {
+	payload := DomainListPayload{DomainListManifest: current.DomainListManifest, Mode: "full"}
+	if !delta {
+		payload.Entries = current.Entries
+		return payload
+	}
+
+	payload.Mode = "delta"
+	payload.BaseVersion = baseVersion
+	payload.Added, payload.Removed = diffDomainListEntries(base, current.Entries)
+
+	return payload
+}
+
+// diffDomainListEntries returns the sorted entries added to and removed
+// from base.
+func diffDomainListEntries(base []string, current []string) ([]string, []string) {
+	baseSet := make(map[string]struct{}, len(base))
+	for _, entry := range base {
+		baseSet[entry] = struct{}{}
+	}
+	currentSet := make(map[string]struct{}, len(current))
+	for _, entry := range current {
+		currentSet[entry] = struct{}{}
+	}
+
+	added := []string{}
+	for _, entry := range current {
+		if _, ok := baseSet[entry]; !ok {
+			added = append(added, entry)
+		}
+	}
+	removed := []string{}
+	for _, entry := range base {
+		if _, ok := currentSet[entry]; !ok {
+			removed = append(removed, entry)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	return added, removed
+}
+
+func normalizeDomainListName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, supported := range supportedDomainLists {
+		if name == supported {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// normalizeDomainListEntries lower-cases, de-duplicates and sorts entries,
+// dropping blanks and # comments.
+func normalizeDomainListEntries(entries []string) []string {
+	seen := map[string]struct{}{}
+	output := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if _, ok := seen[entry]; ok {
+			continue
+		}
+		seen[entry] = struct{}{}
+		output = append(output, entry)
+	}
+	sort.Strings(output)
+
+	return output
+}
+
+// domainListChecksum is the hex SHA-256 of the sorted entries joined by
+// newlines. Workers compute the same value after applying a delta.
+func domainListChecksum(entries []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(entries, "\n")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func domainListKey(name string, field string) string {
+	return fmt.Sprintf("%s:%s:%s", domainListsKey, name, field)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeDomainListEntriesSortsAndDeduplicates(t *testing.T) {
+	entries := normalizeDomainListEntries([]string{" Mailinator.com ", "# comment", "", "10minutemail.com", "mailinator.com"})
+	if strings.Join(entries, ",") != "10minutemail.com,mailinator.com" {
+		t.Fatalf("expected sorted unique entries, got %v", entries)
+	}
+}
+
+func TestDomainListChecksumIsOrderIndependentAfterNormalization(t *testing.T) {
+	first := domainListChecksum(normalizeDomainListEntries([]string{"b.test", "a.test"}))
+	second := domainListChecksum(normalizeDomainListEntries([]string{"A.test", "b.test", "a.test"}))
+	if first != second {
+		t.Fatalf("expected equal checksums, got %s and %s", first, second)
+	}
+	if len(first) != 64 {
+		t.Fatalf("expected hex sha256 checksum, got %q", first)
+	}
+}
+
+func TestBuildDomainListPayloadReturnsDeltaAgainstBase(t *testing.T) {
+	current := domainListRecord{
+		DomainListManifest: DomainListManifest{Name: "disposable_domains", Version: "v2", Checksum: "c2", Count: 3},
+		Entries:            []string{"a.test", "c.test", "d.test"},
+	}
+
+	delta := buildDomainListPayload(current, "v1", []string{"a.test", "b.test"}, true)
+	if delta.Mode != "delta" || delta.BaseVersion != "v1" || len(delta.Entries) != 0 {
+		t.Fatalf("expected delta payload from v1, got %+v", delta)
+	}
+	if strings.Join(delta.Added, ",") != "c.test,d.test" || strings.Join(delta.Removed, ",") != "b.test" {
+		t.Fatalf("unexpected delta added=%v removed=%v", delta.Added, delta.Removed)
+	}
+
+	full := buildDomainListPayload(current, "", nil, false)
+	if full.Mode != "full" || len(full.Entries) != 3 {
+		t.Fatalf("expected full payload, got %+v", full)
+	}
+}
+
+func TestNormalizeDomainListNameRejectsUnknownLists(t *testing.T) {
+	if normalizeDomainListName(" Free_Mail_Domains ") != "free_mail_domains" {
+		t.Fatal("expected supported list name to normalize")
+	}
+	if normalizeDomainListName("typo_domains") != "" {
+		t.Fatal("expected unsupported list to be rejected")
+	}
+}
@@ -3,6 +3,7 @@ module github.com/ASTGD/ValidEmailVerifierGUI/services/go-control-plane
 go 1.22
 
 require (
+	engine-worker-go v0.0.0-00010101000000-000000000000
 	github.com/go-chi/chi/v5 v5.0.12
 	github.com/go-sql-driver/mysql v1.8.1
 	github.com/redis/go-redis/v9 v9.6.1
@@ -12,4 +13,8 @@ require (
 	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/cespare/xxhash/v2 v2.2.0 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	golang.org/x/net v0.24.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
+
+replace engine-worker-go => ./third_party/engine-worker-go
@@ -324,6 +324,52 @@ func (s *Server) handleProviderPolicies(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, http.StatusOK, ProviderPoliciesResponse{Data: data})
 }
 
+func (s *Server) handleDomainLists(w http.ResponseWriter, r *http.Request) {
+	manifests, err := s.store.GetDomainListManifests(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := DomainListsResponse{}
+	response.Data.Lists = manifests
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleDomainList(w http.ResponseWriter, r *http.Request) {
+	payload, found, err := s.store.GetDomainList(r.Context(), chi.URLParam(r, "name"), r.URL.Query().Get("since"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "domain list not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, DomainListResponse{Data: payload})
+}
+
+func (s *Server) handlePublishDomainList(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Entries []string `json:"entries"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	manifest, err := s.store.PublishDomainList(r.Context(), chi.URLParam(r, "name"), payload.Entries)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": manifest,
+	})
+}
+
 func (s *Server) applyPoolProviderModes(ctx context.Context, modes []ProviderModeState, poolSlug string) []ProviderModeState {
 	if s.laravelEngineClient == nil {
 		return modes
@@ -157,6 +157,7 @@ type LaravelDecisionTraceRecord struct {
 	SessionStrategyID      string                   `json:"session_strategy_id"`
 	AttemptRoute           map[string]interface{}   `json:"attempt_route"`
 	AttemptChain           []map[string]interface{} `json:"attempt_chain"`
+	Transcript             []map[string]interface{} `json:"transcript"`
 	ObservedAt             string                   `json:"observed_at"`
 	CreatedAt              string                   `json:"created_at"`
 }
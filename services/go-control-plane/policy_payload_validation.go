@@ -11,6 +11,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"engine-worker-go/policylint"
 )
 
 const (
@@ -19,24 +21,6 @@ const (
 	policyValidationStatusInvalid = "invalid"
 )
 
-var requiredRetryFields = []string{
-	"default_seconds",
-	"tempfail_seconds",
-	"greylist_seconds",
-	"policy_blocked_seconds",
-	"unknown_seconds",
-}
-
-var allowedRuleTags = map[string]struct{}{
-	"greylist":          {},
-	"rate_limit":        {},
-	"mailbox_full":      {},
-	"policy_blocked":    {},
-	"mailbox_not_found": {},
-	"auth_required":     {},
-	"unknown_transient": {},
-}
-
 type PolicyPayloadValidation struct {
 	Version       string
 	Checksum      string
@@ -44,6 +28,7 @@ type PolicyPayloadValidation struct {
 	Status        string
 	ErrorMessage  string
 	PayloadLoaded bool
+	Findings      []policylint.Finding
 }
 
 type PolicyPayloadValidator interface {
@@ -129,7 +114,8 @@ func (v *LaravelPolicyPayloadValidator) ValidateVersion(ctx context.Context, ver
 		return result, fmt.Errorf(result.ErrorMessage)
 	}
 
-	checksum, validationErr := validatePolicyPayloadSchema(normalizedVersion, body.Data.PolicyPayload)
+	report, checksum, validationErr := lintPolicyPayload(normalizedVersion, body.Data.PolicyPayload)
+	result.Findings = report.Findings
 	if validationErr != nil {
 		result.ErrorMessage = validationErr.Error()
 		return result, validationErr
@@ -144,156 +130,37 @@ func (v *LaravelPolicyPayloadValidator) ValidateVersion(ctx context.Context, ver
 }
 
 func validatePolicyPayloadSchema(version string, payload json.RawMessage) (string, error) {
+	_, checksum, err := lintPolicyPayload(version, payload)
+	return checksum, err
+}
+
+// lintPolicyPayload checks the payload against the policy schema shared with
+// the worker and Laravel. Warning findings do not fail validation.
+func lintPolicyPayload(version string, payload json.RawMessage) (policylint.Report, string, error) {
 	if len(payload) == 0 {
-		return "", fmt.Errorf("policy payload is empty")
+		return policylint.Report{}, "", fmt.Errorf("policy payload is empty")
 	}
 
 	compact := bytes.Buffer{}
 	if err := json.Compact(&compact, payload); err != nil {
-		return "", fmt.Errorf("policy payload must be valid JSON: %w", err)
+		return policylint.Report{}, "", fmt.Errorf("policy payload must be valid JSON: %w", err)
 	}
 
-	root := map[string]any{}
-	if err := json.Unmarshal(compact.Bytes(), &root); err != nil {
-		return "", fmt.Errorf("policy payload decode failed: %w", err)
+	report := policylint.Lint(compact.Bytes())
+	if finding, ok := report.FirstError(); ok {
+		return report, "", fmt.Errorf("policy payload %s", finding)
 	}
 
-	enabledValue, hasEnabled := root["enabled"]
-	if !hasEnabled {
-		return "", fmt.Errorf("policy payload missing required field enabled")
-	}
-	if _, ok := enabledValue.(bool); !ok {
-		return "", fmt.Errorf("policy payload field enabled must be boolean")
-	}
-
-	payloadVersion, ok := root["version"].(string)
-	if !ok || strings.TrimSpace(payloadVersion) == "" {
-		return "", fmt.Errorf("policy payload missing required field version")
-	}
-	if normalizePolicyVersion(payloadVersion) != normalizePolicyVersion(version) {
-		return "", fmt.Errorf("policy payload version does not match requested version")
-	}
-
-	schemaVersion := "v2"
-	if value, ok := root["schema_version"].(string); ok && strings.TrimSpace(value) != "" {
-		schemaVersion = strings.ToLower(strings.TrimSpace(value))
-	}
-	if schemaVersion != "v2" && schemaVersion != "v3" && schemaVersion != "v4" {
-		return "", fmt.Errorf("policy payload schema_version must be v2, v3, or v4")
-	}
-
-	profiles, ok := root["profiles"].(map[string]any)
-	if !ok {
-		return "", fmt.Errorf("policy payload missing required object profiles")
-	}
-	genericProfile, ok := profiles["generic"].(map[string]any)
-	if !ok {
-		return "", fmt.Errorf("policy payload missing required profiles.generic block")
-	}
-	retry, ok := genericProfile["retry"].(map[string]any)
-	if !ok {
-		return "", fmt.Errorf("policy payload missing required profiles.generic.retry block")
-	}
-
-	for _, key := range requiredRetryFields {
-		value, exists := retry[key]
-		if !exists {
-			return "", fmt.Errorf("policy payload missing retry field %s", key)
-		}
-		if !isJSONNumber(value) {
-			return "", fmt.Errorf("policy payload retry field %s must be numeric", key)
-		}
+	root := struct {
+		Version string `json:"version"`
+	}{}
+	if err := json.Unmarshal(compact.Bytes(), &root); err != nil {
+		return report, "", fmt.Errorf("policy payload decode failed: %w", err)
 	}
-
-	if schemaVersion == "v3" || schemaVersion == "v4" {
-		session, ok := genericProfile["session"].(map[string]any)
-		if !ok {
-			return "", fmt.Errorf("policy payload missing required profiles.generic.session block for %s", schemaVersion)
-		}
-		for _, key := range []string{
-			"max_concurrency",
-			"connects_per_minute",
-			"reuse_connection_for_retries",
-			"retry_jitter_percent",
-			"ehlo_profile",
-		} {
-			if _, exists := session[key]; !exists {
-				return "", fmt.Errorf("policy payload missing session field %s", key)
-			}
-		}
-
-		modes, ok := root["modes"].(map[string]any)
-		if !ok {
-			return "", fmt.Errorf("policy payload missing required modes object for %s", schemaVersion)
-		}
-		requiredModes := []string{"normal", "cautious", "drain", "quarantine", "degraded_probe"}
-		for _, mode := range requiredModes {
-			modeConfig, ok := modes[mode].(map[string]any)
-			if !ok {
-				return "", fmt.Errorf("policy payload missing required modes.%s object", mode)
-			}
-			for _, field := range []string{
-				"probe_enabled",
-				"max_concurrency_multiplier",
-				"connects_per_minute_multiplier",
-			} {
-				if _, exists := modeConfig[field]; !exists {
-					return "", fmt.Errorf("policy payload missing required modes.%s.%s field", mode, field)
-				}
-			}
-		}
-
-		for profileName, profileValue := range profiles {
-			profileMap, ok := profileValue.(map[string]any)
-			if !ok {
-				continue
-			}
-			for _, collection := range []string{"enhanced_rules", "smtp_code_rules", "message_rules"} {
-				rules, ok := profileMap[collection].([]any)
-				if !ok {
-					continue
-				}
-				for index, ruleValue := range rules {
-					rule, ok := ruleValue.(map[string]any)
-					if !ok {
-						continue
-					}
-					for _, field := range []string{"rule_tag", "confidence_hint", "provider_scope"} {
-						value, exists := rule[field]
-						if !exists || strings.TrimSpace(fmt.Sprintf("%v", value)) == "" {
-							return "", fmt.Errorf(
-								"policy payload missing required profiles.%s.%s.%d.%s field",
-								profileName,
-								collection,
-								index,
-								field,
-							)
-						}
-					}
-					ruleTag := strings.ToLower(strings.TrimSpace(fmt.Sprintf("%v", rule["rule_tag"])))
-					if _, ok := allowedRuleTags[ruleTag]; !ok {
-						return "", fmt.Errorf(
-							"policy payload profiles.%s.%s.%d.rule_tag has unsupported value %q",
-							profileName,
-							collection,
-							index,
-							ruleTag,
-						)
-					}
-				}
-			}
-		}
+	if normalizePolicyVersion(root.Version) != normalizePolicyVersion(version) {
+		return report, "", fmt.Errorf("policy payload version does not match requested version")
 	}
 
 	sum := sha256.Sum256(compact.Bytes())
-	return hex.EncodeToString(sum[:]), nil
-}
-
-func isJSONNumber(value any) bool {
-	switch value.(type) {
-	case int, int32, int64, uint, uint32, uint64, float32, float64, json.Number:
-		return true
-	default:
-		return false
-	}
+	return report, hex.EncodeToString(sum[:]), nil
 }
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"engine-worker-go/policylint"
 )
 
 func TestValidatePolicyPayloadSchemaAcceptsRequiredFields(t *testing.T) {
@@ -235,3 +240,131 @@ func TestValidatePolicyPayloadSchemaRejectsUnsupportedV4RuleTag(t *testing.T) {
 		t.Fatal("expected v4 payload with unsupported rule_tag to fail validation")
 	}
 }
+
+func TestValidatePolicyPayloadSchemaChecksScoringModel(t *testing.T) {
+	payloadWithScoring := func(scoring any) []byte {
+		raw, err := json.Marshal(map[string]any{
+			"enabled": true,
+			"version": "v2.7.0",
+			"profiles": map[string]any{
+				"generic": map[string]any{
+					"retry": map[string]any{
+						"default_seconds":        60,
+						"tempfail_seconds":       90,
+						"greylist_seconds":       180,
+						"policy_blocked_seconds": 300,
+						"unknown_seconds":        75,
+					},
+				},
+			},
+			"scoring": scoring,
+		})
+		if err != nil {
+			t.Fatalf("failed to marshal payload: %v", err)
+		}
+
+		return raw
+	}
+
+	valid := map[string]any{"version": "score-v2", "weights": map[string]any{"free_mail": -10, "smtp_deliverable": 40}}
+	if _, err := validatePolicyPayloadSchema("v2.7.0", payloadWithScoring(valid)); err != nil {
+		t.Fatalf("expected scoring model to validate, got %v", err)
+	}
+
+	invalid := []any{
+		"score-v2",
+		map[string]any{"weights": map[string]any{"free_mail": -10}},
+		map[string]any{"version": "score-v2", "weights": map[string]any{"free_mail": "low"}},
+		map[string]any{"version": "score-v2", "weights": map[string]any{"free_mail": 150}},
+		map[string]any{"version": "score-v2", "weights": map[string]any{"free_mail": 2.5}},
+		map[string]any{"version": "score-v2", "weights": map[string]any{"fre_mail": -10}},
+	}
+	for _, scoring := range invalid {
+		if _, err := validatePolicyPayloadSchema("v2.7.0", payloadWithScoring(scoring)); err == nil {
+			t.Fatalf("expected scoring %v to be rejected", scoring)
+		}
+	}
+}
+
+func TestValidatePolicyPayloadSchemaChecksRuleMatching(t *testing.T) {
+	payloadWithRule := func(rule map[string]any) []byte {
+		raw, err := json.Marshal(map[string]any{
+			"enabled": true,
+			"version": "v2.8.0",
+			"profiles": map[string]any{
+				"generic": map[string]any{
+					"retry": map[string]any{
+						"default_seconds":        60,
+						"tempfail_seconds":       90,
+						"greylist_seconds":       180,
+						"policy_blocked_seconds": 300,
+						"unknown_seconds":        75,
+					},
+				},
+				"microsoft": map[string]any{
+					"message_rules": []any{rule},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to marshal payload: %v", err)
+		}
+
+		return raw
+	}
+
+	valid := map[string]any{
+		"message_patterns": []string{`access denied\. AS\((?P<as>\d+)\)`},
+		"exclude_patterns": []string{"recipient address rejected"},
+		"stages":           []string{"rcpt", "mail_from"},
+		"priority":         10,
+	}
+	if _, err := validatePolicyPayloadSchema("v2.8.0", payloadWithRule(valid)); err != nil {
+		t.Fatalf("expected rule matching fields to validate, got %v", err)
+	}
+
+	invalid := []map[string]any{
+		{"message_patterns": []string{`AS\((\d+`}},
+		{"exclude_patterns": []string{""}},
+		{"message_patterns": "access denied"},
+		{"stages": []string{"data"}},
+		{"priority": 1.5},
+	}
+	for _, rule := range invalid {
+		if _, err := validatePolicyPayloadSchema("v2.8.0", payloadWithRule(rule)); err == nil {
+			t.Fatalf("expected rule %v to be rejected", rule)
+		}
+	}
+}
+
+func TestLaravelPolicyPayloadValidatorReturnsLintFindings(t *testing.T) {
+	payload := `{
+		"enabled": true,
+		"version": "v2.9.0",
+		"profiles": {
+			"generic": {
+				"retry": {"default_seconds": 60, "tempfail_seconds": 90, "greylist_seconds": 180, "policy_blocked_seconds": 300, "unknown_seconds": 75},
+				"enhanced_rules": [
+					{"rule_id": "retry-42", "enhanced_prefixes": ["4.2."], "decision_class": "retryable"},
+					{"rule_id": "mailbox-full", "enhanced_prefixes": ["4.2.2"], "decision_class": "retryable"}
+				]
+			}
+		}
+	}`
+	laravel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"version": "v2.9.0", "policy_payload": ` + payload + `}}`))
+	}))
+	defer laravel.Close()
+
+	validator := NewLaravelPolicyPayloadValidator(Config{LaravelAPIBaseURL: laravel.URL, LaravelVerifierToken: "token"})
+	result, err := validator.ValidateVersion(context.Background(), "v2.9.0")
+	if err != nil {
+		t.Fatalf("expected warnings not to fail validation, got %v", err)
+	}
+	if result.Status != policyValidationStatusValid || result.Checksum == "" {
+		t.Fatalf("expected a valid, checksummed result, got %+v", result)
+	}
+	if len(result.Findings) != 1 || result.Findings[0].Code != policylint.CodeShadowedRule || result.Findings[0].Path != "profiles.generic.enhanced_rules[1]" {
+		t.Fatalf("expected the shadowed rule finding, got %+v", result.Findings)
+	}
+}
@@ -0,0 +1,32 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Copies the worker's policylint package, and the replypolicy package it parses
+# payloads with, into third_party/engine-worker-go so the control plane builds
+# without the worker checkout next to it. Run it after changing either
+# package; CI fails when the copy is stale.
+
+service_dir="$(cd "$(dirname "$0")/.." && pwd)"
+worker_dir="${WORKER_DIR:-$service_dir/../../engine-worker-go}"
+target_dir="$service_dir/third_party/engine-worker-go"
+
+if [[ ! -f "$worker_dir/go.mod" ]]; then
+  echo "Missing worker module at $worker_dir (set WORKER_DIR)"
+  exit 1
+fi
+
+rm -rf "$target_dir"
+mkdir -p "$target_dir/policylint" "$target_dir/replypolicy"
+
+cp "$worker_dir/go.mod" "$worker_dir/go.sum" "$target_dir/"
+cp "$worker_dir/policylint/schema.json" "$target_dir/policylint/"
+for package in policylint replypolicy; do
+  for file in "$worker_dir/$package"/*.go; do
+    case "$file" in
+      *_test.go) ;;
+      *) cp "$file" "$target_dir/$package/" ;;
+    esac
+  done
+done
+
+echo "Synced policylint from $worker_dir"
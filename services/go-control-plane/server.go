@@ -73,6 +73,9 @@ func (s *Server) Router() http.Handler {
 		router.Get("/api/providers/policies", s.handleProviderPolicies)
 		router.Post("/api/providers/{provider}/mode", s.handleProviderMode)
 		router.Post("/api/providers/policies/reload", s.handleProviderPoliciesReload)
+		router.Get("/api/domain-lists", s.handleDomainLists)
+		router.Get("/api/domain-lists/{name}", s.handleDomainList)
+		router.Put("/api/domain-lists/{name}", s.handlePublishDomainList)
 		router.Get("/api/policies/versions", s.handlePolicyVersions)
 		router.Post("/api/policies/validate", s.handlePolicyValidate)
 		router.Post("/api/policies/promote", s.handlePolicyPromote)
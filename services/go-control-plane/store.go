@@ -31,6 +31,10 @@ const (
 	smtpPolicyActiveKey     = "control_plane:smtp_policy_active"
 	smtpPolicyHistoryKey    = "control_plane:smtp_policy_rollout_history"
 	smtpPolicyShadowRunsKey = "control_plane:smtp_policy_shadow_runs"
+
+	// workerCircuitBreakerEventLimit is how many of a worker's most recent
+	// circuit breaker transitions are kept.
+	workerCircuitBreakerEventLimit = 50
 )
 
 type RuntimeSettings struct {
@@ -215,6 +219,15 @@ func (s *Store) UpsertHeartbeat(ctx context.Context, req HeartbeatRequest) (stri
 		providerMetricsJSON = payload
 	}
 
+	ehloProfileMetricsJSON := []byte("[]")
+	if len(req.EHLOProfileMetrics) > 0 {
+		payload, marshalErr := json.Marshal(req.EHLOProfileMetrics)
+		if marshalErr != nil {
+			return "", marshalErr
+		}
+		ehloProfileMetricsJSON = payload
+	}
+
 	routingMetricsJSON := []byte("{}")
 	if req.RoutingMetrics != nil {
 		payload, marshalErr := json.Marshal(req.RoutingMetrics)
@@ -260,6 +273,15 @@ func (s *Store) UpsertHeartbeat(ctx context.Context, req HeartbeatRequest) (stri
 		reasonTagCountsJSON = payload
 	}
 
+	circuitBreakerEvents := make([]interface{}, 0, len(req.CircuitBreakerEvents))
+	for _, event := range req.CircuitBreakerEvents {
+		payload, marshalErr := json.Marshal(event)
+		if marshalErr != nil {
+			return "", marshalErr
+		}
+		circuitBreakerEvents = append(circuitBreakerEvents, payload)
+	}
+
 	now := time.Now().UTC().Format(time.RFC3339)
 
 	pipe := s.rdb.Pipeline()
@@ -273,6 +295,7 @@ func (s *Store) UpsertHeartbeat(ctx context.Context, req HeartbeatRequest) (stri
 	pipe.Set(ctx, workerKey(req.WorkerID, "stage_metrics"), stageMetricsJSON, s.heartbeatTTL)
 	pipe.Set(ctx, workerKey(req.WorkerID, "smtp_metrics"), smtpMetricsJSON, s.heartbeatTTL)
 	pipe.Set(ctx, workerKey(req.WorkerID, "provider_metrics"), providerMetricsJSON, s.heartbeatTTL)
+	pipe.Set(ctx, workerKey(req.WorkerID, "ehlo_profile_metrics"), ehloProfileMetricsJSON, s.heartbeatTTL)
 	pipe.Set(ctx, workerKey(req.WorkerID, "routing_metrics"), routingMetricsJSON, s.heartbeatTTL)
 	pipe.Set(ctx, workerKey(req.WorkerID, "session_metrics"), sessionMetricsJSON, s.heartbeatTTL)
 	pipe.Set(ctx, workerKey(req.WorkerID, "attempt_route_metrics"), attemptRouteMetricsJSON, s.heartbeatTTL)
@@ -283,6 +306,11 @@ func (s *Store) UpsertHeartbeat(ctx context.Context, req HeartbeatRequest) (stri
 	if req.PoolHealthHint != nil {
 		pipe.Set(ctx, workerKey(req.WorkerID, "pool_health_hint"), *req.PoolHealthHint, s.heartbeatTTL)
 	}
+	if len(circuitBreakerEvents) > 0 {
+		eventsKey := workerKey(req.WorkerID, "circuit_breaker_events")
+		pipe.LPush(ctx, eventsKey, circuitBreakerEvents...)
+		pipe.LTrim(ctx, eventsKey, 0, workerCircuitBreakerEventLimit-1)
+	}
 	if req.Pool != "" {
 		pipe.Set(ctx, workerKey(req.WorkerID, "pool"), req.Pool, s.heartbeatTTL)
 		pipe.SAdd(ctx, "pools:known", req.Pool)
@@ -879,6 +907,14 @@ func (s *Store) GetWorkers(ctx context.Context) ([]WorkerSummary, error) {
 			}
 		}
 
+		var ehloProfileMetrics []EHLOProfileMetric
+		if payload, payloadErr := s.rdb.Get(ctx, workerKey(id, "ehlo_profile_metrics")).Result(); payloadErr == nil && payload != "" {
+			parsed := make([]EHLOProfileMetric, 0)
+			if unmarshalErr := json.Unmarshal([]byte(payload), &parsed); unmarshalErr == nil {
+				ehloProfileMetrics = parsed
+			}
+		}
+
 		var routingMetrics *RoutingMetrics
 		if payload, payloadErr := s.rdb.Get(ctx, workerKey(id, "routing_metrics")).Result(); payloadErr == nil && payload != "" {
 			parsed := RoutingMetrics{}
@@ -928,6 +964,16 @@ func (s *Store) GetWorkers(ctx context.Context) ([]WorkerSummary, error) {
 			}
 		}
 
+		var circuitBreakerEvents []CircuitBreakerEvent
+		if payloads, payloadErr := s.rdb.LRange(ctx, workerKey(id, "circuit_breaker_events"), 0, workerCircuitBreakerEventLimit-1).Result(); payloadErr == nil {
+			for _, payload := range payloads {
+				parsed := CircuitBreakerEvent{}
+				if unmarshalErr := json.Unmarshal([]byte(payload), &parsed); unmarshalErr == nil {
+					circuitBreakerEvents = append(circuitBreakerEvents, parsed)
+				}
+			}
+		}
+
 		poolHealthHint := 0.0
 		if payload, payloadErr := s.rdb.Get(ctx, workerKey(id, "pool_health_hint")).Result(); payloadErr == nil && payload != "" {
 			if parsed, parseErr := strconv.ParseFloat(payload, 64); parseErr == nil {
@@ -952,6 +998,7 @@ func (s *Store) GetWorkers(ctx context.Context) ([]WorkerSummary, error) {
 			StageMetrics:          stageMetrics,
 			SMTPMetrics:           smtpMetrics,
 			ProviderMetrics:       providerMetrics,
+			EHLOProfileMetrics:    ehloProfileMetrics,
 			RoutingMetrics:        routingMetrics,
 			SessionMetrics:        sessionMetrics,
 			AttemptRouteMetrics:   attemptRouteMetrics,
@@ -960,6 +1007,7 @@ func (s *Store) GetWorkers(ctx context.Context) ([]WorkerSummary, error) {
 			SessionStrategyID:     sessionStrategyID,
 			ReasonTagCounts:       reasonTagCounts,
 			PoolHealthHint:        poolHealthHint,
+			CircuitBreakerEvents:  circuitBreakerEvents,
 		})
 	}
 
@@ -1416,6 +1464,7 @@ func (s *Store) ValidateSMTPPolicyVersion(ctx context.Context, version string, t
 	record.UpdatedBy = triggeredBy
 
 	validation, validationErr := s.preflightPolicyPayload(ctx, version, true)
+	record.ValidationFindings = validation.Findings
 	if validationErr != nil {
 		record.ValidationStatus = policyValidationStatusInvalid
 		record.ValidationError = validationErr.Error()
@@ -1953,6 +2002,7 @@ func staleWorkerDeleteKeys(workerID string) []string {
 		workerKey(workerID, "stage_metrics"),
 		workerKey(workerID, "smtp_metrics"),
 		workerKey(workerID, "provider_metrics"),
+		workerKey(workerID, "ehlo_profile_metrics"),
 		workerKey(workerID, "routing_metrics"),
 		workerKey(workerID, "session_metrics"),
 		workerKey(workerID, "attempt_route_metrics"),
@@ -1961,6 +2011,7 @@ func staleWorkerDeleteKeys(workerID string) []string {
 		workerKey(workerID, "session_strategy_id"),
 		workerKey(workerID, "reason_tag_counters"),
 		workerKey(workerID, "pool_health_hint"),
+		workerKey(workerID, "circuit_breaker_events"),
 		workerKey(workerID, "pool"),
 		workerKey(workerID, "desired_state"),
 		workerKey(workerID, "desired_state_updated"),
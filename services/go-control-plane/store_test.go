@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -288,6 +290,23 @@ func TestStaleWorkerDeleteKeysIncludePoolAndLastSeen(t *testing.T) {
 	if !containsString(keys, expectedReasonTagCounters) {
 		t.Fatalf("expected stale delete keys to include %q", expectedReasonTagCounters)
 	}
+	expectedCircuitBreakerEvents := workerKey(workerID, "circuit_breaker_events")
+	if !containsString(keys, expectedCircuitBreakerEvents) {
+		t.Fatalf("expected stale delete keys to include %q", expectedCircuitBreakerEvents)
+	}
+}
+
+func TestHeartbeatRequestDecodesCircuitBreakerEvents(t *testing.T) {
+	body := `{"worker_id":"worker-1","status":"running","circuit_breaker_events":[{"mode":"enforce","key":"mx:mx.test","scope":"mx","from":"closed","to":"open","tempfail_rate":0.6,"samples":20,"at":"2026-01-01T00:00:00Z"}]}`
+	request := httptest.NewRequest(http.MethodPost, "/api/workers/heartbeat", strings.NewReader(body))
+
+	var heartbeat HeartbeatRequest
+	if err := decodeJSON(request, &heartbeat); err != nil {
+		t.Fatalf("expected heartbeat with circuit breaker events to decode, got %v", err)
+	}
+	if len(heartbeat.CircuitBreakerEvents) != 1 || heartbeat.CircuitBreakerEvents[0].To != "open" || heartbeat.CircuitBreakerEvents[0].Samples != 20 {
+		t.Fatalf("unexpected circuit breaker events %+v", heartbeat.CircuitBreakerEvents)
+	}
 }
 
 func TestNormalizeProviderMode(t *testing.T) {
@@ -0,0 +1,7 @@
+module engine-worker-go
+
+go 1.22
+
+require golang.org/x/net v0.24.0
+
+require golang.org/x/text v0.14.0 // indirect
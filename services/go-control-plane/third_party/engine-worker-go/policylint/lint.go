@@ -0,0 +1,451 @@
+package policylint
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"engine-worker-go/replypolicy"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding codes. Schema violations and invalid rules are errors; the rest
+// are warnings about rules that will not behave as their author expects.
+const (
+	CodeInvalidJSON              = "invalid_json"
+	CodeMissingField             = "missing_field"
+	CodeInvalidType              = "invalid_type"
+	CodeInvalidValue             = "invalid_value"
+	CodeInvalidRule              = "invalid_rule"
+	CodeDuplicateRuleID          = "duplicate_rule_id"
+	CodeUnreachableRule          = "unreachable_rule"
+	CodeShadowedRule             = "shadowed_rule"
+	CodeConflictingDecisionClass = "conflicting_decision_class"
+	CodeRetryOutOfBounds         = "retry_out_of_bounds"
+	CodeProfileWithoutFallback   = "profile_without_fallback"
+)
+
+// Finding is one lint result. Path names the offending field, e.g.
+// profiles.gmail.smtp_code_rules[1].
+type Finding struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Path == "" {
+		return f.Message
+	}
+
+	return f.Path + ": " + f.Message
+}
+
+// Report lists the findings for one payload. Valid is false when any
+// finding is an error.
+type Report struct {
+	Valid    bool      `json:"valid"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+	Findings []Finding `json:"findings"`
+}
+
+// FirstError returns the first error finding, if any.
+func (r Report) FirstError() (Finding, bool) {
+	for _, finding := range r.Findings {
+		if finding.Severity == SeverityError {
+			return finding, true
+		}
+	}
+
+	return Finding{}, false
+}
+
+// Lint checks a full policy payload against the shared schema and, when it
+// conforms, checks its rules for duplicates, dead rules and conflicts.
+func Lint(raw []byte) Report {
+	l := &linter{}
+
+	root := map[string]any{}
+	if err := json.Unmarshal(raw, &root); err != nil {
+		l.add(SeverityError, CodeInvalidJSON, "", "policy payload must be a JSON object: %v", err)
+		return l.report()
+	}
+
+	l.checkSchema(root)
+	if l.errors == 0 {
+		l.checkRules(raw)
+	}
+
+	return l.report()
+}
+
+type linter struct {
+	findings []Finding
+	errors   int
+	warnings int
+}
+
+func (l *linter) add(severity, code, path, format string, args ...any) {
+	l.findings = append(l.findings, Finding{
+		Severity: severity,
+		Code:     code,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+	if severity == SeverityError {
+		l.errors++
+	} else {
+		l.warnings++
+	}
+}
+
+func (l *linter) report() Report {
+	findings := l.findings
+	if findings == nil {
+		findings = []Finding{}
+	}
+
+	return Report{Valid: l.errors == 0, Errors: l.errors, Warnings: l.warnings, Findings: findings}
+}
+
+func (l *linter) checkSchema(root map[string]any) {
+	if enabled, exists := root["enabled"]; !exists {
+		l.add(SeverityError, CodeMissingField, "enabled", "is required")
+	} else if _, ok := enabled.(bool); !ok {
+		l.add(SeverityError, CodeInvalidType, "enabled", "must be a boolean")
+	}
+
+	if version, ok := root["version"].(string); !ok || strings.TrimSpace(version) == "" {
+		l.add(SeverityError, CodeMissingField, "version", "is required")
+	}
+
+	schemaVersion := schema.DefaultSchemaVersion
+	if value, exists := root["schema_version"]; exists {
+		text, _ := value.(string)
+		schemaVersion = strings.ToLower(strings.TrimSpace(text))
+		if !contains(schema.SchemaVersions, schemaVersion) {
+			l.add(SeverityError, CodeInvalidValue, "schema_version", "must be one of %s", strings.Join(schema.SchemaVersions, ", "))
+		}
+	}
+	structured := schema.structured(schemaVersion)
+
+	profiles, ok := root["profiles"].(map[string]any)
+	if !ok {
+		l.add(SeverityError, CodeMissingField, "profiles", "is required and must be an object")
+		return
+	}
+	if _, ok := profiles["generic"].(map[string]any); !ok {
+		l.add(SeverityError, CodeMissingField, "profiles.generic", "is required and must be an object")
+	}
+
+	for _, name := range sortedKeys(profiles) {
+		path := "profiles." + name
+		profile, ok := profiles[name].(map[string]any)
+		if !ok {
+			l.add(SeverityError, CodeInvalidType, path, "must be an object")
+			continue
+		}
+
+		l.checkRetry(path+".retry", profile["retry"], name == "generic")
+		if structured {
+			l.checkSession(path+".session", profile["session"], schemaVersion)
+		}
+		for _, collection := range schema.RuleCollections {
+			l.checkRuleList(path+"."+collection, profile[collection], structured)
+		}
+		if canonicalization, exists := profile["canonicalization"]; exists {
+			l.checkCanonicalization(path+".canonicalization", canonicalization)
+		}
+	}
+
+	if structured {
+		l.checkModes(root["modes"], schemaVersion)
+	}
+	if scoring, exists := root["scoring"]; exists {
+		l.checkScoring(scoring)
+	}
+}
+
+func (l *linter) checkRetry(path string, value any, required bool) {
+	if value == nil {
+		if required {
+			l.add(SeverityError, CodeMissingField, path, "is required")
+		}
+		return
+	}
+	retry, ok := value.(map[string]any)
+	if !ok {
+		l.add(SeverityError, CodeInvalidType, path, "must be an object")
+		return
+	}
+
+	for _, field := range schema.RequiredRetryFields {
+		fieldValue, exists := retry[field]
+		if !exists {
+			if required {
+				l.add(SeverityError, CodeMissingField, path+"."+field, "must be present and numeric")
+			}
+			continue
+		}
+		seconds, ok := fieldValue.(float64)
+		if !ok {
+			l.add(SeverityError, CodeInvalidType, path+"."+field, "must be numeric")
+			continue
+		}
+		l.checkRetrySeconds(path+"."+field, seconds)
+	}
+}
+
+// checkRetrySeconds warns about retry delays outside the safe range. Zero
+// and negative delays fall back to the defaults and are not reported.
+func (l *linter) checkRetrySeconds(path string, seconds float64) {
+	bounds := schema.RetrySeconds
+	if seconds > 0 && (seconds < float64(bounds.Min) || seconds > float64(bounds.Max)) {
+		l.add(SeverityWarning, CodeRetryOutOfBounds, path, "%g seconds is outside the safe range %d-%d", seconds, bounds.Min, bounds.Max)
+	}
+}
+
+func (l *linter) checkSession(path string, value any, schemaVersion string) {
+	session, ok := value.(map[string]any)
+	if !ok {
+		l.add(SeverityError, CodeMissingField, path, "is required for schema %s", schemaVersion)
+		return
+	}
+
+	for _, field := range schema.RequiredSessionFields {
+		if _, exists := session[field]; !exists {
+			l.add(SeverityError, CodeMissingField, path+"."+field, "is required for schema %s", schemaVersion)
+		}
+	}
+	if tlsMode, exists := session["tls_mode"]; exists {
+		if text, _ := tlsMode.(string); !contains(schema.TLSModes, text) {
+			l.add(SeverityError, CodeInvalidValue, path+".tls_mode", "must be one of %s", strings.Join(schema.TLSModes, ", "))
+		}
+	}
+	if proxy, exists := session["proxy"]; exists {
+		if _, ok := proxy.(string); !ok {
+			l.add(SeverityError, CodeInvalidType, path+".proxy", "must be a string")
+		}
+	}
+}
+
+func (l *linter) checkCanonicalization(path string, value any) {
+	canonicalization, ok := value.(map[string]any)
+	if !ok {
+		l.add(SeverityError, CodeInvalidType, path, "must be an object")
+		return
+	}
+
+	l.stringList(path+".domains", canonicalization["domains"])
+	for _, flag := range schema.CanonicalizationFlags {
+		if flagValue, exists := canonicalization[flag]; exists {
+			if _, ok := flagValue.(bool); !ok {
+				l.add(SeverityError, CodeInvalidType, path+"."+flag, "must be a boolean")
+			}
+		}
+	}
+}
+
+func (l *linter) checkModes(value any, schemaVersion string) {
+	modes, ok := value.(map[string]any)
+	if !ok {
+		l.add(SeverityError, CodeMissingField, "modes", "is required for schema %s", schemaVersion)
+		return
+	}
+
+	for _, mode := range schema.RequiredModes {
+		definition, ok := modes[mode].(map[string]any)
+		if !ok {
+			l.add(SeverityError, CodeMissingField, "modes."+mode, "is required for schema %s", schemaVersion)
+			continue
+		}
+		for _, field := range schema.RequiredModeFields {
+			if _, exists := definition[field]; !exists {
+				l.add(SeverityError, CodeMissingField, "modes."+mode+"."+field, "is required for schema %s", schemaVersion)
+			}
+		}
+	}
+}
+
+func (l *linter) checkRuleList(path string, value any, structured bool) {
+	if value == nil {
+		return
+	}
+	rules, ok := value.([]any)
+	if !ok {
+		l.add(SeverityError, CodeInvalidType, path, "must be an array")
+		return
+	}
+
+	for i, item := range rules {
+		rulePath := fmt.Sprintf("%s[%d]", path, i)
+		rule, ok := item.(map[string]any)
+		if !ok {
+			l.add(SeverityError, CodeInvalidType, rulePath, "must be an object")
+			continue
+		}
+
+		if structured {
+			for _, field := range schema.RequiredRuleFields {
+				if text, _ := rule[field].(string); strings.TrimSpace(text) == "" {
+					l.add(SeverityError, CodeMissingField, rulePath+"."+field, "is required")
+				}
+			}
+			l.checkEnum(rulePath+".rule_tag", rule["rule_tag"], schema.RuleTags)
+		}
+		l.checkEnum(rulePath+".decision_class", rule["decision_class"], schema.DecisionClasses)
+		l.checkEnum(rulePath+".category", rule["category"], schema.Categories)
+		l.checkEnum(rulePath+".confidence_hint", rule["confidence_hint"], schema.ConfidenceHints)
+
+		for _, field := range []string{"enhanced_prefixes", "message_contains"} {
+			l.stringList(rulePath+"."+field, rule[field])
+		}
+		for _, field := range []string{"message_patterns", "exclude_patterns"} {
+			for j, pattern := range l.stringList(rulePath+"."+field, rule[field]) {
+				patternPath := fmt.Sprintf("%s.%s[%d]", rulePath, field, j)
+				if strings.TrimSpace(pattern) == "" {
+					l.add(SeverityError, CodeInvalidValue, patternPath, "pattern is empty")
+				} else if _, err := regexp.Compile(pattern); err != nil {
+					l.add(SeverityError, CodeInvalidValue, patternPath, "%v", err)
+				}
+			}
+		}
+		for j, stage := range l.stringList(rulePath+".stages", rule["stages"]) {
+			if !contains(schema.RuleStages, strings.ToLower(strings.TrimSpace(stage))) {
+				l.add(SeverityError, CodeInvalidValue, fmt.Sprintf("%s.stages[%d]", rulePath, j), "unknown stage %q (expected %s)", stage, strings.Join(schema.RuleStages, ", "))
+			}
+		}
+
+		if codes, exists := rule["smtp_codes"]; exists {
+			list, ok := codes.([]any)
+			if !ok {
+				l.add(SeverityError, CodeInvalidType, rulePath+".smtp_codes", "must be an array of integers")
+			}
+			for _, code := range list {
+				if !isInteger(code) {
+					l.add(SeverityError, CodeInvalidType, rulePath+".smtp_codes", "must be an array of integers")
+					break
+				}
+			}
+		}
+		if priority, exists := rule["priority"]; exists && !isInteger(priority) {
+			l.add(SeverityError, CodeInvalidType, rulePath+".priority", "must be an integer")
+		}
+		if retryAfter, exists := rule["retry_after_seconds"]; exists {
+			if seconds, ok := retryAfter.(float64); ok {
+				l.checkRetrySeconds(rulePath+".retry_after_seconds", seconds)
+			} else {
+				l.add(SeverityError, CodeInvalidType, rulePath+".retry_after_seconds", "must be numeric")
+			}
+		}
+	}
+}
+
+func (l *linter) checkEnum(path string, value any, allowed []string) {
+	if value == nil {
+		return
+	}
+	text, ok := value.(string)
+	if !ok {
+		l.add(SeverityError, CodeInvalidType, path, "must be a string")
+		return
+	}
+
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text != "" && !contains(allowed, text) {
+		l.add(SeverityError, CodeInvalidValue, path, "has unsupported value %q (expected %s)", text, strings.Join(allowed, ", "))
+	}
+}
+
+func (l *linter) stringList(path string, value any) []string {
+	if value == nil {
+		return nil
+	}
+
+	items, ok := value.([]any)
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		text, isString := item.(string)
+		if !isString {
+			ok = false
+			break
+		}
+		out = append(out, text)
+	}
+	if !ok {
+		l.add(SeverityError, CodeInvalidType, path, "must be an array of strings")
+		return nil
+	}
+
+	return out
+}
+
+func (l *linter) checkScoring(value any) {
+	scoring, ok := value.(map[string]any)
+	if !ok {
+		l.add(SeverityError, CodeInvalidType, "scoring", "must be an object")
+		return
+	}
+
+	if version, ok := scoring["version"].(string); !ok || strings.TrimSpace(version) == "" {
+		l.add(SeverityError, CodeMissingField, "scoring.version", "is required")
+	}
+
+	weightsValue, exists := scoring["weights"]
+	if !exists {
+		return
+	}
+	weights, ok := weightsValue.(map[string]any)
+	if !ok {
+		l.add(SeverityError, CodeInvalidType, "scoring.weights", "must be an object")
+		return
+	}
+
+	bounds := schema.ScoringWeight
+	for _, name := range sortedKeys(weights) {
+		if !contains(schema.ScoringFactors, strings.ToLower(strings.TrimSpace(name))) {
+			l.add(SeverityError, CodeInvalidValue, "scoring.weights."+name, "is not a known score factor")
+			continue
+		}
+		weight, ok := weights[name].(float64)
+		if !ok || !isInteger(weight) || weight < float64(bounds.Min) || weight > float64(bounds.Max) {
+			l.add(SeverityError, CodeInvalidValue, "scoring.weights."+name, "must be an integer between %d and %d", bounds.Min, bounds.Max)
+		}
+	}
+}
+
+// checkRules parses the payload the way the worker does and inspects the
+// rules it would apply.
+func (l *linter) checkRules(raw []byte) {
+	normalized, err := replypolicy.Parse(string(raw))
+	if err != nil {
+		l.add(SeverityError, CodeInvalidRule, "", "%v", err)
+		return
+	}
+
+	// Lint the rules as written; lists the payload leaves out come from the
+	// worker defaults and are not the author's to fix.
+	var written replypolicy.Engine
+	if err := json.Unmarshal(raw, &written); err != nil {
+		l.add(SeverityError, CodeInvalidRule, "", "%v", err)
+		return
+	}
+
+	for _, name := range sortedKeys(written.Profiles) {
+		l.checkProfileRules("profiles."+name, written.Profiles[name])
+	}
+
+	for _, name := range sortedKeys(normalized.Profiles) {
+		if len(normalized.Profiles[name].SMTPCodeRules) == 0 {
+			l.add(SeverityWarning, CodeProfileWithoutFallback, "profiles."+name+".smtp_code_rules",
+				"is empty and has no default; replies the profile's enhanced and message rules miss skip the policy")
+		}
+	}
+}
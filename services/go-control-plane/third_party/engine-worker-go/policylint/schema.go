@@ -0,0 +1,77 @@
+// Package policylint checks provider reply policy payloads against the
+// schema shared by the worker, the control plane and Laravel, and reports
+// rules that can never apply as intended.
+package policylint
+
+import (
+	_ "embed"
+	"encoding/json"
+)
+
+//go:embed schema.json
+var schemaJSON []byte
+
+// Bounds is an inclusive numeric range.
+type Bounds struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Schema is the shared policy payload definition. Laravel reads the same
+// schema.json.
+type Schema struct {
+	SchemaVersions           []string `json:"schema_versions"`
+	DefaultSchemaVersion     string   `json:"default_schema_version"`
+	StructuredSchemaVersions []string `json:"structured_schema_versions"`
+	RequiredRetryFields      []string `json:"required_retry_fields"`
+	RetrySeconds             Bounds   `json:"retry_seconds"`
+	RequiredSessionFields    []string `json:"required_session_fields"`
+	TLSModes                 []string `json:"tls_modes"`
+	RequiredModes            []string `json:"required_modes"`
+	RequiredModeFields       []string `json:"required_mode_fields"`
+	RuleCollections          []string `json:"rule_collections"`
+	RequiredRuleFields       []string `json:"required_rule_fields"`
+	RuleTags                 []string `json:"rule_tags"`
+	RuleStages               []string `json:"rule_stages"`
+	DecisionClasses          []string `json:"decision_classes"`
+	Categories               []string `json:"categories"`
+	ConfidenceHints          []string `json:"confidence_hints"`
+	CanonicalizationFlags    []string `json:"canonicalization_flags"`
+	ScoringFactors           []string `json:"scoring_factors"`
+	ScoringWeight            Bounds   `json:"scoring_weight"`
+}
+
+var schema = mustParseSchema(schemaJSON)
+
+func mustParseSchema(raw []byte) Schema {
+	var parsed Schema
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		panic("policylint: invalid schema.json: " + err.Error())
+	}
+
+	return parsed
+}
+
+// Definition returns the shared schema.
+func Definition() Schema {
+	return schema
+}
+
+// SchemaJSON returns schema.json as embedded.
+func SchemaJSON() []byte {
+	return append([]byte(nil), schemaJSON...)
+}
+
+func (s Schema) structured(version string) bool {
+	return contains(s.StructuredSchemaVersions, version)
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,63 @@
+package replypolicy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Canonicalization lists the address domains a provider profile
+// owns and the local-part variants that provider treats as one mailbox.
+type Canonicalization struct {
+	// Domains are matched exactly against the address domain. The generic
+	// profile applies to every domain no other profile lists.
+	Domains []string `json:"domains,omitempty"`
+	// IgnoreDots drops dots from the local part (j.doe and jdoe).
+	IgnoreDots bool `json:"ignore_dots"`
+	// PlusAddressing drops everything from the first plus sign
+	// (jdoe+promo and jdoe).
+	PlusAddressing bool `json:"plus_addressing"`
+}
+
+func (c Canonicalization) isZero() bool {
+	return len(c.Domains) == 0 && !c.IgnoreDots && !c.PlusAddressing
+}
+
+// normalizeCanonicalization keeps the default rules for profiles that do not
+// set any, so a payload only overrides the providers it names.
+func normalizeCanonicalization(value Canonicalization, fallback Canonicalization) Canonicalization {
+	if value.isZero() {
+		return fallback
+	}
+
+	domains := make([]string, 0, len(value.Domains))
+	for _, domain := range value.Domains {
+		if domain = NormalizeCanonicalDomain(domain); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	value.Domains = domains
+
+	return value
+}
+
+// NormalizeCanonicalDomain lowercases domain and drops a trailing dot, the
+// way canonicalization domains are matched.
+func NormalizeCanonicalDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+func validateCanonicalizationDomains(provider string, rules Canonicalization, claimed map[string]string) error {
+	for i, domain := range rules.Domains {
+		path := fmt.Sprintf("profiles.%s.canonicalization.domains[%d]", provider, i)
+		domain = NormalizeCanonicalDomain(domain)
+		if domain == "" {
+			return fmt.Errorf("%s: domain is empty", path)
+		}
+		if owner, exists := claimed[domain]; exists && owner != provider {
+			return fmt.Errorf("%s: domain %q is already listed by profile %s", path, domain, owner)
+		}
+		claimed[domain] = provider
+	}
+
+	return nil
+}
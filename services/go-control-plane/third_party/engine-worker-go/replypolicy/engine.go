@@ -0,0 +1,773 @@
+// Package replypolicy holds the provider reply policy payload: its types,
+// the defaults the worker ships with, and the parser and validation the
+// worker, policylint and the control plane share.
+package replypolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Categories and decision classes rules may assign.
+const (
+	CategoryValid   = "valid"
+	CategoryInvalid = "invalid"
+	CategoryRisky   = "risky"
+
+	DecisionDeliverable   = "deliverable"
+	DecisionUndeliverable = "undeliverable"
+	DecisionRetryable     = "retryable"
+	DecisionPolicyBlocked = "policy_blocked"
+	DecisionUnknown       = "unknown"
+)
+
+// SMTP TLS modes a session rule may set.
+const (
+	TLSNever         = "never"
+	TLSOpportunistic = "opportunistic"
+	TLSRequired      = "required"
+)
+
+type Engine struct {
+	Enabled       bool                `json:"enabled"`
+	Version       string              `json:"version,omitempty"`
+	SchemaVersion string              `json:"schema_version,omitempty"`
+	Profiles      map[string]Profile  `json:"profiles"`
+	Modes         map[string]ModeRule `json:"modes,omitempty"`
+	Scoring       ScoringModel        `json:"scoring"`
+}
+
+type Profile struct {
+	Name          string      `json:"name,omitempty"`
+	EnhancedRules []Rule      `json:"enhanced_rules,omitempty"`
+	SMTPCodeRules []Rule      `json:"smtp_code_rules,omitempty"`
+	MessageRules  []Rule      `json:"message_rules,omitempty"`
+	Retry         RetryPolicy `json:"retry"`
+	Session       SessionRule `json:"session,omitempty"`
+	// Canonicalization says which local-part variants the provider delivers
+	// to the same mailbox. The worker applies it in verifier.CanonicalEmail.
+	Canonicalization Canonicalization `json:"canonicalization,omitempty"`
+}
+
+type Rule struct {
+	RuleID           string   `json:"rule_id,omitempty"`
+	EnhancedPrefixes []string `json:"enhanced_prefixes,omitempty"`
+	SMTPCodes        []int    `json:"smtp_codes,omitempty"`
+	MessageContains  []string `json:"message_contains,omitempty"`
+	MessagePatterns  []string `json:"message_patterns,omitempty"`
+	ExcludePatterns  []string `json:"exclude_patterns,omitempty"`
+	Stages           []string `json:"stages,omitempty"`
+	Priority         int      `json:"priority,omitempty"`
+	DecisionClass    string   `json:"decision_class"`
+	Category         string   `json:"category"`
+	Reason           string   `json:"reason"`
+	ReasonCode       string   `json:"reason_code"`
+	RuleTag          string   `json:"rule_tag,omitempty"`
+	ConfidenceHint   string   `json:"confidence_hint,omitempty"`
+	ProviderScope    string   `json:"provider_scope,omitempty"`
+	RetryAfterSecond int      `json:"retry_after_seconds,omitempty"`
+}
+
+type RetryPolicy struct {
+	DefaultSeconds      int `json:"default_seconds,omitempty"`
+	TempfailSeconds     int `json:"tempfail_seconds,omitempty"`
+	GreylistSeconds     int `json:"greylist_seconds,omitempty"`
+	PolicyBlockedSecond int `json:"policy_blocked_seconds,omitempty"`
+	UnknownSeconds      int `json:"unknown_seconds,omitempty"`
+}
+
+type SessionRule struct {
+	MaxConcurrency            int    `json:"max_concurrency,omitempty"`
+	ConnectsPerMinute         int    `json:"connects_per_minute,omitempty"`
+	ReuseConnectionForRetries bool   `json:"reuse_connection_for_retries"`
+	RetryJitterPercent        int    `json:"retry_jitter_percent,omitempty"`
+	EHLOProfile               string `json:"ehlo_profile,omitempty"`
+	TLSMode                   string `json:"tls_mode,omitempty"`
+	Proxy                     string `json:"proxy,omitempty"`
+}
+
+type ModeRule struct {
+	ProbeEnabled                bool    `json:"probe_enabled"`
+	MaxConcurrencyMultiplier    float64 `json:"max_concurrency_multiplier,omitempty"`
+	ConnectsPerMinuteMultiplier float64 `json:"connects_per_minute_multiplier,omitempty"`
+}
+
+// Default returns the normalized engine the worker ships with.
+func Default() *Engine {
+	engine := Engine{
+		Enabled:       true,
+		Version:       "v4",
+		SchemaVersion: "v4",
+		Profiles:      DefaultProfiles(),
+		Modes:         DefaultModeRules(),
+		Scoring:       DefaultScoringModel(),
+	}
+
+	normalized := normalize(engine)
+	return &normalized
+}
+
+// Parse decodes and validates a policy payload and fills in the defaults
+// for everything it leaves out.
+func Parse(raw string) (*Engine, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("policy json is empty")
+	}
+
+	var parsed Engine
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid provider reply policy json: %w", err)
+	}
+	if err := validate(parsed); err != nil {
+		return nil, fmt.Errorf("invalid provider reply policy: %w", err)
+	}
+
+	normalized := normalize(parsed)
+	return &normalized, nil
+}
+
+func normalize(engine Engine) Engine {
+	defaults := Engine{
+		Version:       "v4",
+		SchemaVersion: "v4",
+		Profiles:      DefaultProfiles(),
+		Modes:         DefaultModeRules(),
+	}
+	genericDefaults := defaults.Profiles["generic"]
+	out := engine
+	if out.Profiles == nil {
+		out.Profiles = map[string]Profile{}
+	}
+	if out.Modes == nil {
+		out.Modes = map[string]ModeRule{}
+	}
+
+	for key, profile := range defaults.Profiles {
+		existing, ok := out.Profiles[key]
+		if !ok {
+			out.Profiles[key] = profile
+			continue
+		}
+
+		if len(existing.EnhancedRules) == 0 {
+			existing.EnhancedRules = profile.EnhancedRules
+			if len(existing.EnhancedRules) == 0 {
+				existing.EnhancedRules = genericDefaults.EnhancedRules
+			}
+		}
+		if len(existing.SMTPCodeRules) == 0 {
+			existing.SMTPCodeRules = profile.SMTPCodeRules
+			if len(existing.SMTPCodeRules) == 0 {
+				existing.SMTPCodeRules = genericDefaults.SMTPCodeRules
+			}
+		}
+		if len(existing.MessageRules) == 0 {
+			existing.MessageRules = profile.MessageRules
+			if len(existing.MessageRules) == 0 {
+				existing.MessageRules = genericDefaults.MessageRules
+			}
+		}
+		existing.EnhancedRules = normalizeRules(existing.EnhancedRules, key)
+		existing.SMTPCodeRules = normalizeRules(existing.SMTPCodeRules, key)
+		existing.MessageRules = normalizeRules(existing.MessageRules, key)
+		existing.Retry = NormalizeRetryPolicy(existing.Retry, profile.Retry)
+		existing.Session = normalizeSessionRule(existing.Session, profile.Session)
+		existing.Canonicalization = normalizeCanonicalization(existing.Canonicalization, profile.Canonicalization)
+		if strings.TrimSpace(existing.Name) == "" {
+			existing.Name = profile.Name
+		}
+
+		out.Profiles[key] = existing
+	}
+
+	if _, ok := out.Profiles["generic"]; !ok {
+		out.Profiles["generic"] = defaults.Profiles["generic"]
+	}
+
+	if strings.TrimSpace(out.Version) == "" {
+		out.Version = defaults.Version
+	}
+	if strings.TrimSpace(out.SchemaVersion) == "" {
+		if hasV3Signals(out) {
+			out.SchemaVersion = "v3"
+		} else {
+			out.SchemaVersion = "v2"
+		}
+	}
+
+	for mode, modeRule := range defaults.Modes {
+		existing, ok := out.Modes[mode]
+		if !ok {
+			out.Modes[mode] = modeRule
+			continue
+		}
+
+		if existing.MaxConcurrencyMultiplier <= 0 {
+			existing.MaxConcurrencyMultiplier = modeRule.MaxConcurrencyMultiplier
+		}
+		if existing.ConnectsPerMinuteMultiplier <= 0 {
+			existing.ConnectsPerMinuteMultiplier = modeRule.ConnectsPerMinuteMultiplier
+		}
+		if mode == "normal" && !existing.ProbeEnabled {
+			existing.ProbeEnabled = true
+		}
+		if mode == "drain" || mode == "quarantine" {
+			existing.ProbeEnabled = false
+		}
+		out.Modes[mode] = existing
+	}
+
+	out.Scoring = normalizeScoringModel(out.Scoring)
+
+	return out
+}
+
+// DefaultProfiles returns the provider profiles the worker ships with.
+func DefaultProfiles() map[string]Profile {
+	return map[string]Profile{
+		"generic": {
+			Name: "generic",
+			EnhancedRules: []Rule{
+				{
+					RuleID:           "generic-enhanced-511-mailbox-not-found",
+					EnhancedPrefixes: []string{"5.1.1", "5.1.10", "5.2."},
+					DecisionClass:    DecisionUndeliverable,
+					Category:         CategoryInvalid,
+					Reason:           "rcpt_rejected",
+					ReasonCode:       "mailbox_not_found",
+					RuleTag:          "mailbox_not_found",
+				},
+				{
+					RuleID:           "generic-enhanced-57-policy-blocked",
+					EnhancedPrefixes: []string{"5.7."},
+					DecisionClass:    DecisionPolicyBlocked,
+					Category:         CategoryRisky,
+					Reason:           "smtp_tempfail",
+					ReasonCode:       "smtp_policy_blocked",
+					RuleTag:          "policy_blocked",
+				},
+				{
+					RuleID:           "generic-enhanced-42-44-retry",
+					EnhancedPrefixes: []string{"4.2.", "4.4."},
+					DecisionClass:    DecisionRetryable,
+					Category:         CategoryRisky,
+					Reason:           "smtp_tempfail",
+					ReasonCode:       "smtp_tempfail",
+					RuleTag:          "unknown_transient",
+				},
+				{
+					RuleID:           "generic-enhanced-422-mailbox-full",
+					EnhancedPrefixes: []string{"4.2.2"},
+					DecisionClass:    DecisionRetryable,
+					Category:         CategoryRisky,
+					Reason:           "smtp_tempfail",
+					ReasonCode:       "mailbox_full",
+					RuleTag:          "mailbox_full",
+				},
+			},
+			SMTPCodeRules: []Rule{
+				{
+					RuleID:        "generic-smtp-421-452-retry",
+					SMTPCodes:     []int{421, 450, 451, 452},
+					DecisionClass: DecisionRetryable,
+					Category:      CategoryRisky,
+					Reason:        "smtp_tempfail",
+					ReasonCode:    "smtp_tempfail",
+					RuleTag:       "unknown_transient",
+				},
+				{
+					RuleID:        "generic-smtp-550-553-undeliverable",
+					SMTPCodes:     []int{550, 551, 553},
+					DecisionClass: DecisionUndeliverable,
+					Category:      CategoryInvalid,
+					Reason:        "rcpt_rejected",
+					ReasonCode:    "rcpt_rejected",
+					RuleTag:       "mailbox_not_found",
+				},
+			},
+			MessageRules: []Rule{
+				{
+					RuleID:           "generic-msg-greylist-retry",
+					MessageContains:  []string{"greylist", "try again later", "temporarily deferred"},
+					DecisionClass:    DecisionRetryable,
+					Category:         CategoryRisky,
+					Reason:           "smtp_tempfail",
+					ReasonCode:       "smtp_tempfail",
+					RetryAfterSecond: 180,
+					RuleTag:          "greylist",
+				},
+				{
+					RuleID:          "generic-msg-policy-blocked",
+					MessageContains: []string{"policy", "blocked", "denied", "forbidden", "authentication", "auth"},
+					DecisionClass:   DecisionPolicyBlocked,
+					Category:        CategoryRisky,
+					Reason:          "smtp_tempfail",
+					ReasonCode:      "smtp_policy_blocked",
+					RuleTag:         "policy_blocked",
+				},
+				{
+					RuleID:          "generic-msg-mailbox-not-found",
+					MessageContains: []string{"user unknown", "no such user", "mailbox unavailable", "recipient address rejected"},
+					DecisionClass:   DecisionUndeliverable,
+					Category:        CategoryInvalid,
+					Reason:          "rcpt_rejected",
+					ReasonCode:      "mailbox_not_found",
+					RuleTag:         "mailbox_not_found",
+				},
+				{
+					RuleID:          "generic-msg-mailbox-full",
+					MessageContains: []string{"mailbox full", "quota exceeded", "mailbox over quota"},
+					DecisionClass:   DecisionRetryable,
+					Category:        CategoryRisky,
+					Reason:          "smtp_tempfail",
+					ReasonCode:      "mailbox_full",
+					RuleTag:         "mailbox_full",
+				},
+				{
+					RuleID:          "generic-msg-auth-required",
+					MessageContains: []string{"authentication required", "auth required", "sender verify failed"},
+					DecisionClass:   DecisionPolicyBlocked,
+					Category:        CategoryRisky,
+					Reason:          "smtp_tempfail",
+					ReasonCode:      "smtp_policy_blocked",
+					RuleTag:         "auth_required",
+				},
+			},
+			Retry: RetryPolicy{
+				DefaultSeconds:      60,
+				TempfailSeconds:     90,
+				GreylistSeconds:     180,
+				PolicyBlockedSecond: 300,
+				UnknownSeconds:      75,
+			},
+			Session: SessionRule{
+				MaxConcurrency:            2,
+				ConnectsPerMinute:         30,
+				ReuseConnectionForRetries: false,
+				RetryJitterPercent:        15,
+				EHLOProfile:               "default",
+			},
+		},
+		"gmail": {
+			Name: "gmail",
+			EnhancedRules: []Rule{
+				{
+					RuleID:           "gmail-enhanced-47-retry",
+					EnhancedPrefixes: []string{"4.7."},
+					DecisionClass:    DecisionRetryable,
+					Category:         CategoryRisky,
+					Reason:           "smtp_tempfail",
+					ReasonCode:       "smtp_tempfail",
+					RuleTag:          "rate_limit",
+				},
+			},
+			SMTPCodeRules: []Rule{
+				{
+					RuleID:        "gmail-smtp-421-451-retry",
+					SMTPCodes:     []int{421, 450, 451},
+					DecisionClass: DecisionRetryable,
+					Category:      CategoryRisky,
+					Reason:        "smtp_tempfail",
+					ReasonCode:    "smtp_tempfail",
+					RuleTag:       "rate_limit",
+				},
+			},
+			MessageRules: []Rule{
+				{
+					RuleID:          "gmail-msg-rate-limit-retry",
+					MessageContains: []string{"rate limited", "temporarily deferred", "try again later"},
+					DecisionClass:   DecisionRetryable,
+					Category:        CategoryRisky,
+					Reason:          "smtp_tempfail",
+					ReasonCode:      "smtp_tempfail",
+					RuleTag:         "rate_limit",
+				},
+			},
+			Retry: RetryPolicy{
+				DefaultSeconds:      90,
+				TempfailSeconds:     120,
+				GreylistSeconds:     240,
+				PolicyBlockedSecond: 480,
+				UnknownSeconds:      120,
+			},
+			Session: SessionRule{
+				MaxConcurrency:            2,
+				ConnectsPerMinute:         20,
+				ReuseConnectionForRetries: false,
+				RetryJitterPercent:        20,
+				EHLOProfile:               "provider-safe-gmail",
+			},
+			Canonicalization: Canonicalization{
+				Domains:        []string{"gmail.com", "googlemail.com"},
+				IgnoreDots:     true,
+				PlusAddressing: true,
+			},
+		},
+		"microsoft": {
+			Name: "microsoft",
+			EnhancedRules: []Rule{
+				{
+					RuleID:           "microsoft-enhanced-47-retry",
+					EnhancedPrefixes: []string{"4.7."},
+					DecisionClass:    DecisionRetryable,
+					Category:         CategoryRisky,
+					Reason:           "smtp_tempfail",
+					ReasonCode:       "smtp_tempfail",
+					RuleTag:          "rate_limit",
+				},
+			},
+			SMTPCodeRules: []Rule{
+				{
+					RuleID:        "microsoft-smtp-421-451-retry",
+					SMTPCodes:     []int{421, 451, 452},
+					DecisionClass: DecisionRetryable,
+					Category:      CategoryRisky,
+					Reason:        "smtp_tempfail",
+					ReasonCode:    "smtp_tempfail",
+					RuleTag:       "rate_limit",
+				},
+			},
+			MessageRules: []Rule{
+				{
+					RuleID:          "microsoft-msg-deferral-retry",
+					MessageContains: []string{"temporarily deferred", "throttled", "try this again later"},
+					DecisionClass:   DecisionRetryable,
+					Category:        CategoryRisky,
+					Reason:          "smtp_tempfail",
+					ReasonCode:      "smtp_tempfail",
+					RuleTag:         "rate_limit",
+				},
+			},
+			Retry: RetryPolicy{
+				DefaultSeconds:      90,
+				TempfailSeconds:     150,
+				GreylistSeconds:     300,
+				PolicyBlockedSecond: 600,
+				UnknownSeconds:      120,
+			},
+			Session: SessionRule{
+				MaxConcurrency:            2,
+				ConnectsPerMinute:         18,
+				ReuseConnectionForRetries: false,
+				RetryJitterPercent:        20,
+				EHLOProfile:               "provider-safe-microsoft",
+			},
+			Canonicalization: Canonicalization{
+				Domains:        []string{"outlook.com", "hotmail.com", "live.com", "msn.com"},
+				PlusAddressing: true,
+			},
+		},
+		"yahoo": {
+			Name: "yahoo",
+			EnhancedRules: []Rule{
+				{
+					RuleID:           "yahoo-enhanced-47-retry",
+					EnhancedPrefixes: []string{"4.7."},
+					DecisionClass:    DecisionRetryable,
+					Category:         CategoryRisky,
+					Reason:           "smtp_tempfail",
+					ReasonCode:       "smtp_tempfail",
+					RuleTag:          "rate_limit",
+				},
+			},
+			SMTPCodeRules: []Rule{
+				{
+					RuleID:        "yahoo-smtp-421-451-retry",
+					SMTPCodes:     []int{421, 451, 452},
+					DecisionClass: DecisionRetryable,
+					Category:      CategoryRisky,
+					Reason:        "smtp_tempfail",
+					ReasonCode:    "smtp_tempfail",
+					RuleTag:       "rate_limit",
+				},
+			},
+			MessageRules: []Rule{
+				{
+					RuleID:          "yahoo-msg-tempfail-retry",
+					MessageContains: []string{"temporarily deferred", "temporarily unavailable", "try again later"},
+					DecisionClass:   DecisionRetryable,
+					Category:        CategoryRisky,
+					Reason:          "smtp_tempfail",
+					ReasonCode:      "smtp_tempfail",
+					RuleTag:         "rate_limit",
+				},
+			},
+			Retry: RetryPolicy{
+				DefaultSeconds:      75,
+				TempfailSeconds:     120,
+				GreylistSeconds:     240,
+				PolicyBlockedSecond: 480,
+				UnknownSeconds:      100,
+			},
+			Session: SessionRule{
+				MaxConcurrency:            2,
+				ConnectsPerMinute:         22,
+				ReuseConnectionForRetries: false,
+				RetryJitterPercent:        15,
+				EHLOProfile:               "provider-safe-yahoo",
+			},
+			Canonicalization: Canonicalization{
+				Domains: []string{"yahoo.com", "ymail.com", "rocketmail.com"},
+			},
+		},
+	}
+}
+
+// DefaultModeRules returns the provider mode rules the worker ships with.
+func DefaultModeRules() map[string]ModeRule {
+	return map[string]ModeRule{
+		"normal": {
+			ProbeEnabled:                true,
+			MaxConcurrencyMultiplier:    1,
+			ConnectsPerMinuteMultiplier: 1,
+		},
+		"cautious": {
+			ProbeEnabled:                true,
+			MaxConcurrencyMultiplier:    0.65,
+			ConnectsPerMinuteMultiplier: 0.6,
+		},
+		"drain": {
+			ProbeEnabled:                false,
+			MaxConcurrencyMultiplier:    0,
+			ConnectsPerMinuteMultiplier: 0,
+		},
+		"quarantine": {
+			ProbeEnabled:                false,
+			MaxConcurrencyMultiplier:    0,
+			ConnectsPerMinuteMultiplier: 0,
+		},
+		"degraded_probe": {
+			ProbeEnabled:                true,
+			MaxConcurrencyMultiplier:    0.4,
+			ConnectsPerMinuteMultiplier: 0.5,
+		},
+	}
+}
+
+// NormalizeRetryPolicy fills every unset delay of value from fallback.
+func NormalizeRetryPolicy(value RetryPolicy, fallback RetryPolicy) RetryPolicy {
+	if value.DefaultSeconds <= 0 {
+		value.DefaultSeconds = fallback.DefaultSeconds
+	}
+	if value.TempfailSeconds <= 0 {
+		value.TempfailSeconds = fallback.TempfailSeconds
+	}
+	if value.GreylistSeconds <= 0 {
+		value.GreylistSeconds = fallback.GreylistSeconds
+	}
+	if value.PolicyBlockedSecond <= 0 {
+		value.PolicyBlockedSecond = fallback.PolicyBlockedSecond
+	}
+	if value.UnknownSeconds <= 0 {
+		value.UnknownSeconds = fallback.UnknownSeconds
+	}
+
+	return value
+}
+
+func normalizeSessionRule(value SessionRule, fallback SessionRule) SessionRule {
+	if value.MaxConcurrency <= 0 {
+		value.MaxConcurrency = fallback.MaxConcurrency
+	}
+	if value.ConnectsPerMinute <= 0 {
+		value.ConnectsPerMinute = fallback.ConnectsPerMinute
+	}
+	if value.RetryJitterPercent < 0 {
+		value.RetryJitterPercent = fallback.RetryJitterPercent
+	}
+	if value.RetryJitterPercent > 50 {
+		value.RetryJitterPercent = 50
+	}
+	if strings.TrimSpace(value.EHLOProfile) == "" {
+		value.EHLOProfile = fallback.EHLOProfile
+	}
+	value.TLSMode = NormalizeTLSMode(value.TLSMode, fallback.TLSMode)
+	value.Proxy = strings.ToLower(strings.TrimSpace(value.Proxy))
+	if value.Proxy == "" {
+		value.Proxy = fallback.Proxy
+	}
+
+	return value
+}
+
+func normalizeRules(rules []Rule, provider string) []Rule {
+	output := make([]Rule, 0, len(rules))
+
+	for _, rule := range rules {
+		normalized := rule
+		if strings.TrimSpace(normalized.RuleTag) == "" {
+			normalized.RuleTag = InferRuleTag(normalized.ReasonCode, normalized.DecisionClass)
+		}
+		if strings.TrimSpace(normalized.ConfidenceHint) == "" {
+			normalized.ConfidenceHint = inferConfidenceHint(normalized.DecisionClass, normalized.Category)
+		}
+		if strings.TrimSpace(normalized.ProviderScope) == "" {
+			normalized.ProviderScope = provider
+		}
+		if len(rule.Stages) > 0 {
+			normalized.Stages = make([]string, 0, len(rule.Stages))
+			for _, stage := range rule.Stages {
+				normalized.Stages = append(normalized.Stages, strings.ToLower(strings.TrimSpace(stage)))
+			}
+		}
+		output = append(output, normalized)
+	}
+
+	return output
+}
+
+// InferRuleTag derives the tag of a rule that does not set one.
+func InferRuleTag(reasonCode string, decisionClass string) string {
+	reasonCode = strings.ToLower(strings.TrimSpace(reasonCode))
+	decisionClass = strings.ToLower(strings.TrimSpace(decisionClass))
+
+	switch {
+	case strings.Contains(reasonCode, "greylist"):
+		return "greylist"
+	case strings.Contains(reasonCode, "mailbox_full"):
+		return "mailbox_full"
+	case strings.Contains(reasonCode, "policy_blocked"):
+		return "policy_blocked"
+	case strings.Contains(reasonCode, "mailbox_not_found"):
+		return "mailbox_not_found"
+	case strings.Contains(reasonCode, "auth"):
+		return "auth_required"
+	case strings.Contains(reasonCode, "rate_limit"):
+		return "rate_limit"
+	case decisionClass == DecisionPolicyBlocked:
+		return "policy_blocked"
+	case decisionClass == DecisionUndeliverable:
+		return "mailbox_not_found"
+	default:
+		return "unknown_transient"
+	}
+}
+
+func inferConfidenceHint(decisionClass string, category string) string {
+	decisionClass = strings.ToLower(strings.TrimSpace(decisionClass))
+	category = strings.ToLower(strings.TrimSpace(category))
+
+	switch decisionClass {
+	case DecisionDeliverable, DecisionUndeliverable:
+		return "high"
+	case DecisionPolicyBlocked, DecisionRetryable:
+		return "medium"
+	case DecisionUnknown:
+		return "low"
+	default:
+		if category == CategoryValid || category == CategoryInvalid {
+			return "medium"
+		}
+		return "low"
+	}
+}
+
+func hasV3Signals(engine Engine) bool {
+	if len(engine.Modes) > 0 {
+		return true
+	}
+
+	for _, profile := range engine.Profiles {
+		if profile.Session.MaxConcurrency > 0 || profile.Session.ConnectsPerMinute > 0 || strings.TrimSpace(profile.Session.EHLOProfile) != "" {
+			return true
+		}
+		for _, rules := range [][]Rule{profile.EnhancedRules, profile.SMTPCodeRules, profile.MessageRules} {
+			for _, rule := range rules {
+				if strings.TrimSpace(rule.RuleTag) != "" ||
+					strings.TrimSpace(rule.ConfidenceHint) != "" ||
+					strings.TrimSpace(rule.ProviderScope) != "" {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Stages a rule's stages filter may name; HELO replies count as ehlo.
+var ruleStages = map[string]bool{
+	"banner":    true,
+	"ehlo":      true,
+	"mail_from": true,
+	"rcpt":      true,
+}
+
+// validate rejects rules with patterns that do not compile
+// or stages the matcher does not know, canonicalization domains that are
+// empty or claimed by two profiles, and unknown scoring weights, naming the
+// offending field.
+func validate(engine Engine) error {
+	providers := make([]string, 0, len(engine.Profiles))
+	for provider := range engine.Profiles {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	claimed := map[string]string{}
+	for _, provider := range providers {
+		profile := engine.Profiles[provider]
+		lists := []struct {
+			name  string
+			rules []Rule
+		}{
+			{"enhanced_rules", profile.EnhancedRules},
+			{"smtp_code_rules", profile.SMTPCodeRules},
+			{"message_rules", profile.MessageRules},
+		}
+		for _, list := range lists {
+			for i, rule := range list.rules {
+				path := fmt.Sprintf("profiles.%s.%s[%d]", provider, list.name, i)
+				if err := validatePatterns(path+".message_patterns", rule.MessagePatterns); err != nil {
+					return err
+				}
+				if err := validatePatterns(path+".exclude_patterns", rule.ExcludePatterns); err != nil {
+					return err
+				}
+				for j, stage := range rule.Stages {
+					if !ruleStages[strings.ToLower(strings.TrimSpace(stage))] {
+						return fmt.Errorf("%s.stages[%d]: unknown stage %q (expected banner, ehlo, mail_from or rcpt)", path, j, stage)
+					}
+				}
+			}
+		}
+
+		if err := validateCanonicalizationDomains(provider, profile.Canonicalization, claimed); err != nil {
+			return err
+		}
+	}
+
+	return validateScoringWeights(engine.Scoring)
+}
+
+func validatePatterns(path string, patterns []string) error {
+	for i, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("%s[%d]: pattern is empty", path, i)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%s[%d]: %w", path, i, err)
+		}
+	}
+
+	return nil
+}
+
+// NormalizeTLSMode maps a configured TLS mode onto never, opportunistic or
+// required. Unknown values fall back to fallback.
+func NormalizeTLSMode(mode, fallback string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case TLSNever:
+		return TLSNever
+	case TLSOpportunistic:
+		return TLSOpportunistic
+	case TLSRequired:
+		return TLSRequired
+	default:
+		return fallback
+	}
+}
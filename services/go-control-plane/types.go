@@ -1,5 +1,7 @@
 package main
 
+import "engine-worker-go/policylint"
+
 type WorkerMetrics struct {
 	EmailsPerSec float64 `json:"emails_per_sec,omitempty"`
 	ErrorsPerMin float64 `json:"errors_per_min,omitempty"`
@@ -35,6 +37,14 @@ type ProviderMetric struct {
 	PolicyBlockRate float64 `json:"policy_block_rate,omitempty"`
 }
 
+type EHLOProfileMetric struct {
+	Profile         string  `json:"profile"`
+	Processed       int64   `json:"processed"`
+	TempfailRate    float64 `json:"tempfail_rate,omitempty"`
+	RejectRate      float64 `json:"reject_rate,omitempty"`
+	PolicyBlockRate float64 `json:"policy_block_rate,omitempty"`
+}
+
 type RoutingMetrics struct {
 	RetryClaimsTotal              int64 `json:"retry_claims_total,omitempty"`
 	RetryAntiAffinitySuccessTotal int64 `json:"retry_anti_affinity_success_total,omitempty"`
@@ -49,37 +59,59 @@ type SessionMetrics struct {
 	SessionRetrySameConnTotal int64   `json:"session_retry_same_conn_total,omitempty"`
 	SessionRetryNewConnTotal  int64   `json:"session_retry_new_conn_total,omitempty"`
 	ThrottleAppliedTotal      int64   `json:"throttle_applied_total,omitempty"`
+	RateLimitWaitsTotal       int64   `json:"rate_limit_waits_total,omitempty"`
+	RateLimitThrottledTotal   int64   `json:"rate_limit_throttled_total,omitempty"`
+	RateLimitWaitAvgMS        float64 `json:"rate_limit_wait_avg_ms,omitempty"`
+	RateLimitWaitMaxMS        float64 `json:"rate_limit_wait_max_ms,omitempty"`
 }
 
 type AttemptRouteMetrics struct {
 	AttemptsTotal           int64 `json:"attempts_total,omitempty"`
 	RetryAttemptsTotal      int64 `json:"retry_attempts_total,omitempty"`
 	MXFallbackAttemptsTotal int64 `json:"mx_fallback_attempts_total,omitempty"`
+	GreylistParkedTotal     int64 `json:"greylist_parked_total,omitempty"`
+	GreylistRecoveredTotal  int64 `json:"greylist_recovered_total,omitempty"`
+	GreylistDeferredTotal   int64 `json:"greylist_deferred_total,omitempty"`
+}
+
+// CircuitBreakerEvent is a circuit breaker state transition reported by a
+// worker.
+type CircuitBreakerEvent struct {
+	Mode         string  `json:"mode,omitempty"`
+	Key          string  `json:"key"`
+	Scope        string  `json:"scope"`
+	From         string  `json:"from"`
+	To           string  `json:"to"`
+	TempfailRate float64 `json:"tempfail_rate"`
+	Samples      int     `json:"samples,omitempty"`
+	At           string  `json:"at"`
 }
 
 type HeartbeatRequest struct {
-	WorkerID              string               `json:"worker_id"`
-	Host                  string               `json:"host,omitempty"`
-	IPAddress             string               `json:"ip_address,omitempty"`
-	Version               string               `json:"version,omitempty"`
-	Pool                  string               `json:"pool,omitempty"`
-	Tags                  []string             `json:"tags,omitempty"`
-	Status                string               `json:"status"`
-	CurrentJobID          string               `json:"current_job_id,omitempty"`
-	CurrentChunkID        string               `json:"current_chunk_id,omitempty"`
-	CorrelationID         string               `json:"correlation_id,omitempty"`
-	Metrics               *WorkerMetrics       `json:"metrics,omitempty"`
-	StageMetrics          *StageMetrics        `json:"stage_metrics,omitempty"`
-	SMTPMetrics           *SMTPMetrics         `json:"smtp_metrics,omitempty"`
-	ProviderMetrics       []ProviderMetric     `json:"provider_metrics,omitempty"`
-	RoutingMetrics        *RoutingMetrics      `json:"routing_metrics,omitempty"`
-	SessionMetrics        *SessionMetrics      `json:"session_metrics,omitempty"`
-	AttemptRouteMetrics   *AttemptRouteMetrics `json:"attempt_route_metrics,omitempty"`
-	RetryAntiAffinityHits int64                `json:"retry_anti_affinity_hits,omitempty"`
-	UnknownReasonTags     map[string]int64     `json:"unknown_reason_tags,omitempty"`
-	SessionStrategyID     string               `json:"session_strategy_id,omitempty"`
-	ReasonTagCounts       map[string]int64     `json:"reason_tag_counters,omitempty"`
-	PoolHealthHint        *float64             `json:"pool_health_hint,omitempty"`
+	WorkerID              string                `json:"worker_id"`
+	Host                  string                `json:"host,omitempty"`
+	IPAddress             string                `json:"ip_address,omitempty"`
+	Version               string                `json:"version,omitempty"`
+	Pool                  string                `json:"pool,omitempty"`
+	Tags                  []string              `json:"tags,omitempty"`
+	Status                string                `json:"status"`
+	CurrentJobID          string                `json:"current_job_id,omitempty"`
+	CurrentChunkID        string                `json:"current_chunk_id,omitempty"`
+	CorrelationID         string                `json:"correlation_id,omitempty"`
+	Metrics               *WorkerMetrics        `json:"metrics,omitempty"`
+	StageMetrics          *StageMetrics         `json:"stage_metrics,omitempty"`
+	SMTPMetrics           *SMTPMetrics          `json:"smtp_metrics,omitempty"`
+	ProviderMetrics       []ProviderMetric      `json:"provider_metrics,omitempty"`
+	EHLOProfileMetrics    []EHLOProfileMetric   `json:"ehlo_profile_metrics,omitempty"`
+	RoutingMetrics        *RoutingMetrics       `json:"routing_metrics,omitempty"`
+	SessionMetrics        *SessionMetrics       `json:"session_metrics,omitempty"`
+	AttemptRouteMetrics   *AttemptRouteMetrics  `json:"attempt_route_metrics,omitempty"`
+	RetryAntiAffinityHits int64                 `json:"retry_anti_affinity_hits,omitempty"`
+	UnknownReasonTags     map[string]int64      `json:"unknown_reason_tags,omitempty"`
+	SessionStrategyID     string                `json:"session_strategy_id,omitempty"`
+	ReasonTagCounts       map[string]int64      `json:"reason_tag_counters,omitempty"`
+	PoolHealthHint        *float64              `json:"pool_health_hint,omitempty"`
+	CircuitBreakerEvents  []CircuitBreakerEvent `json:"circuit_breaker_events,omitempty"`
 }
 
 type HeartbeatResponse struct {
@@ -88,30 +120,32 @@ type HeartbeatResponse struct {
 }
 
 type WorkerSummary struct {
-	WorkerID              string               `json:"worker_id"`
-	Host                  string               `json:"host,omitempty"`
-	IPAddress             string               `json:"ip_address,omitempty"`
-	Version               string               `json:"version,omitempty"`
-	Pool                  string               `json:"pool,omitempty"`
-	Tags                  []string             `json:"tags,omitempty"`
-	Status                string               `json:"status"`
-	DesiredState          string               `json:"desired_state"`
-	Quarantined           bool                 `json:"quarantined"`
-	LastHeartbeat         string               `json:"last_heartbeat_at"`
-	CurrentJobID          string               `json:"current_job_id,omitempty"`
-	CurrentChunkID        string               `json:"current_chunk_id,omitempty"`
-	CorrelationID         string               `json:"correlation_id,omitempty"`
-	StageMetrics          *StageMetrics        `json:"stage_metrics,omitempty"`
-	SMTPMetrics           *SMTPMetrics         `json:"smtp_metrics,omitempty"`
-	ProviderMetrics       []ProviderMetric     `json:"provider_metrics,omitempty"`
-	RoutingMetrics        *RoutingMetrics      `json:"routing_metrics,omitempty"`
-	SessionMetrics        *SessionMetrics      `json:"session_metrics,omitempty"`
-	AttemptRouteMetrics   *AttemptRouteMetrics `json:"attempt_route_metrics,omitempty"`
-	RetryAntiAffinityHits int64                `json:"retry_anti_affinity_hits,omitempty"`
-	UnknownReasonTags     map[string]int64     `json:"unknown_reason_tags,omitempty"`
-	SessionStrategyID     string               `json:"session_strategy_id,omitempty"`
-	ReasonTagCounts       map[string]int64     `json:"reason_tag_counters,omitempty"`
-	PoolHealthHint        float64              `json:"pool_health_hint,omitempty"`
+	WorkerID              string                `json:"worker_id"`
+	Host                  string                `json:"host,omitempty"`
+	IPAddress             string                `json:"ip_address,omitempty"`
+	Version               string                `json:"version,omitempty"`
+	Pool                  string                `json:"pool,omitempty"`
+	Tags                  []string              `json:"tags,omitempty"`
+	Status                string                `json:"status"`
+	DesiredState          string                `json:"desired_state"`
+	Quarantined           bool                  `json:"quarantined"`
+	LastHeartbeat         string                `json:"last_heartbeat_at"`
+	CurrentJobID          string                `json:"current_job_id,omitempty"`
+	CurrentChunkID        string                `json:"current_chunk_id,omitempty"`
+	CorrelationID         string                `json:"correlation_id,omitempty"`
+	StageMetrics          *StageMetrics         `json:"stage_metrics,omitempty"`
+	SMTPMetrics           *SMTPMetrics          `json:"smtp_metrics,omitempty"`
+	ProviderMetrics       []ProviderMetric      `json:"provider_metrics,omitempty"`
+	EHLOProfileMetrics    []EHLOProfileMetric   `json:"ehlo_profile_metrics,omitempty"`
+	RoutingMetrics        *RoutingMetrics       `json:"routing_metrics,omitempty"`
+	SessionMetrics        *SessionMetrics       `json:"session_metrics,omitempty"`
+	AttemptRouteMetrics   *AttemptRouteMetrics  `json:"attempt_route_metrics,omitempty"`
+	RetryAntiAffinityHits int64                 `json:"retry_anti_affinity_hits,omitempty"`
+	UnknownReasonTags     map[string]int64      `json:"unknown_reason_tags,omitempty"`
+	SessionStrategyID     string                `json:"session_strategy_id,omitempty"`
+	ReasonTagCounts       map[string]int64      `json:"reason_tag_counters,omitempty"`
+	PoolHealthHint        float64               `json:"pool_health_hint,omitempty"`
+	CircuitBreakerEvents  []CircuitBreakerEvent `json:"circuit_breaker_events,omitempty"`
 }
 
 type WorkersResponse struct {
@@ -405,6 +439,8 @@ type SMTPPolicyVersionRecord struct {
 	PromotedAt         string `json:"promoted_at,omitempty"`
 	RolledBackAt       string `json:"rolled_back_at,omitempty"`
 	UpdatedBy          string `json:"updated_by,omitempty"`
+	// ValidationFindings are the policy lint findings of the last validate.
+	ValidationFindings []policylint.Finding `json:"validation_findings,omitempty"`
 }
 
 type SMTPPolicyVersionsResponse struct {
@@ -442,3 +478,30 @@ type ProviderModeSemantics struct {
 	MaxConcurrencyMultiplier    float64 `json:"max_concurrency_multiplier,omitempty"`
 	ConnectsPerMinuteMultiplier float64 `json:"connects_per_minute_multiplier,omitempty"`
 }
+
+type DomainListManifest struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Checksum  string `json:"checksum"`
+	Count     int    `json:"count"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+type DomainListPayload struct {
+	DomainListManifest
+	Mode        string   `json:"mode"`
+	BaseVersion string   `json:"base_version,omitempty"`
+	Entries     []string `json:"entries,omitempty"`
+	Added       []string `json:"added,omitempty"`
+	Removed     []string `json:"removed,omitempty"`
+}
+
+type DomainListsResponse struct {
+	Data struct {
+		Lists []DomainListManifest `json:"lists"`
+	} `json:"data"`
+}
+
+type DomainListResponse struct {
+	Data DomainListPayload `json:"data"`
+}
@@ -222,4 +222,69 @@ public function test_recorder_persists_attempt_chain_from_reason_metadata(): voi
         $this->assertSame('reputation-c', $attemptRoute['pool'] ?? null);
         $this->assertSame('gmail', $attemptRoute['provider'] ?? null);
     }
+
+    public function test_recorder_attaches_transcripts_from_sidecar(): void
+    {
+        Storage::fake('local');
+
+        $user = User::factory()->create();
+        $job = VerificationJob::query()->create([
+            'user_id' => $user->id,
+            'status' => VerificationJobStatus::Processing,
+            'original_filename' => 'emails.csv',
+            'input_disk' => 'local',
+            'input_key' => 'uploads/'.$user->id.'/job/input.csv',
+        ]);
+
+        $chunk = VerificationJobChunk::query()->create([
+            'verification_job_id' => (string) $job->id,
+            'chunk_no' => 4,
+            'status' => 'completed',
+            'processing_stage' => 'smtp_probe',
+            'input_disk' => 'local',
+            'input_key' => 'chunks/'.$job->id.'/4/input.csv',
+            'output_disk' => 'local',
+            'invalid_key' => 'results/'.$job->id.'/chunk4-invalid.csv',
+            'transcript_key' => 'results/'.$job->id.'/chunk4-transcript.jsonl',
+            'routing_provider' => 'gmail',
+            'last_worker_ids' => ['worker-d'],
+        ]);
+
+        $emailHash = hash('sha256', 'transcript@example.com');
+        Storage::disk('local')->put($chunk->invalid_key, implode("\n", [
+            'email,reason',
+            'Transcript@Example.com,rcpt_rejected:decision=undeliverable;smtp=550',
+            'other@example.com,rcpt_rejected:decision=undeliverable;smtp=550',
+        ]));
+        Storage::disk('local')->put($chunk->transcript_key, implode("\n", [
+            json_encode([
+                'email_hash' => $emailHash,
+                'transcript' => [
+                    ['attempt' => 1, 'at_ms' => 0, 'reply' => ['220 mx.test ESMTP']],
+                    ['attempt' => 1, 'at_ms' => 12, 'command' => 'RCPT TO:<sha256:'.$emailHash.'>', 'reply' => ['550 5.1.1 No such user'], 'latency_ms' => 9],
+                ],
+            ]),
+            'not json',
+        ]));
+
+        $recorder = app(SmtpDecisionTraceRecorder::class);
+        $this->assertSame(2, $recorder->recordFromChunk($chunk));
+
+        $trace = SmtpDecisionTrace::query()
+            ->where('verification_job_chunk_id', (string) $chunk->id)
+            ->where('email_hash', $emailHash)
+            ->first();
+
+        $this->assertNotNull($trace);
+        $tracePayload = is_array($trace->trace_payload) ? $trace->trace_payload : [];
+        $this->assertCount(2, $tracePayload['transcript'] ?? []);
+        $this->assertSame('220 mx.test ESMTP', data_get($tracePayload, 'transcript.0.reply.0'));
+        $this->assertSame(9, data_get($tracePayload, 'transcript.1.latency_ms'));
+
+        $otherTrace = SmtpDecisionTrace::query()
+            ->where('email_hash', hash('sha256', 'other@example.com'))
+            ->first();
+
+        $this->assertSame([], data_get($otherTrace?->trace_payload, 'transcript'));
+    }
 }
@@ -135,9 +135,156 @@ public function test_validator_rejects_unsupported_rule_tag_in_v4_payload(): voi
         $this->assertTrue($this->containsError($errors, 'unsupported value'));
     }
 
+    public function test_validator_checks_optional_scoring_model(): void
+    {
+        $payload = [
+            'enabled' => true,
+            'version' => 'v2.7.0',
+            'profiles' => [
+                'generic' => [
+                    'retry' => [
+                        'default_seconds' => 60,
+                        'tempfail_seconds' => 90,
+                        'greylist_seconds' => 180,
+                        'policy_blocked_seconds' => 300,
+                        'unknown_seconds' => 75,
+                    ],
+                ],
+            ],
+            'scoring' => [
+                'version' => 'score-v2',
+                'weights' => ['free_mail' => -10, 'smtp_deliverable' => 40],
+            ],
+        ];
+
+        $validator = app(SmtpPolicyPayloadValidator::class);
+        $this->assertSame([], $validator->validate($payload, 'v2.7.0'));
+
+        $payload['scoring'] = ['weights' => ['free_mail' => 150]];
+        $errors = $validator->validate($payload, 'v2.7.0');
+
+        $this->assertTrue($this->containsError($errors, 'scoring.version'));
+        $this->assertTrue($this->containsError($errors, 'scoring.weights.free_mail'));
+
+        $payload['scoring'] = ['version' => 'score-v2', 'weights' => ['fre_mail' => -10]];
+        $errors = $validator->validate($payload, 'v2.7.0');
+
+        $this->assertTrue($this->containsError($errors, 'scoring.weights.fre_mail" is not a known score factor'));
+    }
+
+    public function test_validator_checks_rule_values_against_shared_schema(): void
+    {
+        $payload = [
+            'enabled' => true,
+            'version' => 'v2.8.0',
+            'profiles' => [
+                'generic' => [
+                    'retry' => [
+                        'default_seconds' => 60,
+                        'tempfail_seconds' => 90,
+                        'greylist_seconds' => 180,
+                        'policy_blocked_seconds' => 300,
+                        'unknown_seconds' => 75,
+                    ],
+                ],
+                'microsoft' => [
+                    'message_rules' => [
+                        [
+                            'message_patterns' => ['access denied\\. AS\\((?P<as>\\d+)\\)'],
+                            'stages' => ['rcpt'],
+                            'priority' => 10,
+                            'decision_class' => 'policy_blocked',
+                        ],
+                    ],
+                ],
+            ],
+        ];
+
+        $validator = app(SmtpPolicyPayloadValidator::class);
+        $this->assertSame([], $validator->validate($payload, 'v2.8.0'));
+
+        $payload['profiles']['microsoft']['message_rules'][0]['stages'] = ['data'];
+        $payload['profiles']['microsoft']['message_rules'][0]['decision_class'] = 'maybe';
+        $payload['profiles']['microsoft']['message_rules'][0]['priority'] = 'high';
+        $errors = $validator->validate($payload, 'v2.8.0');
+
+        $this->assertTrue($this->containsError($errors, 'profiles.microsoft.message_rules.0.stages'));
+        $this->assertTrue($this->containsError($errors, 'profiles.microsoft.message_rules.0.decision_class'));
+        $this->assertTrue($this->containsError($errors, 'profiles.microsoft.message_rules.0.priority'));
+    }
+
+    public function test_validator_checks_profile_canonicalization(): void
+    {
+        $payload = [
+            'enabled' => true,
+            'version' => 'v2.8.0',
+            'profiles' => [
+                'generic' => [
+                    'retry' => [
+                        'default_seconds' => 60,
+                        'tempfail_seconds' => 90,
+                        'greylist_seconds' => 180,
+                        'policy_blocked_seconds' => 300,
+                        'unknown_seconds' => 75,
+                    ],
+                ],
+                'gmail' => [
+                    'canonicalization' => [
+                        'domains' => ['gmail.com', 'googlemail.com'],
+                        'ignore_dots' => true,
+                        'plus_addressing' => true,
+                    ],
+                ],
+            ],
+        ];
+
+        $validator = app(SmtpPolicyPayloadValidator::class);
+        $this->assertSame([], $validator->validate($payload, 'v2.8.0'));
+
+        $payload['profiles']['gmail']['canonicalization']['ignore_dots'] = 'yes';
+        $payload['profiles']['workspace'] = ['canonicalization' => ['domains' => ['Gmail.com']]];
+        $errors = $validator->validate($payload, 'v2.8.0');
+
+        $this->assertTrue($this->containsError($errors, 'profiles.gmail.canonicalization.ignore_dots'));
+        $this->assertTrue($this->containsError($errors, 'profiles.workspace.canonicalization.domains'));
+    }
+
     /**
      * @param  array<int, string>  $errors
      */
+    public function test_validator_skips_schema_checks_when_schema_is_missing(): void
+    {
+        config(['verifier.policy_schema_path' => storage_path('framework/testing/missing-policy-schema.json')]);
+
+        $payload = [
+            'enabled' => true,
+            'version' => 'v2.9.0',
+            'schema_version' => 'v9',
+            'profiles' => [
+                'generic' => [
+                    'retry' => ['default_seconds' => 60],
+                ],
+            ],
+        ];
+
+        $validator = app(SmtpPolicyPayloadValidator::class);
+        $this->assertSame([], $validator->validate($payload, 'v2.9.0'));
+
+        unset($payload['profiles']['generic']['retry']);
+        $errors = $validator->validate($payload, 'v2.9.0');
+        $this->assertTrue($this->containsError($errors, 'profiles.generic.retry'));
+    }
+
+    public function test_published_schema_matches_worker_schema(): void
+    {
+        $workerSchema = base_path('engine-worker-go/policylint/schema.json');
+        if (! is_file($workerSchema)) {
+            $this->markTestSkipped('Worker source is not part of this checkout.');
+        }
+
+        $this->assertJsonFileEqualsJsonFile($workerSchema, resource_path('verifier/policy-schema.json'));
+    }
+
     private function containsError(array $errors, string $needle): bool
     {
         foreach ($errors as $error) {
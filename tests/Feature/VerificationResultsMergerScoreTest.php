@@ -170,6 +170,94 @@ public function test_scores_are_clamped_and_cache_adjusted(): void
         $this->assertStringContainsString('invalid@example.com,invalid,syntax,0', $invalidContent);
     }
 
+    public function test_engine_score_metadata_replaces_reason_baseline(): void
+    {
+        Storage::fake('s3');
+
+        config(['engine.deliverability_score.use_engine_score' => true]);
+
+        $storage = app(JobStorage::class);
+        $job = $this->makeJob();
+
+        $chunk = $this->makeChunk($job, 1, [
+            'output_disk' => 's3',
+            'valid_key' => $storage->chunkOutputKey($job, 1, 'valid'),
+            'invalid_key' => $storage->chunkOutputKey($job, 1, 'invalid'),
+            'risky_key' => $storage->chunkOutputKey($job, 1, 'risky'),
+        ]);
+
+        Storage::disk('s3')->put($chunk->valid_key, "email,reason
+scored@example.com,rcpt_ok:score=72;score_model=score-v1
+");
+        Storage::disk('s3')->put($chunk->invalid_key, "email,reason
+");
+        Storage::disk('s3')->put($chunk->risky_key, "email,reason
+");
+
+        \App\Jobs\FinalizeVerificationJob::dispatchSync($job->id);
+        $job->refresh();
+
+        $this->assertStringContainsString('scored@example.com,valid,smtp_connect_ok,72,', Storage::disk('s3')->get($job->valid_key));
+    }
+
+    public function test_engine_score_is_read_from_typo_reason_with_appended_metadata(): void
+    {
+        Storage::fake('s3');
+
+        config(['engine.deliverability_score.use_engine_score' => true]);
+
+        $storage = app(JobStorage::class);
+        $job = $this->makeJob();
+
+        $chunk = $this->makeChunk($job, 1, [
+            'output_disk' => 's3',
+            'valid_key' => $storage->chunkOutputKey($job, 1, 'valid'),
+            'invalid_key' => $storage->chunkOutputKey($job, 1, 'invalid'),
+            'risky_key' => $storage->chunkOutputKey($job, 1, 'risky'),
+        ]);
+
+        Storage::disk('s3')->put($chunk->valid_key, "email,reason
+");
+        Storage::disk('s3')->put($chunk->invalid_key, "email,reason
+");
+        Storage::disk('s3')->put($chunk->risky_key, "email,reason
+typo@gmial.com,domain_typo_suspected:suggest=gmail.com;free_mail=true;score=41;score_model=score-v1
+");
+
+        \App\Jobs\FinalizeVerificationJob::dispatchSync($job->id);
+        $job->refresh();
+
+        $this->assertStringContainsString('typo@gmial.com,risky,domain_typo_suspected,41,', Storage::disk('s3')->get($job->risky_key));
+    }
+
+    public function test_engine_score_metadata_is_ignored_by_default(): void
+    {
+        Storage::fake('s3');
+
+        $storage = app(JobStorage::class);
+        $job = $this->makeJob();
+
+        $chunk = $this->makeChunk($job, 1, [
+            'output_disk' => 's3',
+            'valid_key' => $storage->chunkOutputKey($job, 1, 'valid'),
+            'invalid_key' => $storage->chunkOutputKey($job, 1, 'invalid'),
+            'risky_key' => $storage->chunkOutputKey($job, 1, 'risky'),
+        ]);
+
+        Storage::disk('s3')->put($chunk->valid_key, "email,reason
+scored@example.com,rcpt_ok:score=72;score_model=score-v1
+");
+        Storage::disk('s3')->put($chunk->invalid_key, "email,reason
+");
+        Storage::disk('s3')->put($chunk->risky_key, "email,reason
+");
+
+        \App\Jobs\FinalizeVerificationJob::dispatchSync($job->id);
+        $job->refresh();
+
+        $this->assertStringNotContainsString('scored@example.com,valid,smtp_connect_ok,72,', Storage::disk('s3')->get($job->valid_key));
+    }
+
     public function test_legacy_email_reason_rows_merge_without_crashing(): void
     {
         Storage::fake('s3');
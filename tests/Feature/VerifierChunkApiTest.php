@@ -166,6 +166,37 @@ public function test_chunk_fail_retry_policy(): void
         $this->assertSame(2, $chunk->attempts);
     }
 
+    public function test_chunk_lease_is_extended_for_the_assigned_worker(): void
+    {
+        $this->actingAsVerifier();
+
+        $job = $this->makeJob();
+        $chunk = $this->makeChunk($job, [
+            'assigned_worker_id' => 'worker-1',
+            'claim_expires_at' => now()->addMinute(),
+            'claim_token' => 'token',
+        ]);
+
+        $response = $this->postJson(route('api.verifier.chunks.lease', $chunk), [
+            'worker_id' => 'worker-1',
+            'lease_seconds' => 900,
+        ])->assertOk();
+
+        $chunk->refresh();
+        $this->assertTrue($chunk->claim_expires_at->greaterThan(now()->addMinutes(14)));
+        $this->assertSame($chunk->claim_expires_at->toIso8601String(), $response->json('data.lease_expires_at'));
+
+        $this->postJson(route('api.verifier.chunks.lease', $chunk), [
+            'worker_id' => 'worker-2',
+        ])->assertStatus(409);
+
+        $chunk->update(['status' => 'completed']);
+
+        $this->postJson(route('api.verifier.chunks.lease', $chunk), [
+            'worker_id' => 'worker-1',
+        ])->assertStatus(409);
+    }
+
     public function test_chunk_complete_is_idempotent(): void
     {
         $this->actingAsVerifier();
@@ -273,6 +304,7 @@ public function temporaryUploadUrl(string $disk, string $key, int $expirySeconds
                         'valid' => ['key', 'url'],
                         'invalid' => ['key', 'url'],
                         'risky' => ['key', 'url'],
+                        'transcript' => ['key', 'url'],
                     ],
                 ],
             ]);
@@ -59,6 +59,7 @@ public function test_policy_endpoint_returns_structure(): void
                             'smtp_read_timeout_ms',
                             'max_mx_attempts',
                             'max_concurrency_default',
+                            'chunk_parallelism',
                             'per_domain_concurrency',
                             'global_connects_per_minute',
                             'tempfail_backoff_seconds',
@@ -72,6 +73,7 @@ public function test_policy_endpoint_returns_structure(): void
                             'smtp_read_timeout_ms',
                             'max_mx_attempts',
                             'max_concurrency_default',
+                            'chunk_parallelism',
                             'per_domain_concurrency',
                             'global_connects_per_minute',
                             'tempfail_backoff_seconds',
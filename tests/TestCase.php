@@ -42,6 +42,7 @@ protected function ensureEngineDefaults(): void
                         'smtp_read_timeout_ms' => (int) ($defaults['smtp_read_timeout_ms'] ?? 2000),
                         'max_mx_attempts' => (int) ($defaults['max_mx_attempts'] ?? 2),
                         'max_concurrency_default' => (int) ($defaults['max_concurrency_default'] ?? 1),
+                        'chunk_parallelism' => (int) ($defaults['chunk_parallelism'] ?? 1),
                         'per_domain_concurrency' => (int) ($defaults['per_domain_concurrency'] ?? 2),
                         'catch_all_detection_enabled' => (bool) ($defaults['catch_all_detection_enabled'] ?? false),
                         'global_connects_per_minute' => $defaults['global_connects_per_minute'] ?? null,